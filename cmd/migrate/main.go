@@ -0,0 +1,215 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command migrate converts v1alpha1 AdminNetworkPolicy and
+// BaselineAdminNetworkPolicy manifests to v1alpha2 ClusterNetworkPolicy
+// manifests.
+//
+//	go run ./cmd/migrate [-output cnps.yaml] [-check=false] anps.yaml banp.yaml ...
+//
+// Problems are printed to stderr, and the command exits with status 1 if any
+// policy can't be converted faithfully.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha2"
+	"sigs.k8s.io/network-policy-api/pkg/migrate"
+)
+
+// maxDifferences is the number of connectivity differences printed per policy.
+const maxDifferences = 10
+
+var (
+	output = flag.String("output", "", "file to write the ClusterNetworkPolicies to. If unset, they're written to stdout")
+	check  = flag.Bool("check", true, "simulate each policy before and after conversion against a sample cluster, and report connections which it decides differently")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] FILE...\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var anps []*v1alpha1.AdminNetworkPolicy
+	var banp *v1alpha1.BaselineAdminNetworkPolicy
+	for _, path := range flag.Args() {
+		fileANPs, fileBANP, err := readPolicies(path)
+		if err != nil {
+			log.Fatalf("failed to read %s: %s", path, err)
+		}
+		anps = append(anps, fileANPs...)
+		if fileBANP != nil {
+			if banp != nil {
+				log.Fatalf("%s: found a second BaselineAdminNetworkPolicy", path)
+			}
+			banp = fileBANP
+		}
+	}
+
+	cnps, issues := migrate.Convert(anps, banp)
+	for _, issue := range issues {
+		fmt.Fprintln(os.Stderr, issue)
+	}
+	failed := len(issues) > 0
+
+	if *check {
+		// Each policy is simulated on its own: the conversion keeps names and
+		// priorities, so policies which are equivalent one by one are
+		// equivalent as a set, and the sample cluster stays small.
+		for i, anp := range anps {
+			if !checkConnectivity("AdminNetworkPolicy/"+anp.Name, []*v1alpha1.AdminNetworkPolicy{anp}, nil, cnps[i]) {
+				failed = true
+			}
+		}
+		if banp != nil {
+			if !checkConnectivity("BaselineAdminNetworkPolicy/"+banp.Name, nil, banp, cnps[len(cnps)-1]) {
+				failed = true
+			}
+		}
+	}
+
+	if err := writePolicies(cnps); err != nil {
+		log.Fatalf("failed to write ClusterNetworkPolicies: %s", err)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func checkConnectivity(name string, anps []*v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy, cnp *v1alpha2.ClusterNetworkPolicy) bool {
+	differences, err := migrate.CompareConnectivity(migrate.SampleCluster(anps, banp), anps, banp, []*v1alpha2.ClusterNetworkPolicy{cnp})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: unable to simulate: %s\n", name, err)
+		return false
+	}
+	for i, difference := range differences {
+		if i == maxDifferences {
+			fmt.Fprintf(os.Stderr, "%s: %d more connectivity differences\n", name, len(differences)-maxDifferences)
+			break
+		}
+		fmt.Fprintf(os.Stderr, "%s: connectivity differs: %s\n", name, difference)
+	}
+	return len(differences) == 0
+}
+
+// readPolicies reads the AdminNetworkPolicies and BaselineAdminNetworkPolicy
+// of a YAML or JSON file, which may hold several documents and lists. Other
+// kinds are skipped.
+func readPolicies(path string) ([]*v1alpha1.AdminNetworkPolicy, *v1alpha1.BaselineAdminNetworkPolicy, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var anps []*v1alpha1.AdminNetworkPolicy
+	var banp *v1alpha1.BaselineAdminNetworkPolicy
+	decoder := utilyaml.NewYAMLOrJSONDecoder(file, 4096)
+	for {
+		var document map[string]interface{}
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				return anps, banp, nil
+			}
+			return nil, nil, err
+		}
+		if document == nil {
+			continue
+		}
+		data, err := yaml.Marshal(document)
+		if err != nil {
+			return nil, nil, err
+		}
+		var typeMeta metav1.TypeMeta
+		if err := yaml.Unmarshal(data, &typeMeta); err != nil {
+			return nil, nil, err
+		}
+		if typeMeta.APIVersion != v1alpha1.GroupVersion.String() {
+			continue
+		}
+		switch typeMeta.Kind {
+		case "AdminNetworkPolicy":
+			anp := &v1alpha1.AdminNetworkPolicy{}
+			if err := yaml.UnmarshalStrict(data, anp); err != nil {
+				return nil, nil, err
+			}
+			anps = append(anps, anp)
+		case "AdminNetworkPolicyList":
+			list := &v1alpha1.AdminNetworkPolicyList{}
+			if err := yaml.UnmarshalStrict(data, list); err != nil {
+				return nil, nil, err
+			}
+			for i := range list.Items {
+				anps = append(anps, &list.Items[i])
+			}
+		case "BaselineAdminNetworkPolicy":
+			if banp != nil {
+				return nil, nil, errors.New("found a second BaselineAdminNetworkPolicy")
+			}
+			banp = &v1alpha1.BaselineAdminNetworkPolicy{}
+			if err := yaml.UnmarshalStrict(data, banp); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+}
+
+func writePolicies(cnps []*v1alpha2.ClusterNetworkPolicy) error {
+	buffer := &bytes.Buffer{}
+	for i, cnp := range cnps {
+		if i > 0 {
+			buffer.WriteString("---\n")
+		}
+		object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cnp)
+		if err != nil {
+			return err
+		}
+		// Leave out the empty status and creation timestamp, which aren't part
+		// of a manifest.
+		delete(object, "status")
+		unstructured.RemoveNestedField(object, "metadata", "creationTimestamp")
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return err
+		}
+		buffer.Write(data)
+	}
+	if *output == "" {
+		_, err := os.Stdout.Write(buffer.Bytes())
+		return err
+	}
+	return os.WriteFile(*output, buffer.Bytes(), 0o644)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command migration-controller keeps a v1alpha2 ClusterNetworkPolicy
+// equivalent to each v1alpha1 AdminNetworkPolicy and
+// BaselineAdminNetworkPolicy of a cluster, and reports the outcome in a
+// Migrated condition of the v1alpha1 policies. See config/migration-controller
+// for its deployment.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/network-policy-api/pkg/client/clientset/versioned"
+	"sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions"
+	"sigs.k8s.io/network-policy-api/pkg/migrate"
+)
+
+var (
+	kubeConfig = flag.String("kubeConfig", "", "KUBE_CONFIG for the cluster to use. If unset, the in-cluster configuration is used")
+	workers    = flag.Int("workers", 2, "number of policies to convert concurrently")
+	resync     = flag.Duration("resync", 10*time.Minute, "interval at which all policies are converted again")
+)
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeConfig)
+	if err != nil {
+		klog.Fatalf("Failed to load the client configuration: %v", err)
+	}
+	client, err := versioned.NewForConfig(config)
+	if err != nil {
+		klog.Fatalf("Failed to create the client: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	informers := externalversions.NewSharedInformerFactory(client, *resync)
+	controller, err := migrate.NewController(client, informers)
+	if err != nil {
+		klog.Fatalf("Failed to create the controller: %v", err)
+	}
+	informers.Start(ctx.Done())
+	defer informers.Shutdown()
+
+	if err := controller.Run(ctx, *workers); err != nil {
+		klog.Fatalf("Controller failed: %v", err)
+	}
+}
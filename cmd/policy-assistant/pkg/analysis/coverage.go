@@ -0,0 +1,214 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/olekukonko/tablewriter"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+)
+
+const (
+	DirectionIngress = "ingress"
+	DirectionEgress  = "egress"
+)
+
+// RuleID identifies a single rule of an ANP, BANP, or v1 NetPol.
+// ANP and BANP rules are identified by name.  v1 NetPol rules don't have names,
+// so they are identified by their (1-based) position in the ingress or egress list.
+type RuleID struct {
+	Kind      matcher.PolicyKind
+	Policy    string
+	Direction string
+	Rule      string
+}
+
+func (r RuleID) String() string {
+	return fmt.Sprintf("[%s] %s %s/%s", r.Kind, r.Policy, r.Direction, r.Rule)
+}
+
+type RuleHits struct {
+	Rule RuleID
+	Hits int
+}
+
+// RuleCoverage counts how often each rule decided the verdict for a corpus of traffic.
+// A rule is only counted when it determines the outcome: e.g. an ANP rule shadowed by a
+// higher priority ANP rule, or a BANP rule for traffic already allowed by a v1 NetPol, is not hit.
+// ANP Pass rules are counted, as well as the rules which then decide the traffic.
+type RuleCoverage struct {
+	Flows int
+	Rules []*RuleHits
+	// DefaultAllowed contains traffic which was allowed without any rule allowing it, in both directions
+	DefaultAllowed []*matcher.Traffic
+
+	policy    *matcher.Policy
+	v1Rules   []*v1RulePolicy
+	rulesByID map[RuleID]*RuleHits
+}
+
+// v1RulePolicy is a single v1 NetPol rule, built as its own Policy.  v1 NetPols
+// sharing a subject are combined into one Target, so that their rules can't be
+// told apart from the DirectionResult; evaluating each rule on its own attributes
+// allowed traffic to every rule allowing it.
+type v1RulePolicy struct {
+	Rule   RuleID
+	Policy *matcher.Policy
+}
+
+func NewRuleCoverage(netpols []*networkingv1.NetworkPolicy, anps []*v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy) *RuleCoverage {
+	c := &RuleCoverage{
+		policy:    matcher.BuildV1AndV2NetPols(false, netpols, anps, banp),
+		rulesByID: map[RuleID]*RuleHits{},
+	}
+
+	for _, netpol := range netpols {
+		policyName := fmt.Sprintf("%s/%s", netpol.Namespace, netpol.Name)
+		for i, rule := range netpol.Spec.Ingress {
+			single := netpol.DeepCopy()
+			single.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+			single.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{rule}
+			c.addV1Rule(RuleID{Kind: matcher.NetworkPolicyV1, Policy: policyName, Direction: DirectionIngress, Rule: fmt.Sprintf("rule %d", i+1)}, single)
+		}
+		for i, rule := range netpol.Spec.Egress {
+			single := netpol.DeepCopy()
+			single.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}
+			single.Spec.Egress = []networkingv1.NetworkPolicyEgressRule{rule}
+			c.addV1Rule(RuleID{Kind: matcher.NetworkPolicyV1, Policy: policyName, Direction: DirectionEgress, Rule: fmt.Sprintf("rule %d", i+1)}, single)
+		}
+	}
+
+	for _, anp := range anps {
+		for _, rule := range anp.Spec.Ingress {
+			c.addRule(RuleID{Kind: matcher.AdminNetworkPolicy, Policy: anp.Name, Direction: DirectionIngress, Rule: rule.Name})
+		}
+		for _, rule := range anp.Spec.Egress {
+			c.addRule(RuleID{Kind: matcher.AdminNetworkPolicy, Policy: anp.Name, Direction: DirectionEgress, Rule: rule.Name})
+		}
+	}
+
+	if banp != nil {
+		for _, rule := range banp.Spec.Ingress {
+			c.addRule(RuleID{Kind: matcher.BaselineAdminNetworkPolicy, Policy: banp.Name, Direction: DirectionIngress, Rule: rule.Name})
+		}
+		for _, rule := range banp.Spec.Egress {
+			c.addRule(RuleID{Kind: matcher.BaselineAdminNetworkPolicy, Policy: banp.Name, Direction: DirectionEgress, Rule: rule.Name})
+		}
+	}
+
+	return c
+}
+
+func (c *RuleCoverage) addRule(rule RuleID) {
+	if _, ok := c.rulesByID[rule]; ok {
+		// e.g. duplicate rule names in one ANP
+		return
+	}
+	hits := &RuleHits{Rule: rule}
+	c.rulesByID[rule] = hits
+	c.Rules = append(c.Rules, hits)
+}
+
+func (c *RuleCoverage) addV1Rule(rule RuleID, netpol *networkingv1.NetworkPolicy) {
+	c.addRule(rule)
+	c.v1Rules = append(c.v1Rules, &v1RulePolicy{Rule: rule, Policy: matcher.BuildNetworkPolicies(false, []*networkingv1.NetworkPolicy{netpol})})
+}
+
+// Add records the rules deciding the verdict for the traffic.
+func (c *RuleCoverage) Add(traffic *matcher.Traffic) {
+	c.Flows++
+	result := c.policy.IsTrafficAllowed(traffic)
+	ingressDefault := c.addDirection(traffic, result.Ingress, DirectionIngress)
+	egressDefault := c.addDirection(traffic, result.Egress, DirectionEgress)
+	if result.IsAllowed() && ingressDefault && egressDefault {
+		c.DefaultAllowed = append(c.DefaultAllowed, traffic)
+	}
+}
+
+// addDirection returns true if no rule explicitly allowed or denied the traffic in this direction.
+func (c *RuleCoverage) addDirection(traffic *matcher.Traffic, result matcher.DirectionResult, direction string) bool {
+	anp, npv1, banp := result.Resolve()
+	isDefault := true
+
+	if anp != nil && anp.Verdict != matcher.None {
+		c.hit(RuleID{Kind: matcher.AdminNetworkPolicy, Policy: anp.PolicyName, Direction: direction, Rule: anp.RuleName})
+		isDefault = anp.Verdict == matcher.Pass
+	}
+
+	if npv1 != nil {
+		isDefault = false
+		if npv1.Verdict == matcher.Allow {
+			for _, v1Rule := range c.v1Rules {
+				if v1Rule.Rule.Direction != direction {
+					continue
+				}
+				effects := v1Rule.Policy.IsIngressOrEgressAllowed(traffic, direction == DirectionIngress)
+				if slice.Any(func(e matcher.Effect) bool { return e.Verdict == matcher.Allow }, effects) {
+					c.hit(v1Rule.Rule)
+				}
+			}
+		}
+	}
+
+	if banp != nil && banp.Verdict != matcher.None {
+		c.hit(RuleID{Kind: matcher.BaselineAdminNetworkPolicy, Policy: banp.PolicyName, Direction: direction, Rule: banp.RuleName})
+		isDefault = false
+	}
+
+	return isDefault
+}
+
+func (c *RuleCoverage) hit(rule RuleID) {
+	if _, ok := c.rulesByID[rule]; !ok {
+		c.addRule(rule)
+	}
+	c.rulesByID[rule].Hits++
+}
+
+// UnusedRules returns the rules which never decided the verdict for any traffic.  These are candidates for removal.
+func (c *RuleCoverage) UnusedRules() []RuleID {
+	var unused []RuleID
+	for _, rule := range c.Rules {
+		if rule.Hits == 0 {
+			unused = append(unused, rule.Rule)
+		}
+	}
+	return unused
+}
+
+func (c *RuleCoverage) RenderTable() string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"Type", "Policy", "Direction", "Rule", "Hits"})
+	for _, rule := range c.Rules {
+		hits := fmt.Sprintf("%d", rule.Hits)
+		if rule.Hits == 0 {
+			hits = "0 (unused)"
+		}
+		table.Append([]string{string(rule.Rule.Kind), rule.Rule.Policy, rule.Rule.Direction, rule.Rule.Rule, hits})
+	}
+	table.SetFooter([]string{"", "", "", "Flows", fmt.Sprintf("%d", c.Flows)})
+
+	table.Render()
+	return tableString.String()
+}
+
+func (c *RuleCoverage) RenderDefaultAllowed() string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+
+	table.SetHeader([]string{"Traffic allowed without any policy"})
+	for _, traffic := range c.DefaultAllowed {
+		table.Append([]string{traffic.PrettyString()})
+	}
+
+	table.Render()
+	return tableString.String()
+}
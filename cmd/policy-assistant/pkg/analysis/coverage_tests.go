@@ -0,0 +1,159 @@
+package analysis
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/utils"
+)
+
+func RunCoverageTests() {
+	anpYaml := `
+apiVersion: policy.networking.k8s.io/v1alpha1
+kind: AdminNetworkPolicy
+metadata:
+  name: anp
+spec:
+  priority: 1
+  subject:
+    namespaces:
+      matchLabels:
+        tenant: b
+  ingress:
+  - name: allow-80
+    action: Allow
+    from:
+    - namespaces:
+        namespaceSelector: {}
+    ports:
+    - portNumber:
+        protocol: TCP
+        port: 80
+  - name: pass-81
+    action: Pass
+    from:
+    - namespaces:
+        namespaceSelector: {}
+    ports:
+    - portNumber:
+        protocol: TCP
+        port: 81
+  - name: deny-82
+    action: Deny
+    from:
+    - namespaces:
+        namespaceSelector: {}
+    ports:
+    - portNumber:
+        protocol: TCP
+        port: 82`
+	banpYaml := `
+apiVersion: policy.networking.k8s.io/v1alpha1
+kind: BaselineAdminNetworkPolicy
+metadata:
+  name: default
+spec:
+  subject:
+    namespaces:
+      matchLabels:
+        tenant: b
+  ingress:
+  - name: baseline-deny
+    action: Deny
+    from:
+    - namespaces:
+        namespaceSelector: {}`
+	netpolYaml := `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-81
+  namespace: b1
+spec:
+  podSelector: {}
+  policyTypes:
+  - Ingress
+  ingress:
+  - ports:
+    - port: 81
+  - ports:
+    - port: 81
+    from:
+    - namespaceSelector:
+        matchLabels:
+          tenant: a
+  - ports:
+    - port: 9000`
+	anp, err := utils.ParseYaml[v1alpha1.AdminNetworkPolicy]([]byte(anpYaml))
+	utils.DoOrDie(err)
+	banp, err := utils.ParseYaml[v1alpha1.BaselineAdminNetworkPolicy]([]byte(banpYaml))
+	utils.DoOrDie(err)
+	netpol, err := utils.ParseYaml[networkingv1.NetworkPolicy]([]byte(netpolYaml))
+	utils.DoOrDie(err)
+
+	traffic := func(fromNs string, fromTenant string, toNs string, toTenant string, port int) *matcher.Traffic {
+		return &matcher.Traffic{
+			Source: &matcher.TrafficPeer{
+				Internal: &matcher.InternalPeer{Namespace: fromNs, NamespaceLabels: map[string]string{"tenant": fromTenant}},
+				IP:       "10.0.0.1",
+			},
+			Destination: &matcher.TrafficPeer{
+				Internal: &matcher.InternalPeer{Namespace: toNs, NamespaceLabels: map[string]string{"tenant": toTenant}},
+				IP:       "10.0.0.2",
+			},
+			ResolvedPort: port,
+			Protocol:     v1.ProtocolTCP,
+		}
+	}
+
+	hits := func(coverage *RuleCoverage) map[string]int {
+		counts := map[string]int{}
+		for _, rule := range coverage.Rules {
+			counts[rule.Rule.String()] = rule.Hits
+		}
+		return counts
+	}
+
+	Describe("RuleCoverage", func() {
+		It("should attribute traffic to the rules deciding its verdict", func() {
+			coverage := NewRuleCoverage([]*networkingv1.NetworkPolicy{netpol}, []*v1alpha1.AdminNetworkPolicy{anp}, banp)
+			coverage.Add(traffic("a1", "a", "b1", "b", 80))
+			coverage.Add(traffic("a1", "a", "b1", "b", 80))
+			coverage.Add(traffic("a1", "a", "b1", "b", 81))
+			coverage.Add(traffic("a1", "a", "b1", "b", 83))
+
+			Expect(coverage.Flows).To(Equal(4))
+			Expect(hits(coverage)).To(Equal(map[string]int{
+				"[ANP] anp ingress/allow-80":           2,
+				"[ANP] anp ingress/pass-81":            1,
+				"[ANP] anp ingress/deny-82":            0,
+				"[BANP] default ingress/baseline-deny": 0,
+				"[NPv1] b1/allow-81 ingress/rule 1":    1,
+				"[NPv1] b1/allow-81 ingress/rule 2":    1,
+				"[NPv1] b1/allow-81 ingress/rule 3":    0,
+			}))
+			Expect(coverage.UnusedRules()).To(HaveLen(3))
+			Expect(coverage.DefaultAllowed).To(BeEmpty())
+		})
+
+		It("should count BANP rules once no ANP or v1 NetPol applies", func() {
+			coverage := NewRuleCoverage(nil, []*v1alpha1.AdminNetworkPolicy{anp}, banp)
+			coverage.Add(traffic("a1", "a", "b1", "b", 81))
+			coverage.Add(traffic("a1", "a", "b1", "b", 83))
+
+			Expect(hits(coverage)).To(HaveKeyWithValue("[BANP] default ingress/baseline-deny", 2))
+			Expect(hits(coverage)).To(HaveKeyWithValue("[ANP] anp ingress/pass-81", 1))
+		})
+
+		It("should find traffic allowed without any policy", func() {
+			coverage := NewRuleCoverage(nil, []*v1alpha1.AdminNetworkPolicy{anp}, banp)
+			coverage.Add(traffic("b1", "b", "a1", "a", 80))
+			coverage.Add(traffic("a1", "a", "b1", "b", 80))
+
+			Expect(coverage.DefaultAllowed).To(Equal([]*matcher.Traffic{traffic("b1", "b", "a1", "a", 80)}))
+		})
+	})
+}
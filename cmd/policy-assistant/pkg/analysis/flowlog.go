@@ -0,0 +1,127 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/flowlog"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+)
+
+// FlowResolver maps the IPs of observed flows to pods, using a snapshot of the cluster.
+// IPs which don't belong to any pod are treated as external to the cluster.
+type FlowResolver struct {
+	resources *probe.Resources
+	podsByIP  map[string]*probe.Pod
+}
+
+func NewFlowResolver(resources *probe.Resources) *FlowResolver {
+	podsByIP := map[string]*probe.Pod{}
+	for _, pod := range resources.Pods {
+		if pod.IP != "" {
+			podsByIP[pod.IP] = pod
+		}
+	}
+	return &FlowResolver{resources: resources, podsByIP: podsByIP}
+}
+
+func (r *FlowResolver) peer(ip string) *matcher.TrafficPeer {
+	pod, ok := r.podsByIP[ip]
+	if !ok {
+		return &matcher.TrafficPeer{IP: ip}
+	}
+	return &matcher.TrafficPeer{
+		Internal: &matcher.InternalPeer{
+			PodLabels:       pod.Labels,
+			NamespaceLabels: r.resources.Namespaces[pod.Namespace],
+			Namespace:       pod.Namespace,
+		},
+		IP: ip,
+	}
+}
+
+// Traffic converts a flow to traffic.  If the destination is a known pod, the port name is resolved from its containers.
+func (r *FlowResolver) Traffic(flow *flowlog.Flow) *matcher.Traffic {
+	traffic := &matcher.Traffic{
+		Source:       r.peer(flow.SourceIP),
+		Destination:  r.peer(flow.DestinationIP),
+		ResolvedPort: flow.DestinationPort,
+		Protocol:     flow.Protocol,
+	}
+	if pod, ok := r.podsByIP[flow.DestinationIP]; ok {
+		for _, cont := range pod.Containers {
+			if cont.Port == flow.DestinationPort && cont.Protocol == flow.Protocol {
+				traffic.ResolvedPortName = cont.PortName
+			}
+		}
+	}
+	return traffic
+}
+
+// IsKnown returns true if at least one end of the flow is a pod in the snapshot.
+func (r *FlowResolver) IsKnown(flow *flowlog.Flow) bool {
+	_, sourceOk := r.podsByIP[flow.SourceIP]
+	_, destinationOk := r.podsByIP[flow.DestinationIP]
+	return sourceOk || destinationOk
+}
+
+type FlowDiscrepancy struct {
+	Flow      *flowlog.Flow
+	Traffic   *matcher.Traffic
+	Simulated *matcher.AllowedResult
+}
+
+// FlowLogReport compares verdicts enforced by the dataplane with simulated verdicts.
+type FlowLogReport struct {
+	Flows int
+	// Unknown counts flows for which neither IP belongs to a pod in the snapshot; these aren't evaluated
+	Unknown       int
+	Discrepancies []*FlowDiscrepancy
+}
+
+func NewFlowLogReport(policies *matcher.Policy, resolver *FlowResolver, flows []*flowlog.Flow) *FlowLogReport {
+	report := &FlowLogReport{Flows: len(flows)}
+	for _, flow := range flows {
+		if !resolver.IsKnown(flow) {
+			report.Unknown++
+			continue
+		}
+		traffic := resolver.Traffic(flow)
+		result := policies.IsTrafficAllowed(traffic)
+		if result.IsAllowed() != (flow.Verdict == flowlog.VerdictAllowed) {
+			report.Discrepancies = append(report.Discrepancies, &FlowDiscrepancy{Flow: flow, Traffic: traffic, Simulated: result})
+		}
+	}
+	return report
+}
+
+func (r *FlowLogReport) RenderSummary() string {
+	evaluated := r.Flows - r.Unknown
+	return fmt.Sprintf("%d flows: %d evaluated, %d with unknown IPs; %d discrepancies", r.Flows, evaluated, r.Unknown, len(r.Discrepancies))
+}
+
+func (r *FlowLogReport) RenderTable() string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"Line", "Flow", "Traffic", "Dataplane", "Simulated", "Ingress Walkthrough", "Egress Walkthrough"})
+	for _, d := range r.Discrepancies {
+		ingressFlow := d.Simulated.Ingress.Flow()
+		egressFlow := d.Simulated.Egress.Flow()
+		if ingressFlow == "" {
+			ingressFlow = "no policies targeting ingress"
+		}
+		if egressFlow == "" {
+			egressFlow = "no policies targeting egress"
+		}
+		flow := fmt.Sprintf("%s -> %s:%d (%s)", d.Flow.SourceIP, d.Flow.DestinationIP, d.Flow.DestinationPort, d.Flow.Protocol)
+		table.Append([]string{fmt.Sprintf("%d", d.Flow.Line), flow, d.Traffic.PrettyString(), string(d.Flow.Verdict), d.Simulated.Verdict(), ingressFlow, egressFlow})
+	}
+
+	table.Render()
+	return tableString.String()
+}
@@ -0,0 +1,57 @@
+package analysis
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/flowlog"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+)
+
+func RunFlowLogTests() {
+	Describe("FlowLogReport", func() {
+		denyAll := &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "b1", Name: "deny-all"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			},
+		}
+		policies := matcher.BuildNetworkPolicies(true, []*networkingv1.NetworkPolicy{denyAll})
+		resolver := NewFlowResolver(tenancyTestResources())
+
+		flow := func(source string, destination string, verdict flowlog.Verdict) *flowlog.Flow {
+			return &flowlog.Flow{SourceIP: source, DestinationIP: destination, DestinationPort: 80, Protocol: v1.ProtocolTCP, Verdict: verdict}
+		}
+
+		It("should map IPs to pods", func() {
+			traffic := resolver.Traffic(flow("10.0.0.1", "192.168.0.1", flowlog.VerdictAllowed))
+			Expect(traffic.Source.Internal.Namespace).To(Equal("a1"))
+			Expect(traffic.Source.Internal.NamespaceLabels).To(Equal(map[string]string{"tenant": "a"}))
+			Expect(traffic.Destination.Internal).To(BeNil())
+
+			Expect(resolver.Traffic(flow("10.0.0.1", "10.0.0.3", flowlog.VerdictAllowed)).ResolvedPortName).To(Equal("serve-80-tcp"))
+		})
+
+		It("should report flows where the dataplane disagrees with the simulation", func() {
+			report := NewFlowLogReport(policies, resolver, []*flowlog.Flow{
+				flow("10.0.0.1", "10.0.0.2", flowlog.VerdictAllowed),
+				flow("10.0.0.1", "10.0.0.3", flowlog.VerdictDenied),
+				// stale enforcement: b1 should deny all ingress
+				flow("10.0.0.2", "10.0.0.3", flowlog.VerdictAllowed),
+				// bug: nothing should deny this
+				flow("10.0.0.3", "10.0.0.1", flowlog.VerdictDenied),
+				flow("192.168.0.1", "192.168.0.2", flowlog.VerdictDenied),
+			})
+
+			Expect(report.Flows).To(Equal(5))
+			Expect(report.Unknown).To(Equal(1))
+			Expect(report.Discrepancies).To(HaveLen(2))
+			Expect(report.Discrepancies[0].Flow.SourceIP).To(Equal("10.0.0.2"))
+			Expect(report.Discrepancies[0].Simulated.IsAllowed()).To(BeFalse())
+			Expect(report.Discrepancies[1].Flow.SourceIP).To(Equal("10.0.0.3"))
+			Expect(report.Discrepancies[1].Simulated.IsAllowed()).To(BeTrue())
+		})
+	})
+}
@@ -0,0 +1,17 @@
+package analysis
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAnalysis(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunTenancyTests()
+	RunWhatIfTests()
+	RunCoverageTests()
+	RunFlowLogTests()
+	RunSpecs(t, "analysis suite")
+}
@@ -0,0 +1,173 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/olekukonko/tablewriter"
+	"golang.org/x/exp/maps"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+)
+
+// TenantIsolationReport groups namespaces into tenants by the value of a single namespace label,
+// and records for every ordered pair of distinct tenants whether any traffic is able to cross between them.
+type TenantIsolationReport struct {
+	TenantLabel string
+	// Tenants maps a tenant (label value) to its sorted namespaces
+	Tenants map[string][]string
+	// UnassignedNamespaces do not have the tenant label and are left out of the report
+	UnassignedNamespaces []string
+	Pairs                []*TenantPair
+}
+
+// TenantPair summarizes traffic from one tenant to another.
+// Example is the first allowed flow, if any; otherwise it's the first denied flow, so that
+// the responsible rules show why the tenants are isolated.
+type TenantPair struct {
+	From          string
+	To            string
+	CheckedFlows  int
+	AllowedFlows  int
+	Example       *matcher.Traffic
+	ExampleResult *matcher.AllowedResult
+}
+
+// IsChecked is false if no flows between the tenants were found -- e.g. because one of them has no pods
+// with container ports -- so that nothing is known about whether they're isolated.
+func (p *TenantPair) IsChecked() bool {
+	return p.CheckedFlows > 0
+}
+
+func (p *TenantPair) IsIsolated() bool {
+	return p.IsChecked() && p.AllowedFlows == 0
+}
+
+// NewTenantIsolationReport evaluates traffic from every pod to every container port of every other pod,
+// wherever the two pods belong to different tenants.
+func NewTenantIsolationReport(policies *matcher.Policy, resources *probe.Resources, tenantLabel string) *TenantIsolationReport {
+	report := &TenantIsolationReport{
+		TenantLabel: tenantLabel,
+		Tenants:     map[string][]string{},
+	}
+	tenantOf := map[string]string{}
+	for _, ns := range slice.Sort(maps.Keys(resources.Namespaces)) {
+		tenant, ok := resources.Namespaces[ns][tenantLabel]
+		if !ok {
+			report.UnassignedNamespaces = append(report.UnassignedNamespaces, ns)
+			continue
+		}
+		tenantOf[ns] = tenant
+		report.Tenants[tenant] = append(report.Tenants[tenant], ns)
+	}
+
+	pairs := map[string]*TenantPair{}
+	tenants := slice.Sort(maps.Keys(report.Tenants))
+	for _, from := range tenants {
+		for _, to := range tenants {
+			if from == to {
+				continue
+			}
+			pair := &TenantPair{From: from, To: to}
+			pairs[tenantPairKey(from, to)] = pair
+			report.Pairs = append(report.Pairs, pair)
+		}
+	}
+
+	jobBuilder := &probe.JobBuilder{TimeoutSeconds: 10}
+	jobs := jobBuilder.GetJobsAllAvailableServers(resources, generator.ProbeModeServiceName)
+	for _, job := range slice.SortOn(func(j *probe.Job) string { return j.Key() }, jobs.Valid) {
+		fromTenant, fromOk := tenantOf[job.FromNamespace]
+		toTenant, toOk := tenantOf[job.ToNamespace]
+		if !fromOk || !toOk || fromTenant == toTenant {
+			continue
+		}
+		pair := pairs[tenantPairKey(fromTenant, toTenant)]
+		traffic := job.Traffic()
+		result := policies.IsTrafficAllowed(traffic)
+
+		pair.CheckedFlows++
+		if result.IsAllowed() {
+			if pair.AllowedFlows == 0 {
+				pair.Example, pair.ExampleResult = traffic, result
+			}
+			pair.AllowedFlows++
+		} else if pair.Example == nil {
+			pair.Example, pair.ExampleResult = traffic, result
+		}
+	}
+
+	return report
+}
+
+func tenantPairKey(from string, to string) string {
+	return from + "/" + to
+}
+
+// CrossTenantPairs returns the pairs for which at least one flow is allowed.
+func (r *TenantIsolationReport) CrossTenantPairs() []*TenantPair {
+	return slice.Filter(func(p *TenantPair) bool { return p.AllowedFlows > 0 }, r.Pairs)
+}
+
+// UncheckedPairs returns the pairs for which no flows were found.
+func (r *TenantIsolationReport) UncheckedPairs() []*TenantPair {
+	return slice.Filter(func(p *TenantPair) bool { return !p.IsChecked() }, r.Pairs)
+}
+
+// IsIsolated is true if every pair of tenants was checked and found to be isolated.
+func (r *TenantIsolationReport) IsIsolated() bool {
+	return slice.All(func(p *TenantPair) bool { return p.IsIsolated() }, r.Pairs)
+}
+
+func (r *TenantIsolationReport) RenderTenants() string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{fmt.Sprintf("Tenant (%s)", r.TenantLabel), "Namespaces"})
+	for _, tenant := range slice.Sort(maps.Keys(r.Tenants)) {
+		table.Append([]string{tenant, strings.Join(r.Tenants[tenant], "\n")})
+	}
+	if len(r.UnassignedNamespaces) > 0 {
+		table.Append([]string{"(no tenant)", strings.Join(r.UnassignedNamespaces, "\n")})
+	}
+
+	table.Render()
+	return tableString.String()
+}
+
+func (r *TenantIsolationReport) RenderTable() string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"From", "To", "Cross-Tenant Traffic", "Example Flow", "Ingress Walkthrough", "Egress Walkthrough"})
+	for _, pair := range r.Pairs {
+		status := "isolated"
+		if !pair.IsChecked() {
+			status = "unchecked"
+		} else if !pair.IsIsolated() {
+			status = fmt.Sprintf("possible (%d/%d flows allowed)", pair.AllowedFlows, pair.CheckedFlows)
+		}
+		example, ingressFlow, egressFlow := "no flows checked", "", ""
+		if pair.Example != nil {
+			example = pair.Example.PrettyString()
+			ingressFlow = pair.ExampleResult.Ingress.Flow()
+			egressFlow = pair.ExampleResult.Egress.Flow()
+			if ingressFlow == "" {
+				ingressFlow = "no policies targeting ingress"
+			}
+			if egressFlow == "" {
+				egressFlow = "no policies targeting egress"
+			}
+		}
+		table.Append([]string{pair.From, pair.To, status, example, ingressFlow, egressFlow})
+	}
+
+	table.Render()
+	return tableString.String()
+}
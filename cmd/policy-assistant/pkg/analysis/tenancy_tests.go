@@ -0,0 +1,110 @@
+package analysis
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+)
+
+func tenancyTestResources() *probe.Resources {
+	newPod := func(ns string, ip string) *probe.Pod {
+		return &probe.Pod{
+			Namespace:  ns,
+			Name:       "a",
+			Labels:     map[string]string{"pod": "a"},
+			IP:         ip,
+			Containers: []*probe.Container{{Name: "cont-80-tcp", Port: 80, Protocol: v1.ProtocolTCP, PortName: "serve-80-tcp"}},
+		}
+	}
+	return &probe.Resources{
+		Namespaces: map[string]map[string]string{
+			"a1":          {"tenant": "a"},
+			"a2":          {"tenant": "a"},
+			"b1":          {"tenant": "b"},
+			"kube-system": {},
+		},
+		Pods: []*probe.Pod{
+			newPod("a1", "10.0.0.1"),
+			newPod("a2", "10.0.0.2"),
+			newPod("b1", "10.0.0.3"),
+			newPod("kube-system", "10.0.0.4"),
+		},
+	}
+}
+
+func RunTenancyTests() {
+	Describe("TenantIsolationReport", func() {
+		It("should group namespaces by tenant label", func() {
+			report := NewTenantIsolationReport(matcher.BuildNetworkPolicies(true, nil), tenancyTestResources(), "tenant")
+			Expect(report.Tenants).To(Equal(map[string][]string{"a": {"a1", "a2"}, "b": {"b1"}}))
+			Expect(report.UnassignedNamespaces).To(Equal([]string{"kube-system"}))
+		})
+
+		It("should find cross-tenant traffic when no policies exist", func() {
+			report := NewTenantIsolationReport(matcher.BuildNetworkPolicies(true, nil), tenancyTestResources(), "tenant")
+			Expect(report.Pairs).To(HaveLen(2))
+			Expect(report.IsIsolated()).To(BeFalse())
+
+			aToB := report.Pairs[0]
+			Expect(aToB.From).To(Equal("a"))
+			Expect(aToB.To).To(Equal("b"))
+			Expect(aToB.CheckedFlows).To(Equal(2))
+			Expect(aToB.AllowedFlows).To(Equal(2))
+			Expect(aToB.Example.Source.Internal.Namespace).To(Equal("a1"))
+			Expect(aToB.Example.Destination.Internal.Namespace).To(Equal("b1"))
+		})
+
+		It("should report a tenant as isolated when its ingress is denied", func() {
+			denyAll := &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "b1", Name: "deny-all"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				},
+			}
+			policies := matcher.BuildNetworkPolicies(true, []*networkingv1.NetworkPolicy{denyAll})
+			report := NewTenantIsolationReport(policies, tenancyTestResources(), "tenant")
+
+			Expect(report.IsIsolated()).To(BeFalse())
+			Expect(report.CrossTenantPairs()).To(HaveLen(1))
+			Expect(report.CrossTenantPairs()[0].From).To(Equal("b"))
+
+			aToB := report.Pairs[0]
+			Expect(aToB.IsIsolated()).To(BeTrue())
+			Expect(aToB.Example).ToNot(BeNil())
+			Expect(aToB.ExampleResult.Ingress.Flow()).To(ContainSubstring("b1/deny-all"))
+		})
+
+		It("should report pairs without flows as unchecked, rather than isolated", func() {
+			resources := tenancyTestResources()
+			resources.Namespaces["c1"] = map[string]string{"tenant": "c"}
+			report := NewTenantIsolationReport(matcher.BuildNetworkPolicies(true, nil), resources, "tenant")
+
+			Expect(report.Pairs).To(HaveLen(6))
+			unchecked := report.UncheckedPairs()
+			Expect(unchecked).To(HaveLen(4))
+			for _, pair := range unchecked {
+				Expect(pair.From == "c" || pair.To == "c").To(BeTrue())
+				Expect(pair.IsIsolated()).To(BeFalse())
+			}
+			Expect(report.CrossTenantPairs()).To(HaveLen(2))
+			Expect(report.RenderTable()).To(ContainSubstring("unchecked"))
+
+			// denying all traffic between a and b leaves the pairs with c unchecked
+			denyAll := func(ns string) *networkingv1.NetworkPolicy {
+				return &networkingv1.NetworkPolicy{
+					ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "deny-all"},
+					Spec:       networkingv1.NetworkPolicySpec{PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}},
+				}
+			}
+			policies := matcher.BuildNetworkPolicies(true, []*networkingv1.NetworkPolicy{denyAll("a1"), denyAll("a2"), denyAll("b1")})
+			report = NewTenantIsolationReport(policies, resources, "tenant")
+			Expect(report.CrossTenantPairs()).To(BeEmpty())
+			Expect(report.UncheckedPairs()).To(HaveLen(4))
+			Expect(report.IsIsolated()).To(BeFalse())
+		})
+	})
+}
@@ -0,0 +1,247 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+)
+
+// ApplyActions hypothetically applies namespace and pod actions to the resources.
+// It returns a new object and does not affect the original Resources object.
+func ApplyActions(resources *probe.Resources, actions []*generator.Action) (*probe.Resources, error) {
+	var err error
+	for i, action := range actions {
+		if action.CreateNamespace != nil {
+			resources, err = resources.CreateNamespace(action.CreateNamespace.Namespace, action.CreateNamespace.Labels)
+		} else if action.SetNamespaceLabels != nil {
+			resources, err = resources.UpdateNamespaceLabels(action.SetNamespaceLabels.Namespace, action.SetNamespaceLabels.Labels)
+		} else if action.DeleteNamespace != nil {
+			resources, err = resources.DeleteNamespace(action.DeleteNamespace.Namespace)
+		} else if action.CreatePod != nil {
+			if len(resources.Pods) == 0 {
+				return nil, errors.Errorf("unable to create pod %s/%s at action %d: no existing pod to copy containers from", action.CreatePod.Namespace, action.CreatePod.Pod, i)
+			}
+			resources, err = resources.CreatePod(action.CreatePod.Namespace, action.CreatePod.Pod, action.CreatePod.Labels)
+		} else if action.SetPodLabels != nil {
+			resources, err = resources.SetPodLabels(action.SetPodLabels.Namespace, action.SetPodLabels.Pod, action.SetPodLabels.Labels)
+		} else if action.DeletePod != nil {
+			resources, err = resources.DeletePod(action.DeletePod.Namespace, action.DeletePod.Pod)
+		} else {
+			err = errors.Errorf("unsupported what-if action %d: only namespace and pod actions may be applied", i)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resources, nil
+}
+
+// ParseWhatIfActions parses short descriptions of changes into Actions, for example:
+//
+//	relabel ns payments env=dev       (add or overwrite a namespace label)
+//	relabel pod payments/api tier-    (remove a pod label)
+//	create ns sandbox env=dev
+//	create pod payments/worker app=worker
+//	delete ns payments
+//	delete pod payments/api
+//
+// Relabeling works like 'kubectl label --overwrite': labels which aren't mentioned are kept.
+// Changes are parsed in order, so that each one sees the effect of the ones before it.
+func ParseWhatIfActions(resources *probe.Resources, changes []string) ([]*generator.Action, error) {
+	var actions []*generator.Action
+	for _, change := range changes {
+		action, err := parseWhatIfAction(resources, change)
+		if err != nil {
+			return nil, err
+		}
+		resources, err = ApplyActions(resources, []*generator.Action{action})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "unable to apply '%s'", change)
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+func parseWhatIfAction(resources *probe.Resources, change string) (*generator.Action, error) {
+	fields := strings.Fields(change)
+	if len(fields) < 3 {
+		return nil, errors.Errorf("invalid change '%s': expected '<verb> <ns|pod> <name> [labels]'", change)
+	}
+	verb, kind, name, labelArgs := fields[0], fields[1], fields[2], fields[3:]
+
+	var ns, pod string
+	switch kind {
+	case "ns", "namespace":
+		ns = name
+	case "pod":
+		var ok bool
+		ns, pod, ok = strings.Cut(name, "/")
+		if !ok {
+			return nil, errors.Errorf("invalid change '%s': pod must be written as <namespace>/<pod>", change)
+		}
+	default:
+		return nil, errors.Errorf("invalid change '%s': unrecognized kind '%s'", change, kind)
+	}
+
+	switch verb {
+	case "relabel":
+		if pod == "" {
+			current, ok := resources.Namespaces[ns]
+			if !ok {
+				return nil, errors.Errorf("invalid change '%s': namespace %s not found", change, ns)
+			}
+			labels, err := mergeLabels(current, labelArgs)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "invalid change '%s'", change)
+			}
+			return generator.SetNamespaceLabels(ns, labels), nil
+		}
+		current, err := resources.GetPod(ns, pod)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid change '%s'", change)
+		}
+		labels, err := mergeLabels(current.Labels, labelArgs)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid change '%s'", change)
+		}
+		return generator.SetPodLabels(ns, pod, labels), nil
+	case "create":
+		labels, err := mergeLabels(nil, labelArgs)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid change '%s'", change)
+		}
+		if pod == "" {
+			return generator.CreateNamespace(ns, labels), nil
+		}
+		return generator.CreatePod(ns, pod, labels), nil
+	case "delete":
+		if len(labelArgs) > 0 {
+			return nil, errors.Errorf("invalid change '%s': delete does not take labels", change)
+		}
+		if pod == "" {
+			return generator.DeleteNamespace(ns), nil
+		}
+		return generator.DeletePod(ns, pod), nil
+	default:
+		return nil, errors.Errorf("invalid change '%s': unrecognized verb '%s'", change, verb)
+	}
+}
+
+// mergeLabels copies labels and applies 'key=value' and 'key-' arguments, which may also be comma-separated.
+func mergeLabels(labels map[string]string, args []string) (map[string]string, error) {
+	merged := map[string]string{}
+	for key, value := range labels {
+		merged[key] = value
+	}
+	for _, arg := range args {
+		for _, label := range strings.Split(arg, ",") {
+			if key, value, ok := strings.Cut(label, "="); ok {
+				merged[key] = value
+			} else if key, ok := strings.CutSuffix(label, "-"); ok {
+				delete(merged, key)
+			} else {
+				return nil, errors.Errorf("invalid label '%s': expected 'key=value' or 'key-'", label)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// ConnectivityChange is a flow whose verdict differs between two sets of resources.
+// Before or After is nil if the flow doesn't exist on that side, i.e. its source or destination doesn't exist.
+type ConnectivityChange struct {
+	Job    *probe.Job
+	Before *matcher.AllowedResult
+	After  *matcher.AllowedResult
+}
+
+func (c *ConnectivityChange) Flow() string {
+	return fmt.Sprintf("%s -> %s:%d (%s)", c.Job.FromKey, c.Job.ToKey, c.Job.ResolvedPort, c.Job.Protocol)
+}
+
+type ConnectivityDiff struct {
+	Changes []*ConnectivityChange
+}
+
+// NewConnectivityDiff simulates traffic between all pods and all available server ports,
+// under the same policies, for both sets of resources.
+func NewConnectivityDiff(policies *matcher.Policy, before *probe.Resources, after *probe.Resources) *ConnectivityDiff {
+	beforeJobs, beforeResults := simulateAllAvailable(policies, before)
+	afterJobs, afterResults := simulateAllAvailable(policies, after)
+
+	keys := map[string]bool{}
+	for key := range beforeJobs {
+		keys[key] = true
+	}
+	for key := range afterJobs {
+		keys[key] = true
+	}
+
+	diff := &ConnectivityDiff{}
+	for _, key := range slice.Sort(maps.Keys(keys)) {
+		beforeResult, afterResult := beforeResults[key], afterResults[key]
+		if beforeResult != nil && afterResult != nil && beforeResult.IsAllowed() == afterResult.IsAllowed() {
+			continue
+		}
+		job := afterJobs[key]
+		if job == nil {
+			job = beforeJobs[key]
+		}
+		diff.Changes = append(diff.Changes, &ConnectivityChange{Job: job, Before: beforeResult, After: afterResult})
+	}
+	return diff
+}
+
+func simulateAllAvailable(policies *matcher.Policy, resources *probe.Resources) (map[string]*probe.Job, map[string]*matcher.AllowedResult) {
+	jobBuilder := &probe.JobBuilder{TimeoutSeconds: 10}
+	jobs := map[string]*probe.Job{}
+	results := map[string]*matcher.AllowedResult{}
+	for _, job := range jobBuilder.GetJobsAllAvailableServers(resources, generator.ProbeModeServiceName).Valid {
+		jobs[job.Key()] = job
+		results[job.Key()] = policies.IsTrafficAllowed(job.Traffic())
+	}
+	return jobs, results
+}
+
+func (d *ConnectivityDiff) RenderTable() string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+
+	verdict := func(result *matcher.AllowedResult) string {
+		if result == nil {
+			return "-"
+		}
+		return result.Verdict()
+	}
+
+	table.SetHeader([]string{"Traffic", "Before", "After", "Ingress Walkthrough", "Egress Walkthrough"})
+	for _, change := range d.Changes {
+		// explain the new verdict, or the old one if the flow no longer exists
+		explained := change.After
+		if explained == nil {
+			explained = change.Before
+		}
+		ingressFlow := explained.Ingress.Flow()
+		egressFlow := explained.Egress.Flow()
+		if ingressFlow == "" {
+			ingressFlow = "no policies targeting ingress"
+		}
+		if egressFlow == "" {
+			egressFlow = "no policies targeting egress"
+		}
+		table.Append([]string{change.Flow(), verdict(change.Before), verdict(change.After), ingressFlow, egressFlow})
+	}
+
+	table.Render()
+	return tableString.String()
+}
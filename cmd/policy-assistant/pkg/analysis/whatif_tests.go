@@ -0,0 +1,112 @@
+package analysis
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+)
+
+func RunWhatIfTests() {
+	Describe("ParseWhatIfActions", func() {
+		It("should merge namespace labels", func() {
+			actions, err := ParseWhatIfActions(tenancyTestResources(), []string{"relabel ns a1 env=dev,tenant-"})
+			Expect(err).To(BeNil())
+			Expect(actions).To(Equal([]*generator.Action{generator.SetNamespaceLabels("a1", map[string]string{"env": "dev"})}))
+		})
+
+		It("should see the effects of earlier changes", func() {
+			actions, err := ParseWhatIfActions(tenancyTestResources(), []string{"create pod b1/b app=b", "relabel pod b1/b tier=db"})
+			Expect(err).To(BeNil())
+			Expect(actions).To(Equal([]*generator.Action{
+				generator.CreatePod("b1", "b", map[string]string{"app": "b"}),
+				generator.SetPodLabels("b1", "b", map[string]string{"app": "b", "tier": "db"}),
+			}))
+		})
+
+		It("should reject invalid changes", func() {
+			for _, change := range []string{"relabel ns", "relabel ns qrs env=dev", "relabel pod a1 env=dev", "move ns a1", "delete ns a1 env=dev", "relabel ns a1 env"} {
+				_, err := ParseWhatIfActions(tenancyTestResources(), []string{change})
+				Expect(err).ToNot(BeNil(), change)
+			}
+		})
+	})
+
+	Describe("ConnectivityDiff", func() {
+		allowFromTenantA := &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "b1", Name: "allow-from-tenant-a"},
+			Spec: networkingv1.NetworkPolicySpec{
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					From: []networkingv1.NetworkPolicyPeer{{
+						NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+					}},
+				}},
+			},
+		}
+		policies := matcher.BuildNetworkPolicies(true, []*networkingv1.NetworkPolicy{allowFromTenantA})
+
+		It("should be empty if nothing changes", func() {
+			Expect(NewConnectivityDiff(policies, tenancyTestResources(), tenancyTestResources()).Changes).To(BeEmpty())
+		})
+
+		It("should find flows broken by relabeling a namespace", func() {
+			before := tenancyTestResources()
+			after, err := ApplyActions(before, []*generator.Action{generator.SetNamespaceLabels("a2", map[string]string{"tenant": "c"})})
+			Expect(err).To(BeNil())
+
+			diff := NewConnectivityDiff(policies, before, after)
+			Expect(diff.Changes).To(HaveLen(1))
+			Expect(diff.Changes[0].Flow()).To(Equal("a2/a -> b1/a:80 (TCP)"))
+			Expect(diff.Changes[0].Before.IsAllowed()).To(BeTrue())
+			Expect(diff.Changes[0].After.IsAllowed()).To(BeFalse())
+		})
+
+		It("should report flows of deleted namespaces", func() {
+			before := tenancyTestResources()
+			after, err := ApplyActions(before, []*generator.Action{generator.DeleteNamespace("kube-system")})
+			Expect(err).To(BeNil())
+
+			diff := NewConnectivityDiff(policies, before, after)
+			// to and from the 3 other pods, plus loopback
+			Expect(diff.Changes).To(HaveLen(7))
+			for _, change := range diff.Changes {
+				Expect(change.After).To(BeNil())
+			}
+		})
+
+		It("should give created pods an IP which ipBlocks can match", func() {
+			allowFromPods := &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "b1", Name: "allow-from-pod-cidr"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+					Ingress: []networkingv1.NetworkPolicyIngressRule{{
+						From: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/24"}}},
+					}},
+				},
+			}
+			before := tenancyTestResources()
+			after, err := ApplyActions(before, []*generator.Action{generator.CreatePod("a1", "b", map[string]string{"pod": "b"})})
+			Expect(err).To(BeNil())
+			pod, err := after.GetPod("a1", "b")
+			Expect(err).To(BeNil())
+			Expect(pod.IP).To(Equal("192.0.2.1"))
+
+			diff := NewConnectivityDiff(matcher.BuildNetworkPolicies(true, []*networkingv1.NetworkPolicy{allowFromPods}), before, after)
+			flows := map[string]bool{}
+			for _, change := range diff.Changes {
+				Expect(change.Before).To(BeNil())
+				flows[change.Flow()] = change.After.IsAllowed()
+			}
+			Expect(flows).To(HaveKeyWithValue("a1/b -> b1/a:80 (TCP)", false))
+			Expect(flows).To(HaveKeyWithValue("a1/b -> a2/a:80 (TCP)", true))
+		})
+
+		It("should reject policy actions", func() {
+			_, err := ApplyActions(tenancyTestResources(), []*generator.Action{generator.DeletePolicy("b1", "allow-from-tenant-a")})
+			Expect(err).ToNot(BeNil())
+		})
+	})
+}
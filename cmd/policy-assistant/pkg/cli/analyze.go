@@ -11,6 +11,8 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
 	"sigs.k8s.io/network-policy-api/policy-assistant/examples"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/analysis"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/flowlog"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube/netpol"
 
 	"github.com/mattfenwick/collections/pkg/json"
@@ -34,6 +36,10 @@ const (
 	// QueryTargetMode  = "query-target"
 	ProbeMode              = "probe"
 	VerdictWalkthroughMode = "walkthrough"
+	TenantIsolationMode    = "tenant-isolation"
+	WhatIfMode             = "what-if"
+	CoverageMode           = "coverage"
+	FlowLogMode            = "flow-log"
 )
 
 // should we remove commented out modes or implement them later?
@@ -45,6 +51,10 @@ var AllModes = []string{
 	// QueryTargetMode,
 	ProbeMode,
 	VerdictWalkthroughMode,
+	TenantIsolationMode,
+	WhatIfMode,
+	CoverageMode,
+	FlowLogMode,
 }
 
 const DefaultTimeout = 3 * time.Minute
@@ -62,6 +72,11 @@ type AnalyzeArgs struct {
 	// traffic
 	TrafficPath string
 
+	// flow logs
+	FlowLogPath string
+
+	FlowLogFormat string
+
 	// targets
 	TargetPodPath string
 
@@ -77,6 +92,16 @@ type AnalyzeArgs struct {
 	Port int
 
 	Protocol string
+
+	// tenant isolation
+	TenantLabel string
+
+	FailOnCrossTenantTraffic bool
+
+	// what-if
+	WhatIfChanges []string
+
+	WhatIfPath string
 }
 
 func SetupAnalyzeCommand() *cobra.Command {
@@ -102,12 +127,18 @@ func SetupAnalyzeCommand() *cobra.Command {
 
 	command.Flags().StringVar(&args.TargetPodPath, "target-pod-path", "", "path to json target pod file -- json array of dicts")
 	command.Flags().StringVar(&args.TrafficPath, "traffic-path", "", "path to json traffic file, containing of a list of traffic objects")
+	command.Flags().StringVar(&args.FlowLogPath, "flow-log-path", "", "path to CNI flow log file; IPs are mapped to pods from kube, or from the resources in --probe-path")
+	command.Flags().StringVar(&args.FlowLogFormat, "flow-log-format", "hubble", "format of the flow log; allowed values are "+strings.Join(flowlog.DecoderNames(), ","))
 	command.Flags().StringVar(&args.ProbePath, "probe-path", "", "path to json model file for synthetic probe")
 	command.Flags().DurationVar(&args.Timeout, "kube-client-timeout", DefaultTimeout, "kube client timeout")
 	command.Flags().StringVar(&args.SourceWorkloadTraffic, "src-workload", "", "Source workload traffic in this form namespace/workloadType/workloadName")
 	command.Flags().StringVar(&args.DestinationWorkloadTraffic, "dst-workload", "", "Destination workload traffic Name in this form namespace/workloadType/workloadName")
 	command.Flags().IntVar(&args.Port, "port", 0, "port used for testing network policies")
 	command.Flags().StringVar(&args.Protocol, "protocol", "", "protocol used for testing network policies")
+	command.Flags().StringVar(&args.TenantLabel, "tenant-label", "tenant", "namespace label key used to group namespaces into tenants")
+	command.Flags().StringArrayVar(&args.WhatIfChanges, "what-if", []string{}, "hypothetical change to namespaces or pods, such as 'relabel ns payments env=dev'; may be repeated, changes are applied in order")
+	command.Flags().StringVar(&args.WhatIfPath, "what-if-path", "", "path to json file containing a list of hypothetical actions, applied after any --what-if changes")
+	command.Flags().BoolVar(&args.FailOnCrossTenantTraffic, "fail-on-cross-tenant-traffic", false, "if true, exit with a non-zero status if any cross-tenant traffic is allowed, or if no flows between some tenants were found; useful as a CI gate")
 
 	return command
 }
@@ -130,6 +161,17 @@ func RunAnalyzeCommand(args *AnalyzeArgs) {
 			utils.DoOrDie(err)
 			kubeNamespaces = nsList.Items
 			namespaces = []string{v1.NamespaceAll}
+		} else {
+			for _, ns := range namespaces {
+				kubeNamespace, err := kubeClient.GetNamespace(ns)
+				utils.DoOrDie(err)
+				kubeNamespaces = append(kubeNamespaces, *kubeNamespace)
+			}
+		}
+
+		kubePods, err = kube.GetPodsInNamespaces(kubeClient, namespaces)
+		if err != nil {
+			logrus.Errorf("unable to read pods from kube, ns '%s': %+v", namespaces, err)
 		}
 
 		includeANPS, includeBANPSs := shouldIncludeANPandBANP(kubeClient.ClientSet)
@@ -174,6 +216,7 @@ func RunAnalyzeCommand(args *AnalyzeArgs) {
 	logrus.Debugf("parsed policies:\n%s", json.MustMarshalToString(kubePolicies))
 	policies := matcher.BuildV1AndV2NetPols(args.SimplifyPolicies, kubePolicies, kubeANPs, kubeBANP)
 
+	crossTenantTraffic := false
 	for _, mode := range args.Modes {
 		// see analyze_unimplemented.go for unimplemented modes and the "case" statements for them
 		switch mode {
@@ -186,10 +229,26 @@ func RunAnalyzeCommand(args *AnalyzeArgs) {
 		case VerdictWalkthroughMode:
 			fmt.Println("verdict walkthrough:")
 			VerdictWalkthrough(policies, args.SourceWorkloadTraffic, args.DestinationWorkloadTraffic, args.Port, args.Protocol, args.TrafficPath)
+		case TenantIsolationMode:
+			fmt.Println("tenant isolation:")
+			crossTenantTraffic = !TenantIsolation(policies, args.TenantLabel, args.ProbePath, kubePods, kubeNamespaces)
+		case WhatIfMode:
+			fmt.Println("what-if (simulated connectivity changes):")
+			WhatIf(policies, args.WhatIfChanges, args.WhatIfPath, args.ProbePath, kubePods, kubeNamespaces)
+		case CoverageMode:
+			fmt.Println("rule coverage:")
+			RuleCoverage(kubePolicies, kubeANPs, kubeBANP, args.TrafficPath, args.FlowLogPath, args.FlowLogFormat, args.ProbePath, kubePods, kubeNamespaces)
+		case FlowLogMode:
+			fmt.Println("flow log discrepancies:")
+			FlowLogDiscrepancies(policies, args.FlowLogPath, args.FlowLogFormat, args.ProbePath, kubePods, kubeNamespaces)
 		default:
 			panic(errors.Errorf("unrecognized mode %s", mode))
 		}
 	}
+
+	if args.FailOnCrossTenantTraffic && crossTenantTraffic {
+		logrus.Fatalf("cross-tenant traffic is allowed")
+	}
 }
 
 func ExplainPolicies(explainedPolicies *matcher.Policy) {
@@ -237,14 +296,23 @@ func ProbeSyntheticConnectivity(explainedPolicies *matcher.Policy, modelPath str
 		return
 	}
 
+	resources := ResourcesFromKube(kubePods, kubeNamespaces)
+
+	simRunner := probe.NewSimulatedRunner(explainedPolicies, &probe.JobBuilder{TimeoutSeconds: 10})
+	simulatedProbe := simRunner.RunProbeForConfig(generator.ProbeAllAvailable, resources)
+	fmt.Printf("Ingress:\n%s\n", simulatedProbe.RenderIngress())
+	fmt.Printf("Egress:\n%s\n", simulatedProbe.RenderEgress())
+	fmt.Printf("Combined:\n%s\n\n\n", simulatedProbe.RenderTable())
+}
+
+// ResourcesFromKube builds a model of the cluster from kube pods and namespaces, using the first port of each container.
+func ResourcesFromKube(kubePods []v1.Pod, kubeNamespaces []v1.Namespace) *probe.Resources {
 	resources := &probe.Resources{
 		Namespaces: map[string]map[string]string{},
 		Pods:       []*probe.Pod{},
 	}
 
-	nsMap := map[string]v1.Namespace{}
 	for _, ns := range kubeNamespaces {
-		nsMap[ns.Name] = ns
 		resources.Namespaces[ns.Name] = ns.Labels
 	}
 
@@ -276,11 +344,59 @@ func ProbeSyntheticConnectivity(explainedPolicies *matcher.Policy, modelPath str
 		})
 	}
 
-	simRunner := probe.NewSimulatedRunner(explainedPolicies, &probe.JobBuilder{TimeoutSeconds: 10})
-	simulatedProbe := simRunner.RunProbeForConfig(generator.ProbeAllAvailable, resources)
-	fmt.Printf("Ingress:\n%s\n", simulatedProbe.RenderIngress())
-	fmt.Printf("Egress:\n%s\n", simulatedProbe.RenderEgress())
-	fmt.Printf("Combined:\n%s\n\n\n", simulatedProbe.RenderTable())
+	return resources
+}
+
+// TenantIsolation prints which tenants are able to send traffic to which other tenants,
+// and returns whether all tenants are isolated from each other -- which they aren't known to be if no flows
+// between some of them were found.
+func TenantIsolation(policies *matcher.Policy, tenantLabel string, modelPath string, kubePods []v1.Pod, kubeNamespaces []v1.Namespace) bool {
+	resources := readResources(modelPath, kubePods, kubeNamespaces)
+
+	report := analysis.NewTenantIsolationReport(policies, resources, tenantLabel)
+	fmt.Printf("Tenants:\n%s\n", report.RenderTenants())
+	fmt.Printf("Cross-tenant traffic:\n%s\n", report.RenderTable())
+	for _, pair := range report.UncheckedPairs() {
+		logrus.Warnf("no flows from tenant %s to tenant %s were found, so their isolation is unchecked", pair.From, pair.To)
+	}
+
+	return report.IsIsolated()
+}
+
+// WhatIf prints how connectivity would change if namespaces and pods were modified, without modifying them.
+func WhatIf(policies *matcher.Policy, changes []string, actionsPath string, modelPath string, kubePods []v1.Pod, kubeNamespaces []v1.Namespace) {
+	resources := readResources(modelPath, kubePods, kubeNamespaces)
+
+	actions, err := analysis.ParseWhatIfActions(resources, changes)
+	utils.DoOrDie(err)
+	if actionsPath != "" {
+		actionsFromPath, err := json.ParseFile[[]*generator.Action](actionsPath)
+		utils.DoOrDie(err)
+		actions = append(actions, *actionsFromPath...)
+	}
+	if len(actions) == 0 {
+		logrus.Fatalf("%+v", errors.Errorf("For this mode, you must set --what-if and/or --what-if-path"))
+	}
+
+	updated, err := analysis.ApplyActions(resources, actions)
+	utils.DoOrDie(err)
+
+	diff := analysis.NewConnectivityDiff(policies, resources, updated)
+	if len(diff.Changes) == 0 {
+		fmt.Println("no connectivity changes")
+		return
+	}
+	fmt.Printf("%s\n", diff.RenderTable())
+}
+
+// readResources reads resources from the synthetic probe model if there is one, and otherwise from kube.
+func readResources(modelPath string, kubePods []v1.Pod, kubeNamespaces []v1.Namespace) *probe.Resources {
+	if modelPath != "" {
+		config, err := json.ParseFile[SyntheticProbeConnectivityConfig](modelPath)
+		utils.DoOrDie(err)
+		return config.Resources
+	}
+	return ResourcesFromKube(kubePods, kubeNamespaces)
 }
 
 func shouldIncludeANPandBANP(client *kubernetes.Clientset) (bool, bool) {
@@ -320,53 +436,7 @@ func VerdictWalkthrough(policies *matcher.Policy, sourceWorkloadTraffic string,
 	}
 
 	if trafficPath != "" {
-		allTraffics, err := json.ParseFile[[]*matcher.Traffic](trafficPath)
-		utils.DoOrDie(err)
-		for _, traffic := range *allTraffics {
-			var podA, podB *matcher.TrafficPeer
-
-			// Determine source and destination peer information
-			sourceInternal := traffic.Source.Internal
-			destinationInternal := traffic.Destination.Internal
-
-			podA = matcher.CreateTrafficPeer(traffic.Source.IP, nil)
-			podB = matcher.CreateTrafficPeer(traffic.Destination.IP, nil)
-
-			// Update podA and podB if internal information is available
-			if sourceInternal != nil {
-				podA = matcher.CreateTrafficPeer(traffic.Source.IP, &matcher.InternalPeer{
-					PodLabels:       sourceInternal.PodLabels,
-					NamespaceLabels: sourceInternal.NamespaceLabels,
-					Namespace:       sourceInternal.Namespace,
-					Workload:        sourceInternal.Workload,
-				})
-			}
-
-			if destinationInternal != nil {
-				podB = matcher.CreateTrafficPeer(traffic.Destination.IP, &matcher.InternalPeer{
-					PodLabels:       destinationInternal.PodLabels,
-					NamespaceLabels: destinationInternal.NamespaceLabels,
-					Namespace:       destinationInternal.Namespace,
-					Workload:        destinationInternal.Workload,
-				})
-			}
-
-			// Special case handling for workload-specific traffic (internal vs. external)
-			if sourceInternal != nil {
-				if sourceInternal.Workload != "" {
-					podA = matcher.GetInternalPeerInfo(sourceInternal.Workload)
-				}
-			}
-
-			if destinationInternal != nil {
-				if destinationInternal.Workload != "" {
-					podB = matcher.GetInternalPeerInfo(destinationInternal.Workload)
-				}
-			}
-
-			// Append the resolved traffic to the allTraffic slice
-			allTraffic = append(allTraffic, matcher.CreateTraffic(podA, podB, traffic.ResolvedPort, string(traffic.Protocol)))
-		}
+		allTraffic = readTrafficFile(trafficPath)
 	} else {
 
 		if protocol != "TCP" && protocol != "UDP" && protocol != "SCTP" {
@@ -431,3 +501,103 @@ func VerdictWalkthrough(policies *matcher.Policy, sourceWorkloadTraffic string,
 	table.Render()
 	fmt.Println(tableString.String())
 }
+
+// readTrafficFile parses a json list of traffic, resolving workloads from kube where a workload is given.
+func readTrafficFile(trafficPath string) []*matcher.Traffic {
+	var allTraffic []*matcher.Traffic
+	allTraffics, err := json.ParseFile[[]*matcher.Traffic](trafficPath)
+	utils.DoOrDie(err)
+	for _, traffic := range *allTraffics {
+		var podA, podB *matcher.TrafficPeer
+
+		// Determine source and destination peer information
+		sourceInternal := traffic.Source.Internal
+		destinationInternal := traffic.Destination.Internal
+
+		podA = matcher.CreateTrafficPeer(traffic.Source.IP, nil)
+		podB = matcher.CreateTrafficPeer(traffic.Destination.IP, nil)
+
+		// Update podA and podB if internal information is available
+		if sourceInternal != nil {
+			podA = matcher.CreateTrafficPeer(traffic.Source.IP, &matcher.InternalPeer{
+				PodLabels:       sourceInternal.PodLabels,
+				NamespaceLabels: sourceInternal.NamespaceLabels,
+				Namespace:       sourceInternal.Namespace,
+				Workload:        sourceInternal.Workload,
+			})
+		}
+
+		if destinationInternal != nil {
+			podB = matcher.CreateTrafficPeer(traffic.Destination.IP, &matcher.InternalPeer{
+				PodLabels:       destinationInternal.PodLabels,
+				NamespaceLabels: destinationInternal.NamespaceLabels,
+				Namespace:       destinationInternal.Namespace,
+				Workload:        destinationInternal.Workload,
+			})
+		}
+
+		// Special case handling for workload-specific traffic (internal vs. external)
+		if sourceInternal != nil {
+			if sourceInternal.Workload != "" {
+				podA = matcher.GetInternalPeerInfo(sourceInternal.Workload)
+			}
+		}
+
+		if destinationInternal != nil {
+			if destinationInternal.Workload != "" {
+				podB = matcher.GetInternalPeerInfo(destinationInternal.Workload)
+			}
+		}
+
+		// Append the resolved traffic to the allTraffic slice
+		allTraffic = append(allTraffic, matcher.CreateTraffic(podA, podB, traffic.ResolvedPort, string(traffic.Protocol)))
+	}
+	return allTraffic
+}
+
+// RuleCoverage prints how often each rule decided the verdict for the traffic, and which traffic no policy applied to.
+func RuleCoverage(netpols []*networkingv1.NetworkPolicy, anps []*v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy, trafficPath string, flowLogPath string, flowLogFormat string, modelPath string, kubePods []v1.Pod, kubeNamespaces []v1.Namespace) {
+	if trafficPath == "" && flowLogPath == "" {
+		logrus.Fatalf("%+v", errors.Errorf("For this mode, you must set --traffic-path and/or --flow-log-path"))
+	}
+
+	coverage := analysis.NewRuleCoverage(netpols, anps, banp)
+	if trafficPath != "" {
+		for _, traffic := range readTrafficFile(trafficPath) {
+			coverage.Add(traffic)
+		}
+	}
+	if flowLogPath != "" {
+		resolver := analysis.NewFlowResolver(readResources(modelPath, kubePods, kubeNamespaces))
+		for _, flow := range readFlowLog(flowLogPath, flowLogFormat) {
+			coverage.Add(resolver.Traffic(flow))
+		}
+	}
+
+	fmt.Printf("Rules:\n%s\n", coverage.RenderTable())
+	fmt.Printf("Default allowed traffic:\n%s\n", coverage.RenderDefaultAllowed())
+}
+
+// FlowLogDiscrepancies prints flows from a CNI flow log whose dataplane verdict differs from the simulated verdict.
+func FlowLogDiscrepancies(policies *matcher.Policy, flowLogPath string, flowLogFormat string, modelPath string, kubePods []v1.Pod, kubeNamespaces []v1.Namespace) {
+	if flowLogPath == "" {
+		logrus.Fatalf("%+v", errors.Errorf("For this mode, you must set --flow-log-path"))
+	}
+
+	resolver := analysis.NewFlowResolver(readResources(modelPath, kubePods, kubeNamespaces))
+	report := analysis.NewFlowLogReport(policies, resolver, readFlowLog(flowLogPath, flowLogFormat))
+
+	fmt.Println(report.RenderSummary())
+	if len(report.Discrepancies) > 0 {
+		fmt.Printf("%s\n", report.RenderTable())
+	}
+}
+
+func readFlowLog(flowLogPath string, flowLogFormat string) []*flowlog.Flow {
+	decoder, err := flowlog.GetDecoder(flowLogFormat)
+	utils.DoOrDie(err)
+	flows, skipped, err := flowlog.ReadFile(flowLogPath, decoder)
+	utils.DoOrDie(err)
+	logrus.Infof("read %d flows from %s, skipped %d lines without a policy verdict", len(flows), flowLogPath, skipped)
+	return flows
+}
@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mattfenwick/collections/pkg/file"
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube/inmemory"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/utils"
+)
+
+type FuzzArgs struct {
+	Seed                      int64
+	Iterations                int
+	MaxNetpols                int
+	MaxANPs                   int
+	BANP                      bool
+	OutputDir                 string
+	Replay                    string
+	IgnoreLoopback            bool
+	PerturbationWaitSeconds   int
+	PodCreationTimeoutSeconds int
+	Retries                   int
+	Context                   string
+	ServerPorts               []int
+	ServerProtocols           []string
+	ServerNamespaces          []string
+	ServerPods                []string
+	Mock                      bool
+	MockDropRate              float64
+	MockMisenforceRate        float64
+	JobTimeoutSeconds         int
+	ImageRegistry             string
+}
+
+func SetupFuzzCommand() *cobra.Command {
+	args := &FuzzArgs{}
+
+	command := &cobra.Command{
+		Use:   "fuzz",
+		Short: "fuzz the CNI with random policies",
+		Long:  "generate random network policies from a seed, create and probe against kubernetes, compare to expected results, and shrink each discrepancy to a minimal reproducer",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunFuzzCommand(args)
+		},
+	}
+
+	command.Flags().Int64Var(&args.Seed, "seed", 0, "seed for generating random policies; if 0, a seed is chosen based on the current time.  The same seed always generates the same policies")
+	command.Flags().IntVar(&args.Iterations, "iterations", 20, "number of random test cases to run")
+	command.Flags().IntVar(&args.MaxNetpols, "max-netpols", 3, "maximum number of network policies per test case")
+	command.Flags().IntVar(&args.MaxANPs, "max-anps", 0, "maximum number of ANPs per test case; requires the ANP CRD to be installed")
+	command.Flags().BoolVar(&args.BANP, "banp", false, "if true, test cases may include a BANP; requires the BANP CRD to be installed")
+	command.Flags().StringVar(&args.OutputDir, "output-dir", ".", "directory to write reproducers to")
+	command.Flags().StringVar(&args.Replay, "replay", "", "path to a yaml or json test case file, such as a reproducer written by a previous run; if set, runs those test cases instead of fuzzing")
+
+	command.Flags().StringSliceVar(&args.ServerProtocols, "server-protocol", []string{"TCP", "UDP", "SCTP"}, "protocols to run server on")
+	command.Flags().IntSliceVar(&args.ServerPorts, "server-port", []int{80, 81}, "ports to run server on")
+	command.Flags().StringSliceVar(&args.ServerNamespaces, "namespace", []string{"x", "y", "z"}, "namespaces to create/use pods in")
+	command.Flags().StringSliceVar(&args.ServerPods, "pod", []string{"a", "b", "c"}, "pods to create in namespaces")
+
+	command.Flags().IntVar(&args.Retries, "retries", 1, "number of kube probe retries to allow, if probe fails")
+	command.Flags().BoolVar(&args.IgnoreLoopback, "ignore-loopback", false, "if true, ignore loopback for truthtable correctness verification")
+	command.Flags().IntVar(&args.PerturbationWaitSeconds, "perturbation-wait-seconds", 5, "number of seconds to wait after perturbing the cluster (i.e. create a network policy, modify a ns/pod label) before running probes, to give the CNI time to update the cluster state")
+	command.Flags().IntVar(&args.PodCreationTimeoutSeconds, "pod-creation-timeout-seconds", 60, "number of seconds to wait for pods to create, be running and have IP addresses")
+	command.Flags().StringVar(&args.Context, "context", "", "kubernetes context to use; if empty, uses default context")
+	command.Flags().IntVar(&args.JobTimeoutSeconds, "job-timeout-seconds", 10, "number of seconds to pass on to 'agnhost connect --timeout=%ds' flag")
+	command.Flags().BoolVar(&args.Mock, "mock", false, "if true, run against an in-memory cluster which enforces the policies it stores, instead of against kubernetes")
+	command.Flags().Float64Var(&args.MockDropRate, "mock-drop-rate", 0, "with --mock, the probability that a probe of allowed traffic times out anyway")
+	command.Flags().Float64Var(&args.MockMisenforceRate, "mock-misenforce-rate", 0, "with --mock, the probability that a probe gets the opposite of the policies' verdict")
+	command.Flags().StringVar(&args.ImageRegistry, "image-registry", "registry.k8s.io", "Image registry for agnhost")
+
+	return command
+}
+
+func RunFuzzCommand(args *FuzzArgs) {
+	if args.Seed == 0 {
+		args.Seed = time.Now().UnixNano()
+	}
+	fmt.Printf("args: \n%s\n", json.MustMarshalToString(args))
+
+	var kubernetes kube.IKubernetes
+	if args.Mock {
+		kubernetes = inmemory.NewCluster(&inmemory.MatcherEngine{Simplify: true}, &inmemory.Faults{
+			DropRate:       args.MockDropRate,
+			MisenforceRate: args.MockMisenforceRate,
+		})
+	} else {
+		kubeClient, err := kube.NewKubernetesForContext(args.Context)
+		utils.DoOrDie(err)
+		kubernetes = kubeClient
+	}
+
+	serverProtocols := parseProtocols(args.ServerProtocols)
+	resources, err := probe.NewDefaultResources(kubernetes, args.ServerNamespaces, args.ServerPods, args.ServerPorts, serverProtocols, []string{}, []string{}, args.PodCreationTimeoutSeconds, false, args.ImageRegistry)
+	utils.DoOrDie(err)
+
+	interpreter := connectivity.NewInterpreter(kubernetes, resources, &connectivity.InterpreterConfig{
+		ResetClusterBeforeTestCase:       true,
+		KubeProbeRetries:                 args.Retries,
+		PerturbationWaitSeconds:          args.PerturbationWaitSeconds,
+		VerifyClusterStateBeforeTestCase: true,
+		ResetAdminPolicies:               args.MaxANPs > 0 || args.BANP,
+		IgnoreLoopback:                   args.IgnoreLoopback,
+		JobTimeoutSeconds:                args.JobTimeoutSeconds,
+	})
+	printer := &connectivity.Printer{IgnoreLoopback: args.IgnoreLoopback}
+
+	if args.Replay != "" {
+		testCases, err := generator.ReadTestCasesFromFile(args.Replay)
+		utils.DoOrDie(err)
+		interpreter.Config.ResetAdminPolicies = generator.AnyUsesAdminPolicies(testCases)
+		passed := true
+		for _, testCase := range testCases {
+			result := interpreter.ExecuteTestCase(testCase)
+			utils.DoOrDie(result.Err)
+			printer.PrintTestCaseResult(result)
+			passed = passed && result.Passed(args.IgnoreLoopback)
+		}
+		if !passed {
+			logrus.Fatalf("replayed test case %s failed", args.Replay)
+		}
+		return
+	}
+
+	randomGenerator := generator.NewRandomPolicyGenerator(args.Seed, args.ServerNamespaces, args.ServerPods, args.ServerPorts, serverProtocols)
+	fuzzer := &connectivity.Fuzzer{
+		Interpreter: interpreter,
+		Generator:   randomGenerator,
+		MaxNetpols:  args.MaxNetpols,
+		MaxANPs:     args.MaxANPs,
+		BANP:        args.BANP,
+	}
+	failures, err := fuzzer.Run(args.Iterations)
+	for _, failure := range failures {
+		printer.PrintTestCaseResult(failure.Shrunk)
+		utils.DoOrDie(writeFuzzReproducer(args.OutputDir, args.Seed, failure))
+	}
+	utils.DoOrDie(err)
+
+	if len(failures) > 0 {
+		logrus.Fatalf("found %d discrepancies in %d test cases from seed %d", len(failures), args.Iterations, args.Seed)
+	}
+	fmt.Printf("no discrepancies found in %d test cases from seed %d\n", args.Iterations, args.Seed)
+}
+
+// writeFuzzReproducer writes the policies of the shrunk test case as yaml, and the test case itself as
+// json, which can be rerun with '--replay'.
+func writeFuzzReproducer(dir string, seed int64, failure *connectivity.FuzzFailure) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "unable to create directory %s", dir)
+	}
+	base := path.Join(dir, fmt.Sprintf("seed-%d-%d", seed, failure.Iteration))
+
+	testCase := failure.Shrunk.TestCase
+	var policies []string
+	for _, step := range testCase.Steps {
+		for _, action := range step.Actions {
+			if action.CreatePolicy != nil {
+				policies = append(policies, utils.YamlString(action.CreatePolicy.Policy))
+			} else if action.CreateAdminPolicy != nil {
+				policies = append(policies, utils.YamlString(action.CreateAdminPolicy.Policy))
+			} else if action.CreateBaselineAdminPolicy != nil {
+				policies = append(policies, utils.YamlString(action.CreateBaselineAdminPolicy.Policy))
+			}
+		}
+	}
+	if err := file.WriteString(base+".yaml", strings.Join(policies, "---\n"), 0644); err != nil {
+		return errors.Wrapf(err, "unable to write reproducer yaml")
+	}
+	if err := json.MarshalToFile(generator.NewTestCaseDocument(testCase), base+".json"); err != nil {
+		return errors.Wrapf(err, "unable to write reproducer test case")
+	}
+	logrus.Infof("wrote reproducer for test case #%d to %s.yaml and %s.json", failure.Iteration, base, base)
+	return nil
+}
@@ -2,16 +2,20 @@ package cli
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube/inmemory"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/utils"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/worker"
 )
 
 var (
@@ -20,7 +24,8 @@ var (
 		generator.TagUpstreamE2E,
 		generator.TagExample,
 		generator.TagEndPort,
-		generator.TagNamespacesByDefaultLabel}
+		generator.TagNamespacesByDefaultLabel,
+		generator.TagAdminTier}
 )
 
 type GenerateArgs struct {
@@ -30,6 +35,7 @@ type GenerateArgs struct {
 	PerturbationWaitSeconds   int
 	PodCreationTimeoutSeconds int
 	Retries                   int
+	Repeat                    int
 	Context                   string
 	ServerPorts               []int
 	ServerProtocols           []string
@@ -41,10 +47,31 @@ type GenerateArgs struct {
 	Exclude                   []string
 	DestinationType           string
 	Mock                      bool
+	MockPolicies              bool
+	MockDropRate              float64
+	MockMisenforceRate        float64
 	DryRun                    bool
 	JobTimeoutSeconds         int
 	JunitResultsFile          string
+	HTMLReportFile            string
+	EventsFile                string
+	Baseline                  string
+	WriteBaseline             string
 	ImageRegistry             string
+	ConvergenceTimeoutSeconds int
+	ConvergencePollSeconds    int
+	ResultsDir                string
+	Resume                    bool
+	DumpCases                 string
+	CasesFrom                 string
+	Parallelism               int
+	ExternalTargets           []string
+	Services                  []string
+	ExternalStandInPort       int
+	ProbeKind                 string
+	WorkerServer              bool
+	HTTPPath                  string
+	HTTPStatus                int
 	//BatchJobs                 bool
 }
 
@@ -68,26 +95,49 @@ func SetupGenerateCommand() *cobra.Command {
 
 	//command.Flags().BoolVar(&args.BatchJobs, "batch-jobs", false, "if true, run jobs in batches to avoid saturating the Kube APIServer with too many exec requests")
 	command.Flags().IntVar(&args.Retries, "retries", 1, "number of kube probe retries to allow, if probe fails")
+	command.Flags().BoolVar(&args.WorkerServer, "worker-server", false, "if true, probe through a worker server kept running in each pod and streamed over a single exec, instead of an exec per probe.  The pods run the policy-assistant worker image instead of agnhost")
+	command.Flags().IntVar(&args.Repeat, "repeat", 1, "number of times to run each kube probe; traffic whose probes don't all get the same verdict is reported as flaky, separately from mismatches")
 	command.Flags().BoolVar(&args.AllowDNS, "allow-dns", true, "if using egress, allow tcp and udp over port 53 for DNS resolution")
 	command.Flags().BoolVar(&args.Noisy, "noisy", false, "if true, print all results")
 	command.Flags().BoolVar(&args.IgnoreLoopback, "ignore-loopback", false, "if true, ignore loopback for truthtable correctness verification")
 	command.Flags().IntVar(&args.PerturbationWaitSeconds, "perturbation-wait-seconds", 5, "number of seconds to wait after perturbing the cluster (i.e. create a network policy, modify a ns/pod label) before running probes, to give the CNI time to update the cluster state")
+	command.Flags().IntVar(&args.ConvergenceTimeoutSeconds, "convergence-timeout-seconds", 0, "if positive, instead of waiting perturbation-wait-seconds and probing with retries, probe repeatedly until the results match the expected results or this timeout expires, and report the time to enforce")
+	command.Flags().IntVar(&args.ConvergencePollSeconds, "convergence-poll-seconds", 1, "number of seconds to wait between probes when waiting for convergence; must be at least 1.  Only the last probe is counted in the summary")
 	command.Flags().IntVar(&args.PodCreationTimeoutSeconds, "pod-creation-timeout-seconds", 60, "number of seconds to wait for pods to create, be running and have IP addresses")
 	command.Flags().StringVar(&args.Context, "context", "", "kubernetes context to use; if empty, uses default context")
 	command.Flags().BoolVar(&args.CleanupNamespaces, "cleanup-namespaces", false, "if true, clean up namespaces after completion")
 	command.Flags().BoolVar(&args.FailFast, "fail-fast", false, "if true, stop running tests after the first failure")
 	command.Flags().StringVar(&args.DestinationType, "destination-type", "", "override to set what to direct requests at; if not specified, the tests will be left as-is; one of "+strings.Join(generator.AllProbeModes, ", "))
 	command.Flags().IntVar(&args.JobTimeoutSeconds, "job-timeout-seconds", 10, "number of seconds to pass on to 'agnhost connect --timeout=%ds' flag")
+	command.Flags().StringVar(&args.ProbeKind, "probe-kind", "connect", "kind of kube probe: 'connect' only checks that a connection can be made; 'http' issues a GET to TCP ports and checks the status; 'dns' looks up the destination through the cluster resolver, is compared to the simulated traffic to the DNS pods, and requires --destination-type "+generator.ProbeModeServiceName+".  Probes which connect but fail an http or dns check are still counted as allowed.  One of "+strings.Join(worker.AllRequestKinds, ", ")+"; 'tls' isn't supported, since the server pods don't terminate TLS")
+	command.Flags().StringVar(&args.HTTPPath, "http-path", "/", "path to GET for http and tls probes")
+	command.Flags().IntVar(&args.HTTPStatus, "http-status", 200, "expected status for http probes")
 
 	command.Flags().StringSliceVar(&args.Include, "include", []string{}, "include tests with any of these tags; if empty, all tests will be included.  Valid tags:\n"+strings.Join(generator.TagSlice, "\n"))
 	command.Flags().StringSliceVar(&args.Exclude, "exclude", DefaultExcludeTags, "exclude tests with any of these tags.  See 'include' field for valid tags")
 
 	command.Flags().BoolVar(&args.Mock, "mock", false, "if true, use a mock kube runner (i.e. don't actually run tests against kubernetes; instead, product fake results")
+	command.Flags().BoolVar(&args.MockPolicies, "mock-policies", false, "if true, with --mock, probe results are decided by evaluating the policies in the mock cluster, instead of always succeeding")
+	command.Flags().Float64Var(&args.MockDropRate, "mock-drop-rate", 0, "with --mock-policies, the probability that a probe of allowed traffic times out anyway")
+	command.Flags().Float64Var(&args.MockMisenforceRate, "mock-misenforce-rate", 0, "with --mock-policies, the probability that a probe gets the opposite of the policies' verdict")
 	command.Flags().BoolVar(&args.DryRun, "dry-run", false, "if true, don't actually do anything: just print out what would be done")
 
 	command.Flags().StringVar(&args.JunitResultsFile, "junit-results-file", "", "output junit results to the specified file")
+	command.Flags().StringVar(&args.Baseline, "baseline", "", "if set, compare the outcome of each test case to this baseline from a previous run -- a file written by --write-baseline, or a --results-dir -- and exit with a non-zero status only if a test case newly fails")
+	command.Flags().StringVar(&args.WriteBaseline, "write-baseline", "", "if set, write the outcome of each test case to this file, for use with --baseline; test cases in --baseline which weren't run keep their outcome, and those which no longer exist are dropped")
+	command.Flags().StringVar(&args.EventsFile, "events-file", "", "if set, write an event to this file, as JSON Lines, as each step starts, each kube probe finishes, a mismatch is found, and each step and test case finishes")
+	command.Flags().StringVar(&args.HTMLReportFile, "html-report", "", "write a self-contained html report of the results to the specified file, e.g. to share with a CNI's maintainers")
 	command.Flags().StringVar(&args.ImageRegistry, "image-registry", "registry.k8s.io", "Image registry for agnhost")
 
+	command.Flags().StringVar(&args.ResultsDir, "results-dir", "", "if set, save the result of each test case to this directory as soon as it completes")
+	command.Flags().BoolVar(&args.Resume, "resume", false, "if true, skip test cases whose results are already saved in results-dir, and include the saved results in the summary")
+	command.Flags().StringVar(&args.DumpCases, "dump-cases", "", "if set, write the selected test cases as yaml to this directory and exit, without running them")
+	command.Flags().StringVar(&args.CasesFrom, "cases-from", "", "if set, run the test cases from the yaml and json files in this directory instead of the generated test cases; include and exclude tags are not applied")
+	command.Flags().StringSliceVar(&args.ExternalTargets, "external-target", []string{}, "destinations outside the cluster, as '[protocol://]host:port', to probe from every pod in addition to the pods; they're added to the truth tables as extra columns")
+	command.Flags().StringArrayVar(&args.Services, "service", []string{}, "services in front of several pods, as '[type:]namespace/name=pod,pod...', to probe from every pod in addition to the pods; they're added to the truth tables as extra columns.  Type is one of "+strings.Join(probe.AllServiceTypes, ", ")+"; may be repeated")
+	command.Flags().IntVar(&args.ExternalStandInPort, "external-stand-in-port", 0, "if positive, run a server on this port on a node's network, in namespace "+probe.StandInNamespace+", and probe it as an external target")
+	command.Flags().IntVar(&args.Parallelism, "parallelism", 1, "number of test cases to run at once; each worker after the first gets its own copy of the namespaces, with a suffix such as '-w1'.  Test cases with admin policies are cluster-scoped, so they run one at a time after the others")
+
 	return command
 }
 
@@ -97,11 +147,23 @@ func RunGenerateCommand(args *GenerateArgs) {
 	RunVersionCommand()
 
 	utils.DoOrDie(generator.ValidateTags(append(args.Include, args.Exclude...)))
-
-	externalIPs := []string{} // "http://www.google.com"} // TODO make these be IPs?  or not?
+	probeKind, err := worker.ParseRequestKind(args.ProbeKind)
+	utils.DoOrDie(err)
+	utils.DoOrDie(validateProbeKind(probeKind, args))
+	if args.Parallelism < 1 {
+		logrus.Fatalf("--parallelism must be at least 1, found %d", args.Parallelism)
+	}
+	if args.ConvergenceTimeoutSeconds > 0 && args.ConvergencePollSeconds < 1 {
+		logrus.Fatalf("--convergence-poll-seconds must be at least 1 with --convergence-timeout-seconds, found %d", args.ConvergencePollSeconds)
+	}
 
 	var kubernetes kube.IKubernetes
-	if args.Mock {
+	if args.Mock && args.MockPolicies {
+		kubernetes = inmemory.NewCluster(&inmemory.MatcherEngine{Simplify: true}, &inmemory.Faults{
+			DropRate:       args.MockDropRate,
+			MisenforceRate: args.MockMisenforceRate,
+		})
+	} else if args.Mock {
 		kubernetes = kube.NewMockKubernetes(1.0)
 	} else {
 		kubeClient, err := kube.NewKubernetesForContext(args.Context)
@@ -114,33 +176,53 @@ func RunGenerateCommand(args *GenerateArgs) {
 
 	serverProtocols := parseProtocols(args.ServerProtocols)
 
-	batchJobs := false // args.BatchJobs
-	resources, err := probe.NewDefaultResources(kubernetes, args.ServerNamespaces, args.ServerPods, args.ServerPorts, serverProtocols, externalIPs, args.PodCreationTimeoutSeconds, batchJobs, args.ImageRegistry)
+	// parallel workers probe the same external targets, so the stand-in is only set up once
+	args.ExternalTargets, err = withStandInTarget(kubernetes, args.ExternalTargets, args.ExternalStandInPort, args.ImageRegistry, args.PodCreationTimeoutSeconds)
+	utils.DoOrDie(err)
+
+	// batches are only sent to worker servers; running the worker once per batch isn't exposed
+	batchJobs := args.WorkerServer
+	resources, err := probe.NewDefaultResources(kubernetes, args.ServerNamespaces, args.ServerPods, args.ServerPorts, serverProtocols, args.ExternalTargets, args.Services, args.PodCreationTimeoutSeconds, batchJobs, args.ImageRegistry)
 	utils.DoOrDie(err)
 
 	interpreterConfig := &connectivity.InterpreterConfig{
 		ResetClusterBeforeTestCase:       true,
 		KubeProbeRetries:                 args.Retries,
+		Repeat:                           args.Repeat,
 		PerturbationWaitSeconds:          args.PerturbationWaitSeconds,
 		VerifyClusterStateBeforeTestCase: true,
 		BatchJobs:                        batchJobs,
+		WorkerServer:                     args.WorkerServer,
 		IgnoreLoopback:                   args.IgnoreLoopback,
 		JobTimeoutSeconds:                args.JobTimeoutSeconds,
 		FailFast:                         args.FailFast,
+		ConvergenceTimeoutSeconds:        args.ConvergenceTimeoutSeconds,
+		ConvergencePollSeconds:           args.ConvergencePollSeconds,
+		ProbeKind:                        probeKind,
+		HTTPPath:                         args.HTTPPath,
+		ExpectedHTTPStatus:               args.HTTPStatus,
+	}
+	if args.EventsFile != "" {
+		eventsFile, err := os.Create(args.EventsFile)
+		utils.DoOrDie(err)
+		defer eventsFile.Close()
+		interpreterConfig.Events = connectivity.NewEventStream(eventsFile)
 	}
 	interpreter := connectivity.NewInterpreter(kubernetes, resources, interpreterConfig)
 	printer := &connectivity.Printer{
 		Noisy:            args.Noisy,
 		IgnoreLoopback:   args.IgnoreLoopback,
 		JunitResultsFile: args.JunitResultsFile,
+		HTMLReportFile:   args.HTMLReportFile,
 	}
 
 	zcPod, err := resources.GetPod("z", "c")
 	utils.DoOrDie(err)
 
-	testCaseGenerator := generator.NewTestCaseGenerator(args.AllowDNS, zcPod.IP, args.ServerNamespaces, args.Include, args.Exclude)
-
-	testCases := testCaseGenerator.GenerateTestCases()
+	testCases, allTestCases, err := selectTestCases(args, zcPod.IP)
+	utils.DoOrDie(err)
+	// only touch admin policies if the run creates any; they're cluster-wide guardrails otherwise
+	interpreterConfig.ResetAdminPolicies = generator.AnyUsesAdminPolicies(testCases)
 	fmt.Printf("test cases to run by tag:\n")
 	for tag, count := range generator.CountTestCasesByTag(testCases) {
 		fmt.Printf("- %s: %d\n", tag, count)
@@ -150,44 +232,222 @@ func RunGenerateCommand(args *GenerateArgs) {
 		fmt.Printf("test #%d: %s\n - tags: %+v\n", i+1, testCase.Description, strings.Join(testCase.Tags.Keys(), ", "))
 	}
 
+	if args.DumpCases != "" {
+		utils.DoOrDie(generator.WriteTestCasesToDirectory(args.DumpCases, testCases))
+		fmt.Printf("wrote %d test cases to %s\n", len(testCases), args.DumpCases)
+		return
+	}
+
 	if args.DryRun {
 		return
 	}
 
-	if args.DestinationType != "" {
-		mode, err := generator.ParseProbeMode(args.DestinationType)
+	utils.DoOrDie(overrideProbeMode(allTestCases, args.DestinationType))
+
+	var resultStore *connectivity.ResultStore
+	storedResults := map[string]*connectivity.Result{}
+	if args.ResultsDir != "" {
+		resultStore, err = connectivity.NewResultStore(args.ResultsDir)
 		utils.DoOrDie(err)
-		for _, testCase := range testCases {
-			for _, step := range testCase.Steps {
-				step.Probe.Mode = mode
-			}
+		if args.Resume {
+			storedResults, err = resultStore.LoadAll()
+			utils.DoOrDie(err)
 		}
+	} else if args.Resume {
+		logrus.Fatalf("--resume requires --results-dir")
+	}
+
+	baseline := connectivity.NewBaseline()
+	if args.Baseline != "" {
+		baseline, err = connectivity.ReadBaseline(args.Baseline, args.IgnoreLoopback)
+		utils.DoOrDie(err)
+	}
+	current := connectivity.NewBaseline()
+
+	workers := []*connectivity.ParallelWorker{{Interpreter: interpreter, TestCases: testCases}}
+	for i := 1; i < args.Parallelism; i++ {
+		worker, err := setupGenerateWorker(args, kubernetes, interpreterConfig, i)
+		utils.DoOrDie(err)
+		workers = append(workers, worker)
 	}
 
+	testCaseIDs := map[int]string{}
+	var parallelIndices, serialIndices []int
 	for i, testCase := range testCases {
-		fmt.Printf("starting test case #%d\n", i+1)
+		testCaseID, err := generator.TestCaseID(testCase)
+		utils.DoOrDie(err)
+		if stored, ok := storedResults[testCaseID]; ok {
+			fmt.Printf("skipping completed test case #%d (%s)\n", i+1, testCaseID)
+			printer.AddStoredResult(stored)
+			current.Add(testCaseID, stored, interpreter.Config.IgnoreLoopback)
+			continue
+		}
+		testCaseIDs[i] = testCaseID
+		if len(workers) > 1 && testCase.UsesAdminPolicies() {
+			serialIndices = append(serialIndices, i)
+		} else {
+			parallelIndices = append(parallelIndices, i)
+		}
+	}
 
-		result := interpreter.ExecuteTestCase(testCase)
+	failedFast := false
+	handleResult := func(index int, result *connectivity.Result) bool {
 		utils.DoOrDie(result.Err)
 
+		if resultStore != nil {
+			utils.DoOrDie(resultStore.Save(testCaseIDs[index], result))
+		}
+
 		printer.PrintTestCaseResult(result)
-		fmt.Printf("finished policy #%d\n", i+1)
+		current.Add(testCaseIDs[index], result, interpreter.Config.IgnoreLoopback)
+		fmt.Printf("finished policy #%d\n", index+1)
 
 		if args.FailFast && !result.Passed(interpreter.Config.IgnoreLoopback) {
 			logrus.Warn("failing fast due to failure")
-			break
+			failedFast = true
+			return false
+		}
+		return true
+	}
+	connectivity.RunInParallel(workers, parallelIndices, handleResult)
+	if !failedFast && len(serialIndices) > 0 {
+		logrus.Infof("running %d test cases with admin policies one at a time", len(serialIndices))
+		connectivity.RunInParallel(workers[:1], serialIndices, handleResult)
+	}
+	for _, worker := range workers {
+		if err := worker.Interpreter.Close(); err != nil {
+			logrus.Warnf("%+v", err)
 		}
 	}
 
 	printer.PrintSummary()
 
+	comparison := baseline.Compare(current)
+	if args.Baseline != "" {
+		fmt.Println(comparison.Table())
+	}
+	if args.WriteBaseline != "" {
+		var allTestCaseIDs []string
+		for _, testCase := range allTestCases {
+			testCaseID, err := generator.TestCaseID(testCase)
+			utils.DoOrDie(err)
+			allTestCaseIDs = append(allTestCaseIDs, testCaseID)
+		}
+		utils.DoOrDie(baseline.Update(current, allTestCaseIDs).Write(args.WriteBaseline))
+	}
+
 	if args.CleanupNamespaces {
-		for _, ns := range args.ServerNamespaces {
-			logrus.Infof("cleaning up namespace %s", ns)
-			err = kubernetes.DeleteNamespace(ns)
-			if err != nil {
+		for i := range workers {
+			for _, ns := range generator.WorkerNamespaces(args.ServerNamespaces, i) {
+				logrus.Infof("cleaning up namespace %s", ns)
+				err = kubernetes.DeleteNamespace(ns)
+				if err != nil {
+					logrus.Warnf("%+v", err)
+				}
+			}
+		}
+		if args.ExternalStandInPort > 0 {
+			logrus.Infof("cleaning up namespace %s", probe.StandInNamespace)
+			if err := kubernetes.DeleteNamespace(probe.StandInNamespace); err != nil {
 				logrus.Warnf("%+v", err)
 			}
 		}
 	}
+
+	if args.Baseline != "" && comparison.Regressions() > 0 {
+		logrus.Fatalf("%d test cases newly fail compared to the baseline", comparison.Regressions())
+	}
+}
+
+// selectTestCases reads the test cases from --cases-from, or else generates them.
+// selectTestCases returns the test cases to run, and all test cases which exist -- those to run are
+// among them.
+func selectTestCases(args *GenerateArgs, zcPodIP string) ([]*generator.TestCase, []*generator.TestCase, error) {
+	if args.CasesFrom != "" {
+		testCases, err := generator.ReadTestCasesFromDirectory(args.CasesFrom)
+		return testCases, testCases, err
+	}
+	gen := generator.NewTestCaseGenerator(args.AllowDNS, zcPodIP, args.ServerNamespaces, args.Include, args.Exclude)
+	allTestCases := gen.GenerateAllTestCases()
+	return gen.SelectTestCases(allTestCases), allTestCases, nil
+}
+
+// validateProbeKind rejects kube probes which can't be compared to the simulation: the server pods don't
+// terminate TLS, and a lookup only sends a query -- which is what's simulated -- for a service name.
+func validateProbeKind(kind worker.RequestKind, args *GenerateArgs) error {
+	switch kind {
+	case worker.RequestKindTLS:
+		return errors.Errorf("--probe-kind %s isn't supported: the server pods don't terminate TLS", kind)
+	case worker.RequestKindDNS:
+		if args.DestinationType != generator.ProbeModeServiceName {
+			return errors.Errorf("--probe-kind %s requires --destination-type %s, since looking up an IP doesn't query the cluster's DNS", kind, generator.ProbeModeServiceName)
+		}
+		if len(args.ExternalTargets) > 0 || args.ExternalStandInPort > 0 {
+			return errors.Errorf("--probe-kind %s can't be used with external targets, which are probed by IP", kind)
+		}
+	}
+	return nil
+}
+
+func overrideProbeMode(testCases []*generator.TestCase, destinationType string) error {
+	if destinationType == "" {
+		return nil
+	}
+	mode, err := generator.ParseProbeMode(destinationType)
+	if err != nil {
+		return err
+	}
+	for _, testCase := range testCases {
+		for _, step := range testCase.Steps {
+			step.Probe.Mode = mode
+		}
+	}
+	return nil
+}
+
+// setupGenerateWorker creates the namespaces and pods of a parallel worker, and copies the test cases
+// for its namespaces.  Test cases are selected again, rather than copied from the first worker, since
+// generated test cases may refer to the IP of the worker's z/c pod.
+func setupGenerateWorker(args *GenerateArgs, kubernetes kube.IKubernetes, config *connectivity.InterpreterConfig, worker int) (*connectivity.ParallelWorker, error) {
+	namespaces := generator.WorkerNamespaces(args.ServerNamespaces, worker)
+	mapping := generator.NamespaceMapping(args.ServerNamespaces, namespaces)
+	logrus.Infof("setting up worker %d in namespaces %+v", worker, namespaces)
+
+	var services []string
+	for _, spec := range args.Services {
+		service, err := probe.ParseService(spec)
+		if err != nil {
+			return nil, err
+		}
+		if ns, ok := mapping[service.Namespace]; ok {
+			service.Namespace = ns
+		}
+		services = append(services, service.Spec())
+	}
+
+	resources, err := probe.NewDefaultResources(kubernetes, namespaces, args.ServerPods, args.ServerPorts, parseProtocols(args.ServerProtocols), args.ExternalTargets, services, args.PodCreationTimeoutSeconds, config.BatchJobs, args.ImageRegistry)
+	if err != nil {
+		return nil, err
+	}
+	zcPod, err := resources.GetPod(mapping["z"], "c")
+	if err != nil {
+		return nil, err
+	}
+	testCases, _, err := selectTestCases(args, zcPod.IP)
+	if err != nil {
+		return nil, err
+	}
+	if err := overrideProbeMode(testCases, args.DestinationType); err != nil {
+		return nil, err
+	}
+
+	parallelWorker := &connectivity.ParallelWorker{Interpreter: connectivity.NewInterpreter(kubernetes, resources, config)}
+	for _, testCase := range testCases {
+		rewritten, err := generator.RewriteNamespaces(testCase, mapping)
+		if err != nil {
+			return nil, err
+		}
+		parallelWorker.TestCases = append(parallelWorker.TestCases, rewritten)
+	}
+	return parallelWorker, nil
 }
@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
@@ -24,6 +25,7 @@ type ProbeArgs struct {
 	PolicyPath                string
 	ProbeMode                 string
 	JobTimeoutSeconds         int
+	WorkerServer              bool
 
 	// what to probe on
 	ProbeAllAvailable bool
@@ -36,6 +38,16 @@ type ProbeArgs struct {
 	ServerNamespaces []string
 	ServerPods       []string
 	ImageRegistry    string
+
+	// existing workloads to probe, instead of setting up servers
+	WorkloadNamespaces []string
+	WorkloadSelector   string
+	WorkloadProbeImage string
+
+	// destinations besides the pods
+	Services            []string
+	ExternalTargets     []string
+	ExternalStandInPort int
 }
 
 func SetupProbeCommand() *cobra.Command {
@@ -61,6 +73,7 @@ func SetupProbeCommand() *cobra.Command {
 
 	command.Flags().StringVar(&args.ProbeMode, "probe-mode", generator.ProbeModeServiceName, "probe mode to use, must be one of "+strings.Join(generator.AllProbeModes, ", "))
 	command.Flags().IntVar(&args.JobTimeoutSeconds, "job-timeout-seconds", 10, "number of seconds to pass on to 'agnhost connect --timeout=%ds' flag")
+	command.Flags().BoolVar(&args.WorkerServer, "worker-server", false, "if true, probe through a worker server kept running in each pod and streamed over a single exec, instead of an exec per probe.  The pods run the policy-assistant worker image instead of agnhost")
 
 	command.Flags().BoolVar(&args.Noisy, "noisy", false, "if true, print all results")
 	command.Flags().BoolVar(&args.IgnoreLoopback, "ignore-loopback", false, "if true, ignore loopback for truthtable correctness verification")
@@ -70,11 +83,18 @@ func SetupProbeCommand() *cobra.Command {
 	command.Flags().StringVar(&args.PolicyPath, "policy-path", "", "path to yaml network policy to create in kube; if empty, will not create any policies")
 	command.Flags().StringVar(&args.ImageRegistry, "image-registry", "registry.k8s.io", "Image registry for agnhost")
 
+	command.Flags().StringSliceVar(&args.ExternalTargets, "external-target", []string{}, "destinations outside the cluster, as '[protocol://]host:port', to probe from every pod in addition to the pods; they're added to the truth tables as extra columns")
+	command.Flags().StringArrayVar(&args.Services, "service", []string{}, "services in front of several pods, as '[type:]namespace/name=pod,pod...', to probe from every pod in addition to the pods; they're added to the truth tables as extra columns.  Type is one of "+strings.Join(probe.AllServiceTypes, ", ")+"; may be repeated")
+	command.Flags().IntVar(&args.ExternalStandInPort, "external-stand-in-port", 0, "if positive, run a server on this port on a node's network, in namespace "+probe.StandInNamespace+", and probe it as an external target")
+
+	command.Flags().StringSliceVar(&args.WorkloadNamespaces, "workload-namespace", []string{}, "if set, probe the existing pods in these namespaces -- through an ephemeral container added to each pod -- instead of creating servers")
+	command.Flags().StringVar(&args.WorkloadSelector, "workload-selector", "", "label selector for the existing pods to probe; if empty, probes all pods in the workload namespaces")
+	command.Flags().StringVar(&args.WorkloadProbeImage, "workload-probe-image", "", "image of the ephemeral container which probes existing pods; it must provide /agnhost.  If empty, uses agnhost from --image-registry")
+
 	return command
 }
 
 func RunProbeCommand(args *ProbeArgs) {
-	externalIPs := []string{"http://www.google.com"} // TODO make these be IPs?  or not?
 	if len(args.ServerNamespaces) == 0 || len(args.ServerPods) == 0 {
 		panic(errors.Errorf("found 0 namespaces or pods, must have at least 1 of each"))
 	}
@@ -84,22 +104,52 @@ func RunProbeCommand(args *ProbeArgs) {
 
 	protocols := parseProtocols(args.Protocols)
 	serverProtocols := parseProtocols(args.ServerProtocols)
-
-	resources, err := probe.NewDefaultResources(kubernetes, args.ServerNamespaces, args.ServerPods, args.ServerPorts, serverProtocols, externalIPs, args.PodCreationTimeoutSeconds, false, args.ImageRegistry)
+	externalTargets, err := withStandInTarget(kubernetes, args.ExternalTargets, args.ExternalStandInPort, args.ImageRegistry, args.PodCreationTimeoutSeconds)
 	utils.DoOrDie(err)
 
+	var resources *probe.Resources
+	var actions []*generator.Action
+	if len(args.WorkloadNamespaces) > 0 {
+		// the simulation needs to see everything that applies to the workloads, including admin policies
+		probeImage := args.WorkloadProbeImage
+		if probeImage == "" {
+			probeImage = probe.AgnhostImage(args.ImageRegistry)
+		}
+		resources, err = probe.NewWorkloadResources(kubernetes, args.WorkloadNamespaces, args.WorkloadSelector, probeImage, args.PodCreationTimeoutSeconds)
+		utils.DoOrDie(err)
+		for _, target := range externalTargets {
+			externalTarget, err := probe.ParseExternalTarget(target)
+			utils.DoOrDie(err)
+			resources.ExternalTargets = append(resources.ExternalTargets, externalTarget)
+		}
+		actions = append(actions, generator.ReadAllPolicies(args.WorkloadNamespaces))
+
+		if args.ProbeMode != string(generator.ProbeModePodIP) {
+			logrus.Warnf("existing workloads can only be probed by pod IP, ignoring probe mode %s", args.ProbeMode)
+			args.ProbeMode = string(generator.ProbeModePodIP)
+		}
+	} else {
+		resources, err = probe.NewDefaultResources(kubernetes, args.ServerNamespaces, args.ServerPods, args.ServerPorts, serverProtocols, externalTargets, args.Services, args.PodCreationTimeoutSeconds, args.WorkerServer, args.ImageRegistry)
+		utils.DoOrDie(err)
+		actions = append(actions, generator.ReadNetworkPolicies(args.ServerNamespaces))
+	}
+
 	interpreterConfig := &connectivity.InterpreterConfig{
 		ResetClusterBeforeTestCase:       false,
 		KubeProbeRetries:                 0,
 		PerturbationWaitSeconds:          args.PerturbationWaitSeconds,
 		VerifyClusterStateBeforeTestCase: false,
-		BatchJobs:                        false,
+		BatchJobs:                        args.WorkerServer,
+		WorkerServer:                     args.WorkerServer,
 		IgnoreLoopback:                   args.IgnoreLoopback,
 		JobTimeoutSeconds:                args.JobTimeoutSeconds,
 	}
 	interpreter := connectivity.NewInterpreter(kubernetes, resources, interpreterConfig)
-
-	actions := []*generator.Action{generator.ReadNetworkPolicies(args.ServerNamespaces)}
+	defer func() {
+		if err := interpreter.Close(); err != nil {
+			logrus.Warnf("%+v", err)
+		}
+	}()
 
 	if args.PolicyPath != "" {
 		kubePolicy, err := utils.ParseYamlFromFile[networkingv1.NetworkPolicy](args.PolicyPath)
@@ -130,6 +180,18 @@ func RunProbeCommand(args *ProbeArgs) {
 	}
 }
 
+// withStandInTarget adds the stand-in server to the external targets, if standInPort is positive.
+func withStandInTarget(kubernetes kube.IKubernetes, targets []string, standInPort int, imageRegistry string, podCreationTimeoutSeconds int) ([]string, error) {
+	if standInPort <= 0 {
+		return targets, nil
+	}
+	standIn, err := probe.NewStandInTarget(kubernetes, standInPort, imageRegistry, podCreationTimeoutSeconds)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]string{}, targets...), standIn.Key()), nil
+}
+
 func parseProtocols(strs []string) []v1.Protocol {
 	var protocols []v1.Protocol
 	for _, protocol := range strs {
@@ -35,8 +35,10 @@ func SetupRootCommand() *cobra.Command {
 
 	command.AddCommand(SetupAnalyzeCommand())
 	//command.AddCommand(SetupCompareCommand())
+	command.AddCommand(SetupFuzzCommand())
 	command.AddCommand(SetupGenerateCommand())
 	command.AddCommand(SetupProbeCommand())
+	command.AddCommand(SetupSynthesizeCommand())
 	command.AddCommand(SetupVersionCommand())
 
 	return command
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/analysis"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/flowlog"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/synthesis"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/utils"
+)
+
+type SynthesizeArgs struct {
+	AllNamespaces bool
+	Namespaces    []string
+	Context       string
+	ProbePath     string
+	TrafficPath   string
+	FlowLogPath   string
+	FlowLogFormat string
+	Admin         bool
+	Priority      int
+}
+
+func SetupSynthesizeCommand() *cobra.Command {
+	args := &SynthesizeArgs{}
+
+	command := &cobra.Command{
+		Use:   "synthesize",
+		Short: "synthesize least-privilege policies allowing only observed traffic",
+		Long:  "synthesize least-privilege policies allowing only observed traffic.  Pods are grouped into workloads by their labels, and the generated policies are verified by simulation against the corpus and the cluster snapshot",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, as []string) {
+			RunSynthesizeCommand(args)
+		},
+	}
+
+	command.Flags().BoolVarP(&args.AllNamespaces, "all-namespaces", "A", false, "reads pods from all namespaces; same as kubectl's '--all-namespaces'/'-A' flag")
+	command.Flags().StringSliceVarP(&args.Namespaces, "namespace", "n", []string{}, "namespaces to read pods from")
+	command.Flags().StringVar(&args.Context, "context", "", "selects kube context to read pods from")
+	command.Flags().StringVar(&args.ProbePath, "probe-path", "", "path to json model file to read the snapshot from, instead of kube")
+	command.Flags().StringVar(&args.TrafficPath, "traffic-path", "", "path to json traffic file, containing of a list of traffic objects")
+	command.Flags().StringVar(&args.FlowLogPath, "flow-log-path", "", "path to CNI flow log file; only flows with an allowed verdict are used")
+	command.Flags().StringVar(&args.FlowLogFormat, "flow-log-format", "hubble", "format of the flow log; allowed values are "+strings.Join(flowlog.DecoderNames(), ","))
+	command.Flags().BoolVar(&args.Admin, "admin", false, "if true, synthesize ANPs and a BANP instead of v1 NetworkPolicies")
+	command.Flags().IntVar(&args.Priority, "priority", 100, "priority of the first synthesized ANP; each following ANP gets the next priority")
+
+	return command
+}
+
+func RunSynthesizeCommand(args *SynthesizeArgs) {
+	if args.TrafficPath == "" && args.FlowLogPath == "" {
+		logrus.Fatalf("%+v", errors.Errorf("must set --traffic-path and/or --flow-log-path"))
+	}
+	if args.ProbePath == "" && !args.AllNamespaces && len(args.Namespaces) == 0 {
+		logrus.Fatalf("%+v", errors.Errorf("must set --probe-path, or read the snapshot from kube with --namespace or --all-namespaces"))
+	}
+
+	var kubePods []v1.Pod
+	var kubeNamespaces []v1.Namespace
+	if args.ProbePath == "" {
+		kubeClient, err := kube.NewKubernetesForContext(args.Context)
+		utils.DoOrDie(err)
+
+		namespaces := args.Namespaces
+		if args.AllNamespaces {
+			nsList, err := kubeClient.GetAllNamespaces()
+			utils.DoOrDie(err)
+			kubeNamespaces = nsList.Items
+			namespaces = []string{v1.NamespaceAll}
+		} else {
+			for _, ns := range namespaces {
+				kubeNamespace, err := kubeClient.GetNamespace(ns)
+				utils.DoOrDie(err)
+				kubeNamespaces = append(kubeNamespaces, *kubeNamespace)
+			}
+		}
+
+		kubePods, err = kube.GetPodsInNamespaces(kubeClient, namespaces)
+		utils.DoOrDie(err)
+	}
+	resources := readResources(args.ProbePath, kubePods, kubeNamespaces)
+
+	var traffic []*matcher.Traffic
+	if args.TrafficPath != "" {
+		traffic = append(traffic, readTrafficFile(args.TrafficPath)...)
+	}
+	if args.FlowLogPath != "" {
+		resolver := analysis.NewFlowResolver(resources)
+		for _, flow := range readFlowLog(args.FlowLogPath, args.FlowLogFormat) {
+			if flow.Verdict == flowlog.VerdictAllowed {
+				traffic = append(traffic, resolver.Traffic(flow))
+			}
+		}
+	}
+
+	s := synthesis.Synthesize(resources, traffic)
+	logrus.Infof("synthesizing policies for %d workloads from %d flows, ignoring %d flows between external IPs", len(s.Workloads()), len(s.Traffic), len(s.Ignored))
+
+	var policies *matcher.Policy
+	if args.Admin {
+		anps, banp, warnings, err := s.AdminNetworkPolicies(args.Priority)
+		utils.DoOrDie(err)
+		for _, warning := range warnings {
+			logrus.Warn(warning)
+		}
+		for _, anp := range anps {
+			fmt.Printf("---\n%s", utils.YamlString(anp))
+		}
+		fmt.Printf("---\n%s", utils.YamlString(banp))
+		policies = matcher.BuildV1AndV2NetPols(true, nil, anps, banp)
+	} else {
+		netpols := s.NetworkPolicies()
+		for _, netpol := range netpols {
+			fmt.Printf("---\n%s", utils.YamlString(netpol))
+		}
+		policies = matcher.BuildNetworkPolicies(true, netpols)
+	}
+
+	verification := s.Verify(policies)
+	if verification.Passed() {
+		logrus.Infof("verified: synthesized policies allow all observed traffic, and nothing else between pods of the snapshot")
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s\n", verification.RenderTable())
+	logrus.Fatalf("verification failed: %d observed flows denied, %d unobserved flows allowed", len(verification.Denied), len(verification.Excess))
+}
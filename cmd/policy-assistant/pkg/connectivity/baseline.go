@@ -0,0 +1,158 @@
+package connectivity
+
+import (
+	"os"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+)
+
+// Baseline records whether each test case passed in a run, keyed by test case ID, so that a later run
+// -- e.g. after upgrading a CNI -- can be compared to it.
+type Baseline struct {
+	TestCases map[string]*BaselineTestCase `json:"testCases"`
+}
+
+type BaselineTestCase struct {
+	Description string `json:"description"`
+	Passed      bool   `json:"passed"`
+}
+
+func NewBaseline() *Baseline {
+	return &Baseline{TestCases: map[string]*BaselineTestCase{}}
+}
+
+// ReadBaseline reads a baseline written by Write, or builds one from a results directory written by
+// ResultStore, whose files are named by the same test case IDs.
+func ReadBaseline(path string, ignoreLoopback bool) (*Baseline, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read baseline %s", path)
+	}
+	if info.IsDir() {
+		results, err := (&ResultStore{Dir: path}).LoadAll()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "unable to read baseline %s", path)
+		}
+		baseline := NewBaseline()
+		for id, result := range results {
+			baseline.Add(id, result, ignoreLoopback)
+		}
+		return baseline, nil
+	}
+
+	baseline, err := json.ParseFile[Baseline](path)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "unable to read baseline %s", path)
+	}
+	if baseline.TestCases == nil {
+		baseline.TestCases = map[string]*BaselineTestCase{}
+	}
+	return baseline, nil
+}
+
+func (b *Baseline) Write(path string) error {
+	return errors.WithMessagef(json.MarshalToFile(b, path), "unable to write baseline %s", path)
+}
+
+func (b *Baseline) Add(id string, result *Result, ignoreLoopback bool) {
+	b.TestCases[id] = &BaselineTestCase{Description: result.TestCase.Description, Passed: result.Passed(ignoreLoopback)}
+}
+
+// Update returns a copy of the baseline with the outcomes of the current run.  Test cases which weren't
+// run keep their previous outcomes, so that a run of only some test cases doesn't forget the others;
+// test cases whose ID isn't in testCaseIDs -- the IDs of every test case which still exists, whether
+// it was run or not -- are dropped.
+func (b *Baseline) Update(current *Baseline, testCaseIDs []string) *Baseline {
+	updated := NewBaseline()
+	for _, id := range testCaseIDs {
+		if testCase, ok := b.TestCases[id]; ok {
+			updated.TestCases[id] = testCase
+		}
+	}
+	maps.Copy(updated.TestCases, current.TestCases)
+	return updated
+}
+
+type BaselineOutcome string
+
+const (
+	NewlyFailingOutcome BaselineOutcome = "newly failing"
+	NewlyPassingOutcome BaselineOutcome = "newly passing"
+	StillFailingOutcome BaselineOutcome = "still failing"
+	StableOutcome       BaselineOutcome = "stable"
+	NewOutcome          BaselineOutcome = "new"
+)
+
+var AllBaselineOutcomes = []BaselineOutcome{
+	NewlyFailingOutcome,
+	NewlyPassingOutcome,
+	StillFailingOutcome,
+	StableOutcome,
+	NewOutcome,
+}
+
+// BaselineComparison classifies the test cases of the current run by how their outcome changed.
+type BaselineComparison struct {
+	TestCases map[BaselineOutcome][]*BaselineTestCase
+}
+
+// Compare classifies each test case of the current run.  Test cases missing from the baseline are new:
+// they have nothing to regress from, whether they pass or not.
+func (b *Baseline) Compare(current *Baseline) *BaselineComparison {
+	comparison := &BaselineComparison{TestCases: map[BaselineOutcome][]*BaselineTestCase{}}
+	for _, id := range slice.Sort(maps.Keys(current.TestCases)) {
+		testCase := current.TestCases[id]
+		previous, ok := b.TestCases[id]
+
+		var outcome BaselineOutcome
+		switch {
+		case !ok:
+			outcome = NewOutcome
+		case previous.Passed && !testCase.Passed:
+			outcome = NewlyFailingOutcome
+		case !previous.Passed && testCase.Passed:
+			outcome = NewlyPassingOutcome
+		case !testCase.Passed:
+			outcome = StillFailingOutcome
+		default:
+			outcome = StableOutcome
+		}
+		comparison.TestCases[outcome] = append(comparison.TestCases[outcome], testCase)
+	}
+	return comparison
+}
+
+func (c *BaselineComparison) Regressions() int {
+	return len(c.TestCases[NewlyFailingOutcome])
+}
+
+// Table lists the counts of each outcome, and the test cases whose outcome changed, which still fail, or
+// which are new; stable test cases are only counted.
+func (c *BaselineComparison) Table() string {
+	str := &strings.Builder{}
+	str.WriteString("Comparison to baseline:\n")
+	table := tablewriter.NewWriter(str)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+	table.SetHeader([]string{"Outcome", "Count", "Test cases"})
+	for _, outcome := range AllBaselineOutcomes {
+		testCases := c.TestCases[outcome]
+		var descriptions []string
+		if outcome != StableOutcome {
+			descriptions = slice.Map(func(t *BaselineTestCase) string {
+				if outcome == NewOutcome && !t.Passed {
+					return t.Description + " (failed)"
+				}
+				return t.Description
+			}, testCases)
+		}
+		table.Append([]string{string(outcome), intToString(len(testCases)), strings.Join(descriptions, "\n")})
+	}
+	table.Render()
+	return str.String()
+}
@@ -0,0 +1,61 @@
+package connectivity
+
+import (
+	"path"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func RunBaselineTests() {
+	Describe("Baseline", func() {
+		previous := &Baseline{TestCases: map[string]*BaselineTestCase{
+			"a": {Description: "a", Passed: true},
+			"b": {Description: "b", Passed: true},
+			"c": {Description: "c", Passed: false},
+			"d": {Description: "d", Passed: false},
+			"e": {Description: "e", Passed: false},
+			"f": {Description: "f", Passed: true},
+		}}
+		current := &Baseline{TestCases: map[string]*BaselineTestCase{
+			"a":        {Description: "a", Passed: true},
+			"b":        {Description: "b", Passed: false},
+			"c":        {Description: "c", Passed: true},
+			"d":        {Description: "d", Passed: false},
+			"new-pass": {Description: "new-pass", Passed: true},
+			"new-fail": {Description: "new-fail", Passed: false},
+		}}
+
+		It("should classify test cases by how their outcome changed", func() {
+			comparison := previous.Compare(current)
+			descriptions := func(outcome BaselineOutcome) []string {
+				var ds []string
+				for _, t := range comparison.TestCases[outcome] {
+					ds = append(ds, t.Description)
+				}
+				return ds
+			}
+			Expect(descriptions(NewlyFailingOutcome)).To(Equal([]string{"b"}))
+			Expect(descriptions(NewlyPassingOutcome)).To(Equal([]string{"c"}))
+			Expect(descriptions(StillFailingOutcome)).To(Equal([]string{"d"}))
+			Expect(descriptions(StableOutcome)).To(Equal([]string{"a"}))
+			Expect(descriptions(NewOutcome)).To(Equal([]string{"new-fail", "new-pass"}))
+			Expect(comparison.Regressions()).To(Equal(1))
+			Expect(comparison.Table()).To(ContainSubstring("new-fail (failed)"))
+		})
+
+		It("should keep the outcomes of test cases which weren't run, and drop those which are gone, when updating", func() {
+			filename := path.Join(GinkgoT().TempDir(), "baseline.json")
+			// "e" wasn't run, and "f" was removed
+			Expect(previous.Update(current, []string{"a", "b", "c", "d", "e", "new-pass", "new-fail"}).Write(filename)).To(Succeed())
+
+			updated, err := ReadBaseline(filename, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updated.TestCases).To(HaveLen(7))
+			Expect(updated.TestCases["b"].Passed).To(BeFalse())
+			Expect(updated.TestCases["e"].Passed).To(BeFalse())
+			Expect(updated.TestCases).ToNot(HaveKey("f"))
+			Expect(updated.Compare(current).Regressions()).To(Equal(0))
+		})
+	})
+}
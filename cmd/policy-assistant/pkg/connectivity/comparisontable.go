@@ -14,7 +14,7 @@ type Item struct {
 func (i *Item) ResultsByProtocol() map[bool]map[v1.Protocol]int {
 	counts := map[bool]map[v1.Protocol]int{true: {}, false: {}}
 	for key, kr := range i.Kube.JobResults {
-		counts[kr.Combined == i.Simulated.JobResults[key].Combined][kr.Job.Protocol]++
+		counts[kr.Agrees(i.Simulated.JobResults[key])][kr.Job.Protocol]++
 	}
 	return counts
 }
@@ -23,12 +23,56 @@ func (i *Item) IsSuccess() bool {
 	return equalsDict(i.Kube.JobResults, i.Simulated.JobResults)
 }
 
+// Comparison is flaky if the only kube results which differ from the simulation are flaky results of
+// allowed traffic, so that intermittent drops aren't counted as policy mismatches.
+func (i *Item) Comparison() Comparison {
+	if i.IsSuccess() {
+		return SameComparison
+	}
+	if len(i.Kube.JobResults) != len(i.Simulated.JobResults) {
+		return DifferentComparison
+	}
+	for key, kr := range i.Kube.JobResults {
+		if jobComparison(kr, i.Simulated.JobResults[key]) == DifferentComparison {
+			return DifferentComparison
+		}
+	}
+	return FlakyComparison
+}
+
+// ComparisonsByProtocol compares each job's kube and simulated results.
+func (i *Item) ComparisonsByProtocol() map[v1.Protocol]map[Comparison]int {
+	counts := map[v1.Protocol]map[Comparison]int{}
+	for key, kr := range i.Kube.JobResults {
+		if _, ok := counts[kr.Job.Protocol]; !ok {
+			counts[kr.Job.Protocol] = map[Comparison]int{}
+		}
+		counts[kr.Job.Protocol][jobComparison(kr, i.Simulated.JobResults[key])]++
+	}
+	return counts
+}
+
+func jobComparison(kube *probe.JobResult, simulated *probe.JobResult) Comparison {
+	switch {
+	case kube == nil || simulated == nil:
+		return DifferentComparison
+	case kube.Agrees(simulated):
+		return SameComparison
+	case kube.Combined == probe.ConnectivityFlaky && simulated.Combined.Verdict() == probe.ConnectivityAllowed:
+		// intermittent drops of allowed traffic; traffic which should be denied getting through even
+		// once is a mismatch
+		return FlakyComparison
+	default:
+		return DifferentComparison
+	}
+}
+
 func equalsDict(l map[string]*probe.JobResult, r map[string]*probe.JobResult) bool {
 	if len(l) != len(r) {
 		return false
 	}
 	for k, lv := range l {
-		if rv, ok := r[k]; !ok || rv.Combined != lv.Combined {
+		if rv, ok := r[k]; !ok || !lv.Agrees(rv) {
 			return false
 		}
 	}
@@ -39,8 +83,8 @@ type ComparisonTable struct {
 	Wrapped *probe.TruthTable
 }
 
-func NewComparisonTable(items []string) *ComparisonTable {
-	return &ComparisonTable{Wrapped: probe.NewTruthTableFromItems(items, nil)}
+func NewComparisonTable(froms []string, tos []string) *ComparisonTable {
+	return &ComparisonTable{Wrapped: probe.NewTruthTable(froms, tos, nil)}
 }
 
 func NewComparisonTableFrom(kubeProbe *probe.Table, simulatedProbe *probe.Table) *ComparisonTable {
@@ -58,7 +102,7 @@ func NewComparisonTableFrom(kubeProbe *probe.Table, simulatedProbe *probe.Table)
 		}
 	}
 
-	table := NewComparisonTable(kubeProbe.Wrapped.Froms)
+	table := NewComparisonTable(kubeProbe.Wrapped.Froms, kubeProbe.Wrapped.Tos)
 	for _, key := range kubeProbe.Wrapped.Keys() {
 		table.Set(key.From, key.To, &Item{Kube: kubeProbe.Get(key.From, key.To), Simulated: simulatedProbe.Get(key.From, key.To)})
 	}
@@ -89,17 +133,12 @@ func (c *ComparisonTable) Get(from string, to string) *Item {
 func (c *ComparisonTable) ValueCountsByProtocol(ignoreLoopback bool) map[v1.Protocol]map[Comparison]int {
 	counts := map[v1.Protocol]map[Comparison]int{v1.ProtocolTCP: {}, v1.ProtocolSCTP: {}, v1.ProtocolUDP: {}}
 	for _, key := range c.Wrapped.Keys() {
-		for isSuccess, protocolCounts := range c.Get(key.From, key.To).ResultsByProtocol() {
-			var c Comparison
-			if ignoreLoopback && key.From == key.To {
-				c = IgnoredComparison
-			} else if isSuccess {
-				c = SameComparison
-			} else {
-				c = DifferentComparison
-			}
-			for protocol, count := range protocolCounts {
-				counts[protocol][c] += count
+		for protocol, comparisonCounts := range c.Get(key.From, key.To).ComparisonsByProtocol() {
+			for comparison, count := range comparisonCounts {
+				if ignoreLoopback && key.From == key.To {
+					comparison = IgnoredComparison
+				}
+				counts[protocol][comparison] += count
 			}
 		}
 	}
@@ -112,11 +151,7 @@ func (c *ComparisonTable) ValueCounts(ignoreLoopback bool) map[Comparison]int {
 		if ignoreLoopback && key.From == key.To {
 			counts[IgnoredComparison] += 1
 		} else {
-			if c.Get(key.From, key.To).IsSuccess() {
-				counts[SameComparison] += 1
-			} else {
-				counts[DifferentComparison] += 1
-			}
+			counts[c.Get(key.From, key.To).Comparison()] += 1
 		}
 	}
 	return counts
@@ -124,12 +159,7 @@ func (c *ComparisonTable) ValueCounts(ignoreLoopback bool) map[Comparison]int {
 
 func (c *ComparisonTable) RenderSuccessTable() string {
 	return c.Wrapped.Table("", false, func(fr, to string, i interface{}) string {
-		item := c.Get(fr, to)
-		if item.IsSuccess() {
-			return "."
-		} else {
-			return "X"
-		}
+		return c.Get(fr, to).Comparison().ShortString()
 	})
 }
 
@@ -139,6 +169,7 @@ const (
 	SameComparison      Comparison = "same"
 	DifferentComparison Comparison = "different"
 	IgnoredComparison   Comparison = "ignored"
+	FlakyComparison     Comparison = "flaky"
 )
 
 func (c Comparison) ShortString() string {
@@ -149,6 +180,8 @@ func (c Comparison) ShortString() string {
 		return "X"
 	case IgnoredComparison:
 		return "?"
+	case FlakyComparison:
+		return "F"
 	default:
 		panic(errors.Errorf("invalid Comparison value %+v", c))
 	}
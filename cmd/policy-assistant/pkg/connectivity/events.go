@@ -0,0 +1,116 @@
+package connectivity
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+)
+
+type EventType string
+
+const (
+	StepStartedEvent      EventType = "stepStarted"
+	ProbeFinishedEvent    EventType = "probeFinished"
+	MismatchEvent         EventType = "mismatch"
+	StepFinishedEvent     EventType = "stepFinished"
+	TestCaseFinishedEvent EventType = "testCaseFinished"
+)
+
+// Event is a line of the event stream.  Fields which don't apply to the event's type are omitted.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Type     EventType `json:"type"`
+	TestCase string    `json:"testCase"`
+	Step     int       `json:"step,omitempty"`
+	Try      int       `json:"try,omitempty"`
+	// Counts of the try's comparisons, for probeFinished
+	Counts map[Comparison]int `json:"counts,omitempty"`
+	// Passed is set for stepFinished and testCaseFinished
+	Passed *bool  `json:"passed,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// From, To and Key identify the traffic of a mismatch.  Expected is only set if the kube or simulated
+	// result differs from the connectivity stated by the test step.
+	From        string                   `json:"from,omitempty"`
+	To          string                   `json:"to,omitempty"`
+	Key         string                   `json:"key,omitempty"`
+	Expected    generator.ExpectedResult `json:"expected,omitempty"`
+	Simulated   probe.Connectivity       `json:"simulated,omitempty"`
+	Kube        probe.Connectivity       `json:"kube,omitempty"`
+	Walkthrough []string                 `json:"walkthrough,omitempty"`
+}
+
+// EventStream writes events as JSON Lines as they happen, so that CI systems can follow a run.  It's safe
+// for concurrent use by parallel workers; a nil EventStream discards events.
+type EventStream struct {
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+func NewEventStream(w io.Writer) *EventStream {
+	return &EventStream{encoder: json.NewEncoder(w)}
+}
+
+func (e *EventStream) Emit(event *Event) {
+	if e == nil {
+		return
+	}
+	event.Time = time.Now()
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if err := e.encoder.Encode(event); err != nil {
+		logrus.Errorf("unable to write event: %+v", err)
+	}
+}
+
+func (e *EventStream) probeFinished(testCase string, step int, stepResult *StepResult, ignoreLoopback bool) {
+	try := len(stepResult.KubeProbes)
+	e.Emit(&Event{Type: ProbeFinishedEvent, TestCase: testCase, Step: step, Try: try, Counts: stepResult.Comparison(try - 1).ValueCounts(ignoreLoopback)})
+}
+
+// stepFinished reports the mismatches of the step's last try, and whether it passed.
+func (e *EventStream) stepFinished(testCase string, step int, stepResult *StepResult, ignoreLoopback bool) {
+	if e == nil {
+		return
+	}
+	for _, mismatch := range stepResult.Mismatches(ignoreLoopback) {
+		e.Emit(&Event{
+			Type:        MismatchEvent,
+			TestCase:    testCase,
+			Step:        step,
+			From:        mismatch.From,
+			To:          mismatch.To,
+			Key:         mismatch.Key,
+			Simulated:   mismatch.Simulated,
+			Kube:        mismatch.Kube,
+			Walkthrough: walkthroughStrings(stepResult.Walkthrough(mismatch.Job)),
+		})
+	}
+	for _, mismatch := range stepResult.ExpectationMismatches() {
+		e.Emit(&Event{
+			Type:      MismatchEvent,
+			TestCase:  testCase,
+			Step:      step,
+			From:      mismatch.From,
+			To:        mismatch.To,
+			Key:       mismatch.Key,
+			Expected:  mismatch.Expected,
+			Simulated: mismatch.Simulated,
+			Kube:      mismatch.Kube,
+		})
+	}
+	passed := stepResult.Passed(ignoreLoopback)
+	e.Emit(&Event{Type: StepFinishedEvent, TestCase: testCase, Step: step, Passed: &passed})
+}
+
+func walkthroughStrings(walkthroughs []*Walkthrough) []string {
+	var lines []string
+	for _, w := range walkthroughs {
+		lines = append(lines, w.String())
+	}
+	return lines
+}
@@ -0,0 +1,61 @@
+package connectivity
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+)
+
+func RunEventTests() {
+	Describe("EventStream", func() {
+		It("should stream events as a test case executes", func() {
+			mock := kube.NewMockKubernetes(1.0)
+			resources, err := probe.NewDefaultResources(mock, []string{"x", "y"}, []string{"a", "b"}, []int{80}, []v1.Protocol{v1.ProtocolTCP}, []string{}, []string{}, 10, false, "registry.k8s.io")
+			Expect(err).ToNot(HaveOccurred())
+			out := &bytes.Buffer{}
+			interpreter := NewInterpreter(mock, resources, &InterpreterConfig{
+				ResetClusterBeforeTestCase:       true,
+				VerifyClusterStateBeforeTestCase: true,
+				IgnoreLoopback:                   true,
+				Events:                           NewEventStream(out),
+			})
+
+			// the mock allows all traffic, so it never enforces the policy
+			testCase := generator.NewTestCase("unenforced", generator.NewStringSet(),
+				generator.NewTestStep(generator.ProbeAllAvailable),
+				generator.NewTestStep(generator.ProbeAllAvailable, generator.CreatePolicy(generator.BuildPolicy().NetworkPolicy())))
+			result := interpreter.ExecuteTestCase(testCase)
+			Expect(result.Err).ToNot(HaveOccurred())
+
+			var types []EventType
+			var mismatches []*Event
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				event := &Event{}
+				Expect(json.Unmarshal([]byte(line), event)).To(Succeed())
+				Expect(event.TestCase).To(Equal("unenforced"))
+				if event.Type == MismatchEvent {
+					mismatches = append(mismatches, event)
+				} else {
+					types = append(types, event.Type)
+				}
+			}
+			Expect(types).To(Equal([]EventType{
+				StepStartedEvent, ProbeFinishedEvent, StepFinishedEvent,
+				StepStartedEvent, ProbeFinishedEvent, StepFinishedEvent,
+				TestCaseFinishedEvent,
+			}))
+			Expect(mismatches).To(HaveLen(len(result.Steps[1].Mismatches(true))))
+			Expect(mismatches[0].Step).To(Equal(2))
+			Expect(mismatches[0].Simulated).To(Equal(probe.ConnectivityBlocked))
+			Expect(mismatches[0].Kube).To(Equal(probe.ConnectivityAllowed))
+			Expect(mismatches[0].Walkthrough).To(HaveLen(1))
+		})
+	})
+}
@@ -0,0 +1,76 @@
+package connectivity
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+)
+
+// FuzzFailure is a random test case whose simulated and kube results differed, along with the smallest
+// test case found which still shows a difference.
+type FuzzFailure struct {
+	Iteration int
+	Original  *Result
+	Shrunk    *Result
+}
+
+type Fuzzer struct {
+	Interpreter *Interpreter
+	Generator   *generator.RandomPolicyGenerator
+	MaxNetpols  int
+	MaxANPs     int
+	BANP        bool
+}
+
+func (f *Fuzzer) failed(result *Result) bool {
+	return result.Err == nil && !result.Passed(f.Interpreter.Config.IgnoreLoopback)
+}
+
+// Run executes the given number of random test cases, shrinking each one which fails.  An error in
+// executing a test case stops the run.
+func (f *Fuzzer) Run(iterations int) ([]*FuzzFailure, error) {
+	var failures []*FuzzFailure
+	for i := 1; i <= iterations; i++ {
+		description := fmt.Sprintf("random test case #%d from seed %d", i, f.Generator.Seed)
+		testCase := f.Generator.TestCase(description, f.MaxNetpols, f.MaxANPs, f.BANP)
+
+		logrus.Infof("fuzzing: %s", description)
+		result := f.Interpreter.ExecuteTestCase(testCase)
+		if result.Err != nil {
+			return failures, errors.WithMessagef(result.Err, "unable to execute %s", description)
+		}
+		if !f.failed(result) {
+			continue
+		}
+
+		logrus.Warnf("found discrepancy in %s, shrinking", description)
+		failures = append(failures, &FuzzFailure{Iteration: i, Original: result, Shrunk: f.Shrink(result)})
+	}
+	return failures, nil
+}
+
+// Shrink repeatedly replaces a failing result with the first smaller test case which still fails,
+// until no smaller test case fails.
+func (f *Fuzzer) Shrink(result *Result) *Result {
+	current := result
+	for {
+		var next *Result
+		for _, candidate := range generator.ShrinkCandidates(current.TestCase) {
+			candidateResult := f.Interpreter.ExecuteTestCase(candidate)
+			if candidateResult.Err != nil {
+				logrus.Warnf("unable to execute shrink candidate: %+v", candidateResult.Err)
+				continue
+			}
+			if f.failed(candidateResult) {
+				next = candidateResult
+				break
+			}
+		}
+		if next == nil {
+			return current
+		}
+		current = next
+	}
+}
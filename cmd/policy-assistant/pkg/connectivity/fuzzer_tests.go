@@ -0,0 +1,38 @@
+package connectivity
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+)
+
+func RunFuzzerTests() {
+	Describe("Fuzzer", func() {
+		It("should shrink discrepancies found against a mock cluster", func() {
+			// the mock allows all traffic, so any random policy which denies something is a discrepancy
+			mock := kube.NewMockKubernetes(1.0)
+			namespaces, pods, ports, protocols := []string{"x", "y"}, []string{"a", "b"}, []int{80}, []v1.Protocol{v1.ProtocolTCP}
+			resources, err := probe.NewDefaultResources(mock, namespaces, pods, ports, protocols, []string{}, []string{}, 10, false, "registry.k8s.io")
+			Expect(err).ToNot(HaveOccurred())
+
+			fuzzer := &Fuzzer{
+				Interpreter: NewInterpreter(mock, resources, &InterpreterConfig{ResetClusterBeforeTestCase: true, VerifyClusterStateBeforeTestCase: true}),
+				Generator:   generator.NewRandomPolicyGenerator(5, namespaces, pods, ports, protocols),
+				MaxNetpols:  3,
+			}
+			failures, err := fuzzer.Run(5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(failures).ToNot(BeEmpty())
+			for _, failure := range failures {
+				Expect(failure.Shrunk.Passed(false)).To(BeFalse())
+				Expect(len(failure.Shrunk.TestCase.Steps[0].Actions)).To(Equal(1))
+				Expect(generator.ShrinkCandidates(failure.Shrunk.TestCase)).ToNot(ContainElement(Satisfy(func(tc *generator.TestCase) bool {
+					return !fuzzer.Interpreter.ExecuteTestCase(tc).Passed(false)
+				})))
+			}
+		})
+	})
+}
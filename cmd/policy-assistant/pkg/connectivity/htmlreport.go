@@ -0,0 +1,395 @@
+package connectivity
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/utils"
+)
+
+// htmlReport is the data behind the html report.  Everything is computed up front, so that the template
+// only has to lay it out.
+type htmlReport struct {
+	Passed    int
+	Failed    int
+	Features  []*markdownRow
+	Tags      []*markdownRow
+	Protocols []*htmlProtocol
+	Cases     []*htmlCase
+}
+
+type htmlProtocol struct {
+	Protocol v1.Protocol
+	Passed   int
+	Failed   int
+	Flaky    int
+}
+
+type htmlCase struct {
+	Number      int
+	Description string
+	Tags        []string
+	Passed      bool
+	Wrong       int
+	Err         string
+	Steps       []*htmlStep
+}
+
+type htmlStep struct {
+	Number                int
+	Probe                 string
+	Passed                bool
+	Wrong                 int
+	Ignored               int
+	Right                 int
+	Flaky                 int
+	Policies              []*htmlPolicy
+	PoliciesRecorded      bool
+	Expected              *htmlTable
+	Actual                *htmlTable
+	Tries                 int
+	Mismatches            []*htmlMismatch
+	ExpectationMismatches []string
+}
+
+type htmlPolicy struct {
+	Kind string
+	Yaml string
+}
+
+type htmlTable struct {
+	Tos  []string
+	Rows []*htmlRow
+}
+
+type htmlRow struct {
+	From  string
+	Cells []*htmlCell
+}
+
+type htmlCell struct {
+	Mismatch bool
+	Flaky    bool
+	Ignored  bool
+	Entries  []*htmlEntry
+}
+
+type htmlEntry struct {
+	Key      string
+	Value    string
+	Mismatch bool
+	Flaky    bool
+	// Ratio is the fraction of a repeated probe's tries which were allowed
+	Ratio string
+}
+
+type htmlMismatch struct {
+	*Mismatch
+	Walkthrough []*Walkthrough
+}
+
+// WriteHTMLReport writes the results as a single html page, with its styles and scripts inlined, so that
+// it can be shared with -- and opened by -- people without access to the cluster or to policy-assistant.
+func WriteHTMLReport(filename string, results []*Result, ignoreLoopback bool) error {
+	if filename == "" {
+		return nil
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create file %s for html report", filename)
+	}
+	defer f.Close()
+
+	return errors.Wrapf(htmlReportTemplate.Execute(f, newHTMLReport(results, ignoreLoopback)), "unable to write html report to %s", filename)
+}
+
+func newHTMLReport(results []*Result, ignoreLoopback bool) *htmlReport {
+	summary := NewSummaryTableFromResults(ignoreLoopback, results)
+	report := &htmlReport{
+		Features: markdownRows(summary.FeaturePrimaryCounts, summary.FeatureCounts),
+		Tags:     markdownRows(summary.TagPrimaryCounts, summary.TagCounts),
+	}
+	for _, protocol := range []v1.Protocol{v1.ProtocolTCP, v1.ProtocolUDP, v1.ProtocolSCTP} {
+		counts := summary.ProtocolCounts[protocol]
+		report.Protocols = append(report.Protocols, &htmlProtocol{
+			Protocol: protocol,
+			Passed:   counts[SameComparison],
+			Failed:   counts[DifferentComparison],
+			Flaky:    counts[FlakyComparison],
+		})
+	}
+
+	for i, result := range results {
+		htmlCase := newHTMLCase(i+1, result, ignoreLoopback)
+		if htmlCase.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Cases = append(report.Cases, htmlCase)
+	}
+	return report
+}
+
+func newHTMLCase(number int, result *Result, ignoreLoopback bool) *htmlCase {
+	htmlCase := &htmlCase{
+		Number:      number,
+		Description: result.TestCase.Description,
+		Tags:        slice.Sort(result.TestCase.Tags.Keys()),
+		Passed:      result.Passed(ignoreLoopback),
+	}
+	if result.Err != nil {
+		htmlCase.Err = fmt.Sprintf("%+v", result.Err)
+		return htmlCase
+	}
+	for i, stepResult := range result.Steps {
+		step := newHTMLStep(i+1, result.TestCase.Steps[i], stepResult, ignoreLoopback)
+		htmlCase.Wrong += step.Wrong
+		htmlCase.Steps = append(htmlCase.Steps, step)
+	}
+	return htmlCase
+}
+
+func newHTMLStep(number int, step *generator.TestStep, stepResult *StepResult, ignoreLoopback bool) *htmlStep {
+	htmlStep := &htmlStep{
+		Number: number,
+		Probe:  "all available ports/protocols",
+		Passed: stepResult.Passed(ignoreLoopback),
+		// results loaded from a results directory don't keep their policies
+		PoliciesRecorded: stepResult.Policy != nil,
+		Tries:            len(stepResult.KubeProbes),
+	}
+	counts := stepResult.LastComparison().ValueCounts(ignoreLoopback)
+	htmlStep.Wrong, htmlStep.Ignored, htmlStep.Right = counts[DifferentComparison], counts[IgnoredComparison], counts[SameComparison]
+	htmlStep.Flaky = counts[FlakyComparison]
+	if step.Probe.PortProtocol != nil {
+		htmlStep.Probe = fmt.Sprintf("port %s, protocol %s", step.Probe.PortProtocol.Port.String(), step.Probe.PortProtocol.Protocol)
+	}
+
+	for _, p := range stepResult.KubePolicies {
+		htmlStep.Policies = append(htmlStep.Policies, &htmlPolicy{Kind: "Network policy", Yaml: PrintNetworkPolicy(p)})
+	}
+	for _, anp := range stepResult.ANPs {
+		htmlStep.Policies = append(htmlStep.Policies, &htmlPolicy{Kind: "Admin network policy", Yaml: utils.YamlString(anp)})
+	}
+	if stepResult.BANP != nil {
+		htmlStep.Policies = append(htmlStep.Policies, &htmlPolicy{Kind: "Baseline admin network policy", Yaml: utils.YamlString(stepResult.BANP)})
+	}
+
+	simulated, kube := stepResult.SimulatedProbe, stepResult.LastKubeProbe()
+	htmlStep.Expected = newHTMLTable(simulated, kube, simulated, ignoreLoopback)
+	htmlStep.Actual = newHTMLTable(simulated, kube, kube, ignoreLoopback)
+
+	for _, mismatch := range stepResult.Mismatches(ignoreLoopback) {
+		htmlStep.Mismatches = append(htmlStep.Mismatches, &htmlMismatch{Mismatch: mismatch, Walkthrough: stepResult.Walkthrough(mismatch.Job)})
+	}
+
+	for _, mismatch := range stepResult.ExpectationMismatches() {
+		htmlStep.ExpectationMismatches = append(htmlStep.ExpectationMismatches, mismatch.String())
+	}
+	return htmlStep
+}
+
+// newHTMLTable lays out one of the simulated or kube tables, marking the cells which differ between them.
+func newHTMLTable(simulated *probe.Table, kube *probe.Table, table *probe.Table, ignoreLoopback bool) *htmlTable {
+	htmlTable := &htmlTable{Tos: table.Wrapped.Tos}
+	for _, from := range table.Wrapped.Froms {
+		row := &htmlRow{From: from}
+		for _, to := range table.Wrapped.Tos {
+			cell := &htmlCell{Ignored: ignoreLoopback && from == to}
+			simulatedResults, kubeResults := simulated.Get(from, to).JobResults, kube.Get(from, to).JobResults
+			jobResults := table.Get(from, to).JobResults
+			for _, jobKey := range slice.Sort(maps.Keys(jobResults)) {
+				jobComparison := jobComparison(kubeResults[jobKey], simulatedResults[jobKey])
+				entry := &htmlEntry{
+					Value:    jobResults[jobKey].Combined.ShortString(),
+					Mismatch: !cell.Ignored && jobComparison == DifferentComparison,
+					Flaky:    !cell.Ignored && jobComparison == FlakyComparison,
+				}
+				if len(jobResults) > 1 {
+					entry.Key = jobKey
+				}
+				if jobResults[jobKey].Probes > 0 {
+					entry.Ratio = fmt.Sprintf("%d/%d", jobResults[jobKey].AllowedProbes, jobResults[jobKey].Probes)
+				}
+				cell.Mismatch = cell.Mismatch || entry.Mismatch
+				cell.Flaky = cell.Flaky || entry.Flaky
+				cell.Entries = append(cell.Entries, entry)
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		htmlTable.Rows = append(htmlTable.Rows, row)
+	}
+	return htmlTable
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>policy-assistant results</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 0.5em 0 1em; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+table.sortable th { cursor: pointer; }
+table.sortable th::after { content: " \2195"; color: #999; }
+table.truth td { font-family: monospace; text-align: center; white-space: nowrap; }
+.primary { font-weight: bold; }
+.pass { color: #1a7f37; }
+.fail { color: #cf222e; }
+td.mismatch, span.mismatch { background: #ffd7d5; font-weight: bold; }
+td.flaky, span.flaky { background: #fff3c4; }
+td.ignored { color: #999; }
+.ratio { color: #666; font-size: 0.8em; }
+.tag { display: inline-block; background: #eef; border-radius: 3px; padding: 0 0.3em; margin: 0.1em; font-size: 0.85em; }
+pre { background: #f6f8fa; padding: 0.5em; overflow-x: auto; }
+details { margin: 0.5em 0; }
+summary { cursor: pointer; }
+.step { border-left: 3px solid #ccc; padding-left: 1em; margin: 1em 0; }
+</style>
+</head>
+<body>
+<h1>policy-assistant results</h1>
+<p>{{.Passed}} test cases passed, {{.Failed}} failed.</p>
+
+<h2>Test cases</h2>
+<table class="sortable">
+<thead><tr><th>#</th><th>Test</th><th>Result</th><th>Steps</th><th>Wrong</th><th>Tags</th></tr></thead>
+<tbody>
+{{- range .Cases}}
+<tr>
+<td>{{.Number}}</td>
+<td><a href="#case-{{.Number}}">{{.Description}}</a></td>
+<td>{{if .Passed}}<span class="pass">passed</span>{{else}}<span class="fail">failed</span>{{end}}</td>
+<td>{{len .Steps}}</td>
+<td>{{.Wrong}}</td>
+<td>{{range .Tags}}<span class="tag">{{.}}</span>{{end}}</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+
+<h2>Results by feature</h2>
+{{template "passFail" .Features}}
+<h2>Results by tag</h2>
+{{template "passFail" .Tags}}
+
+<h2>Probes by protocol</h2>
+<table>
+<thead><tr><th>Protocol</th><th>Passed</th><th>Failed</th><th>Flaky</th></tr></thead>
+<tbody>
+{{- range .Protocols}}
+<tr><td>{{.Protocol}}</td><td>{{.Passed}}</td><td>{{.Failed}}</td><td>{{.Flaky}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+
+<h2>Details</h2>
+<p>Connectivity: <code>.</code> allowed, <code>X</code> blocked, <code>R</code> refused, <code>T</code> timed out,
+<code>E</code> reset, <code>A</code> connected but failed the application check, <code>M</code> mixed across a
+service's endpoints, <code>P</code> invalid named port, <code>N</code> invalid port/protocol, <code>#</code> loopback,
+<code>F</code> flaky across repeated probes, <code>?</code> unknown.  Red cells differ between the simulation and the cluster;
+yellow cells are flaky, and show how many of their probes were allowed.</p>
+{{- range .Cases}}
+<details id="case-{{.Number}}"{{if not .Passed}} open{{end}}>
+<summary><strong>{{.Number}}: {{.Description}}</strong> &mdash; {{if .Passed}}<span class="pass">passed</span>{{else}}<span class="fail">failed</span>{{end}}</summary>
+{{- if .Err}}
+<p class="fail">test case failed to execute:</p>
+<pre>{{.Err}}</pre>
+{{- end}}
+{{- range .Steps}}
+<div class="step">
+<h3>Step {{.Number}} on {{.Probe}} &mdash; {{if .Passed}}<span class="pass">passed</span>{{else}}<span class="fail">failed</span>{{end}}</h3>
+<p>{{.Wrong}} wrong, {{.Ignored}} ignored, {{.Right}} correct, {{.Flaky}} flaky, after {{.Tries}} tries.</p>
+{{- if not .PoliciesRecorded}}
+<p>Policies were not recorded for this step.</p>
+{{- else if not .Policies}}
+<p>No policies.</p>
+{{- end}}
+{{- range .Policies}}
+<details><summary>{{.Kind}}</summary><pre>{{.Yaml}}</pre></details>
+{{- end}}
+<h4>Expected (simulated)</h4>
+{{template "truthTable" .Expected}}
+<h4>Actual (cluster, last try)</h4>
+{{template "truthTable" .Actual}}
+{{- if .ExpectationMismatches}}
+<h4>Differences from the expected connectivity</h4>
+<ul>{{range .ExpectationMismatches}}<li>{{.}}</li>{{end}}</ul>
+{{- end}}
+{{- if .Mismatches}}
+<h4>Mismatches</h4>
+{{- range .Mismatches}}
+<p><code>{{.From}}</code> &rarr; <code>{{.To}}</code> {{.Key}}: simulated {{.Simulated}}, cluster {{.Kube}}</p>
+{{- if .Walkthrough}}
+<table>
+<thead><tr><th>Traffic</th><th>Verdict</th><th>Ingress walkthrough</th><th>Egress walkthrough</th></tr></thead>
+<tbody>
+{{- range .Walkthrough}}
+<tr><td>{{.Traffic}}</td><td>{{.Verdict}}</td><td>{{.Ingress}}</td><td>{{.Egress}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- end}}
+{{- end}}
+</div>
+{{- end}}
+</details>
+{{- end}}
+
+<script>
+document.querySelectorAll("table.sortable th").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var body = th.closest("table").tBodies[0];
+    var column = th.cellIndex;
+    var ascending = th.dataset.order !== "asc";
+    th.dataset.order = ascending ? "asc" : "desc";
+    var rows = Array.prototype.slice.call(body.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[column].textContent, y = b.cells[column].textContent;
+      var order = (x !== "" && y !== "" && !isNaN(x) && !isNaN(y)) ? x - y : x.localeCompare(y);
+      return ascending ? order : -order;
+    });
+    rows.forEach(function (row) { body.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+{{define "passFail"}}
+<table>
+<thead><tr><th>Name</th><th>Result</th></tr></thead>
+<tbody>
+{{- range .}}
+<tr><td{{if .IsPrimary}} class="primary"{{end}}>{{.GetName}}</td><td>{{.GetResult}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{end}}
+{{define "truthTable"}}
+<table class="truth">
+<thead><tr><th></th>{{range .Tos}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr><th>{{.From}}</th>
+{{- range .Cells}}<td class="{{if .Mismatch}}mismatch{{else if .Flaky}}flaky{{else if .Ignored}}ignored{{end}}">
+{{- range $i, $entry := .Entries}}{{if $i}}<br>{{end}}{{if $entry.Key}}{{$entry.Key}}: {{end}}
+{{- if $entry.Mismatch}}<span class="mismatch">{{$entry.Value}}</span>{{else if $entry.Flaky}}<span class="flaky">{{$entry.Value}}</span>{{else}}{{$entry.Value}}{{end}}
+{{- if $entry.Ratio}} <span class="ratio">{{$entry.Ratio}}</span>{{end}}{{end -}}
+</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+{{end}}
+`))
@@ -0,0 +1,47 @@
+package connectivity
+
+import (
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+)
+
+func RunHTMLReportTests() {
+	Describe("HTML report", func() {
+		It("should highlight and explain the probes which differ from the simulation", func() {
+			mock := kube.NewMockKubernetes(1.0)
+			resources, err := probe.NewDefaultResources(mock, []string{"x", "y"}, []string{"a", "b"}, []int{80}, []v1.Protocol{v1.ProtocolTCP}, []string{}, []string{}, 10, false, "registry.k8s.io")
+			Expect(err).ToNot(HaveOccurred())
+			interpreter := NewInterpreter(mock, resources, &InterpreterConfig{ResetClusterBeforeTestCase: true, VerifyClusterStateBeforeTestCase: true, IgnoreLoopback: true})
+
+			// the mock allows all traffic, so it never enforces the policy
+			tags := generator.NewStringSet(generator.TagIngress)
+			testCase := generator.NewTestCase("unenforced <policy>", tags,
+				generator.NewTestStep(generator.ProbeAllAvailable),
+				generator.NewTestStep(generator.ProbeAllAvailable, generator.CreatePolicy(generator.BuildPolicy().NetworkPolicy())))
+			result := interpreter.ExecuteTestCase(testCase)
+			Expect(result.Err).ToNot(HaveOccurred())
+
+			filename := path.Join(GinkgoT().TempDir(), "report.html")
+			Expect(WriteHTMLReport(filename, []*Result{result}, true)).To(Succeed())
+			contents, err := os.ReadFile(filename)
+			Expect(err).ToNot(HaveOccurred())
+			report := string(contents)
+
+			Expect(report).To(ContainSubstring("unenforced &lt;policy&gt;"))
+			Expect(report).To(ContainSubstring(`<span class="tag">` + generator.TagIngress + `</span>`))
+			Expect(report).To(ContainSubstring("Network policy"))
+			Expect(report).To(ContainSubstring(`<td class="mismatch">`))
+			Expect(report).To(ContainSubstring("simulated blocked, cluster allowed"))
+			Expect(report).To(ContainSubstring("[NPv1] Dropped"))
+			Expect(report).ToNot(ContainSubstring("src="))
+			Expect(report).ToNot(ContainSubstring(`href="http`))
+		})
+	})
+}
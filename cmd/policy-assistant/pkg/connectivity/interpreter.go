@@ -7,10 +7,12 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/worker"
 )
 
 const (
@@ -25,16 +27,45 @@ type InterpreterConfig struct {
 	KubeProbeRetries                 int
 	PerturbationWaitSeconds          int
 	VerifyClusterStateBeforeTestCase bool
-	BatchJobs                        bool
-	IgnoreLoopback                   bool
-	JobTimeoutSeconds                int
-	FailFast                         bool
+	// ResetAdminPolicies extends resetting and verifying the cluster to the admin policies created by
+	// earlier test cases.  It should only be set if the run has test cases using admin policies; admin
+	// policies not created by policy-assistant are never touched
+	ResetAdminPolicies bool
+	BatchJobs          bool
+	// WorkerServer, if BatchJobs is set, keeps a worker server running in each pod instead of running
+	// the worker once per batch
+	WorkerServer      bool
+	IgnoreLoopback    bool
+	JobTimeoutSeconds int
+	FailFast          bool
+	// ProbeKind, HTTPPath and ExpectedHTTPStatus select an application-layer kube probe; see worker.Request
+	ProbeKind          worker.RequestKind
+	HTTPPath           string
+	ExpectedHTTPStatus int
+	// ConvergenceTimeoutSeconds, if positive, replaces the fixed PerturbationWaitSeconds sleep and
+	// KubeProbeRetries: kube is probed every ConvergencePollSeconds, until the results match the
+	// simulation or the timeout expires
+	ConvergenceTimeoutSeconds int
+	ConvergencePollSeconds    int
+	// Repeat, if greater than 1, is how many times each kube probe is run; traffic whose probes get
+	// different verdicts is flaky, and isn't counted as a mismatch
+	Repeat int
+	// Events, if set, receives an event as each step starts, each kube probe finishes, and so on
+	Events *EventStream
 }
 
 func (i *InterpreterConfig) PerturbationWaitDuration() time.Duration {
 	return time.Duration(i.PerturbationWaitSeconds) * time.Second
 }
 
+func (i *InterpreterConfig) ConvergenceTimeoutDuration() time.Duration {
+	return time.Duration(i.ConvergenceTimeoutSeconds) * time.Second
+}
+
+func (i *InterpreterConfig) ConvergencePollDuration() time.Duration {
+	return time.Duration(i.ConvergencePollSeconds) * time.Second
+}
+
 type Interpreter struct {
 	kubernetes kube.IKubernetes
 	resources  *probe.Resources
@@ -46,13 +77,19 @@ type Interpreter struct {
 func NewInterpreter(kubernetes kube.IKubernetes, resources *probe.Resources, config *InterpreterConfig) *Interpreter {
 	fmt.Printf("resources:\n%s\n", resources.RenderTable())
 
-	jobBuilder := &probe.JobBuilder{TimeoutSeconds: config.JobTimeoutSeconds}
+	jobBuilder := &probe.JobBuilder{
+		TimeoutSeconds:     config.JobTimeoutSeconds,
+		Kind:               config.ProbeKind,
+		HTTPPath:           config.HTTPPath,
+		ExpectedHTTPStatus: config.ExpectedHTTPStatus,
+	}
 	var kubeRunner *probe.Runner
 	if config.BatchJobs {
-		kubeRunner = probe.NewKubeBatchRunner(kubernetes, defaultBatchWorkersCount, jobBuilder)
+		kubeRunner = probe.NewKubeBatchRunner(kubernetes, defaultBatchWorkersCount, config.WorkerServer, jobBuilder)
 	} else {
 		kubeRunner = probe.NewKubeRunner(kubernetes, defaultWorkersCount, jobBuilder)
 	}
+	kubeRunner.Repeat = config.Repeat
 
 	return &Interpreter{
 		kubernetes: kubernetes,
@@ -63,9 +100,16 @@ func NewInterpreter(kubernetes kube.IKubernetes, resources *probe.Resources, con
 	}
 }
 
+// Close stops the worker servers kept running by the kube probes, if any.  It should be called once
+// all test cases have run.
+func (t *Interpreter) Close() error {
+	return t.kubeRunner.Close()
+}
+
 func (t *Interpreter) ExecuteTestCase(testCase *generator.TestCase) *Result {
 	result := &Result{InitialResources: t.resources, TestCase: testCase}
 	var err error
+	defer t.testCaseFinished(result)
 
 	// keep track of what's in the cluster, so that we can correctly simulate expected results
 	testCaseState := &TestCaseState{
@@ -75,7 +119,7 @@ func (t *Interpreter) ExecuteTestCase(testCase *generator.TestCase) *Result {
 	}
 
 	if t.Config.ResetClusterBeforeTestCase {
-		err = testCaseState.ResetClusterState()
+		err = testCaseState.ResetClusterState(t.Config.ResetAdminPolicies)
 		if err != nil {
 			result.Err = err
 			return result
@@ -84,7 +128,7 @@ func (t *Interpreter) ExecuteTestCase(testCase *generator.TestCase) *Result {
 	}
 
 	if t.Config.VerifyClusterStateBeforeTestCase {
-		err = testCaseState.VerifyClusterState()
+		err = testCaseState.VerifyClusterState(t.Config.ResetAdminPolicies)
 		if err != nil {
 			result.Err = err
 			return result
@@ -95,6 +139,7 @@ func (t *Interpreter) ExecuteTestCase(testCase *generator.TestCase) *Result {
 	// perform perturbations one at a time, and run a probe after each change
 	for stepIndex, step := range testCase.Steps {
 		// TODO grab actual netpols from kube and record in results, for extra debugging/sanity checks
+		t.Config.Events.Emit(&Event{Type: StepStartedEvent, TestCase: testCase.Description, Step: stepIndex + 1})
 
 		for actionIndex, action := range step.Actions {
 			if action.CreatePolicy != nil {
@@ -103,6 +148,18 @@ func (t *Interpreter) ExecuteTestCase(testCase *generator.TestCase) *Result {
 				err = testCaseState.UpdatePolicy(action.UpdatePolicy.Policy)
 			} else if action.DeletePolicy != nil {
 				err = testCaseState.DeletePolicy(action.DeletePolicy.Namespace, action.DeletePolicy.Name)
+			} else if action.CreateAdminPolicy != nil {
+				err = testCaseState.CreateAdminPolicy(action.CreateAdminPolicy.Policy)
+			} else if action.UpdateAdminPolicy != nil {
+				err = testCaseState.UpdateAdminPolicy(action.UpdateAdminPolicy.Policy)
+			} else if action.DeleteAdminPolicy != nil {
+				err = testCaseState.DeleteAdminPolicy(action.DeleteAdminPolicy.Name)
+			} else if action.CreateBaselineAdminPolicy != nil {
+				err = testCaseState.CreateBaselineAdminPolicy(action.CreateBaselineAdminPolicy.Policy)
+			} else if action.UpdateBaselineAdminPolicy != nil {
+				err = testCaseState.UpdateBaselineAdminPolicy(action.UpdateBaselineAdminPolicy.Policy)
+			} else if action.DeleteBaselineAdminPolicy != nil {
+				err = testCaseState.DeleteBaselineAdminPolicy(action.DeleteBaselineAdminPolicy.Name)
 			} else if action.CreateNamespace != nil {
 				err = testCaseState.CreateNamespace(action.CreateNamespace.Namespace, action.CreateNamespace.Labels)
 			} else if action.SetNamespaceLabels != nil {
@@ -111,6 +168,9 @@ func (t *Interpreter) ExecuteTestCase(testCase *generator.TestCase) *Result {
 				err = testCaseState.DeleteNamespace(action.DeleteNamespace.Namespace)
 			} else if action.ReadNetworkPolicies != nil {
 				err = testCaseState.ReadPolicies(action.ReadNetworkPolicies.Namespaces)
+				if err == nil && action.ReadNetworkPolicies.AdminPolicies {
+					err = testCaseState.ReadAdminPolicies()
+				}
 			} else if action.CreatePod != nil {
 				err = testCaseState.CreatePod(action.CreatePod.Namespace, action.CreatePod.Pod, action.CreatePod.Labels)
 			} else if action.SetPodLabels != nil {
@@ -127,11 +187,18 @@ func (t *Interpreter) ExecuteTestCase(testCase *generator.TestCase) *Result {
 			}
 		}
 
-		logrus.Infof("step %d: waiting %d seconds for perturbation to take effect", stepIndex+1, t.Config.PerturbationWaitSeconds)
-		time.Sleep(t.Config.PerturbationWaitDuration())
+		var stepResult *StepResult
+		if t.Config.ConvergenceTimeoutSeconds > 0 {
+			logrus.Infof("step %d: probing for up to %d seconds for perturbation to take effect", stepIndex+1, t.Config.ConvergenceTimeoutSeconds)
+			stepResult = t.runProbeUntilConverged(testCaseState, testCase.Description, stepIndex+1, step)
+		} else {
+			logrus.Infof("step %d: waiting %d seconds for perturbation to take effect", stepIndex+1, t.Config.PerturbationWaitSeconds)
+			time.Sleep(t.Config.PerturbationWaitDuration())
 
-		stepResult := t.runProbe(testCaseState, step.Probe)
+			stepResult = t.runProbe(testCaseState, testCase.Description, stepIndex+1, step)
+		}
 		result.Steps = append(result.Steps, stepResult)
+		t.Config.Events.stepFinished(testCase.Description, stepIndex+1, stepResult, t.Config.IgnoreLoopback)
 
 		if t.Config.FailFast && !stepResult.Passed(t.Config.IgnoreLoopback) {
 			break
@@ -141,8 +208,22 @@ func (t *Interpreter) ExecuteTestCase(testCase *generator.TestCase) *Result {
 	return result
 }
 
-func (t *Interpreter) runProbe(testCaseState *TestCaseState, probeConfig *generator.ProbeConfig) *StepResult {
-	parsedPolicy := matcher.BuildNetworkPolicies(true, testCaseState.Policies)
+func (t *Interpreter) testCaseFinished(result *Result) {
+	if t.Config.Events == nil {
+		return
+	}
+	event := &Event{Type: TestCaseFinishedEvent, TestCase: result.TestCase.Description}
+	if result.Err != nil {
+		event.Error = result.Err.Error()
+	} else {
+		passed := result.Passed(t.Config.IgnoreLoopback)
+		event.Passed = &passed
+	}
+	t.Config.Events.Emit(event)
+}
+
+func (t *Interpreter) simulateProbe(testCaseState *TestCaseState, probeConfig *generator.ProbeConfig, expected *generator.ExpectedConnectivity) *StepResult {
+	parsedPolicy := matcher.BuildV1AndV2NetPols(true, testCaseState.Policies, testCaseState.ANPs, testCaseState.BANP)
 
 	logrus.Infof("running probe %+v", probeConfig)
 	logrus.Debugf("with resources:\n%s", testCaseState.Resources.RenderTable())
@@ -153,10 +234,19 @@ func (t *Interpreter) runProbe(testCaseState *TestCaseState, probeConfig *genera
 		simRunner.RunProbeForConfig(probeConfig, testCaseState.Resources),
 		parsedPolicy,
 		append([]*networkingv1.NetworkPolicy{}, testCaseState.Policies...)) // this looks weird, but just making a new copy to avoid accidentally mutating it elsewhere
+	stepResult.ANPs = append([]*v1alpha1.AdminNetworkPolicy{}, testCaseState.ANPs...)
+	stepResult.BANP = testCaseState.BANP
+	stepResult.Expected = expected
+	return stepResult
+}
+
+func (t *Interpreter) runProbe(testCaseState *TestCaseState, description string, stepNumber int, step *generator.TestStep) *StepResult {
+	stepResult := t.simulateProbe(testCaseState, step.Probe, step.Expected)
 
 	for i := 0; i <= t.Config.KubeProbeRetries; i++ {
 		logrus.Infof("running kube probe on try %d", i+1)
-		stepResult.AddKubeProbe(t.kubeRunner.RunProbeForConfig(probeConfig, testCaseState.Resources))
+		stepResult.AddKubeProbe(t.kubeRunner.RunProbeForConfig(step.Probe, testCaseState.Resources))
+		t.Config.Events.probeFinished(description, stepNumber, stepResult, t.Config.IgnoreLoopback)
 		// no differences between synthetic and kube probes?  then we can stop
 		if stepResult.Passed(t.Config.IgnoreLoopback) {
 			break
@@ -165,3 +255,30 @@ func (t *Interpreter) runProbe(testCaseState *TestCaseState, probeConfig *genera
 
 	return stepResult
 }
+
+// runProbeUntilConverged probes kube until the results match the simulation, and records how long that
+// took.  Since the time is measured when a matching probe finishes, it includes the duration of that probe.
+func (t *Interpreter) runProbeUntilConverged(testCaseState *TestCaseState, description string, stepNumber int, step *generator.TestStep) *StepResult {
+	start := time.Now()
+	stepResult := t.simulateProbe(testCaseState, step.Probe, step.Expected)
+
+	for {
+		logrus.Infof("running kube probe on try %d", len(stepResult.KubeProbes)+1)
+		stepResult.AddKubeProbe(t.kubeRunner.RunProbeForConfig(step.Probe, testCaseState.Resources))
+		t.Config.Events.probeFinished(description, stepNumber, stepResult, t.Config.IgnoreLoopback)
+		elapsed := time.Since(start)
+		if stepResult.Passed(t.Config.IgnoreLoopback) {
+			logrus.Infof("converged after %s", elapsed)
+			stepResult.Convergence = &Convergence{Converged: true, TimeToEnforce: elapsed}
+			break
+		}
+		if elapsed >= t.Config.ConvergenceTimeoutDuration() {
+			logrus.Warnf("did not converge within %s", t.Config.ConvergenceTimeoutDuration())
+			stepResult.Convergence = &Convergence{Converged: false, TimeToEnforce: elapsed}
+			break
+		}
+		time.Sleep(t.Config.ConvergencePollDuration())
+	}
+
+	return stepResult
+}
@@ -2,15 +2,24 @@ package connectivity
 
 import (
 	"encoding/xml"
+	"fmt"
 	"os"
+	"strings"
 
 	junit "github.com/jstemmer/go-junit-report/formatter"
+	"github.com/mattfenwick/collections/pkg/slice"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/exp/maps"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
 )
 
 type JUnitTestResult struct {
-	Passed bool
-	Name   string
+	Passed    bool
+	Name      string
+	Classname string
+	// Message and Details describe a failure
+	Message string
+	Details string
 }
 
 func PrintJUnitResults(filename string, results []*Result, ignoreLoopback bool) error {
@@ -20,10 +29,7 @@ func PrintJUnitResults(filename string, results []*Result, ignoreLoopback bool)
 
 	var junitResults []*JUnitTestResult
 	for _, result := range results {
-		junitResults = append(junitResults, &JUnitTestResult{
-			Passed: result.Passed(ignoreLoopback),
-			Name:   result.TestCase.Description,
-		})
+		junitResults = append(junitResults, NewJUnitTestResults(result, ignoreLoopback)...)
 	}
 
 	f, err := os.Create(filename)
@@ -39,16 +45,65 @@ func PrintJUnitResults(filename string, results []*Result, ignoreLoopback bool)
 	return enc.Encode(junitTestSuite)
 }
 
+// NewJUnitTestResults has a JUnit test case for each step which passed, and for each mismatch of a step
+// which failed, so that each failure is reported with its expected and actual verdicts and the simulation's
+// walkthrough.  Test cases are grouped into classes by their primary tags.
+func NewJUnitTestResults(result *Result, ignoreLoopback bool) []*JUnitTestResult {
+	classname := junitClassname(result.TestCase.Tags)
+	if result.Err != nil {
+		return []*JUnitTestResult{{
+			Name:      result.TestCase.Description,
+			Classname: classname,
+			Message:   "test case failed to execute",
+			Details:   fmt.Sprintf("%+v", result.Err),
+		}}
+	}
+
+	var junitResults []*JUnitTestResult
+	for i, step := range result.Steps {
+		name := fmt.Sprintf("%s / step %d", result.TestCase.Description, i+1)
+		if step.Passed(ignoreLoopback) {
+			junitResults = append(junitResults, &JUnitTestResult{Passed: true, Name: name, Classname: classname})
+			continue
+		}
+		for _, mismatch := range step.Mismatches(ignoreLoopback) {
+			junitResults = append(junitResults, &JUnitTestResult{
+				Name:      fmt.Sprintf("%s / %s -> %s %s", name, mismatch.From, mismatch.To, mismatch.Key),
+				Classname: classname,
+				Message:   fmt.Sprintf("expected %s (simulated), got %s", mismatch.Simulated, mismatch.Kube),
+				Details:   strings.Join(walkthroughStrings(step.Walkthrough(mismatch.Job)), "\n"),
+			})
+		}
+		for _, mismatch := range step.ExpectationMismatches() {
+			junitResults = append(junitResults, &JUnitTestResult{
+				Name:      fmt.Sprintf("%s / %s -> %s %s (expected connectivity)", name, mismatch.From, mismatch.To, mismatch.Key),
+				Classname: classname,
+				Message:   fmt.Sprintf("expected %s, simulated %s, got %s", mismatch.Expected, mismatch.Simulated, mismatch.Kube),
+			})
+		}
+	}
+	return junitResults
+}
+
+func junitClassname(tags generator.StringSet) string {
+	primaries := slice.Sort(maps.Keys(tags.GroupTags()))
+	if len(primaries) == 0 {
+		return "policy-assistant.untagged"
+	}
+	return "policy-assistant." + strings.Join(primaries, "-")
+}
+
 func ResultsToJUnit(results []*JUnitTestResult) junit.JUnitTestSuite {
 	var testCases []junit.JUnitTestCase
 	failed := 0
 
 	for _, result := range results {
 		testCase := junit.JUnitTestCase{
-			Name: result.Name,
+			Name:      result.Name,
+			Classname: result.Classname,
 		}
 		if !result.Passed {
-			testCase.Failure = &junit.JUnitFailure{}
+			testCase.Failure = &junit.JUnitFailure{Message: result.Message, Contents: result.Details}
 			failed++
 		}
 		testCases = append(testCases, testCase)
@@ -0,0 +1,71 @@
+package connectivity
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+)
+
+// ParallelWorker executes test cases against its own namespaces and resources.  TestCases holds the
+// worker's copy of every test case -- rewritten for its namespaces -- so that all workers agree on
+// which test case an index refers to.
+type ParallelWorker struct {
+	Interpreter *Interpreter
+	TestCases   []*generator.TestCase
+}
+
+type parallelResult struct {
+	Index  int
+	Result *Result
+}
+
+// RunInParallel executes the test cases at the given indices, each on whichever worker is free first.
+// handle is called in the calling goroutine with each result, in order of completion; once it returns
+// false, no more test cases are started, although results of test cases already running are still handled.
+func RunInParallel(workers []*ParallelWorker, indices []int, handle func(index int, result *Result) bool) {
+	jobs := make(chan int)
+	results := make(chan *parallelResult)
+	stop := make(chan struct{})
+
+	go func() {
+		defer close(jobs)
+		for _, index := range indices {
+			select {
+			case jobs <- index:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i, worker := range workers {
+		wg.Add(1)
+		go func(workerIndex int, worker *ParallelWorker) {
+			defer wg.Done()
+			for index := range jobs {
+				select {
+				case <-stop:
+					continue
+				default:
+				}
+				logrus.Infof("worker %d: starting test case #%d", workerIndex, index+1)
+				results <- &parallelResult{Index: index, Result: worker.Interpreter.ExecuteTestCase(worker.TestCases[index])}
+			}
+		}(i, worker)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	stopped := false
+	for result := range results {
+		if !handle(result.Index, result.Result) && !stopped {
+			stopped = true
+			close(stop)
+		}
+	}
+}
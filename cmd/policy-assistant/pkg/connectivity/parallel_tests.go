@@ -0,0 +1,71 @@
+package connectivity
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+)
+
+func RunParallelTests() {
+	Describe("RunInParallel", func() {
+		namespaces := []string{"x", "y", "z"}
+		testCases := generator.NewTestCaseGenerator(true, "1.2.3.4", namespaces, []string{}, []string{}).RulesTestCases()
+
+		setupWorkers := func(count int) []*ParallelWorker {
+			mock := kube.NewMockKubernetes(1.0)
+			var workers []*ParallelWorker
+			for i := 0; i < count; i++ {
+				workerNamespaces := generator.WorkerNamespaces(namespaces, i)
+				resources, err := probe.NewDefaultResources(mock, workerNamespaces, []string{"a", "b"}, []int{80, 81}, []v1.Protocol{v1.ProtocolTCP, v1.ProtocolUDP}, []string{}, []string{}, 10, false, "registry.k8s.io")
+				Expect(err).ToNot(HaveOccurred())
+
+				worker := &ParallelWorker{
+					Interpreter: NewInterpreter(mock, resources, &InterpreterConfig{ResetClusterBeforeTestCase: true, VerifyClusterStateBeforeTestCase: true}),
+				}
+				for _, testCase := range testCases {
+					rewritten, err := generator.RewriteNamespaces(testCase, generator.NamespaceMapping(namespaces, workerNamespaces))
+					Expect(err).ToNot(HaveOccurred())
+					worker.TestCases = append(worker.TestCases, rewritten)
+				}
+				workers = append(workers, worker)
+			}
+			return workers
+		}
+
+		var indices []int
+		for i := range testCases {
+			indices = append(indices, i)
+		}
+
+		It("should give the same results as running serially", func() {
+			serial := setupWorkers(1)[0]
+			results := map[int]*Result{}
+			RunInParallel(setupWorkers(3), indices, func(index int, result *Result) bool {
+				Expect(results).ToNot(HaveKey(index))
+				results[index] = result
+				return true
+			})
+
+			Expect(results).To(HaveLen(len(testCases)))
+			for i, testCase := range testCases {
+				Expect(results[i].Err).ToNot(HaveOccurred())
+				expected := serial.Interpreter.ExecuteTestCase(testCase)
+				Expect(expected.Err).ToNot(HaveOccurred())
+				Expect(results[i].ResultsByProtocol()).To(Equal(expected.ResultsByProtocol()), testCase.Description)
+			}
+		})
+
+		It("should stop starting test cases once handle returns false", func() {
+			workers := setupWorkers(2)
+			handled := 0
+			RunInParallel(workers, indices, func(index int, result *Result) bool {
+				handled++
+				return false
+			})
+			Expect(handled).To(BeNumerically("<=", 1+len(workers)))
+		})
+	})
+}
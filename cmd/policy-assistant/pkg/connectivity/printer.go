@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"strings"
+	"time"
 
 	"github.com/mattfenwick/collections/pkg/slice"
 	"github.com/sirupsen/logrus"
@@ -23,6 +24,7 @@ type Printer struct {
 	Noisy            bool
 	IgnoreLoopback   bool
 	JunitResultsFile string
+	HTMLReportFile   string
 	Results          []*Result
 }
 
@@ -33,6 +35,9 @@ func (t *Printer) PrintSummary() {
 		fmt.Println(passFailTable(primary, counts, nil, nil))
 	}
 	fmt.Println(protocolPassFailTable(summary.ProtocolCounts))
+	if len(summary.TimesToEnforce) > 0 || summary.NotConverged > 0 {
+		fmt.Println(timeToEnforceTable(summary))
+	}
 
 	fmt.Printf("Feature results:\n%s\n\n", t.printMarkdownFeatureTable(summary.FeaturePrimaryCounts, summary.FeatureCounts))
 	fmt.Printf("Tag results:\n%s\n", t.printMarkdownFeatureTable(summary.TagPrimaryCounts, summary.TagCounts))
@@ -40,6 +45,9 @@ func (t *Printer) PrintSummary() {
 	if err := PrintJUnitResults(t.JunitResultsFile, t.Results, t.IgnoreLoopback); err != nil {
 		logrus.Errorf("unable to dump JUnit test results: %+v", err)
 	}
+	if err := WriteHTMLReport(t.HTMLReportFile, t.Results, t.IgnoreLoopback); err != nil {
+		logrus.Errorf("unable to write html report: %+v", err)
+	}
 }
 
 const (
@@ -74,6 +82,16 @@ func (m *markdownRow) GetResult() string {
 }
 
 func (t *Printer) printMarkdownFeatureTable(primaryCounts map[string]map[bool]int, tagCounts map[string]map[string]map[bool]int) string {
+	lines := []string{"| Tag | Result |", "| --- | --- |"}
+	for _, row := range markdownRows(primaryCounts, tagCounts) {
+		lines = append(lines, fmt.Sprintf("| %s | %s |", row.GetName(), row.GetResult()))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// markdownRows lists each primary feature or tag followed by its sub-features or sub-tags.
+func markdownRows(primaryCounts map[string]map[bool]int, tagCounts map[string]map[string]map[bool]int) []*markdownRow {
 	primaries := slice.Sort(maps.Keys(tagCounts))
 
 	var rows []*markdownRow
@@ -90,13 +108,7 @@ func (t *Printer) printMarkdownFeatureTable(primaryCounts map[string]map[bool]in
 			})
 		}
 	}
-
-	lines := []string{"| Tag | Result |", "| --- | --- |"}
-	for _, row := range rows {
-		lines = append(lines, fmt.Sprintf("| %s | %s |", row.GetName(), row.GetResult()))
-	}
-
-	return strings.Join(lines, "\n")
+	return rows
 }
 
 func (t *Printer) printTestSummary(rows [][]string) {
@@ -105,7 +117,7 @@ func (t *Printer) printTestSummary(rows [][]string) {
 	table := tablewriter.NewWriter(tableString)
 	table.SetRowLine(true)
 
-	table.SetHeader([]string{"Test", "Result", "Step/Try", "Wrong", "Right", "Ignored", "TCP", "SCTP", "UDP"})
+	table.SetHeader([]string{"Test", "Result", "Step/Try", "Wrong", "Right", "Ignored", "Flaky", "TCP", "SCTP", "UDP"})
 
 	table.AppendBulk(rows)
 
@@ -159,19 +171,30 @@ func protocolPassFailTable(protocolCounts map[v1.Protocol]map[Comparison]int) st
 	table.SetAutoWrapText(false)
 	str.WriteString("Pass/Fail for probes on protocols:\n")
 
-	table.SetHeader([]string{"Protocol", "Passed", "Failed", "Passed %"})
+	table.SetHeader([]string{"Protocol", "Passed", "Failed", "Passed %", "Flaky"})
 
-	var rows []*passFailRow
 	for protocol, counts := range protocolCounts {
-		rows = append(rows, &passFailRow{
+		row := &passFailRow{
 			Feature: fmt.Sprintf("probe on %s", protocol),
 			Passed:  counts[SameComparison],
 			Failed:  counts[DifferentComparison],
-		})
+		}
+		table.Append([]string{row.Feature, intToString(row.Passed), intToString(row.Failed), fmt.Sprintf("%.0f", PassedPercentage(row)), intToString(counts[FlakyComparison])})
 	}
 
-	for _, row := range rows {
-		table.Append([]string{row.Feature, intToString(row.Passed), intToString(row.Failed), fmt.Sprintf("%.0f", PassedPercentage(row))})
+	table.Render()
+	return str.String()
+}
+
+func timeToEnforceTable(summary *SummaryTable) string {
+	str := &strings.Builder{}
+	table := tablewriter.NewWriter(str)
+	table.SetAutoWrapText(false)
+	str.WriteString(fmt.Sprintf("Time to enforce (%d steps converged, %d did not):\n", len(summary.TimesToEnforce), summary.NotConverged))
+
+	table.SetHeader([]string{"Percentile", "Time"})
+	for _, percentile := range []float64{50, 90, 99, 100} {
+		table.Append([]string{fmt.Sprintf("p%.0f", percentile), summary.Percentile(percentile).Round(time.Millisecond).String()})
 	}
 
 	table.Render()
@@ -189,6 +212,12 @@ func intToString(i int) string {
 	return fmt.Sprintf("%d", i)
 }
 
+// AddStoredResult includes a result from a previous run in the summary, without printing its steps.
+func (t *Printer) AddStoredResult(result *Result) {
+	t.Results = append(t.Results, result)
+	fmt.Printf("using stored result for test case: %s\n\n", result.TestCase.Description)
+}
+
 func (t *Printer) PrintTestCaseResult(result *Result) {
 	t.Results = append(t.Results, result)
 
@@ -233,6 +262,12 @@ func (t *Printer) PrintStep(i int, step *generator.TestStep, stepResult *StepRes
 	} else {
 		fmt.Println("no network policies")
 	}
+	for _, anp := range stepResult.ANPs {
+		fmt.Printf("Admin network policy:\n\n%s\n", utils.YamlString(anp))
+	}
+	if stepResult.BANP != nil {
+		fmt.Printf("Baseline admin network policy:\n\n%s\n", utils.YamlString(stepResult.BANP))
+	}
 
 	if len(stepResult.KubeProbes) == 0 {
 		panic(errors.Errorf("found 0 KubeResults for step, expected 1 or more"))
@@ -243,7 +278,25 @@ func (t *Printer) PrintStep(i int, step *generator.TestStep, stepResult *StepRes
 	if counts[DifferentComparison] > 0 {
 		fmt.Printf("Discrepancy found:")
 	}
-	fmt.Printf("%d wrong, %d ignored, %d correct\n", counts[DifferentComparison], counts[IgnoredComparison], counts[SameComparison])
+	fmt.Printf("%d wrong, %d ignored, %d correct", counts[DifferentComparison], counts[IgnoredComparison], counts[SameComparison])
+	if counts[FlakyComparison] > 0 {
+		fmt.Printf(", %d flaky", counts[FlakyComparison])
+	}
+	fmt.Println()
+	if stepResult.Convergence != nil {
+		if stepResult.Convergence.Converged {
+			fmt.Printf("enforced after %s and %d probes\n", stepResult.Convergence.TimeToEnforce.Round(time.Millisecond), len(stepResult.KubeProbes))
+		} else {
+			fmt.Printf("not enforced after %s and %d probes\n", stepResult.Convergence.TimeToEnforce.Round(time.Millisecond), len(stepResult.KubeProbes))
+		}
+	}
+
+	if mismatches := stepResult.ExpectationMismatches(); len(mismatches) > 0 {
+		fmt.Printf("%d results differ from the expected connectivity:\n", len(mismatches))
+		for _, mismatch := range mismatches {
+			fmt.Printf(" - %s\n", mismatch.String())
+		}
+	}
 
 	if counts[DifferentComparison] > 0 || t.Noisy {
 		fmt.Printf("Expected ingress:\n%s\n", stepResult.SimulatedProbe.RenderIngress())
@@ -4,6 +4,10 @@ import (
 	junit "github.com/jstemmer/go-junit-report/formatter"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
 )
 
 func RunPrinterTests() {
@@ -47,5 +51,31 @@ func RunPrinterTests() {
 				Expect(actual).To(Equal(testCase.junit))
 			}
 		})
+
+		It("should report each mismatch of a failed step as a junit test case", func() {
+			mock := kube.NewMockKubernetes(1.0)
+			resources, err := probe.NewDefaultResources(mock, []string{"x", "y"}, []string{"a", "b"}, []int{80}, []v1.Protocol{v1.ProtocolTCP}, []string{}, []string{}, 10, false, "registry.k8s.io")
+			Expect(err).ToNot(HaveOccurred())
+			interpreter := NewInterpreter(mock, resources, &InterpreterConfig{ResetClusterBeforeTestCase: true, VerifyClusterStateBeforeTestCase: true, IgnoreLoopback: true})
+
+			// the mock allows all traffic, so it never enforces the policy, which restricts traffic to and from x/a
+			testCase := generator.NewTestCase("unenforced", generator.NewStringSet(generator.TagIngress),
+				generator.NewTestStep(generator.ProbeAllAvailable),
+				generator.NewTestStep(generator.ProbeAllAvailable, generator.CreatePolicy(generator.BuildPolicy().NetworkPolicy())))
+			result := interpreter.ExecuteTestCase(testCase)
+			Expect(result.Err).ToNot(HaveOccurred())
+
+			junitResults := NewJUnitTestResults(result, true)
+			Expect(junitResults[0]).To(Equal(&JUnitTestResult{Passed: true, Name: "unenforced / step 1", Classname: "policy-assistant.direction"}))
+			Expect(len(junitResults)).To(Equal(1 + result.Steps[1].LastComparison().ValueCounts(true)[DifferentComparison]))
+			for _, failed := range junitResults[1:] {
+				Expect(failed.Passed).To(BeFalse())
+				Expect(failed.Name).To(HavePrefix("unenforced / step 2 / "))
+				Expect(failed.Name).To(ContainSubstring("x/a"))
+				Expect(failed.Message).To(Equal("expected blocked (simulated), got allowed"))
+				Expect(failed.Details).To(ContainSubstring(": Denied; ingress: "))
+				Expect(failed.Details).To(ContainSubstring("[NPv1] Dropped (x/base"))
+			}
+		})
 	})
 }
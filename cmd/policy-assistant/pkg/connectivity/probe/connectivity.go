@@ -11,6 +11,17 @@ const (
 	ConnectivityInvalidPortProtocol Connectivity = "invalidportprotocol"
 	ConnectivityBlocked             Connectivity = "blocked"
 	ConnectivityAllowed             Connectivity = "allowed"
+	// ConnectivityRefused, ConnectivityTimedOut and ConnectivityReset are ways in which a kube probe can
+	// be blocked: by a policy which rejects or drops traffic, or by a missing listener
+	ConnectivityRefused  Connectivity = "refused"
+	ConnectivityTimedOut Connectivity = "timedout"
+	ConnectivityReset    Connectivity = "reset"
+	// ConnectivityAppError means a kube probe connected, but failed an application-layer check
+	ConnectivityAppError Connectivity = "apperror"
+	// ConnectivityMixed means traffic to a service is allowed to some of its endpoints, but not to others
+	ConnectivityMixed Connectivity = "mixed"
+	// ConnectivityFlaky means repeated kube probes of the same traffic didn't all get the same verdict
+	ConnectivityFlaky Connectivity = "flaky"
 	// ConnectivityUndefined e.g. for loopback traffic
 	ConnectivityUndefined Connectivity = "undefined"
 )
@@ -22,6 +33,35 @@ var AllConnectivity = []Connectivity{
 	ConnectivityInvalidPortProtocol,
 	ConnectivityBlocked,
 	ConnectivityAllowed,
+	ConnectivityRefused,
+	ConnectivityTimedOut,
+	ConnectivityReset,
+	ConnectivityAppError,
+	ConnectivityMixed,
+	ConnectivityFlaky,
+}
+
+// Verdict reduces the reasons a probe failed to whether traffic was blocked: a probe that connected is
+// allowed, even if the application-layer check failed.
+func (p Connectivity) Verdict() Connectivity {
+	switch p {
+	case ConnectivityRefused, ConnectivityTimedOut, ConnectivityReset:
+		return ConnectivityBlocked
+	case ConnectivityAppError:
+		return ConnectivityAllowed
+	default:
+		return p
+	}
+}
+
+// Agrees is true if a kube result has the same verdict as the simulated one.  A mixed simulation is only
+// agreed with by a kube result which saw both verdicts: mixed, or flaky across repetitions.  See
+// JobResult.Agrees for kube results which only saw one.
+func (p Connectivity) Agrees(simulated Connectivity) bool {
+	if simulated == ConnectivityMixed {
+		return p == ConnectivityMixed || p == ConnectivityFlaky
+	}
+	return p.Verdict() == simulated.Verdict()
 }
 
 func (p Connectivity) ShortString() string {
@@ -40,6 +80,18 @@ func (p Connectivity) ShortString() string {
 		return "N"
 	case ConnectivityUndefined:
 		return "#"
+	case ConnectivityRefused:
+		return "R"
+	case ConnectivityTimedOut:
+		return "T"
+	case ConnectivityReset:
+		return "E"
+	case ConnectivityAppError:
+		return "A"
+	case ConnectivityMixed:
+		return "M"
+	case ConnectivityFlaky:
+		return "F"
 	default:
 		panic(errors.Errorf("invalid Connectivity value: %+v", p))
 	}
@@ -0,0 +1,122 @@
+package probe
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+)
+
+const (
+	// StandInNamespace holds the stand-in server for external targets
+	StandInNamespace = "policy-assistant-external"
+	standInPodName   = "stand-in"
+)
+
+// ExternalTarget is a destination outside the cluster, which is probed from every pod.  Policies see it
+// only by IP, so a hostname is resolved -- locally, not by the cluster's resolver -- for the simulation.
+type ExternalTarget struct {
+	Host     string
+	IP       string
+	Port     int
+	Protocol v1.Protocol
+}
+
+// ParseExternalTarget parses '[protocol://]host:port'; the protocol defaults to tcp.
+func ParseExternalTarget(target string) (*ExternalTarget, error) {
+	protocol, address := v1.ProtocolTCP, target
+	if index := strings.Index(target, "://"); index >= 0 {
+		parsedProtocol, err := kube.ParseProtocol(target[:index])
+		if err != nil {
+			return nil, err
+		}
+		protocol, address = parsedProtocol, target[index+3:]
+	}
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid external target %s", target)
+	}
+	port, err := strconv.Atoi(portString)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid port in external target %s", target)
+	}
+
+	ip := host
+	if net.ParseIP(host) == nil {
+		ips, err := net.LookupIP(host)
+		if err != nil || len(ips) == 0 {
+			return nil, errors.Wrapf(err, "unable to resolve host of external target %s", target)
+		}
+		ip = ips[0].String()
+	}
+	return &ExternalTarget{Host: host, IP: ip, Port: port, Protocol: protocol}, nil
+}
+
+// Key identifies the target in truth tables.
+func (e *ExternalTarget) Key() string {
+	return fmt.Sprintf("%s://%s", strings.ToLower(string(e.Protocol)), net.JoinHostPort(e.Host, strconv.Itoa(e.Port)))
+}
+
+// NewStandInTarget runs a server on the network of one of the cluster's nodes, to stand in for an
+// external destination: traffic from pods to a node's IP is treated by policies as traffic to an IP
+// outside the cluster.
+func NewStandInTarget(kubernetes kube.IKubernetes, port int, imageRegistry string, podCreationTimeoutSeconds int) (*ExternalTarget, error) {
+	if _, err := kubernetes.GetNamespace(StandInNamespace); err != nil {
+		if _, err := kubernetes.CreateNamespace(KubeNamespace(StandInNamespace, map[string]string{})); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := kubernetes.GetPod(StandInNamespace, standInPodName); err != nil {
+		if _, err := kubernetes.CreatePod(standInKubePod(port, imageRegistry)); err != nil {
+			return nil, err
+		}
+	}
+
+	sleep := 5
+	for i := 0; i < podCreationTimeoutSeconds; i += sleep {
+		kubePod, err := kubernetes.GetPod(StandInNamespace, standInPodName)
+		if err != nil {
+			return nil, err
+		}
+		if kubePod.Status.Phase == v1.PodRunning && kubePod.Status.HostIP != "" {
+			logrus.Infof("stand-in for external target running on %s", kubePod.Status.HostIP)
+			return &ExternalTarget{Host: kubePod.Status.HostIP, IP: kubePod.Status.HostIP, Port: port, Protocol: v1.ProtocolTCP}, nil
+		}
+
+		logrus.Infof("waiting for stand-in for external target to be running")
+		time.Sleep(time.Duration(sleep) * time.Second)
+	}
+	return nil, errors.Errorf("stand-in for external target not ready")
+}
+
+func standInKubePod(port int, imageRegistry string) *v1.Pod {
+	zero := int64(0)
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      standInPodName,
+			Namespace: StandInNamespace,
+			Labels:    map[string]string{"pod": standInPodName},
+		},
+		Spec: v1.PodSpec{
+			TerminationGracePeriodSeconds: &zero,
+			HostNetwork:                   true,
+			Containers: []v1.Container{
+				{
+					Name:            "cont-stand-in",
+					ImagePullPolicy: v1.PullIfNotPresent,
+					Image:           AgnhostImage(imageRegistry),
+					Command:         []string{"/agnhost", "serve-hostname", "--http", "--port", fmt.Sprintf("%d", port)},
+					Ports:           []v1.ContainerPort{{ContainerPort: int32(port), Protocol: v1.ProtocolTCP}},
+					SecurityContext: &v1.SecurityContext{},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,94 @@
+package probe
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+)
+
+func RunExternalTargetTests() {
+	Describe("ParseExternalTarget", func() {
+		It("should parse ip targets, defaulting to tcp", func() {
+			target, err := ParseExternalTarget("8.8.8.8:53")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target).To(Equal(&ExternalTarget{Host: "8.8.8.8", IP: "8.8.8.8", Port: 53, Protocol: v1.ProtocolTCP}))
+			Expect(target.Key()).To(Equal("tcp://8.8.8.8:53"))
+
+			target, err = ParseExternalTarget("udp://[fd00::1]:53")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target).To(Equal(&ExternalTarget{Host: "fd00::1", IP: "fd00::1", Port: 53, Protocol: v1.ProtocolUDP}))
+			Expect(target.Key()).To(Equal("udp://[fd00::1]:53"))
+		})
+
+		It("should reject invalid targets", func() {
+			for _, target := range []string{"8.8.8.8", "8.8.8.8:http", "icmp://8.8.8.8:53"} {
+				_, err := ParseExternalTarget(target)
+				Expect(err).To(HaveOccurred(), target)
+			}
+		})
+	})
+
+	Describe("External targets", func() {
+		resources := &Resources{
+			Namespaces: map[string]map[string]string{"x": {}},
+			Pods: []*Pod{
+				{Namespace: "x", Name: "a", IP: "192.168.1.1", Containers: []*Container{{Name: "cont-80-tcp", Port: 80, Protocol: v1.ProtocolTCP, PortName: "serve-80-tcp"}}},
+				{Namespace: "x", Name: "b", IP: "192.168.1.2", Containers: []*Container{{Name: "cont-80-tcp", Port: 80, Protocol: v1.ProtocolTCP, PortName: "serve-80-tcp"}}},
+			},
+			ExternalTargets: []*ExternalTarget{
+				{Host: "8.8.8.8", IP: "8.8.8.8", Port: 53, Protocol: v1.ProtocolUDP},
+				{Host: "1.1.1.1", IP: "1.1.1.1", Port: 80, Protocol: v1.ProtocolTCP},
+			},
+		}
+
+		It("should add a column per target", func() {
+			Expect(resources.SortedTargetNames()).To(Equal([]string{"x/a", "x/b", "tcp://1.1.1.1:80", "udp://8.8.8.8:53"}))
+
+			runner := NewSimulatedRunner(matcher.BuildV1AndV2NetPols(true, nil, nil, nil), &JobBuilder{})
+			table := runner.RunProbeForConfig(generator.NewAllAvailable(generator.ProbeModePodIP), resources)
+			Expect(table.Wrapped.Froms).To(Equal([]string{"x/a", "x/b"}))
+			Expect(table.Wrapped.Tos).To(Equal(resources.SortedTargetNames()))
+			Expect(table.Get("x/a", "udp://8.8.8.8:53").JobResults).To(HaveKey("UDP/53"))
+			Expect(table.Get("x/a", "udp://8.8.8.8:53").JobResults["UDP/53"].Combined).To(Equal(ConnectivityAllowed))
+		})
+
+		It("should only probe a target on its own port and protocol", func() {
+			jobs := (&JobBuilder{}).GetJobsForNamedPortProtocol(resources, intstr.FromInt(80), v1.ProtocolTCP, generator.ProbeModePodIP)
+			Expect(jobs.Valid).To(HaveLen(6))
+			Expect(jobs.BadPortProtocol).To(HaveLen(2))
+			for _, job := range jobs.BadPortProtocol {
+				Expect(job.ToKey).To(Equal("udp://8.8.8.8:53"))
+			}
+
+			jobs = (&JobBuilder{}).GetJobsForNamedPortProtocol(resources, intstr.FromString("serve-80-tcp"), v1.ProtocolTCP, generator.ProbeModePodIP)
+			Expect(jobs.Valid).To(HaveLen(4))
+			Expect(jobs.BadNamedPort).To(HaveLen(4))
+		})
+
+		It("should simulate targets as peers outside the cluster", func() {
+			jobs := (&JobBuilder{}).GetJobsAllAvailableServers(resources, generator.ProbeModePodIP)
+			for _, job := range jobs.Valid {
+				traffic := job.Traffic()
+				Expect(traffic.Source.Internal).ToNot(BeNil())
+				if job.ToExternal {
+					Expect(traffic.Destination.Internal).To(BeNil())
+					Expect(traffic.Destination.IP).To(Equal(job.ToIP))
+				} else {
+					Expect(traffic.Destination.Internal).ToNot(BeNil())
+				}
+			}
+		})
+
+		It("should run a stand-in server on a node's network", func() {
+			target, err := NewStandInTarget(kube.NewMockKubernetes(1.0), 8080, "registry.k8s.io", 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target.IP).ToNot(BeEmpty())
+			Expect(target.Port).To(Equal(8080))
+			Expect(target.Protocol).To(Equal(v1.ProtocolTCP))
+		})
+	})
+}
@@ -4,10 +4,11 @@ import (
 	"fmt"
 	"net"
 	"strconv"
-	"strings"
 
 	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/worker"
 )
 
 type Jobs struct {
@@ -21,12 +22,45 @@ type JobResult struct {
 	Ingress  *Connectivity
 	Egress   *Connectivity
 	Combined Connectivity
+	// Probes and AllowedProbes count how often a repeated job was run, and how often it was allowed
+	Probes        int `json:",omitempty"`
+	AllowedProbes int `json:",omitempty"`
+	// Attempts counts the probes of a job to a service, across its repetitions
+	Attempts int `json:",omitempty"`
+}
+
+// Agrees is true if a kube result has the same verdict as the simulated one.  Each probe to a service is
+// forwarded to an endpoint picked at random, so a kube result of a service whose simulated result is
+// mixed may only have reached the allowed -- or the blocked -- endpoints; that's only a mismatch once
+// every endpoint has been probed serviceAttemptsPerEndpoint times on average, when the chance of missing
+// one of them is below 1%.
+func (jr *JobResult) Agrees(simulated *JobResult) bool {
+	if jr.Combined.Agrees(simulated.Combined) {
+		return true
+	}
+	if simulated.Combined != ConnectivityMixed {
+		return false
+	}
+	switch jr.Combined.Verdict() {
+	case ConnectivityAllowed, ConnectivityBlocked:
+		return jr.Attempts < serviceAttemptsPerEndpoint*len(jr.Job.ToEndpoints)
+	default:
+		return false
+	}
 }
 
 func (jr *JobResult) Key() string {
 	return fmt.Sprintf("%s/%d", jr.Job.Protocol, jr.Job.ResolvedPort)
 }
 
+// SuccessRatio is the fraction of a repeated job's probes which were allowed.
+func (jr *JobResult) SuccessRatio() float64 {
+	if jr.Probes == 0 {
+		return 0
+	}
+	return float64(jr.AllowedProbes) / float64(jr.Probes)
+}
+
 type Job struct {
 	FromKey             string
 	FromNamespace       string
@@ -43,26 +77,116 @@ type Job struct {
 	ToPodLabels       map[string]string
 	ToContainer       string
 	ToIP              string
+	// ToExternal is set for jobs to an ExternalTarget, which policies only see by IP
+	ToExternal bool `json:",omitempty"`
+	// ToService is set for jobs to a Service, and ToEndpoints are the pods the traffic may be forwarded to.
+	// RequestPort is the port requests are sent to, when it differs from ResolvedPort -- the port of the
+	// endpoints -- as for a NodePort service.  Since each request reaches one endpoint, a job to a service
+	// is run as several attempts.
+	ToService   bool        `json:",omitempty"`
+	ToEndpoints []*Endpoint `json:",omitempty"`
+	RequestPort int         `json:",omitempty"`
+	Attempt     int         `json:",omitempty"`
+	// Repetition distinguishes the copies of a job which is probed several times to detect flakiness
+	Repetition int `json:",omitempty"`
 
 	ResolvedPort     int
 	ResolvedPortName string
 	Protocol         v1.Protocol
 
 	TimeoutSeconds int
+	// Kind, HTTPPath and ExpectedHTTPStatus select an application-layer probe; by default, jobs only
+	// check that a connection can be made
+	Kind               worker.RequestKind
+	HTTPPath           string
+	ExpectedHTTPStatus int
+}
+
+// Endpoint is a pod which traffic to a service may be forwarded to.
+type Endpoint struct {
+	Key             string
+	Namespace       string
+	NamespaceLabels map[string]string
+	PodLabels       map[string]string
+	IP              string
 }
 
 func (j *Job) Key() string {
-	return fmt.Sprintf("%s/%s/%s/%s/%s/%d", j.FromKey, j.FromContainer, j.ToKey, j.ToContainer, j.Protocol, j.ResolvedPort)
+	key := fmt.Sprintf("%s/%s/%s/%s/%s/%d", j.FromKey, j.FromContainer, j.ToKey, j.ToContainer, j.Protocol, j.ResolvedPort)
+	if j.Repetition > 0 {
+		key = fmt.Sprintf("%s@%d", key, j.Repetition)
+	}
+	if j.Attempt > 0 {
+		return fmt.Sprintf("%s#%d", key, j.Attempt)
+	}
+	return key
+}
+
+// Attempts is how many times the job should be run: once, unless it's to a service with several endpoints.
+// A lookup of a service's name doesn't reach its endpoints, so it's only run once.
+func (j *Job) Attempts() []*Job {
+	if len(j.ToEndpoints) <= 1 || j.Kind == worker.RequestKindDNS {
+		return []*Job{j}
+	}
+	attempts := make([]*Job, serviceAttemptsPerEndpoint*len(j.ToEndpoints))
+	for i := range attempts {
+		attempt := *j
+		attempt.Attempt = i
+		attempts[i] = &attempt
+	}
+	return attempts
+}
+
+// Repetitions copies the job, so that it's probed count times.
+func (j *Job) Repetitions(count int) []*Job {
+	if count <= 1 {
+		return []*Job{j}
+	}
+	repetitions := make([]*Job, count)
+	for i := range repetitions {
+		repetition := *j
+		repetition.Repetition = i
+		repetitions[i] = &repetition
+	}
+	return repetitions
+}
+
+func (j *Job) requestPort() int {
+	if j.RequestPort != 0 {
+		return j.RequestPort
+	}
+	return j.ResolvedPort
 }
 
 func (j *Job) ToAddress() string {
-	return net.JoinHostPort(j.ToHost, strconv.Itoa(j.ResolvedPort))
+	return net.JoinHostPort(j.ToHost, strconv.Itoa(j.requestPort()))
+}
+
+// Request describes the job as a worker request, which both the batch worker and a single exec can issue.
+func (j *Job) Request() *worker.Request {
+	return &worker.Request{
+		Key:            j.Key(),
+		Protocol:       j.Protocol,
+		Host:           j.ToHost,
+		Port:           j.requestPort(),
+		Kind:           j.requestKind(),
+		Path:           j.HTTPPath,
+		ExpectedStatus: j.ExpectedHTTPStatus,
+		TimeoutSeconds: j.TimeoutSeconds,
+	}
+}
+
+// requestKind is the kind of request to issue: http and tls requests are sent over TCP, so jobs over other
+// protocols only check that a connection can be made.
+func (j *Job) requestKind() worker.RequestKind {
+	if (j.Kind == worker.RequestKindHTTP || j.Kind == worker.RequestKindTLS) && j.Protocol != v1.ProtocolTCP {
+		return worker.RequestKindConnect
+	}
+	return j.Kind
 }
 
 func (j *Job) ClientCommand() []string {
-	return []string{"/agnhost", "connect", j.ToAddress(),
-		fmt.Sprintf("--timeout=%ds", j.TimeoutSeconds),
-		fmt.Sprintf("--protocol=%s", strings.ToLower(string(j.Protocol)))}
+	return j.Request().Command()
 }
 
 func (j *Job) KubeExecCommand() []string {
@@ -76,7 +200,56 @@ func (j *Job) KubeExecCommand() []string {
 		j.ClientCommand()...)
 }
 
+// IsDNS is true if the job looks up its destination, instead of connecting to it.
+func (j *Job) IsDNS() bool {
+	return j.Kind == worker.RequestKindDNS
+}
+
+// Traffic is the traffic to the job's destination; for a DNS lookup, it's the query to the cluster's DNS
+// pods, whose IPs aren't known, so ipBlock peers never match it.
 func (j *Job) Traffic() *matcher.Traffic {
+	if j.IsDNS() {
+		traffic := j.traffic(&matcher.TrafficPeer{
+			Internal: &matcher.InternalPeer{
+				PodLabels:       kube.DNSPodLabels,
+				NamespaceLabels: map[string]string{kube.DefaultNamespaceLabel: kube.DNSNamespace},
+				Namespace:       kube.DNSNamespace,
+			},
+		})
+		traffic.ResolvedPort, traffic.ResolvedPortName, traffic.Protocol = kube.DNSPort, kube.DNSPortName, v1.ProtocolUDP
+		return traffic
+	}
+	destination := &matcher.TrafficPeer{
+		Internal: &matcher.InternalPeer{
+			PodLabels:       j.ToPodLabels,
+			NamespaceLabels: j.ToNamespaceLabels,
+			Namespace:       j.ToNamespace,
+		},
+		IP: j.ToIP,
+	}
+	if j.ToExternal {
+		destination = &matcher.TrafficPeer{IP: j.ToIP}
+	}
+	return j.traffic(destination)
+}
+
+// EndpointTraffic is the traffic to each of the service's endpoints, after DNAT.
+func (j *Job) EndpointTraffic() []*matcher.Traffic {
+	var traffic []*matcher.Traffic
+	for _, endpoint := range j.ToEndpoints {
+		traffic = append(traffic, j.traffic(&matcher.TrafficPeer{
+			Internal: &matcher.InternalPeer{
+				PodLabels:       endpoint.PodLabels,
+				NamespaceLabels: endpoint.NamespaceLabels,
+				Namespace:       endpoint.Namespace,
+			},
+			IP: endpoint.IP,
+		}))
+	}
+	return traffic
+}
+
+func (j *Job) traffic(destination *matcher.TrafficPeer) *matcher.Traffic {
 	return &matcher.Traffic{
 		Source: &matcher.TrafficPeer{
 			Internal: &matcher.InternalPeer{
@@ -86,14 +259,7 @@ func (j *Job) Traffic() *matcher.Traffic {
 			},
 			IP: j.FromIP,
 		},
-		Destination: &matcher.TrafficPeer{
-			Internal: &matcher.InternalPeer{
-				PodLabels:       j.ToPodLabels,
-				NamespaceLabels: j.ToNamespaceLabels,
-				Namespace:       j.ToNamespace,
-			},
-			IP: j.ToIP,
-		},
+		Destination:      destination,
 		ResolvedPort:     j.ResolvedPort,
 		ResolvedPortName: j.ResolvedPortName,
 		Protocol:         j.Protocol,
@@ -1,14 +1,21 @@
 package probe
 
 import (
+	"fmt"
+
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/worker"
 )
 
 type JobBuilder struct {
 	TimeoutSeconds int
+	// Kind, HTTPPath and ExpectedHTTPStatus are copied to every job
+	Kind               worker.RequestKind
+	HTTPPath           string
+	ExpectedHTTPStatus int
 }
 
 func (j *JobBuilder) GetJobsForProbeConfig(resources *Resources, config *generator.ProbeConfig) *Jobs {
@@ -31,7 +38,7 @@ func (j *JobBuilder) GetJobsForNamedPortProtocol(resources *Resources, port ints
 				FromNamespaceLabels: resources.Namespaces[podFrom.Namespace],
 				FromPod:             podFrom.Name,
 				FromPodLabels:       podFrom.Labels,
-				FromContainer:       podFrom.Containers[0].Name,
+				FromContainer:       podFrom.FromContainer(),
 				FromIP:              podFrom.IP,
 				ToKey:               podTo.PodString().String(),
 				ToHost:              podTo.Host(mode),
@@ -43,6 +50,9 @@ func (j *JobBuilder) GetJobsForNamedPortProtocol(resources *Resources, port ints
 				ResolvedPortName:    "",
 				Protocol:            protocol,
 				TimeoutSeconds:      j.TimeoutSeconds,
+				Kind:                j.Kind,
+				HTTPPath:            j.HTTPPath,
+				ExpectedHTTPStatus:  j.ExpectedHTTPStatus,
 			}
 
 			switch port.Type {
@@ -70,6 +80,48 @@ func (j *JobBuilder) GetJobsForNamedPortProtocol(resources *Resources, port ints
 
 			jobs.Valid = append(jobs.Valid, job)
 		}
+		for _, service := range resources.Services {
+			switch port.Type {
+			case intstr.String:
+				servicePort := service.NamedPort(port.StrVal)
+				if servicePort == nil {
+					job := j.serviceJob(resources, podFrom, service, &Container{Port: -1, PortName: port.StrVal, Protocol: protocol}, mode)
+					jobs.BadNamedPort = append(jobs.BadNamedPort, job)
+				} else {
+					jobs.Valid = append(jobs.Valid, j.serviceJob(resources, podFrom, service, servicePort, mode))
+				}
+			case intstr.Int:
+				servicePort := service.NumberedPort(int(port.IntVal), protocol)
+				if servicePort == nil {
+					job := j.serviceJob(resources, podFrom, service, &Container{Port: int(port.IntVal), Protocol: protocol}, mode)
+					jobs.BadPortProtocol = append(jobs.BadPortProtocol, job)
+				} else {
+					jobs.Valid = append(jobs.Valid, j.serviceJob(resources, podFrom, service, servicePort, mode))
+				}
+			default:
+				panic(errors.Errorf("invalid IntOrString value %+v", port))
+			}
+		}
+		for _, target := range resources.ExternalTargets {
+			job := j.externalJob(resources, podFrom, target)
+			job.Protocol = protocol
+			switch port.Type {
+			case intstr.String:
+				// external targets don't have named ports
+				job.ResolvedPortName = port.StrVal
+				job.ResolvedPort = -1
+				jobs.BadNamedPort = append(jobs.BadNamedPort, job)
+			case intstr.Int:
+				job.ResolvedPort = int(port.IntVal)
+				if job.ResolvedPort != target.Port || protocol != target.Protocol {
+					jobs.BadPortProtocol = append(jobs.BadPortProtocol, job)
+				} else {
+					jobs.Valid = append(jobs.Valid, job)
+				}
+			default:
+				panic(errors.Errorf("invalid IntOrString value %+v", port))
+			}
+		}
 	}
 	return jobs
 }
@@ -85,7 +137,7 @@ func (j *JobBuilder) GetJobsAllAvailableServers(resources *Resources, mode gener
 					FromNamespaceLabels: resources.Namespaces[podFrom.Namespace],
 					FromPod:             podFrom.Name,
 					FromPodLabels:       podFrom.Labels,
-					FromContainer:       podFrom.Containers[0].Name,
+					FromContainer:       podFrom.FromContainer(),
 					FromIP:              podFrom.IP,
 					ToKey:               podTo.PodString().String(),
 					ToHost:              podTo.Host(mode),
@@ -98,9 +150,78 @@ func (j *JobBuilder) GetJobsAllAvailableServers(resources *Resources, mode gener
 					ResolvedPortName:    contTo.PortName,
 					Protocol:            contTo.Protocol,
 					TimeoutSeconds:      j.TimeoutSeconds,
+					Kind:                j.Kind,
+					HTTPPath:            j.HTTPPath,
+					ExpectedHTTPStatus:  j.ExpectedHTTPStatus,
 				})
 			}
 		}
+		for _, service := range resources.Services {
+			for _, servicePort := range service.Ports {
+				jobs = append(jobs, j.serviceJob(resources, podFrom, service, servicePort, mode))
+			}
+		}
+		for _, target := range resources.ExternalTargets {
+			jobs = append(jobs, j.externalJob(resources, podFrom, target))
+		}
 	}
 	return &Jobs{Valid: jobs}
 }
+
+// jobFrom starts a job from a pod, leaving the destination to be filled in.
+func (j *JobBuilder) jobFrom(resources *Resources, podFrom *Pod) *Job {
+	return &Job{
+		FromKey:             podFrom.PodString().String(),
+		FromNamespace:       podFrom.Namespace,
+		FromNamespaceLabels: resources.Namespaces[podFrom.Namespace],
+		FromPod:             podFrom.Name,
+		FromPodLabels:       podFrom.Labels,
+		FromContainer:       podFrom.FromContainer(),
+		FromIP:              podFrom.IP,
+		TimeoutSeconds:      j.TimeoutSeconds,
+		Kind:                j.Kind,
+		HTTPPath:            j.HTTPPath,
+		ExpectedHTTPStatus:  j.ExpectedHTTPStatus,
+	}
+}
+
+func (j *JobBuilder) externalJob(resources *Resources, podFrom *Pod, target *ExternalTarget) *Job {
+	job := j.jobFrom(resources, podFrom)
+	job.ToKey = target.Key()
+	job.ToHost = target.Host
+	job.ToIP = target.IP
+	job.ToExternal = true
+	job.ResolvedPort = target.Port
+	job.Protocol = target.Protocol
+	return job
+}
+
+// serviceJob is to one of the service's ports, forwarded to the endpoints currently serving that port.
+func (j *JobBuilder) serviceJob(resources *Resources, podFrom *Pod, service *Service, port *Container, mode generator.ProbeMode) *Job {
+	job := j.jobFrom(resources, podFrom)
+	job.ToKey = service.Key()
+	job.ToHost = service.Host(mode)
+	job.ToNamespace = service.Namespace
+	job.ToNamespaceLabels = resources.Namespaces[service.Namespace]
+	job.ToIP = service.IP
+	job.ToService = true
+	job.ToEndpoints = []*Endpoint{}
+	job.ResolvedPort = port.Port
+	job.ResolvedPortName = port.PortName
+	job.Protocol = port.Protocol
+	if service.Type == ServiceTypeNodePort {
+		job.RequestPort = service.NodePorts[fmt.Sprintf("%s/%d", port.Protocol, port.Port)]
+	}
+	for _, pod := range service.Endpoints(resources.Pods) {
+		if pod.IsServingPortProtocol(port.Port, port.Protocol) {
+			job.ToEndpoints = append(job.ToEndpoints, &Endpoint{
+				Key:             pod.PodString().String(),
+				Namespace:       pod.Namespace,
+				NamespaceLabels: resources.Namespaces[pod.Namespace],
+				PodLabels:       pod.Labels,
+				IP:              pod.IP,
+			})
+		}
+	}
+	return job
+}
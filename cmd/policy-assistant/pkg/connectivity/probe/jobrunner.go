@@ -1,7 +1,9 @@
 package probe
 
 import (
+	"io"
 	"strings"
+	"time"
 
 	"github.com/mattfenwick/collections/pkg/json"
 	"github.com/sirupsen/logrus"
@@ -11,9 +13,17 @@ import (
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/worker"
 )
 
+const (
+	defaultWorkerServerConcurrency = 10
+	defaultWorkerServerTimeout     = 5 * time.Minute
+)
+
 type Runner struct {
 	JobRunner  JobRunner
 	JobBuilder *JobBuilder
+	// Repeat, if greater than 1, is how many times each job is probed; jobs whose probes get different
+	// verdicts are flaky
+	Repeat int
 }
 
 func NewSimulatedRunner(policies *matcher.Policy, jobBuilder *JobBuilder) *Runner {
@@ -24,8 +34,16 @@ func NewKubeRunner(kubernetes kube.IKubernetes, workers int, jobBuilder *JobBuil
 	return &Runner{JobRunner: &KubeJobRunner{Kubernetes: kubernetes, Workers: workers}, JobBuilder: jobBuilder}
 }
 
-func NewKubeBatchRunner(kubernetes kube.IKubernetes, workers int, jobBuilder *JobBuilder) *Runner {
-	return &Runner{JobRunner: NewKubeBatchJobRunner(kubernetes, workers), JobBuilder: jobBuilder}
+func NewKubeBatchRunner(kubernetes kube.IKubernetes, workers int, workerServer bool, jobBuilder *JobBuilder) *Runner {
+	return &Runner{JobRunner: NewKubeBatchJobRunner(kubernetes, workers, workerServer), JobBuilder: jobBuilder}
+}
+
+// Close releases what the job runner keeps between probes, such as worker servers.
+func (p *Runner) Close() error {
+	if closer, ok := p.JobRunner.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
 }
 
 func (p *Runner) RunProbeForConfig(probeConfig *generator.ProbeConfig, resources *Resources) *Table {
@@ -33,7 +51,17 @@ func (p *Runner) RunProbeForConfig(probeConfig *generator.ProbeConfig, resources
 }
 
 func (p *Runner) runProbe(jobs *Jobs) []*JobResult {
-	resultSlice := p.JobRunner.RunJobs(jobs.Valid)
+	var repetitions, attempts []*Job
+	for _, job := range jobs.Valid {
+		repetitions = append(repetitions, job.Repetitions(p.Repeat)...)
+	}
+	for _, job := range repetitions {
+		attempts = append(attempts, job.Attempts()...)
+	}
+	resultSlice := combineAttempts(repetitions, p.JobRunner.RunJobs(attempts))
+	if p.Repeat > 1 {
+		resultSlice = combineRepetitions(jobs.Valid, resultSlice)
+	}
 
 	invalidPP := ConnectivityInvalidPortProtocol
 	unknown := ConnectivityUnknown
@@ -59,6 +87,85 @@ func (p *Runner) runProbe(jobs *Jobs) []*JobResult {
 	return resultSlice
 }
 
+// combineAttempts folds the results of each job's attempts into one result: if some attempts were allowed
+// and others weren't, the job's result is mixed.
+func combineAttempts(jobs []*Job, results []*JobResult) []*JobResult {
+	attemptResults := map[string][]*JobResult{}
+	for _, result := range results {
+		key := result.Job.Key()
+		if result.Job.Attempt > 0 {
+			attempt := *result.Job
+			attempt.Attempt = 0
+			key = attempt.Key()
+		}
+		attemptResults[key] = append(attemptResults[key], result)
+	}
+
+	var combined []*JobResult
+	for _, job := range jobs {
+		jobResults := attemptResults[job.Key()]
+		if len(jobResults) == 0 {
+			continue
+		}
+		result := &JobResult{Job: job, Ingress: jobResults[0].Ingress, Egress: jobResults[0].Egress, Combined: jobResults[0].Combined, Attempts: len(jobResults)}
+		for _, attemptResult := range jobResults[1:] {
+			result.Ingress = combineConnectivityPointers(result.Ingress, attemptResult.Ingress)
+			result.Egress = combineConnectivityPointers(result.Egress, attemptResult.Egress)
+			result.Combined = combineConnectivity(result.Combined, attemptResult.Combined)
+		}
+		combined = append(combined, result)
+	}
+	return combined
+}
+
+// combineRepetitions folds the results of each job's repetitions into one result, which is flaky if the
+// repetitions got different verdicts.
+func combineRepetitions(jobs []*Job, results []*JobResult) []*JobResult {
+	repetitionResults := map[string][]*JobResult{}
+	for _, result := range results {
+		repetition := *result.Job
+		repetition.Repetition = 0
+		repetitionResults[repetition.Key()] = append(repetitionResults[repetition.Key()], result)
+	}
+
+	var combined []*JobResult
+	for _, job := range jobs {
+		jobResults := repetitionResults[job.Key()]
+		if len(jobResults) == 0 {
+			continue
+		}
+		first := jobResults[0]
+		result := &JobResult{Job: job, Ingress: first.Ingress, Egress: first.Egress, Combined: first.Combined, Probes: len(jobResults)}
+		for _, repetitionResult := range jobResults {
+			result.Attempts += repetitionResult.Attempts
+			if repetitionResult.Combined.Verdict() == ConnectivityAllowed {
+				result.AllowedProbes++
+			}
+			if repetitionResult.Combined.Verdict() != first.Combined.Verdict() {
+				result.Combined = ConnectivityFlaky
+			}
+		}
+		combined = append(combined, result)
+	}
+	return combined
+}
+
+// combineConnectivity keeps the first reason a probe failed, unless the verdicts differ.
+func combineConnectivity(l Connectivity, r Connectivity) Connectivity {
+	if l.Verdict() != r.Verdict() {
+		return ConnectivityMixed
+	}
+	return l
+}
+
+func combineConnectivityPointers(l *Connectivity, r *Connectivity) *Connectivity {
+	if l == nil || r == nil {
+		return l
+	}
+	c := combineConnectivity(*l, *r)
+	return &c
+}
+
 type JobRunner interface {
 	RunJobs(job []*Job) []*JobResult
 }
@@ -81,6 +188,10 @@ func (s *SimulatedJobRunner) RunJob(job *Job) *JobResult {
 		return &JobResult{Job: job, Ingress: &connUndefined, Egress: &connUndefined, Combined: ConnectivityUndefined}
 	}
 
+	if job.ToService && !job.IsDNS() {
+		return s.runServiceJob(job)
+	}
+
 	allowed := s.Policies.IsTrafficAllowed(job.Traffic())
 	// TODO could also keep the whole `allowed` struct somewhere
 
@@ -100,6 +211,40 @@ func (s *SimulatedJobRunner) RunJob(job *Job) *JobResult {
 	return &JobResult{Job: job, Ingress: &ingress, Egress: &egress, Combined: combined}
 }
 
+// runServiceJob evaluates the traffic to each of the service's endpoints.  A service without endpoints
+// is blocked, since it rejects connections; traffic forwarded back to its source isn't subject to policies.
+func (s *SimulatedJobRunner) runServiceJob(job *Job) *JobResult {
+	result := &JobResult{Job: job}
+	if len(job.ToEndpoints) == 0 {
+		blocked := ConnectivityBlocked
+		return &JobResult{Job: job, Ingress: &blocked, Egress: &blocked, Combined: ConnectivityBlocked}
+	}
+	for i, traffic := range job.EndpointTraffic() {
+		var combined, ingress, egress = ConnectivityAllowed, ConnectivityAllowed, ConnectivityAllowed
+		if job.ToEndpoints[i].Key != job.FromKey {
+			allowed := s.Policies.IsTrafficAllowed(traffic)
+			logrus.Tracef("to %s via %s\n%s\n", job.ToEndpoints[i].Key, job.ToKey, allowed.Table())
+			if !allowed.Ingress.IsAllowed() {
+				ingress = ConnectivityBlocked
+			}
+			if !allowed.Egress.IsAllowed() {
+				egress = ConnectivityBlocked
+			}
+			if !allowed.IsAllowed() {
+				combined = ConnectivityBlocked
+			}
+		}
+		if i == 0 {
+			result.Ingress, result.Egress, result.Combined = &ingress, &egress, combined
+		} else {
+			result.Ingress = combineConnectivityPointers(result.Ingress, &ingress)
+			result.Egress = combineConnectivityPointers(result.Egress, &egress)
+			result.Combined = combineConnectivity(result.Combined, combined)
+		}
+	}
+	return result
+}
+
 type KubeJobRunner struct {
 	Kubernetes kube.IKubernetes
 	Workers    int
@@ -148,20 +293,49 @@ func probeConnectivity(k8s kube.IKubernetes, job *Job) (Connectivity, string) {
 	}
 	if commandErr != nil {
 		logrus.Debugf("unable to run command %s: %+v", commandDebugString, commandErr)
-		return ConnectivityBlocked, commandDebugString
 	}
-	return ConnectivityAllowed, commandDebugString
+	return outcomeConnectivity(job.Request().Classify(stdout+stderr, commandErr)), commandDebugString
+}
+
+func outcomeConnectivity(outcome worker.Outcome) Connectivity {
+	switch outcome {
+	case worker.OutcomeSuccess:
+		return ConnectivityAllowed
+	case worker.OutcomeRefused:
+		return ConnectivityRefused
+	case worker.OutcomeTimeout:
+		return ConnectivityTimedOut
+	case worker.OutcomeReset:
+		return ConnectivityReset
+	case worker.OutcomeAppError:
+		return ConnectivityAppError
+	default:
+		return ConnectivityBlocked
+	}
 }
 
 type KubeBatchJobRunner struct {
-	Client  *worker.Client
+	Client  worker.BatchClient
 	Workers int
 }
 
-func NewKubeBatchJobRunner(k8s kube.IKubernetes, workers int) *KubeBatchJobRunner {
+// NewKubeBatchJobRunner issues each pod's jobs as a single batch.  If workerServer is true, batches are
+// sent to a worker server kept running in each pod, instead of running the worker once per batch.
+func NewKubeBatchJobRunner(k8s kube.IKubernetes, workers int, workerServer bool) *KubeBatchJobRunner {
+	if workerServer {
+		return &KubeBatchJobRunner{Client: worker.NewStreamClient(k8s, defaultWorkerServerConcurrency, defaultWorkerServerTimeout), Workers: workers}
+	}
 	return &KubeBatchJobRunner{Client: &worker.Client{Kubernetes: k8s}, Workers: workers}
 }
 
+// Close stops the worker servers, if batches are sent to worker servers.
+func (k *KubeBatchJobRunner) Close() error {
+	if closer, ok := k.Client.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
 func (k *KubeBatchJobRunner) RunJobs(jobs []*Job) []*JobResult {
 	jobMap := map[string]*Job{}
 
@@ -173,12 +347,7 @@ func (k *KubeBatchJobRunner) RunJobs(jobs []*Job) []*JobResult {
 			batches[job.FromKey] = &worker.Batch{Namespace: ns, Pod: pod, Container: job.FromContainer}
 		}
 		batch := batches[job.FromKey]
-		batch.Requests = append(batch.Requests, &worker.Request{
-			Key:      job.Key(),
-			Protocol: job.Protocol,
-			Host:     job.ToHost,
-			Port:     job.ResolvedPort,
-		})
+		batch.Requests = append(batch.Requests, job.Request())
 
 		jobMap[job.Key()] = job
 	}
@@ -222,7 +391,7 @@ func (k *KubeBatchJobRunner) worker(jobMap map[string]*Job, batches <-chan *work
 					c = ConnectivityAllowed
 				} else {
 					logrus.Debugf("request to %s failed: %s", r.Request.Key, r.Error)
-					c = ConnectivityBlocked
+					c = outcomeConnectivity(r.Outcome)
 				}
 				jobResults <- &JobResult{
 					Job:      jobMap[r.Request.Key],
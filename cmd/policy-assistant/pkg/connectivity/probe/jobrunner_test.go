@@ -0,0 +1,86 @@
+package probe
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+)
+
+// outputMock answers each command with the output agnhost connect gives for the destination port.
+type outputMock struct {
+	*kube.MockKubernetes
+}
+
+func (o *outputMock) ExecuteRemoteCommand(namespace string, pod string, container string, command []string) (string, string, error, error) {
+	switch {
+	case strings.HasSuffix(command[2], ":81"):
+		return "", "REFUSED\n", errors.Errorf("command terminated with exit code 1"), nil
+	case strings.HasSuffix(command[2], ":82"):
+		return "", "TIMEOUT\n", errors.Errorf("command terminated with exit code 1"), nil
+	default:
+		return "", "", nil, nil
+	}
+}
+
+// alternatingJobRunner times out every other repetition of jobs to port 81, and refuses jobs to port 82.
+type alternatingJobRunner struct{}
+
+func (alternatingJobRunner) RunJobs(jobs []*Job) []*JobResult {
+	var results []*JobResult
+	for _, job := range jobs {
+		connectivity := ConnectivityAllowed
+		if job.ResolvedPort == 81 && job.Repetition%2 == 1 {
+			connectivity = ConnectivityTimedOut
+		} else if job.ResolvedPort == 82 {
+			connectivity = ConnectivityRefused
+		}
+		results = append(results, &JobResult{Job: job, Combined: connectivity})
+	}
+	return results
+}
+
+func RunJobRunnerTests() {
+	Describe("KubeJobRunner", func() {
+		It("should distinguish the ways a probe fails", func() {
+			runner := &KubeJobRunner{Kubernetes: &outputMock{MockKubernetes: kube.NewMockKubernetes(1.0)}, Workers: 2}
+			var jobs []*Job
+			for _, port := range []int{80, 81, 82} {
+				jobs = append(jobs, &Job{FromKey: "x/a", ToKey: "y/b", ToHost: "1.2.3.4", ResolvedPort: port, Protocol: v1.ProtocolTCP, TimeoutSeconds: 1})
+			}
+
+			connectivity := map[int]Connectivity{}
+			for _, result := range runner.RunJobs(jobs) {
+				connectivity[result.Job.ResolvedPort] = result.Combined
+			}
+			Expect(connectivity).To(Equal(map[int]Connectivity{80: ConnectivityAllowed, 81: ConnectivityRefused, 82: ConnectivityTimedOut}))
+			Expect(ConnectivityRefused.Verdict()).To(Equal(ConnectivityBlocked))
+			Expect(ConnectivityTimedOut.Verdict()).To(Equal(ConnectivityBlocked))
+			Expect(ConnectivityAppError.Verdict()).To(Equal(ConnectivityAllowed))
+		})
+
+		It("should classify repeated probes as consistent or flaky", func() {
+			runner := &Runner{JobRunner: alternatingJobRunner{}, Repeat: 4}
+			var jobs []*Job
+			for _, port := range []int{80, 81, 82} {
+				jobs = append(jobs, &Job{FromKey: "x/a", ToKey: "y/b", ToHost: "1.2.3.4", ResolvedPort: port, Protocol: v1.ProtocolTCP})
+			}
+
+			results := map[int]*JobResult{}
+			for _, result := range runner.runProbe(&Jobs{Valid: jobs}) {
+				results[result.Job.ResolvedPort] = result
+			}
+			Expect(results).To(HaveLen(3))
+			Expect(results[80].Combined).To(Equal(ConnectivityAllowed))
+			Expect(results[80].SuccessRatio()).To(Equal(1.0))
+			Expect(results[81].Combined).To(Equal(ConnectivityFlaky))
+			Expect(results[81].AllowedProbes).To(Equal(2))
+			Expect(results[81].Probes).To(Equal(4))
+			Expect(results[82].Combined).To(Equal(ConnectivityRefused))
+			Expect(results[82].SuccessRatio()).To(Equal(0.0))
+		})
+	})
+}
@@ -51,6 +51,16 @@ type Pod struct {
 	ServiceIP  string
 	IP         string
 	Containers []*Container
+	// ProbeContainer, if set, is the container to probe from, instead of the first of Containers
+	ProbeContainer string
+}
+
+// FromContainer is the container that probes from this pod are run in.
+func (p *Pod) FromContainer() string {
+	if p.ProbeContainer != "" {
+		return p.ProbeContainer
+	}
+	return p.Containers[0].Name
 }
 
 func (p *Pod) Host(probeMode generator.ProbeMode) string {
@@ -152,11 +162,12 @@ func (p *Pod) IsServingPortProtocol(port int, protocol v1.Protocol) bool {
 
 func (p *Pod) SetLabels(labels map[string]string) *Pod {
 	return &Pod{
-		Namespace:  p.Namespace,
-		Name:       p.Name,
-		Labels:     labels,
-		IP:         p.IP,
-		Containers: p.Containers,
+		Namespace:      p.Namespace,
+		Name:           p.Name,
+		Labels:         labels,
+		IP:             p.IP,
+		Containers:     p.Containers,
+		ProbeContainer: p.ProbeContainer,
 	}
 }
 
@@ -196,7 +207,12 @@ func (c *Container) Image() string {
 	if c.BatchJobs {
 		return policyAssistantWorkerImage
 	}
-	return c.ImageRegistry + "/" + agnhostImage
+	return AgnhostImage(c.ImageRegistry)
+}
+
+// AgnhostImage is the agnhost image which servers and probes run, pulled from imageRegistry.
+func AgnhostImage(imageRegistry string) string {
+	return imageRegistry + "/" + agnhostImage
 }
 
 func (c *Container) KubeContainer() v1.Container {
@@ -205,7 +221,8 @@ func (c *Container) KubeContainer() v1.Container {
 
 	switch c.Protocol {
 	case v1.ProtocolTCP:
-		cmd = []string{"/agnhost", "serve-hostname", "--tcp", "--http=false", "--port", fmt.Sprintf("%d", c.Port)}
+		// an http server accepts plain connections too, so tcp servers can answer connect and http probes
+		cmd = []string{"/agnhost", "serve-hostname", "--http", "--port", fmt.Sprintf("%d", c.Port)}
 	case v1.ProtocolUDP:
 		cmd = []string{"/agnhost", "serve-hostname", "--udp", "--http=false", "--port", fmt.Sprintf("%d", c.Port)}
 	case v1.ProtocolSCTP:
@@ -1,6 +1,7 @@
 package probe
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/mattfenwick/collections/pkg/slice"
@@ -15,19 +16,27 @@ import (
 type Resources struct {
 	Namespaces map[string]map[string]string
 	Pods       []*Pod
-	//ExternalIPs []string
-	ports     []int
-	protocols []v1.Protocol
+	// Services and ExternalTargets are probed from every pod, in addition to the pods
+	Services        []*Service
+	ExternalTargets []*ExternalTarget
+	ports           []int
+	protocols       []v1.Protocol
 }
 
-func NewDefaultResources(kubernetes kube.IKubernetes, namespaces []string, podNames []string, ports []int, protocols []v1.Protocol, externalIPs []string, podCreationTimeoutSeconds int, batchJobs bool, imageRegistry string) (*Resources, error) {
-	//sort.Strings(externalIPs) // TODO why is this here?
-
+// NewDefaultResources creates the pods and services in kube; externalTargets are parsed with
+// ParseExternalTarget, and services with ParseService.
+func NewDefaultResources(kubernetes kube.IKubernetes, namespaces []string, podNames []string, ports []int, protocols []v1.Protocol, externalTargets []string, services []string, podCreationTimeoutSeconds int, batchJobs bool, imageRegistry string) (*Resources, error) {
 	r := &Resources{
 		Namespaces: map[string]map[string]string{},
-		//ExternalIPs: externalIPs,
-		ports:     ports,
-		protocols: protocols,
+		ports:      ports,
+		protocols:  protocols,
+	}
+	for _, target := range externalTargets {
+		externalTarget, err := ParseExternalTarget(target)
+		if err != nil {
+			return nil, err
+		}
+		r.ExternalTargets = append(r.ExternalTargets, externalTarget)
 	}
 
 	for _, ns := range namespaces {
@@ -36,6 +45,25 @@ func NewDefaultResources(kubernetes kube.IKubernetes, namespaces []string, podNa
 		}
 		r.Namespaces[ns] = map[string]string{"ns": ns}
 	}
+	for _, spec := range services {
+		service, err := ParseService(spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, podName := range service.Pods {
+			pod, err := r.GetPod(service.Namespace, podName)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "unable to back service %s", service.Key())
+			}
+			pod.Labels[service.SelectorLabel()] = "true"
+		}
+		for _, protocol := range protocols {
+			for _, port := range ports {
+				service.Ports = append(service.Ports, NewDefaultContainer(port, protocol, batchJobs, imageRegistry))
+			}
+		}
+		r.Services = append(r.Services, service)
+	}
 
 	if err := r.CreateResourcesInKube(kubernetes); err != nil {
 		return nil, err
@@ -49,6 +77,9 @@ func NewDefaultResources(kubernetes kube.IKubernetes, namespaces []string, podNa
 	if err := r.getNamespaceLabelsFromKube(kubernetes); err != nil {
 		return nil, err
 	}
+	if err := r.getServicesFromKube(kubernetes); err != nil {
+		return nil, err
+	}
 
 	return r, nil
 }
@@ -105,6 +136,23 @@ func (r *Resources) getPodIPsFromKube(kubernetes kube.IKubernetes) error {
 	return nil
 }
 
+func (r *Resources) getServicesFromKube(kubernetes kube.IKubernetes) error {
+	for _, service := range r.Services {
+		kubeService, err := kubernetes.GetService(service.Namespace, service.Name)
+		if err != nil {
+			return err
+		}
+		// any node will do for a NodePort service: pick the one the first endpoint is on
+		kubePod, err := kubernetes.GetPod(service.Namespace, service.Pods[0])
+		if err != nil {
+			return err
+		}
+		service.setFromKube(kubeService, kubePod.Status.HostIP)
+		logrus.Debugf("ip for service %s: %s", service.Key(), service.IP)
+	}
+	return nil
+}
+
 func (r *Resources) getNamespaceLabelsFromKube(kubernetes kube.IKubernetes) error {
 	nsList, err := kubernetes.GetAllNamespaces()
 	if err != nil {
@@ -142,8 +190,10 @@ func (r *Resources) CreateNamespace(ns string, labels map[string]string) (*Resou
 	}
 	newNamespaces[ns] = labels
 	return &Resources{
-		Namespaces: newNamespaces,
-		Pods:       r.Pods,
+		Namespaces:      newNamespaces,
+		Pods:            r.Pods,
+		Services:        r.Services,
+		ExternalTargets: r.ExternalTargets,
 	}, nil
 }
 
@@ -158,8 +208,10 @@ func (r *Resources) UpdateNamespaceLabels(ns string, labels map[string]string) (
 	}
 	newNamespaces[ns] = labels
 	return &Resources{
-		Namespaces: newNamespaces,
-		Pods:       r.Pods,
+		Namespaces:      newNamespaces,
+		Pods:            r.Pods,
+		Services:        r.Services,
+		ExternalTargets: r.ExternalTargets,
 	}, nil
 }
 
@@ -183,8 +235,10 @@ func (r *Resources) DeleteNamespace(ns string) (*Resources, error) {
 		}
 	}
 	return &Resources{
-		Namespaces: newNamespaces,
-		Pods:       pods,
+		Namespaces:      newNamespaces,
+		Pods:            pods,
+		Services:        slice.Filter(func(s *Service) bool { return s.Namespace != ns }, r.Services),
+		ExternalTargets: r.ExternalTargets,
 	}, nil
 }
 
@@ -195,13 +249,33 @@ func (r *Resources) CreatePod(ns string, podName string, labels map[string]strin
 	if _, ok := r.Namespaces[ns]; !ok {
 		return nil, errors.Errorf("can't find namespace %s", ns)
 	}
+	ip, err := r.placeholderPodIP()
+	if err != nil {
+		return nil, err
+	}
 	return &Resources{
-		Namespaces: r.Namespaces,
-		Pods:       append(append([]*Pod{}, r.Pods...), NewPod(ns, podName, labels, "TODO", r.Pods[0].Containers)),
-		//ExternalIPs: r.ExternalIPs,
+		Namespaces:      r.Namespaces,
+		Pods:            append(append([]*Pod{}, r.Pods...), NewPod(ns, podName, labels, ip, r.Pods[0].Containers)),
+		Services:        r.Services,
+		ExternalTargets: r.ExternalTargets,
 	}, nil
 }
 
+// placeholderPodIP picks an address which no pod has from 192.0.2.0/24, which is reserved for
+// documentation, so that a new pod whose IP isn't known yet can still be matched against ipBlocks.
+func (r *Resources) placeholderPodIP() (string, error) {
+	used := map[string]bool{}
+	for _, pod := range r.Pods {
+		used[pod.IP] = true
+	}
+	for i := 1; i < 255; i++ {
+		if ip := fmt.Sprintf("192.0.2.%d", i); !used[ip] {
+			return ip, nil
+		}
+	}
+	return "", errors.Errorf("no placeholder IP left for a new pod")
+}
+
 // SetPodLabels returns a new object with an updated pod.  It should not affect the original Resources object.
 func (r *Resources) SetPodLabels(ns string, podName string, labels map[string]string) (*Resources, error) {
 	var pods []*Pod
@@ -218,9 +292,10 @@ func (r *Resources) SetPodLabels(ns string, podName string, labels map[string]st
 		return nil, errors.Errorf("no pod named %s/%s found", ns, podName)
 	}
 	return &Resources{
-		Namespaces: r.Namespaces,
-		Pods:       pods,
-		//ExternalIPs: r.ExternalIPs,
+		Namespaces:      r.Namespaces,
+		Pods:            pods,
+		Services:        r.Services,
+		ExternalTargets: r.ExternalTargets,
 	}, nil
 }
 
@@ -239,9 +314,10 @@ func (r *Resources) DeletePod(ns string, podName string) (*Resources, error) {
 		return nil, errors.Errorf("pod %s/%s not found", ns, podName)
 	}
 	return &Resources{
-		Namespaces: r.Namespaces,
-		Pods:       newPods,
-		//ExternalIPs: r.ExternalIPs,
+		Namespaces:      r.Namespaces,
+		Pods:            newPods,
+		Services:        r.Services,
+		ExternalTargets: r.ExternalTargets,
 	}, nil
 }
 
@@ -251,6 +327,14 @@ func (r *Resources) SortedPodNames() []string {
 		r.Pods))
 }
 
+// SortedTargetNames are the destinations of probes: the pods, followed by the services and the external
+// targets.
+func (r *Resources) SortedTargetNames() []string {
+	names := r.SortedPodNames()
+	names = append(names, slice.Sort(slice.Map(func(s *Service) string { return s.Key() }, r.Services))...)
+	return append(names, slice.Sort(slice.Map(func(e *ExternalTarget) string { return e.Key() }, r.ExternalTargets))...)
+}
+
 func (r *Resources) NamespacesSlice() []string {
 	return maps.Keys(r.Namespaces)
 }
@@ -282,6 +366,15 @@ func (r *Resources) CreateResourcesInKube(kubernetes kube.IKubernetes) error {
 			}
 		}
 	}
+	for _, service := range r.Services {
+		_, err := kubernetes.GetService(service.Namespace, service.Name)
+		if err != nil {
+			_, err = kubernetes.CreateService(service.KubeService())
+			if err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
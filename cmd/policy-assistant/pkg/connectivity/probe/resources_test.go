@@ -3,6 +3,10 @@ package probe
 import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
 )
 
 func RunResourcesTests() {
@@ -50,4 +54,69 @@ func RunResourcesTests() {
 			Expect(r2.Pods[0].Labels).To(Equal(map[string]string{}))
 		})
 	})
+
+	Describe("NewWorkloadResources", func() {
+		workloadPod := func(name string, labels map[string]string, ports ...v1.ContainerPort) *v1.Pod {
+			return &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: name, Labels: labels},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "main", Ports: ports}}},
+			}
+		}
+
+		setupMock := func() *kube.MockKubernetes {
+			mock := kube.NewMockKubernetes(1.0)
+			_, err := mock.CreateNamespace(KubeNamespace("app", map[string]string{"team": "a"}))
+			Expect(err).To(Succeed())
+			for _, pod := range []*v1.Pod{
+				workloadPod("web", map[string]string{"tier": "web"}, v1.ContainerPort{Name: "http", ContainerPort: 8080}),
+				workloadPod("db", map[string]string{"tier": "db"}, v1.ContainerPort{ContainerPort: 5432, Protocol: v1.ProtocolTCP}, v1.ContainerPort{ContainerPort: 53, Protocol: v1.ProtocolUDP}),
+				workloadPod("batch", map[string]string{"tier": "batch"}),
+			} {
+				_, err = mock.CreatePod(pod)
+				Expect(err).To(Succeed())
+			}
+			return mock
+		}
+
+		It("should discover the pods and their declared ports", func() {
+			mock := setupMock()
+			r, err := NewWorkloadResources(mock, []string{"app"}, "tier in (web, db)", AgnhostImage("registry.k8s.io"), 10)
+			Expect(err).To(Succeed())
+
+			Expect(r.Namespaces["app"]).To(HaveKeyWithValue("team", "a"))
+			Expect(r.SortedPodNames()).To(Equal([]string{"app/db", "app/web"}))
+			web, err := r.GetPod("app", "web")
+			Expect(err).To(Succeed())
+			Expect(web.Containers).To(Equal([]*Container{{Name: "main", Port: 8080, Protocol: v1.ProtocolTCP, PortName: "http"}}))
+			db, err := r.GetPod("app", "db")
+			Expect(err).To(Succeed())
+			Expect(db.Containers).To(HaveLen(2))
+			Expect(db.Containers[1].Protocol).To(Equal(v1.ProtocolUDP))
+		})
+
+		It("should probe from an ephemeral container, added once", func() {
+			mock := setupMock()
+			_, err := NewWorkloadResources(mock, []string{"app"}, "", "example.com/probe:1.0", 10)
+			Expect(err).To(Succeed())
+			r, err := NewWorkloadResources(mock, []string{"app"}, "", "example.com/probe:1.0", 10)
+			Expect(err).To(Succeed())
+
+			kubePod, err := mock.GetPod("app", "batch")
+			Expect(err).To(Succeed())
+			Expect(kubePod.Spec.EphemeralContainers).To(HaveLen(1))
+			Expect(kubePod.Spec.EphemeralContainers[0].Name).To(Equal(WorkloadProbeContainerName))
+			Expect(kubePod.Spec.EphemeralContainers[0].Image).To(Equal("example.com/probe:1.0"))
+
+			jobs := (&JobBuilder{}).GetJobsAllAvailableServers(r, generator.ProbeModePodIP)
+			Expect(jobs.Valid).To(HaveLen(3 * 3))
+			for _, job := range jobs.Valid {
+				Expect(job.FromContainer).To(Equal(WorkloadProbeContainerName))
+			}
+		})
+
+		It("should fail if no pods match", func() {
+			_, err := NewWorkloadResources(setupMock(), []string{"app"}, "tier=cache", AgnhostImage("registry.k8s.io"), 10)
+			Expect(err).ToNot(Succeed())
+		})
+	})
 }
@@ -0,0 +1,169 @@
+package probe
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+)
+
+type ServiceType string
+
+const (
+	ServiceTypeClusterIP ServiceType = "clusterip"
+	ServiceTypeNodePort  ServiceType = "nodeport"
+	ServiceTypeHeadless  ServiceType = "headless"
+)
+
+var AllServiceTypes = []string{
+	string(ServiceTypeClusterIP),
+	string(ServiceTypeNodePort),
+	string(ServiceTypeHeadless),
+}
+
+// serviceAttemptsPerEndpoint is how many times a service is probed per endpoint, so that -- since each
+// probe is forwarded to whichever endpoint kube-proxy or DNS picks -- every endpoint is likely to be
+// reached: the chance of missing one of n endpoints in 5n uniformly random attempts is below e^-5.
+const serviceAttemptsPerEndpoint = 5
+
+// Service sits in front of several probe pods, which back it by carrying its label.  Each service is an
+// extra column of the truth tables: a probe to it is DNAT'd -- or, for a headless service, resolved -- to
+// one of its endpoints, so policies are evaluated against each endpoint, and the result is mixed if only
+// some of them are allowed.
+type Service struct {
+	Namespace string
+	Name      string
+	Type      ServiceType
+	// Pods are the names of the pods, in Namespace, which initially back the service
+	Pods []string
+	// Ports are exposed by the service, and forwarded to the endpoints' ports of the same name
+	Ports []*Container
+
+	// IP is the cluster IP, NodeIP the IP of a node and NodePorts the node ports -- keyed like job
+	// results -- of a NodePort service
+	IP        string
+	NodeIP    string
+	NodePorts map[string]int
+}
+
+// ParseService parses '[type:]namespace/name=pod,pod...'; the type defaults to clusterip.
+func ParseService(service string) (*Service, error) {
+	serviceType, spec := ServiceTypeClusterIP, service
+	if index := strings.Index(service, ":"); index >= 0 {
+		serviceType, spec = ServiceType(strings.ToLower(service[:index])), service[index+1:]
+		if !slices.Contains(AllServiceTypes, string(serviceType)) {
+			return nil, errors.Errorf("invalid type in service %s: must be one of %s", service, strings.Join(AllServiceTypes, ", "))
+		}
+	}
+	name, pods, ok := strings.Cut(spec, "=")
+	if !ok || pods == "" {
+		return nil, errors.Errorf("invalid service %s: expected '[type:]namespace/name=pod,pod...'", service)
+	}
+	namespace, name, ok := strings.Cut(name, "/")
+	if !ok || namespace == "" || name == "" {
+		return nil, errors.Errorf("invalid service %s: expected '[type:]namespace/name=pod,pod...'", service)
+	}
+	return &Service{Namespace: namespace, Name: name, Type: serviceType, Pods: strings.Split(pods, ",")}, nil
+}
+
+// Spec is the inverse of ParseService.
+func (s *Service) Spec() string {
+	return fmt.Sprintf("%s:%s/%s=%s", s.Type, s.Namespace, s.Name, strings.Join(s.Pods, ","))
+}
+
+// Key identifies the service in truth tables.
+func (s *Service) Key() string {
+	return fmt.Sprintf("svc/%s/%s", s.Namespace, s.Name)
+}
+
+// SelectorLabel is added to the pods backing the service.
+func (s *Service) SelectorLabel() string {
+	return "policy-assistant/svc-" + s.Name
+}
+
+// Endpoints are the pods the service currently selects; labels may have changed since it was created.
+func (s *Service) Endpoints(pods []*Pod) []*Pod {
+	return slice.Filter(func(pod *Pod) bool {
+		_, ok := pod.Labels[s.SelectorLabel()]
+		return pod.Namespace == s.Namespace && ok
+	}, pods)
+}
+
+// Host is where probes to the service are sent.  Only cluster IP services can be probed either by name or
+// by IP, so other services ignore the probe mode: a headless service is probed by name, and a NodePort
+// service through a node.
+func (s *Service) Host(probeMode generator.ProbeMode) string {
+	switch {
+	case s.Type == ServiceTypeHeadless:
+		return kube.QualifiedServiceAddress(s.Name, s.Namespace)
+	case s.Type == ServiceTypeNodePort:
+		return s.NodeIP
+	case probeMode == generator.ProbeModeServiceName:
+		return kube.QualifiedServiceAddress(s.Name, s.Namespace)
+	default:
+		return s.IP
+	}
+}
+
+func (s *Service) KubeService() *v1.Service {
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.Name,
+			Namespace: s.Namespace,
+		},
+		Spec: v1.ServiceSpec{
+			Selector: map[string]string{s.SelectorLabel(): "true"},
+		},
+	}
+	for _, container := range s.Ports {
+		servicePort := container.KubeServicePort()
+		servicePort.TargetPort = intstr.FromString(container.PortName)
+		svc.Spec.Ports = append(svc.Spec.Ports, servicePort)
+	}
+	switch s.Type {
+	case ServiceTypeHeadless:
+		svc.Spec.ClusterIP = v1.ClusterIPNone
+	case ServiceTypeNodePort:
+		svc.Spec.Type = v1.ServiceTypeNodePort
+		// with the default Cluster policy, kube-proxy SNATs traffic to the node's IP, so endpoints would
+		// see the node as the source instead of the client pod that's simulated
+		svc.Spec.ExternalTrafficPolicy = v1.ServiceExternalTrafficPolicyLocal
+	}
+	return svc
+}
+
+// NamedPort finds the service port with the same name as the endpoints' port.
+func (s *Service) NamedPort(name string) *Container {
+	for _, port := range s.Ports {
+		if port.PortName == name {
+			return port
+		}
+	}
+	return nil
+}
+
+func (s *Service) NumberedPort(port int, protocol v1.Protocol) *Container {
+	for _, servicePort := range s.Ports {
+		if servicePort.Port == port && servicePort.Protocol == protocol {
+			return servicePort
+		}
+	}
+	return nil
+}
+
+// setFromKube records the addresses assigned to the service.
+func (s *Service) setFromKube(kubeService *v1.Service, nodeIP string) {
+	s.IP = kubeService.Spec.ClusterIP
+	s.NodeIP = nodeIP
+	s.NodePorts = map[string]int{}
+	for _, port := range kubeService.Spec.Ports {
+		s.NodePorts[fmt.Sprintf("%s/%d", port.Protocol, port.Port)] = int(port.NodePort)
+	}
+}
@@ -0,0 +1,162 @@
+package probe
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/worker"
+)
+
+func RunServiceTests() {
+	Describe("ParseService", func() {
+		It("should parse services, defaulting to cluster ip", func() {
+			service, err := ParseService("x/web=a,b")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(service).To(Equal(&Service{Namespace: "x", Name: "web", Type: ServiceTypeClusterIP, Pods: []string{"a", "b"}}))
+			Expect(service.Key()).To(Equal("svc/x/web"))
+
+			service, err = ParseService("NodePort:y/all=a,b,c")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(service.Type).To(Equal(ServiceTypeNodePort))
+
+			parsed, err := ParseService(service.Spec())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed).To(Equal(service))
+		})
+
+		It("should reject invalid services", func() {
+			for _, service := range []string{"x/web", "web=a", "loadbalancer:x/web=a", "x/=a"} {
+				_, err := ParseService(service)
+				Expect(err).To(HaveOccurred(), service)
+			}
+		})
+	})
+
+	Describe("Services", func() {
+		var resources *Resources
+
+		BeforeEach(func() {
+			var err error
+			resources, err = NewDefaultResources(kube.NewMockKubernetes(1.0), []string{"x", "y"}, []string{"a", "b"}, []int{80}, []v1.Protocol{v1.ProtocolTCP}, []string{}, []string{"x/web=a,b", "nodeport:y/all=a,b"}, 10, false, "registry.k8s.io")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should add a column per service, backed by the pods carrying its label", func() {
+			Expect(resources.SortedTargetNames()).To(Equal([]string{"x/a", "x/b", "y/a", "y/b", "svc/x/web", "svc/y/all"}))
+			pod, err := resources.GetPod("x", "a")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pod.Labels).To(HaveKeyWithValue("policy-assistant/svc-web", "true"))
+
+			jobs := (&JobBuilder{}).GetJobsAllAvailableServers(resources, generator.ProbeModeServiceName)
+			for _, job := range jobs.Valid {
+				if job.ToKey == "svc/x/web" {
+					Expect(job.ToService).To(BeTrue())
+					Expect(job.ToHost).To(Equal("web.x.svc.cluster.local"))
+					Expect(job.ToEndpoints).To(HaveLen(2))
+					Expect(job.Attempts()).To(HaveLen(2 * serviceAttemptsPerEndpoint))
+				}
+			}
+		})
+
+		It("should report mixed results when only some endpoints are allowed", func() {
+			denyToA := &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "deny-to-a"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"pod": "a"}},
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				},
+			}
+			runner := NewSimulatedRunner(matcher.BuildV1AndV2NetPols(true, []*networkingv1.NetworkPolicy{denyToA}, nil, nil), &JobBuilder{})
+			table := runner.RunProbeForConfig(generator.NewAllAvailable(generator.ProbeModeServiceName), resources)
+
+			Expect(table.Get("y/a", "svc/x/web").JobResults["TCP/80"].Combined).To(Equal(ConnectivityMixed))
+			Expect(*table.Get("y/a", "svc/x/web").JobResults["TCP/80"].Ingress).To(Equal(ConnectivityMixed))
+			Expect(*table.Get("y/a", "svc/x/web").JobResults["TCP/80"].Egress).To(Equal(ConnectivityAllowed))
+			Expect(table.Get("x/b", "svc/x/web").JobResults["TCP/80"].Combined).To(Equal(ConnectivityMixed))
+			// traffic forwarded back to its source isn't subject to policies
+			Expect(table.Get("x/a", "svc/x/web").JobResults["TCP/80"].Combined).To(Equal(ConnectivityAllowed))
+			Expect(table.Get("x/a", "svc/y/all").JobResults["TCP/80"].Combined).To(Equal(ConnectivityAllowed))
+		})
+
+		It("should simulate lookups as traffic to the DNS pods", func() {
+			denyEgressFromA := &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "deny-egress-from-a"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"pod": "a"}},
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+				},
+			}
+			denyToA := &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "y", Name: "deny-to-a"},
+				Spec: networkingv1.NetworkPolicySpec{
+					PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"pod": "a"}},
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				},
+			}
+			policies := matcher.BuildV1AndV2NetPols(true, []*networkingv1.NetworkPolicy{denyEgressFromA, denyToA}, nil, nil)
+			builder := &JobBuilder{Kind: worker.RequestKindDNS}
+			table := NewSimulatedRunner(policies, builder).RunProbeForConfig(generator.NewAllAvailable(generator.ProbeModeServiceName), resources)
+
+			Expect(table.Get("x/a", "svc/x/web").JobResults["TCP/80"].Combined).To(Equal(ConnectivityBlocked))
+			Expect(table.Get("x/a", "y/b").JobResults["TCP/80"].Combined).To(Equal(ConnectivityBlocked))
+			// the destination's ingress policies don't apply to the lookup
+			Expect(table.Get("x/b", "y/a").JobResults["TCP/80"].Combined).To(Equal(ConnectivityAllowed))
+
+			for _, job := range builder.GetJobsAllAvailableServers(resources, generator.ProbeModeServiceName).Valid {
+				Expect(job.Attempts()).To(HaveLen(1))
+			}
+		})
+
+		It("should only accept a single kube verdict for a mixed simulation until every endpoint was likely reached", func() {
+			job := &Job{FromKey: "x/a", ToKey: "svc/x/web", ToService: true, ResolvedPort: 80, Protocol: v1.ProtocolTCP, ToEndpoints: []*Endpoint{{Key: "x/a"}, {Key: "x/b"}}}
+			simulated := &JobResult{Job: job, Combined: ConnectivityMixed}
+			for _, kube := range []Connectivity{ConnectivityMixed, ConnectivityFlaky} {
+				Expect((&JobResult{Job: job, Combined: kube, Attempts: 100}).Agrees(simulated)).To(BeTrue(), string(kube))
+			}
+			for _, kube := range []Connectivity{ConnectivityAllowed, ConnectivityTimedOut, ConnectivityRefused} {
+				Expect((&JobResult{Job: job, Combined: kube, Attempts: 2*serviceAttemptsPerEndpoint - 1}).Agrees(simulated)).To(BeTrue(), string(kube))
+				Expect((&JobResult{Job: job, Combined: kube, Attempts: 2 * serviceAttemptsPerEndpoint}).Agrees(simulated)).To(BeFalse(), string(kube))
+			}
+			Expect((&JobResult{Job: job, Combined: ConnectivityUnknown}).Agrees(simulated)).To(BeFalse())
+
+			// the other direction: a mixed kube result never agrees with a single simulated verdict
+			for _, sim := range []Connectivity{ConnectivityAllowed, ConnectivityBlocked} {
+				Expect((&JobResult{Job: job, Combined: ConnectivityMixed, Attempts: 1}).Agrees(&JobResult{Job: job, Combined: sim})).To(BeFalse(), string(sim))
+			}
+			Expect((&JobResult{Job: job, Combined: ConnectivityTimedOut}).Agrees(&JobResult{Job: job, Combined: ConnectivityBlocked})).To(BeTrue())
+		})
+
+		It("should count the attempts of a service across repetitions", func() {
+			job := &Job{FromKey: "x/a", ToKey: "svc/x/web", ToService: true, ResolvedPort: 80, Protocol: v1.ProtocolTCP, ToEndpoints: []*Endpoint{{Key: "x/a"}, {Key: "x/b"}}}
+			var attempts []*Job
+			for _, repetition := range job.Repetitions(2) {
+				attempts = append(attempts, repetition.Attempts()...)
+			}
+			var results []*JobResult
+			for _, attempt := range attempts {
+				results = append(results, &JobResult{Job: attempt, Combined: ConnectivityAllowed})
+			}
+			combined := combineRepetitions([]*Job{job}, combineAttempts(job.Repetitions(2), results))
+			Expect(combined).To(HaveLen(1))
+			Expect(combined[0].Attempts).To(Equal(2 * 2 * serviceAttemptsPerEndpoint))
+			Expect(combined[0].Agrees(&JobResult{Job: job, Combined: ConnectivityMixed})).To(BeFalse())
+		})
+
+		It("should combine the attempts of a job", func() {
+			job := &Job{FromKey: "x/a", ToKey: "svc/x/web", ToService: true, ResolvedPort: 80, Protocol: v1.ProtocolTCP}
+			attempts := []*JobResult{
+				{Job: &Job{FromKey: "x/a", ToKey: "svc/x/web", ToService: true, ResolvedPort: 80, Protocol: v1.ProtocolTCP, Attempt: 1}, Combined: ConnectivityTimedOut},
+				{Job: job, Combined: ConnectivityRefused},
+			}
+			Expect(combineAttempts([]*Job{job}, attempts)[0].Combined).To(Equal(ConnectivityTimedOut))
+
+			attempts = append(attempts, &JobResult{Job: &Job{FromKey: "x/a", ToKey: "svc/x/web", ToService: true, ResolvedPort: 80, Protocol: v1.ProtocolTCP, Attempt: 2}, Combined: ConnectivityAllowed})
+			Expect(combineAttempts([]*Job{job}, attempts)[0].Combined).To(Equal(ConnectivityMixed))
+		})
+	})
+}
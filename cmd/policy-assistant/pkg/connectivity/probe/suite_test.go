@@ -10,5 +10,8 @@ import (
 func TestProbe(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunResourcesTests()
+	RunJobRunnerTests()
+	RunExternalTargetTests()
+	RunServiceTests()
 	RunSpecs(t, "generator suite")
 }
@@ -102,8 +102,10 @@ func setCombined(jr *JobResult) {
 	}
 }
 
-func NewTable(items []string) *Table {
-	return &Table{Wrapped: NewTruthTableFromItems(items, func(fr, to string) interface{} {
+// NewTable creates an empty table; probes are sent from pods, so the tos are a superset of the froms
+// when there are external targets.
+func NewTable(froms []string, tos []string) *Table {
+	return &Table{Wrapped: NewTruthTable(froms, tos, func(fr, to string) interface{} {
 		return &Item{
 			From:       fr,
 			To:         to,
@@ -113,7 +115,7 @@ func NewTable(items []string) *Table {
 }
 
 func NewTableFromJobResults(resources *Resources, jobResults []*JobResult) *Table {
-	table := NewTable(resources.SortedPodNames())
+	table := NewTable(resources.SortedPodNames(), resources.SortedTargetNames())
 	for _, result := range jobResults {
 		fr := result.Job.FromKey
 		to := result.Job.ToKey
@@ -152,7 +154,6 @@ func (t *Table) renderTableHelper(render func(*JobResult) string) string {
 		dict := t.Get(key.From, key.To).JobResults
 		if len(dict) != 1 {
 			isSingleElement = false
-			break
 		}
 		keys := slice.Sort(maps.Keys(dict))
 		schema[strings.Join(keys, "_")] = true
@@ -0,0 +1,165 @@
+package probe
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+)
+
+// WorkloadProbeContainerName is the ephemeral container that probes are run in, when probing existing pods.
+const WorkloadProbeContainerName = "policy-assistant-worker"
+
+// NewWorkloadResources uses existing pods -- those in the namespaces which match the label selector --
+// instead of creating its own.  Probes are run from an ephemeral container added to each pod, which
+// shares the pod's network namespace and is therefore subject to the same policies as the application.
+// Nothing else in the cluster is modified.
+//
+// Ports are discovered from the containers' specs, so ports which are served but not declared won't be
+// probed.  The pods aren't backed by services created by policy-assistant, so only pod IP probes work.
+// The ephemeral container runs probeImage, which must provide /agnhost, e.g. AgnhostImage.
+func NewWorkloadResources(kubernetes kube.IKubernetes, namespaces []string, selector string, probeImage string, containerTimeoutSeconds int) (*Resources, error) {
+	labelSelector, err := labels.Parse(selector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse label selector %s", selector)
+	}
+
+	r := &Resources{Namespaces: map[string]map[string]string{}}
+	for _, ns := range namespaces {
+		r.Namespaces[ns] = map[string]string{}
+		kubePods, err := kubernetes.GetPodsInNamespace(ns)
+		if err != nil {
+			return nil, err
+		}
+		for _, kubePod := range kubePods {
+			if !labelSelector.Matches(labels.Set(kubePod.Labels)) {
+				continue
+			}
+			if kubePod.Spec.HostNetwork {
+				logrus.Infof("skipping pod %s/%s: network policies don't apply to host network pods", kubePod.Namespace, kubePod.Name)
+				continue
+			}
+			if kubePod.Status.Phase != v1.PodRunning || kubePod.Status.PodIP == "" {
+				logrus.Infof("skipping pod %s/%s: not running or no IP address", kubePod.Namespace, kubePod.Name)
+				continue
+			}
+			r.Pods = append(r.Pods, NewWorkloadPod(&kubePod))
+		}
+	}
+	if len(r.Pods) == 0 {
+		return nil, errors.Errorf("no running pods matching selector '%s' found in namespaces %+v", selector, namespaces)
+	}
+
+	if err := r.addProbeContainers(kubernetes, probeImage); err != nil {
+		return nil, err
+	}
+	if err := r.waitForProbeContainersRunning(kubernetes, containerTimeoutSeconds); err != nil {
+		return nil, err
+	}
+	if err := r.getNamespaceLabelsFromKube(kubernetes); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// NewWorkloadPod builds a Pod with a Container for each port declared by kubePod's containers.
+func NewWorkloadPod(kubePod *v1.Pod) *Pod {
+	var containers []*Container
+	for _, kubeCont := range kubePod.Spec.Containers {
+		for _, port := range kubeCont.Ports {
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = v1.ProtocolTCP
+			}
+			containers = append(containers, &Container{
+				Name:     kubeCont.Name,
+				Port:     int(port.ContainerPort),
+				Protocol: protocol,
+				PortName: port.Name,
+			})
+		}
+	}
+	return &Pod{
+		Namespace:      kubePod.Namespace,
+		Name:           kubePod.Name,
+		Labels:         kubePod.Labels,
+		IP:             kubePod.Status.PodIP,
+		Containers:     containers,
+		ProbeContainer: WorkloadProbeContainerName,
+	}
+}
+
+func WorkloadProbeContainer(image string) *v1.EphemeralContainer {
+	return &v1.EphemeralContainer{
+		EphemeralContainerCommon: v1.EphemeralContainerCommon{
+			Name:            WorkloadProbeContainerName,
+			Image:           image,
+			ImagePullPolicy: v1.PullIfNotPresent,
+			Command:         []string{"/agnhost", "pause"},
+		},
+	}
+}
+
+// addProbeContainers adds the probe container to each pod which doesn't already have one: ephemeral
+// containers can't be removed, so one left over from a previous run is reused.
+func (r *Resources) addProbeContainers(kubernetes kube.IKubernetes, image string) error {
+	for _, pod := range r.Pods {
+		kubePod, err := kubernetes.GetPod(pod.Namespace, pod.Name)
+		if err != nil {
+			return err
+		}
+		if findEphemeralContainer(kubePod, WorkloadProbeContainerName) {
+			logrus.Debugf("reusing container %s in pod %s/%s", WorkloadProbeContainerName, pod.Namespace, pod.Name)
+			continue
+		}
+		if _, err := kubernetes.AddEphemeralContainer(pod.Namespace, pod.Name, WorkloadProbeContainer(image)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Resources) waitForProbeContainersRunning(kubernetes kube.IKubernetes, timeoutSeconds int) error {
+	sleep := 5
+	for i := 0; i < timeoutSeconds; i += sleep {
+		var notRunning []string
+		for _, pod := range r.Pods {
+			kubePod, err := kubernetes.GetPod(pod.Namespace, pod.Name)
+			if err != nil {
+				return err
+			}
+			if !isEphemeralContainerRunning(kubePod, WorkloadProbeContainerName) {
+				notRunning = append(notRunning, pod.PodString().String())
+			}
+		}
+		if len(notRunning) == 0 {
+			return nil
+		}
+
+		logrus.Infof("waiting for probe containers to be running in %d pods: %+v", len(notRunning), notRunning)
+		time.Sleep(time.Duration(sleep) * time.Second)
+	}
+	return errors.Errorf("probe containers not running after %d seconds", timeoutSeconds)
+}
+
+func findEphemeralContainer(kubePod *v1.Pod, name string) bool {
+	for _, cont := range kubePod.Spec.EphemeralContainers {
+		if cont.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isEphemeralContainerRunning(kubePod *v1.Pod, name string) bool {
+	for _, status := range kubePod.Status.EphemeralContainerStatuses {
+		if status.Name == name {
+			return status.State.Running != nil
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package connectivity
+
+import (
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/json"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+)
+
+// ResultStore persists results to a directory, one json file per test case, named by the test case ID.
+// Stored results keep what's needed to rebuild the summary -- the test case, probe results and
+// convergence -- but not the parsed policies or initial resources.
+type ResultStore struct {
+	Dir string
+}
+
+func NewResultStore(dir string) (*ResultStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "unable to create results directory %s", dir)
+	}
+	return &ResultStore{Dir: dir}, nil
+}
+
+type storedTable struct {
+	Items []string
+	// Tos is only stored when it differs from Items, which is when there are external targets
+	Tos        []string `json:",omitempty"`
+	JobResults []*probe.JobResult
+}
+
+func newStoredTable(table *probe.Table) *storedTable {
+	stored := &storedTable{Items: table.Wrapped.Froms}
+	if len(table.Wrapped.Tos) != len(table.Wrapped.Froms) {
+		stored.Tos = table.Wrapped.Tos
+	}
+	for _, key := range table.Wrapped.Keys() {
+		jobResults := table.Get(key.From, key.To).JobResults
+		for _, jobKey := range slice.Sort(maps.Keys(jobResults)) {
+			stored.JobResults = append(stored.JobResults, jobResults[jobKey])
+		}
+	}
+	return stored
+}
+
+func (s *storedTable) Table() (*probe.Table, error) {
+	tos := s.Tos
+	if tos == nil {
+		tos = s.Items
+	}
+	table := probe.NewTable(s.Items, tos)
+	for _, jobResult := range s.JobResults {
+		if err := table.Get(jobResult.Job.FromKey, jobResult.Job.ToKey).AddJobResult(jobResult); err != nil {
+			return nil, err
+		}
+	}
+	return table, nil
+}
+
+type storedStep struct {
+	SimulatedProbe *storedTable
+	KubeProbes     []*storedTable
+	Convergence    *Convergence
+}
+
+type storedResult struct {
+	TestCase *generator.TestCaseDocument
+	Steps    []*storedStep
+}
+
+func (s *ResultStore) path(id string) string {
+	return path.Join(s.Dir, id+".json")
+}
+
+// Save writes a result which executed without error.  The file is written under a temporary name and then
+// renamed, so that an interrupted run never leaves a partial result behind.
+func (s *ResultStore) Save(id string, result *Result) error {
+	if result.Err != nil {
+		return errors.Errorf("unable to save result of test case %s: test case failed to execute", id)
+	}
+	stored := &storedResult{TestCase: generator.NewTestCaseDocument(result.TestCase)}
+	for _, step := range result.Steps {
+		storedStep := &storedStep{SimulatedProbe: newStoredTable(step.SimulatedProbe), Convergence: step.Convergence}
+		for _, kubeProbe := range step.KubeProbes {
+			storedStep.KubeProbes = append(storedStep.KubeProbes, newStoredTable(kubeProbe))
+		}
+		stored.Steps = append(stored.Steps, storedStep)
+	}
+
+	temporaryPath := s.path(id) + ".tmp"
+	if err := json.MarshalToFile(stored, temporaryPath); err != nil {
+		return errors.Wrapf(err, "unable to write result of test case %s", id)
+	}
+	return errors.Wrapf(os.Rename(temporaryPath, s.path(id)), "unable to write result of test case %s", id)
+}
+
+func (s *ResultStore) load(id string) (*Result, error) {
+	stored, err := json.ParseFile[storedResult](s.path(id))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "unable to read result of test case %s", id)
+	}
+	if stored.TestCase == nil || stored.TestCase.TestCase == nil || len(stored.TestCase.Steps) != len(stored.Steps) {
+		return nil, errors.Errorf("invalid stored result for test case %s", id)
+	}
+
+	result := &Result{TestCase: stored.TestCase.TestCase}
+	for i, step := range stored.Steps {
+		simulated, err := step.SimulatedProbe.Table()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid stored result for test case %s", id)
+		}
+		stepResult := &StepResult{SimulatedProbe: simulated, Expected: result.TestCase.Steps[i].Expected, Convergence: step.Convergence}
+		for _, kubeProbe := range step.KubeProbes {
+			kube, err := kubeProbe.Table()
+			if err != nil {
+				return nil, errors.WithMessagef(err, "invalid stored result for test case %s", id)
+			}
+			stepResult.AddKubeProbe(kube)
+		}
+		result.Steps = append(result.Steps, stepResult)
+	}
+	return result, nil
+}
+
+// LoadAll reads all stored results, keyed by test case ID.
+func (s *ResultStore) LoadAll() (map[string]*Result, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read results directory %s", s.Dir)
+	}
+	results := map[string]*Result{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		result, err := s.load(id)
+		if err != nil {
+			return nil, err
+		}
+		results[id] = result
+	}
+	return results, nil
+}
@@ -0,0 +1,68 @@
+package connectivity
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+)
+
+func RunResultStoreTests() {
+	Describe("ResultStore", func() {
+		It("should rebuild the same summary from stored results", func() {
+			dir, err := os.MkdirTemp("", "results")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			mock := kube.NewMockKubernetes(1.0)
+			resources, err := probe.NewDefaultResources(mock, []string{"x", "y", "z"}, []string{"a", "b"}, []int{80, 81}, []v1.Protocol{v1.ProtocolTCP, v1.ProtocolUDP}, []string{}, []string{}, 10, false, "registry.k8s.io")
+			Expect(err).ToNot(HaveOccurred())
+			interpreter := NewInterpreter(mock, resources, &InterpreterConfig{ResetClusterBeforeTestCase: true, VerifyClusterStateBeforeTestCase: true})
+
+			store, err := NewResultStore(dir)
+			Expect(err).ToNot(HaveOccurred())
+
+			var results []*Result
+			var ids []string
+			for _, testCase := range generator.NewTestCaseGenerator(true, "1.2.3.4", []string{"x", "y", "z"}, []string{}, []string{}).ActionTestCases() {
+				result := interpreter.ExecuteTestCase(testCase)
+				Expect(result.Err).ToNot(HaveOccurred())
+				id, err := generator.TestCaseID(testCase)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(store.Save(id, result)).To(Succeed())
+				results = append(results, result)
+				ids = append(ids, id)
+			}
+
+			stored, err := store.LoadAll()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stored).To(HaveLen(len(results)))
+			var loaded []*Result
+			for _, id := range ids {
+				Expect(stored).To(HaveKey(id))
+				loaded = append(loaded, stored[id])
+			}
+
+			for _, ignoreLoopback := range []bool{false, true} {
+				original, rebuilt := NewSummaryTableFromResults(ignoreLoopback, results), NewSummaryTableFromResults(ignoreLoopback, loaded)
+				Expect(rebuilt.Tests).To(Equal(original.Tests))
+				Expect(rebuilt.Passed).To(Equal(original.Passed))
+				Expect(rebuilt.Failed).To(Equal(original.Failed))
+				Expect(rebuilt.ProtocolCounts).To(Equal(original.ProtocolCounts))
+				Expect(rebuilt.TagCounts).To(Equal(original.TagCounts))
+				Expect(rebuilt.FeatureCounts).To(Equal(original.FeatureCounts))
+
+				baseline, err := ReadBaseline(dir, ignoreLoopback)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(baseline.TestCases).To(HaveLen(len(results)))
+				for i, id := range ids {
+					Expect(baseline.TestCases[id]).To(Equal(&BaselineTestCase{Description: results[i].TestCase.Description, Passed: results[i].Passed(ignoreLoopback)}))
+				}
+			}
+		})
+	})
+}
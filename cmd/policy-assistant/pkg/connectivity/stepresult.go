@@ -1,8 +1,15 @@
 package connectivity
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/mattfenwick/collections/pkg/slice"
+	"golang.org/x/exp/maps"
+
 	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
 
 	networkingv1 "k8s.io/api/networking/v1"
@@ -15,9 +22,18 @@ type StepResult struct {
 	KubePolicies   []*networkingv1.NetworkPolicy
 	ANPs           []*v1alpha1.AdminNetworkPolicy
 	BANP           *v1alpha1.BaselineAdminNetworkPolicy
+	Expected       *generator.ExpectedConnectivity
+	Convergence    *Convergence
 	comparisons    []*ComparisonTable
 }
 
+// Convergence records how long it took for kube to enforce a step's policies, or -- if it never
+// converged -- how long we waited.  It's only recorded when probing until kube matches the simulation.
+type Convergence struct {
+	Converged     bool
+	TimeToEnforce time.Duration
+}
+
 func NewStepResult(simulated *probe.Table, policy *matcher.Policy, kubePolicies []*networkingv1.NetworkPolicy) *StepResult {
 	return &StepResult{
 		SimulatedProbe: simulated,
@@ -47,5 +63,133 @@ func (s *StepResult) LastKubeProbe() *probe.Table {
 }
 
 func (s *StepResult) Passed(ignoreLoopback bool) bool {
-	return s.LastComparison().ValueCounts(ignoreLoopback)[DifferentComparison] == 0
+	return s.LastComparison().ValueCounts(ignoreLoopback)[DifferentComparison] == 0 && len(s.ExpectationMismatches()) == 0
+}
+
+// Mismatch is traffic for which the last kube result differs from the simulated result.
+type Mismatch struct {
+	From      string
+	To        string
+	Key       string
+	Simulated probe.Connectivity
+	Kube      probe.Connectivity
+	Job       *probe.Job
+}
+
+func (m *Mismatch) String() string {
+	return fmt.Sprintf("%s -> %s %s: simulated %s, kube %s", m.From, m.To, m.Key, m.Simulated, m.Kube)
+}
+
+// Mismatches lists the traffic counted as different by LastComparison, one entry per port and protocol.
+// Flaky kube results aren't mismatches.
+func (s *StepResult) Mismatches(ignoreLoopback bool) []*Mismatch {
+	var mismatches []*Mismatch
+	kubeProbe := s.LastKubeProbe()
+	for _, key := range s.SimulatedProbe.Wrapped.Keys() {
+		if ignoreLoopback && key.From == key.To {
+			continue
+		}
+		kubeResults := kubeProbe.Get(key.From, key.To).JobResults
+		simulatedResults := s.SimulatedProbe.Get(key.From, key.To).JobResults
+		for _, jobKey := range slice.Sort(maps.Keys(simulatedResults)) {
+			simulated, kube := simulatedResults[jobKey], kubeResults[jobKey]
+			if jobComparison(kube, simulated) != DifferentComparison {
+				continue
+			}
+			mismatch := &Mismatch{From: key.From, To: key.To, Key: jobKey, Simulated: simulated.Combined, Kube: probe.ConnectivityUnknown, Job: simulated.Job}
+			if kube != nil {
+				mismatch.Kube = kube.Combined
+			}
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+	return mismatches
+}
+
+// Walkthrough explains how the simulation reached its verdict on a job, the same way as 'analyze --mode
+// walkthrough': once per destination, which for a service is each of its endpoints.  Results loaded from a
+// results directory don't keep their policies, so they can't be explained.
+func (s *StepResult) Walkthrough(job *probe.Job) []*Walkthrough {
+	if s.Policy == nil {
+		return nil
+	}
+	traffic := []*matcher.Traffic{job.Traffic()}
+	if job.ToService && !job.IsDNS() {
+		traffic = job.EndpointTraffic()
+	}
+
+	var walkthroughs []*Walkthrough
+	for _, t := range traffic {
+		allowed := s.Policy.IsTrafficAllowed(t)
+		ingress, egress := allowed.Ingress.Flow(), allowed.Egress.Flow()
+		if ingress == "" {
+			ingress = "no policies targeting ingress"
+		}
+		if egress == "" {
+			egress = "no policies targeting egress"
+		}
+		walkthroughs = append(walkthroughs, &Walkthrough{Traffic: t.PrettyString(), Verdict: allowed.Verdict(), Ingress: ingress, Egress: egress})
+	}
+	return walkthroughs
+}
+
+type Walkthrough struct {
+	Traffic string
+	Verdict string
+	Ingress string
+	Egress  string
+}
+
+func (w *Walkthrough) String() string {
+	return fmt.Sprintf("%s: %s; ingress: %s; egress: %s", w.Traffic, w.Verdict, w.Ingress, w.Egress)
+}
+
+// ExpectationMismatch is traffic for which the simulated or kube result differs from the expected result
+// stated by the test step.
+type ExpectationMismatch struct {
+	From      string
+	To        string
+	Key       string
+	Expected  generator.ExpectedResult
+	Simulated probe.Connectivity
+	Kube      probe.Connectivity
+}
+
+func (e *ExpectationMismatch) String() string {
+	return fmt.Sprintf("%s -> %s %s: expected %s, simulated %s, kube %s", e.From, e.To, e.Key, e.Expected, e.Simulated, e.Kube)
+}
+
+// ExpectationMismatches compares the simulated and last kube probes to the expected connectivity, if any.
+// Loopback traffic is not checked, and neither are flaky kube results of traffic expected to be allowed.
+func (s *StepResult) ExpectationMismatches() []*ExpectationMismatch {
+	if s.Expected == nil {
+		return nil
+	}
+	var mismatches []*ExpectationMismatch
+	kubeProbe := s.LastKubeProbe()
+	for _, key := range s.SimulatedProbe.Wrapped.Keys() {
+		if key.From == key.To {
+			continue
+		}
+		kubeResults := kubeProbe.Get(key.From, key.To).JobResults
+		for jobKey, simulated := range s.SimulatedProbe.Get(key.From, key.To).JobResults {
+			expected := s.Expected.Expect(key.From, key.To, simulated.Job.Protocol, simulated.Job.ResolvedPort)
+			kube := probe.ConnectivityUnknown
+			if kubeResult, ok := kubeResults[jobKey]; ok {
+				kube = kubeResult.Combined
+			}
+			kubeMismatch := string(kube.Verdict()) != string(expected) && !(kube == probe.ConnectivityFlaky && expected == generator.ExpectedAllowed)
+			if string(simulated.Combined.Verdict()) != string(expected) || kubeMismatch {
+				mismatches = append(mismatches, &ExpectationMismatch{
+					From:      key.From,
+					To:        key.To,
+					Key:       jobKey,
+					Expected:  expected,
+					Simulated: simulated.Combined,
+					Kube:      kube,
+				})
+			}
+		}
+	}
+	return mismatches
 }
@@ -0,0 +1,133 @@
+package connectivity
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+)
+
+func RunStepResultTests() {
+	Describe("StepResult expectations", func() {
+		It("should fail steps whose results differ from the expected connectivity", func() {
+			// the mock allows all traffic, so -- apart from loopback -- the simulated and kube results agree if there are no policies
+			mock := kube.NewMockKubernetes(1.0)
+			resources, err := probe.NewDefaultResources(mock, []string{"x", "y"}, []string{"a", "b"}, []int{80}, []v1.Protocol{v1.ProtocolTCP}, []string{}, []string{}, 10, false, "registry.k8s.io")
+			Expect(err).ToNot(HaveOccurred())
+			interpreter := NewInterpreter(mock, resources, &InterpreterConfig{ResetClusterBeforeTestCase: true, VerifyClusterStateBeforeTestCase: true})
+
+			allowed := &generator.ExpectedConnectivity{Default: generator.ExpectedAllowed}
+			blocked := &generator.ExpectedConnectivity{
+				Default:    generator.ExpectedAllowed,
+				Exceptions: []*generator.ExpectedTraffic{{From: "x/a", To: "y/b", Protocol: v1.ProtocolTCP, Result: generator.ExpectedBlocked}},
+			}
+			testCase := generator.NewTestCase("expectations", generator.NewStringSet(),
+				&generator.TestStep{Probe: generator.ProbeAllAvailable, Expected: allowed},
+				&generator.TestStep{Probe: generator.ProbeAllAvailable, Expected: blocked})
+
+			result := interpreter.ExecuteTestCase(testCase)
+			Expect(result.Err).ToNot(HaveOccurred())
+			Expect(result.Steps[0].Passed(true)).To(BeTrue())
+			Expect(result.Steps[1].Passed(true)).To(BeFalse())
+			mismatches := result.Steps[1].ExpectationMismatches()
+			Expect(mismatches).To(HaveLen(1))
+			Expect(mismatches[0].String()).To(Equal("x/a -> y/b TCP/80: expected blocked, simulated allowed, kube allowed"))
+		})
+	})
+	Describe("Convergence", func() {
+		It("should probe until kube matches the simulation, or the timeout expires", func() {
+			mock := kube.NewMockKubernetes(1.0)
+			resources, err := probe.NewDefaultResources(mock, []string{"x", "y"}, []string{"a", "b"}, []int{80}, []v1.Protocol{v1.ProtocolTCP}, []string{}, []string{}, 10, false, "registry.k8s.io")
+			Expect(err).ToNot(HaveOccurred())
+			interpreter := NewInterpreter(mock, resources, &InterpreterConfig{
+				ResetClusterBeforeTestCase:       true,
+				VerifyClusterStateBeforeTestCase: true,
+				IgnoreLoopback:                   true,
+				ConvergenceTimeoutSeconds:        1,
+				ConvergencePollSeconds:           1,
+			})
+
+			// the mock allows all traffic, so it never enforces the deny-all policy
+			testCase := generator.NewTestCase("convergence", generator.NewStringSet(),
+				generator.NewTestStep(generator.ProbeAllAvailable),
+				generator.NewTestStep(generator.ProbeAllAvailable, generator.CreatePolicy(generator.BuildPolicy().NetworkPolicy())))
+			result := interpreter.ExecuteTestCase(testCase)
+			Expect(result.Err).ToNot(HaveOccurred())
+
+			Expect(result.Steps[0].Convergence.Converged).To(BeTrue())
+			Expect(result.Steps[0].KubeProbes).To(HaveLen(1))
+			Expect(result.Steps[1].Convergence.Converged).To(BeFalse())
+			Expect(result.Steps[1].Convergence.TimeToEnforce).To(BeNumerically(">=", time.Second))
+			Expect(len(result.Steps[1].KubeProbes)).To(BeNumerically(">=", 2))
+
+			summary := NewSummaryTableFromResults(true, []*Result{result})
+			Expect(summary.TimesToEnforce).To(HaveLen(1))
+			Expect(summary.NotConverged).To(Equal(1))
+			// only the last probe of each step is counted
+			Expect(summary.Tests).To(HaveLen(1 + 2))
+			expected := map[Comparison]int{}
+			for _, step := range result.Steps {
+				for comparison, count := range step.LastComparison().ValueCountsByProtocol(true)[v1.ProtocolTCP] {
+					expected[comparison] += count
+				}
+			}
+			Expect(summary.ProtocolCounts[v1.ProtocolTCP][SameComparison]).To(Equal(expected[SameComparison]))
+			Expect(summary.ProtocolCounts[v1.ProtocolTCP][DifferentComparison]).To(Equal(expected[DifferentComparison]))
+		})
+
+		It("should count flaky kube results separately from mismatches", func() {
+			job := &probe.Job{FromKey: "x/a", ToKey: "y/b", ResolvedPort: 80, Protocol: v1.ProtocolTCP}
+			table := func(connectivity probe.Connectivity) *probe.Table {
+				t := probe.NewTable([]string{"x/a", "y/b"}, []string{"y/b"})
+				Expect(t.Get("x/a", "y/b").AddJobResult(&probe.JobResult{Job: job, Combined: connectivity})).To(Succeed())
+				loopback := *job
+				loopback.FromKey = "y/b"
+				Expect(t.Get("y/b", "y/b").AddJobResult(&probe.JobResult{Job: &loopback, Combined: probe.ConnectivityAllowed})).To(Succeed())
+				return t
+			}
+
+			stepResult := NewStepResult(table(probe.ConnectivityAllowed), nil, nil)
+			stepResult.AddKubeProbe(table(probe.ConnectivityFlaky))
+			Expect(stepResult.LastComparison().ValueCounts(true)).To(Equal(map[Comparison]int{FlakyComparison: 1, IgnoredComparison: 1}))
+			Expect(stepResult.LastComparison().ValueCountsByProtocol(false)[v1.ProtocolTCP]).To(Equal(map[Comparison]int{FlakyComparison: 1, SameComparison: 1}))
+			Expect(stepResult.Passed(true)).To(BeTrue())
+
+			stepResult.AddKubeProbe(table(probe.ConnectivityBlocked))
+			Expect(stepResult.LastComparison().ValueCounts(true)).To(Equal(map[Comparison]int{DifferentComparison: 1, IgnoredComparison: 1}))
+			Expect(stepResult.Passed(true)).To(BeFalse())
+		})
+
+		It("should count flaky kube results of denied traffic as mismatches", func() {
+			job := &probe.Job{FromKey: "x/a", ToKey: "y/b", ResolvedPort: 80, Protocol: v1.ProtocolTCP}
+			table := func(connectivity probe.Connectivity) *probe.Table {
+				t := probe.NewTable([]string{"x/a"}, []string{"y/b"})
+				Expect(t.Get("x/a", "y/b").AddJobResult(&probe.JobResult{Job: job, Combined: connectivity})).To(Succeed())
+				return t
+			}
+
+			stepResult := NewStepResult(table(probe.ConnectivityBlocked), nil, nil)
+			stepResult.Expected = &generator.ExpectedConnectivity{Default: generator.ExpectedBlocked}
+			stepResult.AddKubeProbe(table(probe.ConnectivityFlaky))
+			Expect(stepResult.LastComparison().ValueCounts(true)).To(Equal(map[Comparison]int{DifferentComparison: 1}))
+			Expect(stepResult.ExpectationMismatches()).To(HaveLen(1))
+			Expect(stepResult.Passed(true)).To(BeFalse())
+		})
+
+		It("should compute nearest-rank percentiles of the time to enforce", func() {
+			summary := &SummaryTable{}
+			Expect(summary.Percentile(50)).To(Equal(time.Duration(0)))
+			for i := 10; i >= 1; i-- {
+				summary.TimesToEnforce = append(summary.TimesToEnforce, time.Duration(i)*time.Second)
+			}
+			Expect(summary.Percentile(50)).To(Equal(5 * time.Second))
+			Expect(summary.Percentile(90)).To(Equal(9 * time.Second))
+			Expect(summary.Percentile(99)).To(Equal(10 * time.Second))
+			Expect(summary.Percentile(100)).To(Equal(10 * time.Second))
+			Expect(summary.Percentile(0)).To(Equal(1 * time.Second))
+		})
+	})
+}
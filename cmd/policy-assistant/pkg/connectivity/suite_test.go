@@ -11,5 +11,12 @@ func TestConnectivity(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunTestCaseStateTests()
 	RunPrinterTests()
+	RunFuzzerTests()
+	RunStepResultTests()
+	RunResultStoreTests()
+	RunParallelTests()
+	RunHTMLReportTests()
+	RunBaselineTests()
+	RunEventTests()
 	RunSpecs(t, "connectivity suite")
 }
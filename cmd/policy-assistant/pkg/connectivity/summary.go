@@ -2,6 +2,10 @@ package connectivity
 
 import (
 	"fmt"
+	"math"
+	"sort"
+	"time"
+
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -14,6 +18,8 @@ type SummaryTable struct {
 	TagPrimaryCounts     map[string]map[bool]int
 	FeatureCounts        map[string]map[string]map[bool]int
 	FeaturePrimaryCounts map[string]map[bool]int
+	TimesToEnforce       []time.Duration
+	NotConverged         int
 }
 
 func NewSummaryTableFromResults(ignoreLoopback bool, results []*Result) *SummaryTable {
@@ -60,12 +66,24 @@ func NewSummaryTableFromResults(ignoreLoopback bool, results []*Result) *Summary
 
 		summary.Tests = append(summary.Tests, []string{
 			fmt.Sprintf("%d: %s", testNumber+1, result.TestCase.Description),
-			testResult, "", "", "", "",
+			testResult, "", "", "", "", "",
 			"", "", "",
 		})
 
 		for stepNumber, step := range result.Steps {
-			for tryNumber := range step.KubeProbes {
+			if step.Convergence != nil {
+				if step.Convergence.Converged {
+					summary.TimesToEnforce = append(summary.TimesToEnforce, step.Convergence.TimeToEnforce)
+				} else {
+					summary.NotConverged++
+				}
+			}
+			firstTry := 0
+			if step.Convergence != nil {
+				// the probes before the last only measure the time to enforce
+				firstTry = len(step.KubeProbes) - 1
+			}
+			for tryNumber := firstTry; tryNumber < len(step.KubeProbes); tryNumber++ {
 				counts := step.Comparison(tryNumber).ValueCounts(ignoreLoopback)
 				tryProtocolCounts := step.Comparison(tryNumber).ValueCountsByProtocol(ignoreLoopback)
 				tcp := tryProtocolCounts[v1.ProtocolTCP]
@@ -78,6 +96,7 @@ func NewSummaryTableFromResults(ignoreLoopback bool, results []*Result) *Summary
 					intToString(counts[DifferentComparison]),
 					intToString(counts[SameComparison]),
 					intToString(counts[IgnoredComparison]),
+					intToString(counts[FlakyComparison]),
 					protocolResult(tcp[SameComparison], tcp[DifferentComparison]),
 					protocolResult(sctp[SameComparison], sctp[DifferentComparison]),
 					protocolResult(udp[SameComparison], udp[DifferentComparison]),
@@ -89,6 +108,9 @@ func NewSummaryTableFromResults(ignoreLoopback bool, results []*Result) *Summary
 				summary.ProtocolCounts[v1.ProtocolSCTP][DifferentComparison] += sctp[DifferentComparison]
 				summary.ProtocolCounts[v1.ProtocolUDP][SameComparison] += udp[SameComparison]
 				summary.ProtocolCounts[v1.ProtocolUDP][DifferentComparison] += udp[DifferentComparison]
+				summary.ProtocolCounts[v1.ProtocolTCP][FlakyComparison] += tcp[FlakyComparison]
+				summary.ProtocolCounts[v1.ProtocolSCTP][FlakyComparison] += sctp[FlakyComparison]
+				summary.ProtocolCounts[v1.ProtocolUDP][FlakyComparison] += udp[FlakyComparison]
 			}
 		}
 	}
@@ -99,6 +121,20 @@ func NewSummaryTableFromResults(ignoreLoopback bool, results []*Result) *Summary
 	return summary
 }
 
+// Percentile returns the time to enforce at the given percentile, using the nearest-rank method.
+func (s *SummaryTable) Percentile(percentile float64) time.Duration {
+	if len(s.TimesToEnforce) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, s.TimesToEnforce...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
 func incrementCounts(dict map[string]map[bool]int, keys []string, b bool) {
 	for _, k := range keys {
 		if _, ok := dict[k]; !ok {
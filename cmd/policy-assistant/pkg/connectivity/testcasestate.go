@@ -8,14 +8,27 @@ import (
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+)
+
+const (
+	// ManagedByLabel marks the admin policies created by test cases, so that resetting the cluster only
+	// deletes those, and leaves the cluster's own admin policies alone
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedBy      = "policy-assistant"
 )
 
 type TestCaseState struct {
 	Kubernetes kube.IKubernetes
 	Resources  *probe.Resources
 	Policies   []*networkingv1.NetworkPolicy
+	ANPs       []*v1alpha1.AdminNetworkPolicy
+	BANP       *v1alpha1.BaselineAdminNetworkPolicy
 }
 
 func (t *TestCaseState) CreatePolicy(policy *networkingv1.NetworkPolicy) error {
@@ -51,6 +64,109 @@ func (t *TestCaseState) UpdatePolicy(policy *networkingv1.NetworkPolicy) error {
 	return err
 }
 
+func (t *TestCaseState) findAdminPolicy(name string) int {
+	for i, anp := range t.ANPs {
+		if anp.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *TestCaseState) CreateAdminPolicy(policy *v1alpha1.AdminNetworkPolicy) error {
+	if t.findAdminPolicy(policy.Name) >= 0 {
+		return errors.Errorf("cannot create admin policy %s: already exists", policy.Name)
+	}
+	t.ANPs = append(t.ANPs, policy)
+
+	managed := policy.DeepCopy()
+	setManagedByLabel(&managed.ObjectMeta)
+	_, err := t.Kubernetes.CreateAdminNetworkPolicy(context.TODO(), managed)
+	return err
+}
+
+func (t *TestCaseState) UpdateAdminPolicy(policy *v1alpha1.AdminNetworkPolicy) error {
+	index := t.findAdminPolicy(policy.Name)
+	if index < 0 {
+		return errors.Errorf("cannot update admin policy %s: not found", policy.Name)
+	}
+
+	t.ANPs[index] = policy
+	// the update must carry the resourceVersion of the object in kube
+	kubeANPs, err := t.Kubernetes.GetAdminNetworkPolicies(context.TODO())
+	if err != nil {
+		return err
+	}
+	updated := policy.DeepCopy()
+	setManagedByLabel(&updated.ObjectMeta)
+	for _, kubeANP := range kubeANPs {
+		if kubeANP.Name == policy.Name {
+			updated.ResourceVersion = kubeANP.ResourceVersion
+		}
+	}
+	_, err = t.Kubernetes.UpdateAdminNetworkPolicy(context.TODO(), updated)
+	return err
+}
+
+func (t *TestCaseState) DeleteAdminPolicy(name string) error {
+	index := t.findAdminPolicy(name)
+	if index < 0 {
+		return errors.Errorf("cannot delete admin policy %s: not found", name)
+	}
+
+	var newANPs []*v1alpha1.AdminNetworkPolicy
+	for i, anp := range t.ANPs {
+		if i != index {
+			newANPs = append(newANPs, anp)
+		}
+	}
+	t.ANPs = newANPs
+
+	return t.Kubernetes.DeleteAdminNetworkPolicy(context.TODO(), name)
+}
+
+func (t *TestCaseState) CreateBaselineAdminPolicy(policy *v1alpha1.BaselineAdminNetworkPolicy) error {
+	// there can only be one BANP in a cluster
+	if t.BANP != nil {
+		return errors.Errorf("cannot create baseline admin policy %s: %s already exists", policy.Name, t.BANP.Name)
+	}
+	t.BANP = policy
+
+	managed := policy.DeepCopy()
+	setManagedByLabel(&managed.ObjectMeta)
+	_, err := t.Kubernetes.CreateBaselineAdminNetworkPolicy(context.TODO(), managed)
+	return err
+}
+
+func (t *TestCaseState) UpdateBaselineAdminPolicy(policy *v1alpha1.BaselineAdminNetworkPolicy) error {
+	if t.BANP == nil || t.BANP.Name != policy.Name {
+		return errors.Errorf("cannot update baseline admin policy %s: not found", policy.Name)
+	}
+
+	t.BANP = policy
+	// the update must carry the resourceVersion of the object in kube
+	kubeBANP, err := t.Kubernetes.GetBaselineAdminNetworkPolicy(context.TODO())
+	if err != nil {
+		return err
+	}
+	updated := policy.DeepCopy()
+	setManagedByLabel(&updated.ObjectMeta)
+	if kubeBANP != nil {
+		updated.ResourceVersion = kubeBANP.ResourceVersion
+	}
+	_, err = t.Kubernetes.UpdateBaselineAdminNetworkPolicy(context.TODO(), updated)
+	return err
+}
+
+func (t *TestCaseState) DeleteBaselineAdminPolicy(name string) error {
+	if t.BANP == nil || t.BANP.Name != name {
+		return errors.Errorf("cannot delete baseline admin policy %s: not found", name)
+	}
+	t.BANP = nil
+
+	return t.Kubernetes.DeleteBaselineAdminNetworkPolicy(context.TODO(), name)
+}
+
 func (t *TestCaseState) CreateNamespace(ns string, labels map[string]string) error {
 	newResources, err := t.Resources.CreateNamespace(ns, labels)
 	if err != nil {
@@ -149,6 +265,29 @@ func (t *TestCaseState) ReadPolicies(namespaces []string) error {
 	return nil
 }
 
+// ReadAdminPolicies adds the ANPs and BANP already in the cluster, so that they're taken into account
+// by the simulation.  It fails if the simulation can't build them -- e.g. if two ANPs share a priority,
+// so that their order is undefined.
+func (t *TestCaseState) ReadAdminPolicies() error {
+	anps, banp, err := t.readAdminPolicies()
+	if err != nil {
+		return err
+	}
+	for i := range anps {
+		if t.findAdminPolicy(anps[i].Name) >= 0 {
+			return errors.Errorf("cannot read admin policy %s: already exists", anps[i].Name)
+		}
+		t.ANPs = append(t.ANPs, &anps[i])
+	}
+	if banp != nil {
+		if t.BANP != nil {
+			return errors.Errorf("cannot read baseline admin policy %s: %s already exists", banp.Name, t.BANP.Name)
+		}
+		t.BANP = banp
+	}
+	return errors.WithMessagef(matcher.ValidateAdminPolicies(t.ANPs, t.BANP), "cannot simulate the admin policies in the cluster")
+}
+
 func (t *TestCaseState) DeletePolicy(ns string, name string) error {
 	// make sure this policy exists
 	index := -1
@@ -231,6 +370,20 @@ func (t *TestCaseState) verifyClusterStateHelper() error {
 		}
 	}
 
+	for _, service := range t.Resources.Services {
+		expected := service.KubeService()
+		svc, err := t.Kubernetes.GetService(expected.Namespace, expected.Name)
+		if err != nil {
+			return err
+		}
+		if !NewLabelsDiff(svc.Spec.Selector, expected.Spec.Selector).AreLabelsEqual() {
+			return errors.Errorf("for service %s, expected selector %+v (found %+v)", service.Key(), expected.Spec.Selector, svc.Spec.Selector)
+		}
+		if len(expected.Spec.Ports) != len(svc.Spec.Ports) {
+			return errors.Errorf("for service %s, expected %d ports (found %d)", service.Key(), len(expected.Spec.Ports), len(svc.Spec.Ports))
+		}
+	}
+
 	// 3. namespaces: names, labels
 	for ns, expectedNamespaceLabels := range t.Resources.Namespaces {
 		namespace, err := t.Kubernetes.GetNamespace(ns)
@@ -308,16 +461,104 @@ func (t *TestCaseState) resetLabelsInKubeHelper() error {
 	return nil
 }
 
-func (t *TestCaseState) ResetClusterState() error {
+// readAdminPolicies reads the cluster-scoped ANPs and BANP from kube.  If the CRDs aren't installed,
+// there can't be any admin policies, so that's not an error.
+func (t *TestCaseState) readAdminPolicies() ([]v1alpha1.AdminNetworkPolicy, *v1alpha1.BaselineAdminNetworkPolicy, error) {
+	anps, err := t.Kubernetes.GetAdminNetworkPolicies(context.TODO())
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			logrus.Debugf("unable to read admin network policies, assuming CRD is not installed: %+v", err)
+			anps = nil
+		} else {
+			return nil, nil, err
+		}
+	}
+	banp, err := t.Kubernetes.GetBaselineAdminNetworkPolicy(context.TODO())
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			logrus.Debugf("unable to read baseline admin network policy, assuming CRD is not installed: %+v", err)
+			banp = nil
+		} else {
+			return nil, nil, err
+		}
+	}
+	return anps, banp, nil
+}
+
+func setManagedByLabel(meta *metav1.ObjectMeta) {
+	labels := map[string]string{}
+	for key, value := range meta.Labels {
+		labels[key] = value
+	}
+	labels[ManagedByLabel] = ManagedBy
+	meta.Labels = labels
+}
+
+func isManaged(meta *metav1.ObjectMeta) bool {
+	return meta.Labels[ManagedByLabel] == ManagedBy
+}
+
+// readManagedAdminPolicies reads the admin policies created by test cases, and counts the others.
+func (t *TestCaseState) readManagedAdminPolicies() ([]v1alpha1.AdminNetworkPolicy, *v1alpha1.BaselineAdminNetworkPolicy, int, error) {
+	anps, banp, err := t.readAdminPolicies()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	var managed []v1alpha1.AdminNetworkPolicy
+	unmanaged := 0
+	for _, anp := range anps {
+		if isManaged(&anp.ObjectMeta) {
+			managed = append(managed, anp)
+		} else {
+			unmanaged++
+		}
+	}
+	if banp != nil && !isManaged(&banp.ObjectMeta) {
+		banp = nil
+		unmanaged++
+	}
+	return managed, banp, unmanaged, nil
+}
+
+func (t *TestCaseState) resetAdminPoliciesInKubeHelper() error {
+	anps, banp, _, err := t.readManagedAdminPolicies()
+	if err != nil {
+		return err
+	}
+	for _, anp := range anps {
+		err = t.Kubernetes.DeleteAdminNetworkPolicy(context.TODO(), anp.Name)
+		if err != nil {
+			return err
+		}
+	}
+	if banp != nil {
+		return t.Kubernetes.DeleteBaselineAdminNetworkPolicy(context.TODO(), banp.Name)
+	}
+	return nil
+}
+
+// ResetClusterState deletes the network policies in the test namespaces and resets pod and namespace
+// labels.  If adminPolicies is set, the admin policies created by earlier test cases are deleted too.
+func (t *TestCaseState) ResetClusterState(adminPolicies bool) error {
 	err := kube.DeleteAllNetworkPoliciesInNamespaces(t.Kubernetes, t.Resources.NamespacesSlice())
 	if err != nil {
 		return err
 	}
 
+	if adminPolicies {
+		err = t.resetAdminPoliciesInKubeHelper()
+		if err != nil {
+			return err
+		}
+	}
+
 	return t.resetLabelsInKubeHelper()
 }
 
-func (t *TestCaseState) VerifyClusterState() error {
+// VerifyClusterState checks that the cluster matches the initial resources, without network policies in
+// the test namespaces.  If adminPolicies is set, it also checks that no admin policies created by earlier
+// test cases are left; admin policies created by others are only reported, since they affect the results.
+func (t *TestCaseState) VerifyClusterState(adminPolicies bool) error {
 	err := t.verifyClusterStateHelper()
 	if err != nil {
 		return err
@@ -330,5 +571,22 @@ func (t *TestCaseState) VerifyClusterState() error {
 	if len(policies) > 0 {
 		return errors.Errorf("expected 0 policies in namespaces %+v, found %d", t.Resources.NamespacesSlice(), len(policies))
 	}
+
+	if !adminPolicies {
+		return nil
+	}
+	anps, banp, unmanaged, err := t.readManagedAdminPolicies()
+	if err != nil {
+		return err
+	}
+	if len(anps) > 0 {
+		return errors.Errorf("expected 0 admin network policies created by test cases, found %d", len(anps))
+	}
+	if banp != nil {
+		return errors.Errorf("expected no baseline admin network policy created by test cases, found %s", banp.Name)
+	}
+	if unmanaged > 0 {
+		logrus.Warnf("found %d admin policies not created by policy-assistant; they aren't simulated, so results may not match", unmanaged)
+	}
 	return nil
 }
@@ -2,8 +2,13 @@ package connectivity
 
 import (
 	"fmt"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
 )
 
 type buildLabelDiffCase struct {
@@ -103,4 +108,92 @@ func RunTestCaseStateTests() {
 			}
 		})
 	})
+
+	Describe("TestCaseState admin policies", func() {
+		anp := &v1alpha1.AdminNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "anp"}, Spec: v1alpha1.AdminNetworkPolicySpec{
+			Priority: 10,
+			Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{{
+				Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+				From:   []v1alpha1.AdminNetworkPolicyPeer{{Namespaces: &v1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{}}}},
+			}},
+		}}
+		banp := &v1alpha1.BaselineAdminNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "default"}, Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{{
+				Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+				From:   []v1alpha1.AdminNetworkPolicyPeer{{Namespaces: &v1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{}}}},
+			}},
+		}}
+
+		It("should track admin policies in kube", func() {
+			mock := kube.NewMockKubernetes(1.0)
+			state := &TestCaseState{Kubernetes: mock, Resources: &probe.Resources{Namespaces: map[string]map[string]string{}}}
+
+			Expect(state.CreateAdminPolicy(anp)).To(Succeed())
+			Expect(state.CreateAdminPolicy(anp)).ToNot(Succeed())
+			Expect(state.CreateBaselineAdminPolicy(banp)).To(Succeed())
+			Expect(state.CreateBaselineAdminPolicy(banp)).ToNot(Succeed())
+			Expect(mock.AdminNetworkPolicies).To(HaveLen(1))
+			Expect(mock.BaselineNetworkPolicy).ToNot(BeNil())
+
+			updated := anp.DeepCopy()
+			updated.Spec.Priority = 20
+			Expect(state.UpdateAdminPolicy(updated)).To(Succeed())
+			Expect(state.ANPs[0].Spec.Priority).To(BeEquivalentTo(20))
+			Expect(mock.AdminNetworkPolicies[0].Spec.Priority).To(BeEquivalentTo(20))
+
+			Expect(state.VerifyClusterState(true)).ToNot(Succeed())
+
+			Expect(state.DeleteAdminPolicy("anp")).To(Succeed())
+			Expect(state.DeleteAdminPolicy("anp")).ToNot(Succeed())
+			Expect(state.DeleteBaselineAdminPolicy("default")).To(Succeed())
+			Expect(state.ANPs).To(BeEmpty())
+			Expect(state.BANP).To(BeNil())
+			Expect(state.VerifyClusterState(true)).To(Succeed())
+		})
+
+		It("should only delete the admin policies it created when resetting the cluster", func() {
+			mock := kube.NewMockKubernetes(1.0)
+			managed := anp.DeepCopy()
+			managed.Name = "managed"
+			managed.Labels = map[string]string{ManagedByLabel: ManagedBy}
+			mock.AdminNetworkPolicies = []v1alpha1.AdminNetworkPolicy{*anp, *managed}
+			mock.BaselineNetworkPolicy = banp
+			state := &TestCaseState{Kubernetes: mock, Resources: &probe.Resources{Namespaces: map[string]map[string]string{}}}
+
+			Expect(state.VerifyClusterState(true)).ToNot(Succeed())
+			Expect(state.ResetClusterState(false)).To(Succeed())
+			Expect(mock.AdminNetworkPolicies).To(HaveLen(2))
+
+			Expect(state.ResetClusterState(true)).To(Succeed())
+			Expect(mock.AdminNetworkPolicies).To(HaveLen(1))
+			Expect(mock.AdminNetworkPolicies[0].Name).To(Equal(anp.Name))
+			Expect(mock.BaselineNetworkPolicy).To(Equal(banp))
+			Expect(state.VerifyClusterState(true)).To(Succeed())
+		})
+
+		It("should read the admin policies already in the cluster", func() {
+			mock := kube.NewMockKubernetes(1.0)
+			mock.AdminNetworkPolicies = []v1alpha1.AdminNetworkPolicy{*anp}
+			mock.BaselineNetworkPolicy = banp
+			state := &TestCaseState{Kubernetes: mock, Resources: &probe.Resources{Namespaces: map[string]map[string]string{}}}
+
+			Expect(state.ReadAdminPolicies()).To(Succeed())
+			Expect(state.ANPs).To(HaveLen(1))
+			Expect(state.ANPs[0].Name).To(Equal(anp.Name))
+			Expect(state.BANP).To(Equal(banp))
+			Expect(state.ReadAdminPolicies()).ToNot(Succeed())
+		})
+
+		It("should fail to read admin policies which the simulation can't build", func() {
+			mock := kube.NewMockKubernetes(1.0)
+			samePriority := anp.DeepCopy()
+			samePriority.Name = "same-priority"
+			mock.AdminNetworkPolicies = []v1alpha1.AdminNetworkPolicy{*anp, *samePriority}
+			state := &TestCaseState{Kubernetes: mock, Resources: &probe.Resources{Namespaces: map[string]map[string]string{}}}
+
+			err := state.ReadAdminPolicies()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("have the same priority 10"))
+		})
+	})
 }
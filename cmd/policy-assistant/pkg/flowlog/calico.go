@@ -0,0 +1,57 @@
+package flowlog
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// CalicoDecoder reads Calico flow logs, as exported in json by fluentd: one aggregated flow per line.
+// Flows whose source or destination IP was aggregated away (written as "-") are skipped.
+type CalicoDecoder struct{}
+
+func (c *CalicoDecoder) Name() string {
+	return "calico"
+}
+
+func (c *CalicoDecoder) Decode(line []byte) (*Flow, error) {
+	var flow struct {
+		SourceIP string          `json:"source_ip"`
+		DestIP   string          `json:"dest_ip"`
+		DestPort json.RawMessage `json:"dest_port"`
+		Proto    string          `json:"proto"`
+		Action   string          `json:"action"`
+	}
+	if err := json.Unmarshal(line, &flow); err != nil {
+		return nil, errors.Wrapf(err, "unable to unmarshal json")
+	}
+
+	var verdict Verdict
+	switch flow.Action {
+	case "allow":
+		verdict = VerdictAllowed
+	case "deny":
+		verdict = VerdictDenied
+	default:
+		return nil, nil
+	}
+	protocol, ok := parseProtocol(flow.Proto)
+	if !ok || flow.SourceIP == "" || flow.SourceIP == "-" || flow.DestIP == "" || flow.DestIP == "-" {
+		return nil, nil
+	}
+
+	// dest_port is a number, but may be null or "-" for aggregated flows
+	port, err := strconv.Atoi(string(flow.DestPort))
+	if err != nil {
+		return nil, nil
+	}
+
+	return &Flow{
+		SourceIP:        flow.SourceIP,
+		DestinationIP:   flow.DestIP,
+		DestinationPort: port,
+		Protocol:        protocol,
+		Verdict:         verdict,
+	}, nil
+}
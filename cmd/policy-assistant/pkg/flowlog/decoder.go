@@ -0,0 +1,60 @@
+package flowlog
+
+import (
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Decoder parses a single line of a flow log.
+// It returns nil, without an error, for lines which don't describe a policy verdict.
+type Decoder interface {
+	Name() string
+	Decode(line []byte) (*Flow, error)
+}
+
+var decoders = map[string]Decoder{}
+
+// RegisterDecoder makes a Decoder available by name.  It panics if the name is already taken.
+func RegisterDecoder(decoder Decoder) {
+	if _, ok := decoders[decoder.Name()]; ok {
+		panic(errors.Errorf("flow log decoder %s already registered", decoder.Name()))
+	}
+	decoders[decoder.Name()] = decoder
+}
+
+func GetDecoder(name string) (Decoder, error) {
+	decoder, ok := decoders[name]
+	if !ok {
+		return nil, errors.Errorf("unknown flow log format %s; supported formats are %s", name, strings.Join(DecoderNames(), ","))
+	}
+	return decoder, nil
+}
+
+func DecoderNames() []string {
+	return slice.Sort(maps.Keys(decoders))
+}
+
+func init() {
+	RegisterDecoder(&HubbleDecoder{})
+	RegisterDecoder(&CalicoDecoder{})
+	RegisterDecoder(&OVNDecoder{})
+}
+
+// parseProtocol accepts protocol names in any case, as well as IANA protocol numbers.
+// It returns false for protocols which network policies can't select, such as ICMP.
+func parseProtocol(protocol string) (v1.Protocol, bool) {
+	switch strings.ToLower(protocol) {
+	case "tcp", "6":
+		return v1.ProtocolTCP, true
+	case "udp", "17":
+		return v1.ProtocolUDP, true
+	case "sctp", "132":
+		return v1.ProtocolSCTP, true
+	default:
+		return "", false
+	}
+}
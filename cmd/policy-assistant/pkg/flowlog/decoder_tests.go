@@ -0,0 +1,93 @@
+package flowlog
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+)
+
+func RunDecoderTests() {
+	Describe("Hubble", func() {
+		decoder := &HubbleDecoder{}
+
+		It("should decode forwarded and policy-denied flows", func() {
+			forwarded := `{"flow":{"verdict":"FORWARDED","IP":{"source":"10.0.0.1","destination":"10.0.0.2","ipVersion":"IPv4"},"l4":{"TCP":{"source_port":40000,"destination_port":80,"flags":{"SYN":true}}},"is_reply":false},"node_name":"kind-worker"}`
+			Expect(decoder.Decode([]byte(forwarded))).To(Equal(&Flow{SourceIP: "10.0.0.1", DestinationIP: "10.0.0.2", DestinationPort: 80, Protocol: v1.ProtocolTCP, Verdict: VerdictAllowed}))
+
+			dropped := `{"verdict":"DROPPED","drop_reason_desc":"POLICY_DENIED","IP":{"source":"10.0.0.1","destination":"10.0.0.2"},"l4":{"UDP":{"source_port":40000,"destination_port":53}}}`
+			Expect(decoder.Decode([]byte(dropped))).To(Equal(&Flow{SourceIP: "10.0.0.1", DestinationIP: "10.0.0.2", DestinationPort: 53, Protocol: v1.ProtocolUDP, Verdict: VerdictDenied}))
+		})
+
+		It("should skip replies, non-policy drops, and non-initial TCP packets", func() {
+			for _, line := range []string{
+				`{"flow":{"verdict":"FORWARDED","IP":{"source":"10.0.0.2","destination":"10.0.0.1"},"l4":{"TCP":{"destination_port":40000,"flags":{"SYN":true,"ACK":true}}},"is_reply":true}}`,
+				`{"flow":{"verdict":"FORWARDED","IP":{"source":"10.0.0.1","destination":"10.0.0.2"},"l4":{"TCP":{"destination_port":80,"flags":{"ACK":true}}}}}`,
+				`{"flow":{"verdict":"DROPPED","drop_reason_desc":"CT_NO_MAP_FOUND","IP":{"source":"10.0.0.1","destination":"10.0.0.2"},"l4":{"TCP":{"destination_port":80}}}}`,
+				`{"flow":{"verdict":"FORWARDED","IP":{"source":"10.0.0.1","destination":"10.0.0.2"},"l4":{"ICMPv4":{"type":8}}}}`,
+			} {
+				Expect(decoder.Decode([]byte(line))).To(BeNil(), line)
+			}
+		})
+	})
+
+	Describe("Calico", func() {
+		decoder := &CalicoDecoder{}
+
+		It("should decode allowed and denied flows", func() {
+			allowed := `{"start_time":1700000000,"source_ip":"10.0.0.1","dest_ip":"10.0.0.2","dest_port":80,"proto":"tcp","action":"allow","reporter":"dst"}`
+			Expect(decoder.Decode([]byte(allowed))).To(Equal(&Flow{SourceIP: "10.0.0.1", DestinationIP: "10.0.0.2", DestinationPort: 80, Protocol: v1.ProtocolTCP, Verdict: VerdictAllowed}))
+
+			denied := `{"source_ip":"10.0.0.1","dest_ip":"10.0.0.2","dest_port":5353,"proto":"17","action":"deny","reporter":"src"}`
+			Expect(decoder.Decode([]byte(denied))).To(Equal(&Flow{SourceIP: "10.0.0.1", DestinationIP: "10.0.0.2", DestinationPort: 5353, Protocol: v1.ProtocolUDP, Verdict: VerdictDenied}))
+		})
+
+		It("should skip aggregated flows", func() {
+			Expect(decoder.Decode([]byte(`{"source_ip":"-","dest_ip":"10.0.0.2","dest_port":80,"proto":"tcp","action":"allow"}`))).To(BeNil())
+			Expect(decoder.Decode([]byte(`{"source_ip":"10.0.0.1","dest_ip":"10.0.0.2","dest_port":null,"proto":"tcp","action":"allow"}`))).To(BeNil())
+		})
+	})
+
+	Describe("OVN", func() {
+		decoder := &OVNDecoder{}
+
+		It("should decode ACL logs", func() {
+			dropped := `2024-01-01T00:00:00.000Z|00001|acl_log(ovn_pinctrl0)|INFO|name="NP:x:Ingress", verdict=drop, severity=alert, direction=to-lport: tcp,vlan_tci=0x0000,dl_src=0a:58:0a:f4:00:01,dl_dst=0a:58:0a:f4:00:02,nw_src=10.244.0.1,nw_dst=10.244.0.2,nw_tos=0,nw_ecn=0,nw_ttl=64,tp_src=40000,tp_dst=80,tcp_flags=syn`
+			Expect(decoder.Decode([]byte(dropped))).To(Equal(&Flow{SourceIP: "10.244.0.1", DestinationIP: "10.244.0.2", DestinationPort: 80, Protocol: v1.ProtocolTCP, Verdict: VerdictDenied}))
+
+			allowed := `2024-01-01T00:00:00.000Z|00002|acl_log(ovn_pinctrl0)|INFO|name="ANP:allow", verdict=allow, severity=info: udp6,vlan_tci=0x0000,ipv6_src=fd00::1,ipv6_dst=fd00::2,tp_src=40000,tp_dst=53`
+			Expect(decoder.Decode([]byte(allowed))).To(Equal(&Flow{SourceIP: "fd00::1", DestinationIP: "fd00::2", DestinationPort: 53, Protocol: v1.ProtocolUDP, Verdict: VerdictAllowed}))
+		})
+
+		It("should skip other log lines and reject unknown verdicts", func() {
+			Expect(decoder.Decode([]byte(`2024-01-01T00:00:00.000Z|00003|binding|INFO|Claiming lport x`))).To(BeNil())
+			_, err := decoder.Decode([]byte(`2024-01-01T00:00:00.000Z|00004|acl_log(ovn_pinctrl0)|INFO|name="x", verdict=maybe: tcp,nw_src=1.2.3.4,nw_dst=1.2.3.5,tp_dst=80`))
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Describe("Read", func() {
+		It("should count skipped lines and record line numbers", func() {
+			log := strings.Join([]string{
+				`{"source_ip":"10.0.0.1","dest_ip":"10.0.0.2","dest_port":80,"proto":"tcp","action":"allow"}`,
+				``,
+				`{"source_ip":"-","dest_ip":"10.0.0.2","dest_port":80,"proto":"tcp","action":"allow"}`,
+				`{"source_ip":"10.0.0.2","dest_ip":"10.0.0.1","dest_port":81,"proto":"tcp","action":"deny"}`,
+			}, "\n")
+			decoder, err := GetDecoder("calico")
+			Expect(err).To(BeNil())
+
+			flows, skipped, err := Read(strings.NewReader(log), decoder)
+			Expect(err).To(BeNil())
+			Expect(skipped).To(Equal(1))
+			Expect(flows).To(HaveLen(2))
+			Expect(flows[1].Line).To(Equal(4))
+		})
+
+		It("should report malformed lines", func() {
+			_, _, err := Read(strings.NewReader("not json"), &HubbleDecoder{})
+			Expect(err).ToNot(BeNil())
+		})
+	})
+}
@@ -0,0 +1,69 @@
+package flowlog
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+type Verdict string
+
+const (
+	VerdictAllowed Verdict = "Allowed"
+	VerdictDenied  Verdict = "Denied"
+)
+
+// Flow is a single connection observed by the dataplane, along with the verdict the dataplane enforced.
+type Flow struct {
+	SourceIP        string
+	DestinationIP   string
+	DestinationPort int
+	Protocol        v1.Protocol
+	Verdict         Verdict
+	// Line is the 1-based line number of the flow in the log
+	Line int
+}
+
+// maxLineBytes bounds the length of a single log line; Hubble flows with full metadata can be several KB.
+const maxLineBytes = 1024 * 1024
+
+// Read decodes one flow per line.  Lines which the decoder skips (e.g. reply packets,
+// or drops which weren't caused by policy) are counted but not returned.
+func Read(reader io.Reader, decoder Decoder) ([]*Flow, int, error) {
+	var flows []*Flow
+	skipped := 0
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		flow, err := decoder.Decode(line)
+		if err != nil {
+			return nil, 0, errors.WithMessagef(err, "unable to decode %s flow at line %d", decoder.Name(), lineNumber)
+		}
+		if flow == nil {
+			skipped++
+			continue
+		}
+		flow.Line = lineNumber
+		flows = append(flows, flow)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, errors.Wrapf(err, "unable to read %s flows", decoder.Name())
+	}
+	return flows, skipped, nil
+}
+
+func ReadFile(path string, decoder Decoder) ([]*Flow, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "unable to open file %s", path)
+	}
+	defer file.Close()
+	return Read(file, decoder)
+}
@@ -0,0 +1,92 @@
+package flowlog
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+// HubbleDecoder reads the json output of `hubble observe -o json`, or of the Hubble flow exporter.
+// Only flows which were forwarded, or dropped by policy, are returned.  Replies, and TCP packets other
+// than the initial SYN, are skipped so that each connection is counted once.
+type HubbleDecoder struct{}
+
+func (h *HubbleDecoder) Name() string {
+	return "hubble"
+}
+
+type hubbleFlow struct {
+	Verdict        string `json:"verdict"`
+	DropReasonDesc string `json:"drop_reason_desc"`
+	IsReply        *bool  `json:"is_reply"`
+	IP             *struct {
+		Source      string `json:"source"`
+		Destination string `json:"destination"`
+	} `json:"IP"`
+	L4 *struct {
+		TCP *struct {
+			DestinationPort int `json:"destination_port"`
+			Flags           *struct {
+				SYN bool `json:"SYN"`
+				ACK bool `json:"ACK"`
+			} `json:"flags"`
+		} `json:"TCP"`
+		UDP *struct {
+			DestinationPort int `json:"destination_port"`
+		} `json:"UDP"`
+		SCTP *struct {
+			DestinationPort int `json:"destination_port"`
+		} `json:"SCTP"`
+	} `json:"l4"`
+}
+
+func (h *HubbleDecoder) Decode(line []byte) (*Flow, error) {
+	var wrapper struct {
+		Flow *hubbleFlow `json:"flow"`
+	}
+	if err := json.Unmarshal(line, &wrapper); err != nil {
+		return nil, errors.Wrapf(err, "unable to unmarshal json")
+	}
+	flow := wrapper.Flow
+	if flow == nil {
+		// older versions of hubble print flows without the wrapper object
+		flow = &hubbleFlow{}
+		if err := json.Unmarshal(line, flow); err != nil {
+			return nil, errors.Wrapf(err, "unable to unmarshal json")
+		}
+	}
+
+	var verdict Verdict
+	switch flow.Verdict {
+	case "FORWARDED":
+		verdict = VerdictAllowed
+	case "DROPPED":
+		if flow.DropReasonDesc != "POLICY_DENIED" && flow.DropReasonDesc != "POLICY_DENY" {
+			return nil, nil
+		}
+		verdict = VerdictDenied
+	default:
+		return nil, nil
+	}
+	if flow.IP == nil || flow.L4 == nil || (flow.IsReply != nil && *flow.IsReply) {
+		return nil, nil
+	}
+
+	f := &Flow{SourceIP: flow.IP.Source, DestinationIP: flow.IP.Destination, Verdict: verdict}
+	switch {
+	case flow.L4.TCP != nil:
+		if flags := flow.L4.TCP.Flags; flags != nil && (!flags.SYN || flags.ACK) {
+			return nil, nil
+		}
+		f.Protocol, f.DestinationPort = v1.ProtocolTCP, flow.L4.TCP.DestinationPort
+	case flow.L4.UDP != nil:
+		f.Protocol, f.DestinationPort = v1.ProtocolUDP, flow.L4.UDP.DestinationPort
+	case flow.L4.SCTP != nil:
+		f.Protocol, f.DestinationPort = v1.ProtocolSCTP, flow.L4.SCTP.DestinationPort
+	default:
+		// e.g. ICMP, which policies can't select
+		return nil, nil
+	}
+	return f, nil
+}
@@ -0,0 +1,74 @@
+package flowlog
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// OVNDecoder reads OVN ACL logs, as written by ovn-controller when ACL logging is enabled, e.g.:
+//
+//	2024-01-01T00:00:00.000Z|00001|acl_log(ovn_pinctrl0)|INFO|name="NP:x:Ingress", verdict=drop, severity=alert, direction=to-lport: tcp,vlan_tci=0x0000,dl_src=0a:58:0a:f4:00:01,dl_dst=0a:58:0a:f4:00:02,nw_src=10.244.0.1,nw_dst=10.244.0.2,nw_tos=0,nw_ecn=0,nw_ttl=64,tp_src=40000,tp_dst=80,tcp_flags=syn
+//
+// Lines which aren't ACL logs are skipped.
+type OVNDecoder struct{}
+
+func (o *OVNDecoder) Name() string {
+	return "ovn"
+}
+
+func (o *OVNDecoder) Decode(line []byte) (*Flow, error) {
+	text := string(line)
+	if !strings.Contains(text, "|acl_log(") {
+		return nil, nil
+	}
+	meta, packet, ok := strings.Cut(text, ": ")
+	if !ok {
+		return nil, errors.Errorf("invalid ACL log: missing packet description")
+	}
+
+	fields := map[string]string{}
+	for _, field := range strings.Split(meta, ", ") {
+		if key, value, ok := strings.Cut(field, "="); ok {
+			fields[key] = value
+		}
+	}
+	var verdict Verdict
+	switch fields["verdict"] {
+	case "allow", "allow-related", "allow-stateless":
+		verdict = VerdictAllowed
+	case "drop", "reject":
+		verdict = VerdictDenied
+	default:
+		return nil, errors.Errorf("invalid ACL log: unknown verdict '%s'", fields["verdict"])
+	}
+
+	packetFields := strings.Split(strings.TrimSpace(packet), ",")
+	protocol, ok := parseProtocol(strings.TrimSuffix(packetFields[0], "6"))
+	if !ok {
+		return nil, nil
+	}
+	for _, field := range packetFields[1:] {
+		if key, value, ok := strings.Cut(field, "="); ok {
+			fields[key] = value
+		}
+	}
+
+	source, destination := fields["nw_src"], fields["nw_dst"]
+	if source == "" {
+		source, destination = fields["ipv6_src"], fields["ipv6_dst"]
+	}
+	port, err := strconv.Atoi(fields["tp_dst"])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid ACL log: unable to parse tp_dst")
+	}
+
+	return &Flow{
+		SourceIP:        source,
+		DestinationIP:   destination,
+		DestinationPort: port,
+		Protocol:        protocol,
+		Verdict:         verdict,
+	}, nil
+}
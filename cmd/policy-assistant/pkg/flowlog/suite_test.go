@@ -0,0 +1,14 @@
+package flowlog
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFlowLog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunDecoderTests()
+	RunSpecs(t, "flow log suite")
+}
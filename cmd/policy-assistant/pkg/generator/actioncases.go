@@ -3,6 +3,7 @@ package generator
 import (
 	. "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
 )
 
 func (t *TestCaseGenerator) ActionTestCases() []*TestCase {
@@ -80,3 +81,53 @@ func (t *TestCaseGenerator) ActionTestCases() []*TestCase {
 		},
 	}
 }
+
+// AdminActionTestCases require the ANP and BANP CRDs to be installed in the cluster.
+func (t *TestCaseGenerator) AdminActionTestCases() []*TestCase {
+	return []*TestCase{
+		{
+			Description: "Create/delete admin policy",
+			Tags:        NewStringSet(TagANP, TagCreateAdminPolicy, TagDeleteAdminPolicy),
+			Steps: []*TestStep{
+				NewTestStep(ProbeAllAvailable, CreateAdminPolicy(baseTestAdminPolicy())),
+				NewTestStep(ProbeAllAvailable, DeleteAdminPolicy(baseTestAdminPolicy().Name)),
+			},
+		},
+		{
+			Description: "Create/update admin policy",
+			Tags:        NewStringSet(TagANP, TagCreateAdminPolicy, TagUpdateAdminPolicy),
+			Steps: []*TestStep{
+				NewTestStep(ProbeAllAvailable, CreateAdminPolicy(baseTestAdminPolicy())),
+				NewTestStep(ProbeAllAvailable, UpdateAdminPolicy(setAdminPolicyAction(baseTestAdminPolicy(), v1alpha1.AdminNetworkPolicyRuleActionAllow))),
+				NewTestStep(ProbeAllAvailable, UpdateAdminPolicy(setAdminPolicyAction(baseTestAdminPolicy(), v1alpha1.AdminNetworkPolicyRuleActionPass))),
+			},
+		},
+		{
+			Description: "Create/delete baseline admin policy",
+			Tags:        NewStringSet(TagBANP, TagCreateBaselineAdminPolicy, TagDeleteBaselineAdminPolicy),
+			Steps: []*TestStep{
+				NewTestStep(ProbeAllAvailable, CreateBaselineAdminPolicy(baseTestBaselineAdminPolicy())),
+				NewTestStep(ProbeAllAvailable, DeleteBaselineAdminPolicy(BaselineAdminPolicyName)),
+			},
+		},
+		{
+			Description: "Create/update baseline admin policy",
+			Tags:        NewStringSet(TagBANP, TagCreateBaselineAdminPolicy, TagUpdateBaselineAdminPolicy),
+			Steps: []*TestStep{
+				NewTestStep(ProbeAllAvailable, CreateBaselineAdminPolicy(baseTestBaselineAdminPolicy())),
+				NewTestStep(ProbeAllAvailable, UpdateBaselineAdminPolicy(setBaselineAdminPolicyAction(baseTestBaselineAdminPolicy(), v1alpha1.BaselineAdminNetworkPolicyRuleActionAllow))),
+			},
+		},
+		{
+			Description: "Admin policy passes to network policy, which is overridden by baseline admin policy once deleted",
+			Tags:        NewStringSet(TagANP, TagBANP, TagCreatePolicy, TagDeletePolicy, TagCreateAdminPolicy, TagCreateBaselineAdminPolicy),
+			Steps: []*TestStep{
+				NewTestStep(ProbeAllAvailable,
+					CreateBaselineAdminPolicy(setBaselineAdminPolicyAction(baseTestBaselineAdminPolicy(), v1alpha1.BaselineAdminNetworkPolicyRuleActionAllow)),
+					CreateAdminPolicy(setAdminPolicyAction(baseTestAdminPolicy(), v1alpha1.AdminNetworkPolicyRuleActionPass))),
+				NewTestStep(ProbeAllAvailable, CreatePolicy(baseTestPolicy().NetworkPolicy())),
+				NewTestStep(ProbeAllAvailable, DeletePolicy(baseTestPolicy().Target.Namespace, baseTestPolicy().Name)),
+			},
+		},
+	}
+}
@@ -0,0 +1,213 @@
+package generator
+
+import (
+	. "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+/*
+Admin tier semantics, for traffic to (ingress) or from (egress) pod x/a:
+ - tier ordering: ANP > NetworkPolicy > BANP
+ - priority: lower number wins; ANPs with non-overlapping rules all apply, whatever their priority
+ - Pass: skips remaining ANPs, delegating to NetworkPolicy, and then to BANP
+ - rule ordering: within a policy, the first matching rule wins
+ - peers: SameLabels and NotSameLabels
+ - ports: numbered, named, and ranges
+
+ANPs with equal priorities are undefined in the v1alpha1 API (and rejected by the simulator), and
+networks and nodes peers aren't part of it, so they aren't covered.
+*/
+
+const (
+	anpAllow = v1alpha1.AdminNetworkPolicyRuleActionAllow
+	anpDeny  = v1alpha1.AdminNetworkPolicyRuleActionDeny
+	anpPass  = v1alpha1.AdminNetworkPolicyRuleActionPass
+)
+
+func directionTag(isIngress bool) string {
+	if isIngress {
+		return TagIngress
+	}
+	return TagEgress
+}
+
+// adminTestNetpol builds a NetworkPolicy for pod x/a in one direction.
+func adminTestNetpol(isIngress bool, rules []*Rule) *NetworkPolicy {
+	netpol := &Netpol{
+		Name:   "base",
+		Target: NewNetpolTarget("x", map[string]string{"pod": "a"}, nil),
+	}
+	if isIngress {
+		netpol.Ingress = &NetpolPeers{Rules: rules}
+	} else {
+		netpol.Egress = &NetpolPeers{Rules: rules}
+	}
+	return netpol.NetworkPolicy()
+}
+
+func allowFromNamespaceYRule() *Rule {
+	return &Rule{Peers: []NetworkPolicyPeer{{NamespaceSelector: nsYMatchLabelsSelector}}}
+}
+
+func (t *TestCaseGenerator) AdminTestCases() []*TestCase {
+	return flatten(
+		t.TierOrderingTestCases(),
+		t.PriorityTestCases(),
+		t.PassTestCases(),
+		t.AdminRuleOrderingTestCases(),
+		t.AdminPeersTestCases(),
+		t.AdminPortTestCases())
+}
+
+func (t *TestCaseGenerator) TierOrderingTestCases() []*TestCase {
+	var cases []*TestCase
+	for _, isIngress := range []bool{true, false} {
+		dir := directionTag(isIngress)
+		cases = append(cases,
+			NewSingleStepTestCase(dir+": ANP deny overrides NetworkPolicy allow",
+				NewStringSet(TagANP, TagTierOrdering, dir),
+				ProbeAllAvailable,
+				CreatePolicy(adminTestNetpol(isIngress, AllowAllRules)),
+				CreateAdminPolicy(BuildAdminPolicy("deny-all", 10, isIngress, NewAdminRule("deny-all", anpDeny, nil, allNamespacesAdminPeer)))),
+			NewSingleStepTestCase(dir+": ANP allow overrides NetworkPolicy deny",
+				NewStringSet(TagANP, TagTierOrdering, dir),
+				ProbeAllAvailable,
+				CreatePolicy(adminTestNetpol(isIngress, DenyAllRules)),
+				CreateAdminPolicy(BuildAdminPolicy("allow-all", 10, isIngress, NewAdminRule("allow-all", anpAllow, nil, allNamespacesAdminPeer)))),
+			NewTestCase(dir+": NetworkPolicy overrides BANP",
+				NewStringSet(TagBANP, TagTierOrdering, dir, TagCreatePolicy, TagDeletePolicy),
+				NewTestStep(ProbeAllAvailable,
+					CreateBaselineAdminPolicy(BuildBaselineAdminPolicy(isIngress, NewAdminRule("deny-all", anpDeny, nil, allNamespacesAdminPeer)))),
+				NewTestStep(ProbeAllAvailable,
+					CreatePolicy(adminTestNetpol(isIngress, []*Rule{allowFromNamespaceYRule()}))),
+				NewTestStep(ProbeAllAvailable,
+					DeletePolicy("x", "base"))),
+			NewSingleStepTestCase(dir+": ANP overrides BANP",
+				NewStringSet(TagANP, TagBANP, TagTierOrdering, dir),
+				ProbeAllAvailable,
+				CreateBaselineAdminPolicy(BuildBaselineAdminPolicy(isIngress, NewAdminRule("deny-all", anpDeny, nil, allNamespacesAdminPeer))),
+				CreateAdminPolicy(BuildAdminPolicy("allow-y", 10, isIngress, NewAdminRule("allow-y", anpAllow, nil, nsYAdminPeer)))),
+		)
+	}
+	return cases
+}
+
+func (t *TestCaseGenerator) PriorityTestCases() []*TestCase {
+	var cases []*TestCase
+	for _, isIngress := range []bool{true, false} {
+		dir := directionTag(isIngress)
+		allow := func(priority int32) *v1alpha1.AdminNetworkPolicy {
+			return BuildAdminPolicy("allow-y", priority, isIngress, NewAdminRule("allow-y", anpAllow, nil, nsYAdminPeer))
+		}
+		deny := func(priority int32) *v1alpha1.AdminNetworkPolicy {
+			return BuildAdminPolicy("deny-all", priority, isIngress, NewAdminRule("deny-all", anpDeny, nil, allNamespacesAdminPeer))
+		}
+		cases = append(cases,
+			NewTestCase(dir+": lower priority number wins",
+				NewStringSet(TagANP, TagPriority, dir, TagUpdateAdminPolicy),
+				NewTestStep(ProbeAllAvailable, CreateAdminPolicy(allow(10)), CreateAdminPolicy(deny(20))),
+				NewTestStep(ProbeAllAvailable, UpdateAdminPolicy(allow(30))),
+				NewTestStep(ProbeAllAvailable, UpdateAdminPolicy(deny(40)))),
+			NewSingleStepTestCase(dir+": ANPs with non-overlapping rules all apply",
+				NewStringSet(TagANP, TagPriority, dir),
+				ProbeAllAvailable,
+				CreateAdminPolicy(BuildAdminPolicy("deny-80-tcp", 10, isIngress,
+					NewAdminRule("deny-80-tcp", anpDeny, []v1alpha1.AdminNetworkPolicyPort{AdminPortNumber(tcp, 80)}, allNamespacesAdminPeer))),
+				CreateAdminPolicy(BuildAdminPolicy("deny-81-udp", 11, isIngress,
+					NewAdminRule("deny-81-udp", anpDeny, []v1alpha1.AdminNetworkPolicyPort{AdminPortNumber(udp, 81)}, allNamespacesAdminPeer)))),
+		)
+	}
+	return cases
+}
+
+func (t *TestCaseGenerator) PassTestCases() []*TestCase {
+	var cases []*TestCase
+	for _, isIngress := range []bool{true, false} {
+		dir := directionTag(isIngress)
+		pass := BuildAdminPolicy("pass-all", 10, isIngress, NewAdminRule("pass-all", anpPass, nil, allNamespacesAdminPeer))
+		cases = append(cases,
+			NewTestCase(dir+": Pass delegates to NetworkPolicy, then to BANP",
+				NewStringSet(TagANP, TagBANP, TagPass, dir, TagCreatePolicy, TagDeletePolicy),
+				NewTestStep(ProbeAllAvailable,
+					CreateAdminPolicy(pass),
+					CreateBaselineAdminPolicy(BuildBaselineAdminPolicy(isIngress, NewAdminRule("deny-all", anpDeny, nil, allNamespacesAdminPeer)))),
+				NewTestStep(ProbeAllAvailable,
+					CreatePolicy(adminTestNetpol(isIngress, []*Rule{allowFromNamespaceYRule()}))),
+				NewTestStep(ProbeAllAvailable,
+					DeletePolicy("x", "base"))),
+			NewSingleStepTestCase(dir+": Pass skips lower priority ANPs",
+				NewStringSet(TagANP, TagPass, TagPriority, dir),
+				ProbeAllAvailable,
+				CreateAdminPolicy(pass),
+				CreateAdminPolicy(BuildAdminPolicy("deny-all", 20, isIngress, NewAdminRule("deny-all", anpDeny, nil, allNamespacesAdminPeer)))),
+			NewSingleStepTestCase(dir+": Pass without NetworkPolicy or BANP allows",
+				NewStringSet(TagANP, TagPass, dir),
+				ProbeAllAvailable,
+				CreateAdminPolicy(pass)),
+		)
+	}
+	return cases
+}
+
+func (t *TestCaseGenerator) AdminRuleOrderingTestCases() []*TestCase {
+	var cases []*TestCase
+	for _, isIngress := range []bool{true, false} {
+		dir := directionTag(isIngress)
+		allow80 := NewAdminRule("allow-80-tcp", anpAllow, []v1alpha1.AdminNetworkPolicyPort{AdminPortNumber(tcp, 80)}, allNamespacesAdminPeer)
+		denyAll := NewAdminRule("deny-all", anpDeny, nil, allNamespacesAdminPeer)
+		cases = append(cases,
+			NewTestCase(dir+": first matching rule wins",
+				NewStringSet(TagANP, TagRuleOrdering, dir, TagUpdateAdminPolicy),
+				NewTestStep(ProbeAllAvailable, CreateAdminPolicy(BuildAdminPolicy("ordered", 10, isIngress, allow80, denyAll))),
+				NewTestStep(ProbeAllAvailable, UpdateAdminPolicy(BuildAdminPolicy("ordered", 10, isIngress, denyAll, allow80)))),
+			NewTestCase(dir+": BANP first matching rule wins",
+				NewStringSet(TagBANP, TagRuleOrdering, dir, TagUpdateBaselineAdminPolicy),
+				NewTestStep(ProbeAllAvailable, CreateBaselineAdminPolicy(BuildBaselineAdminPolicy(isIngress, allow80, denyAll))),
+				NewTestStep(ProbeAllAvailable, UpdateBaselineAdminPolicy(BuildBaselineAdminPolicy(isIngress, denyAll, allow80)))),
+		)
+	}
+	return cases
+}
+
+func (t *TestCaseGenerator) AdminPeersTestCases() []*TestCase {
+	var cases []*TestCase
+	for _, isIngress := range []bool{true, false} {
+		dir := directionTag(isIngress)
+		cases = append(cases,
+			NewSingleStepTestCase(dir+": deny namespaces with the same labels",
+				NewStringSet(TagANP, TagSameLabels, dir),
+				ProbeAllAvailable,
+				CreateAdminPolicy(BuildAdminPolicy("same-labels", 10, isIngress, NewAdminRule("deny-same-ns", anpDeny, nil, AdminSameLabelsPeer("ns"))))),
+			NewSingleStepTestCase(dir+": deny namespaces with different labels",
+				NewStringSet(TagANP, TagNotSameLabels, dir),
+				ProbeAllAvailable,
+				CreateAdminPolicy(BuildAdminPolicy("not-same-labels", 10, isIngress, NewAdminRule("deny-other-ns", anpDeny, nil, AdminNotSameLabelsPeer("ns"))))),
+			NewSingleStepTestCase(dir+": deny pods by label in all namespaces",
+				NewStringSet(TagANP, TagAdminPodPeer, dir),
+				ProbeAllAvailable,
+				CreateAdminPolicy(BuildAdminPolicy("pods", 10, isIngress, NewAdminRule("deny-pods-bc", anpDeny, nil, AdminPodsPeer(emptySelector, podBCMatchExpressionsSelector))))),
+		)
+	}
+	return cases
+}
+
+func (t *TestCaseGenerator) AdminPortTestCases() []*TestCase {
+	var cases []*TestCase
+	for _, isIngress := range []bool{true, false} {
+		dir := directionTag(isIngress)
+		deny := func(name string, tag string, port v1alpha1.AdminNetworkPolicyPort) *TestCase {
+			return NewSingleStepTestCase(dir+": deny "+name,
+				NewStringSet(TagANP, tag, dir),
+				ProbeAllAvailable,
+				CreateAdminPolicy(BuildAdminPolicy("deny-"+name, 10, isIngress, NewAdminRule("deny-"+name, anpDeny, []v1alpha1.AdminNetworkPolicyPort{port}, allNamespacesAdminPeer))))
+		}
+		cases = append(cases,
+			deny("81-sctp", TagAdminNumberedPort, AdminPortNumber(sctp, 81)),
+			deny("serve-80-tcp", TagAdminNamedPort, AdminNamedPort("serve-80-tcp")),
+			deny("serve-81-udp", TagAdminNamedPort, AdminNamedPort("serve-81-udp")),
+			deny("80-81-tcp", TagAdminPortRange, AdminPortRange(tcp, 80, 81)),
+			deny("81-82-udp", TagAdminPortRange, AdminPortRange(udp, 81, 82)),
+		)
+	}
+	return cases
+}
@@ -0,0 +1,174 @@
+package generator
+
+import (
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+// BaselineAdminPolicyName is the only name a BANP may have.
+const BaselineAdminPolicyName = "default"
+
+// AdminRule is a single ANP or BANP rule, without the From/To dance.  BANP rules can't use the Pass action.
+type AdminRule struct {
+	Name   string
+	Action v1alpha1.AdminNetworkPolicyRuleAction
+	Ports  *[]v1alpha1.AdminNetworkPolicyPort
+	Peers  []v1alpha1.AdminNetworkPolicyPeer
+}
+
+func NewAdminRule(name string, action v1alpha1.AdminNetworkPolicyRuleAction, ports []v1alpha1.AdminNetworkPolicyPort, peers ...v1alpha1.AdminNetworkPolicyPeer) *AdminRule {
+	rule := &AdminRule{Name: name, Action: action, Peers: peers}
+	if len(ports) > 0 {
+		rule.Ports = &ports
+	}
+	return rule
+}
+
+func (r *AdminRule) baselineAction() v1alpha1.BaselineAdminNetworkPolicyRuleAction {
+	switch r.Action {
+	case v1alpha1.AdminNetworkPolicyRuleActionAllow:
+		return v1alpha1.BaselineAdminNetworkPolicyRuleActionAllow
+	case v1alpha1.AdminNetworkPolicyRuleActionDeny:
+		return v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny
+	}
+	panic(errors.Errorf("invalid BANP rule action %s", r.Action))
+}
+
+// adminTestSubject selects pod x/a, the same pod as baseTestPolicy.
+func adminTestSubject() v1alpha1.AdminNetworkPolicySubject {
+	return v1alpha1.AdminNetworkPolicySubject{
+		Pods: &v1alpha1.NamespacedPodSubject{
+			NamespaceSelector: *nsXMatchLabelsSelector,
+			PodSelector:       *podAMatchLabelsSelector,
+		},
+	}
+}
+
+// BuildAdminPolicy builds an ANP with the rules in one direction, whose subject is pod x/a.
+func BuildAdminPolicy(name string, priority int32, isIngress bool, rules ...*AdminRule) *v1alpha1.AdminNetworkPolicy {
+	policy := &v1alpha1.AdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1alpha1.AdminNetworkPolicySpec{
+			Priority: priority,
+			Subject:  adminTestSubject(),
+		},
+	}
+	for _, rule := range rules {
+		if isIngress {
+			policy.Spec.Ingress = append(policy.Spec.Ingress, v1alpha1.AdminNetworkPolicyIngressRule{
+				Name:   rule.Name,
+				Action: rule.Action,
+				From:   rule.Peers,
+				Ports:  rule.Ports,
+			})
+		} else {
+			policy.Spec.Egress = append(policy.Spec.Egress, v1alpha1.AdminNetworkPolicyEgressRule{
+				Name:   rule.Name,
+				Action: rule.Action,
+				To:     rule.Peers,
+				Ports:  rule.Ports,
+			})
+		}
+	}
+	return policy
+}
+
+// BuildBaselineAdminPolicy builds a BANP with the rules in one direction, whose subject is pod x/a.
+func BuildBaselineAdminPolicy(isIngress bool, rules ...*AdminRule) *v1alpha1.BaselineAdminNetworkPolicy {
+	policy := &v1alpha1.BaselineAdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: BaselineAdminPolicyName},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: adminTestSubject(),
+		},
+	}
+	for _, rule := range rules {
+		if isIngress {
+			policy.Spec.Ingress = append(policy.Spec.Ingress, v1alpha1.BaselineAdminNetworkPolicyIngressRule{
+				Name:   rule.Name,
+				Action: rule.baselineAction(),
+				From:   rule.Peers,
+				Ports:  rule.Ports,
+			})
+		} else {
+			policy.Spec.Egress = append(policy.Spec.Egress, v1alpha1.BaselineAdminNetworkPolicyEgressRule{
+				Name:   rule.Name,
+				Action: rule.baselineAction(),
+				To:     rule.Peers,
+				Ports:  rule.Ports,
+			})
+		}
+	}
+	return policy
+}
+
+func AdminNamespacesPeer(selector *metav1.LabelSelector) v1alpha1.AdminNetworkPolicyPeer {
+	return v1alpha1.AdminNetworkPolicyPeer{Namespaces: &v1alpha1.NamespacedPeer{NamespaceSelector: selector}}
+}
+
+func AdminPodsPeer(namespaceSelector *metav1.LabelSelector, podSelector *metav1.LabelSelector) v1alpha1.AdminNetworkPolicyPeer {
+	return v1alpha1.AdminNetworkPolicyPeer{Pods: &v1alpha1.NamespacedPodPeer{
+		Namespaces:  v1alpha1.NamespacedPeer{NamespaceSelector: namespaceSelector},
+		PodSelector: *podSelector,
+	}}
+}
+
+func AdminSameLabelsPeer(keys ...string) v1alpha1.AdminNetworkPolicyPeer {
+	return v1alpha1.AdminNetworkPolicyPeer{Namespaces: &v1alpha1.NamespacedPeer{SameLabels: keys}}
+}
+
+func AdminNotSameLabelsPeer(keys ...string) v1alpha1.AdminNetworkPolicyPeer {
+	return v1alpha1.AdminNetworkPolicyPeer{Namespaces: &v1alpha1.NamespacedPeer{NotSameLabels: keys}}
+}
+
+func AdminPortNumber(protocol v1.Protocol, port int32) v1alpha1.AdminNetworkPolicyPort {
+	return v1alpha1.AdminNetworkPolicyPort{PortNumber: &v1alpha1.Port{Protocol: protocol, Port: port}}
+}
+
+func AdminNamedPort(name string) v1alpha1.AdminNetworkPolicyPort {
+	return v1alpha1.AdminNetworkPolicyPort{NamedPort: &name}
+}
+
+func AdminPortRange(protocol v1.Protocol, start int32, end int32) v1alpha1.AdminNetworkPolicyPort {
+	return v1alpha1.AdminNetworkPolicyPort{PortRange: &v1alpha1.PortRange{Protocol: protocol, Start: start, End: end}}
+}
+
+var (
+	allNamespacesAdminPeer = AdminNamespacesPeer(emptySelector)
+	nsYAdminPeer           = AdminNamespacesPeer(nsYMatchLabelsSelector)
+)
+
+func baseTestAdminPolicy() *v1alpha1.AdminNetworkPolicy {
+	return BuildAdminPolicy("base-admin", 10, true,
+		NewAdminRule("deny-from-y", v1alpha1.AdminNetworkPolicyRuleActionDeny, []v1alpha1.AdminNetworkPolicyPort{AdminPortNumber(tcp, 80)}, nsYAdminPeer))
+}
+
+func baseTestBaselineAdminPolicy() *v1alpha1.BaselineAdminNetworkPolicy {
+	return BuildBaselineAdminPolicy(true,
+		NewAdminRule("deny-all", v1alpha1.AdminNetworkPolicyRuleActionDeny, nil, allNamespacesAdminPeer))
+}
+
+// setAdminPolicyAction returns a copy of the policy with the action of every rule replaced.
+func setAdminPolicyAction(policy *v1alpha1.AdminNetworkPolicy, action v1alpha1.AdminNetworkPolicyRuleAction) *v1alpha1.AdminNetworkPolicy {
+	updated := policy.DeepCopy()
+	for i := range updated.Spec.Ingress {
+		updated.Spec.Ingress[i].Action = action
+	}
+	for i := range updated.Spec.Egress {
+		updated.Spec.Egress[i].Action = action
+	}
+	return updated
+}
+
+// setBaselineAdminPolicyAction returns a copy of the policy with the action of every rule replaced.
+func setBaselineAdminPolicyAction(policy *v1alpha1.BaselineAdminNetworkPolicy, action v1alpha1.BaselineAdminNetworkPolicyRuleAction) *v1alpha1.BaselineAdminNetworkPolicy {
+	updated := policy.DeepCopy()
+	for i := range updated.Spec.Ingress {
+		updated.Spec.Ingress[i].Action = action
+	}
+	for i := range updated.Spec.Egress {
+		updated.Spec.Egress[i].Action = action
+	}
+	return updated
+}
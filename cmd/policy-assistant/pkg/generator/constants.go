@@ -101,6 +101,7 @@ var (
 	}
 
 	nsXMatchLabelsSelector       = &metav1.LabelSelector{MatchLabels: map[string]string{"ns": "x"}}
+	nsYMatchLabelsSelector       = &metav1.LabelSelector{MatchLabels: map[string]string{"ns": "y"}}
 	nsXYMatchExpressionsSelector = &metav1.LabelSelector{
 		MatchExpressions: []metav1.LabelSelectorRequirement{
 			{
@@ -11,6 +11,14 @@ const (
 	ActionFeatureUpdatePolicy = "action: update policy"
 	ActionFeatureDeletePolicy = "action: delete policy"
 
+	ActionFeatureCreateAdminPolicy = "action: create admin policy"
+	ActionFeatureUpdateAdminPolicy = "action: update admin policy"
+	ActionFeatureDeleteAdminPolicy = "action: delete admin policy"
+
+	ActionFeatureCreateBaselineAdminPolicy = "action: create baseline admin policy"
+	ActionFeatureUpdateBaselineAdminPolicy = "action: update baseline admin policy"
+	ActionFeatureDeleteBaselineAdminPolicy = "action: delete baseline admin policy"
+
 	ActionFeatureCreateNamespace    = "action: create namespace"
 	ActionFeatureSetNamespaceLabels = "action: set namespace labels"
 	ActionFeatureDeleteNamespace    = "action: delete namespace"
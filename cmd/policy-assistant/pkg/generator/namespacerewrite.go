@@ -0,0 +1,172 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+)
+
+// namespaceLabelKeys are the labels whose values are namespace names: the label set on every namespace
+// by policy-assistant, and the label set by kubernetes.
+var namespaceLabelKeys = map[string]bool{
+	"ns":                       true,
+	kube.DefaultNamespaceLabel: true,
+}
+
+// WorkerNamespaces returns the namespaces of a parallel worker: worker 0 uses the given namespaces, and
+// every other worker adds a suffix, so that worker 2 uses x-w2, y-w2, and so on.
+func WorkerNamespaces(namespaces []string, worker int) []string {
+	if worker == 0 {
+		return namespaces
+	}
+	var workerNamespaces []string
+	for _, ns := range namespaces {
+		workerNamespaces = append(workerNamespaces, fmt.Sprintf("%s-w%d", ns, worker))
+	}
+	return workerNamespaces
+}
+
+// NamespaceMapping maps each namespace to the namespace at the same index in the replacements.
+func NamespaceMapping(namespaces []string, replacements []string) map[string]string {
+	mapping := map[string]string{}
+	for i, ns := range namespaces {
+		mapping[ns] = replacements[i]
+	}
+	return mapping
+}
+
+// UsesAdminPolicies is true if any action of the test case creates, updates or deletes an ANP or BANP.
+func (t *TestCase) UsesAdminPolicies() bool {
+	for _, step := range t.Steps {
+		for _, action := range step.Actions {
+			if action.CreateAdminPolicy != nil || action.UpdateAdminPolicy != nil || action.DeleteAdminPolicy != nil ||
+				action.CreateBaselineAdminPolicy != nil || action.UpdateBaselineAdminPolicy != nil || action.DeleteBaselineAdminPolicy != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AnyUsesAdminPolicies returns whether any of the test cases creates, updates or deletes admin policies.
+func AnyUsesAdminPolicies(testCases []*TestCase) bool {
+	for _, testCase := range testCases {
+		if testCase.UsesAdminPolicies() {
+			return true
+		}
+	}
+	return false
+}
+
+// RewriteNamespaces copies a test case, renaming namespaces according to the mapping: in actions, in the
+// namespaces of network policies, in the values of namespace name labels -- both in labels set by actions
+// and in network policy selectors -- and in expected traffic.  Namespaces not in the mapping are unchanged.
+// Admin policies are cluster-scoped and are copied as is; test cases using them can't be isolated by
+// namespace, and should only run against the namespaces they were written for.
+func RewriteNamespaces(testCase *TestCase, mapping map[string]string) (*TestCase, error) {
+	bs, err := json.Marshal(NewTestCaseDocument(testCase))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to copy test case '%s'", testCase.Description)
+	}
+	document := &TestCaseDocument{}
+	if err := json.Unmarshal(bs, document); err != nil {
+		return nil, errors.Wrapf(err, "unable to copy test case '%s'", testCase.Description)
+	}
+	rewritten := document.TestCase
+
+	rename := func(ns string) string {
+		if renamed, ok := mapping[ns]; ok {
+			return renamed
+		}
+		return ns
+	}
+	for _, step := range rewritten.Steps {
+		for _, action := range step.Actions {
+			rewriteActionNamespaces(action, rename)
+		}
+		if step.Expected != nil {
+			for _, exception := range step.Expected.Exceptions {
+				exception.From = rewritePodKey(exception.From, rename)
+				exception.To = rewritePodKey(exception.To, rename)
+			}
+		}
+	}
+	return rewritten, nil
+}
+
+func rewriteActionNamespaces(action *Action, rename func(string) string) {
+	if action.CreatePolicy != nil {
+		rewritePolicyNamespaces(action.CreatePolicy.Policy, rename)
+	} else if action.UpdatePolicy != nil {
+		rewritePolicyNamespaces(action.UpdatePolicy.Policy, rename)
+	} else if action.DeletePolicy != nil {
+		action.DeletePolicy.Namespace = rename(action.DeletePolicy.Namespace)
+	} else if action.CreateNamespace != nil {
+		action.CreateNamespace.Namespace = rename(action.CreateNamespace.Namespace)
+		rewriteLabelValues(action.CreateNamespace.Labels, rename)
+	} else if action.SetNamespaceLabels != nil {
+		action.SetNamespaceLabels.Namespace = rename(action.SetNamespaceLabels.Namespace)
+		rewriteLabelValues(action.SetNamespaceLabels.Labels, rename)
+	} else if action.DeleteNamespace != nil {
+		action.DeleteNamespace.Namespace = rename(action.DeleteNamespace.Namespace)
+	} else if action.ReadNetworkPolicies != nil {
+		for i, ns := range action.ReadNetworkPolicies.Namespaces {
+			action.ReadNetworkPolicies.Namespaces[i] = rename(ns)
+		}
+	} else if action.CreatePod != nil {
+		action.CreatePod.Namespace = rename(action.CreatePod.Namespace)
+	} else if action.SetPodLabels != nil {
+		action.SetPodLabels.Namespace = rename(action.SetPodLabels.Namespace)
+	} else if action.DeletePod != nil {
+		action.DeletePod.Namespace = rename(action.DeletePod.Namespace)
+	}
+}
+
+func rewritePolicyNamespaces(policy *networkingv1.NetworkPolicy, rename func(string) string) {
+	policy.Namespace = rename(policy.Namespace)
+	for _, rule := range policy.Spec.Ingress {
+		for _, peer := range rule.From {
+			rewriteSelectorNamespaces(peer.NamespaceSelector, rename)
+		}
+	}
+	for _, rule := range policy.Spec.Egress {
+		for _, peer := range rule.To {
+			rewriteSelectorNamespaces(peer.NamespaceSelector, rename)
+		}
+	}
+}
+
+func rewriteSelectorNamespaces(selector *metav1.LabelSelector, rename func(string) string) {
+	if selector == nil {
+		return
+	}
+	rewriteLabelValues(selector.MatchLabels, rename)
+	for _, expression := range selector.MatchExpressions {
+		if namespaceLabelKeys[expression.Key] {
+			for i, value := range expression.Values {
+				expression.Values[i] = rename(value)
+			}
+		}
+	}
+}
+
+func rewriteLabelValues(labels map[string]string, rename func(string) string) {
+	for key, value := range labels {
+		if namespaceLabelKeys[key] {
+			labels[key] = rename(value)
+		}
+	}
+}
+
+func rewritePodKey(key string, rename func(string) string) string {
+	ns, pod, found := strings.Cut(key, "/")
+	if !found {
+		return key
+	}
+	return rename(ns) + "/" + pod
+}
@@ -0,0 +1,83 @@
+package generator
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func RunNamespaceRewriteTests() {
+	Describe("RewriteNamespaces", func() {
+		namespaces := []string{"x", "y", "z"}
+		mapping := NamespaceMapping(namespaces, WorkerNamespaces(namespaces, 1))
+
+		It("should suffix the namespaces of workers other than the first", func() {
+			Expect(WorkerNamespaces(namespaces, 0)).To(Equal(namespaces))
+			Expect(WorkerNamespaces(namespaces, 2)).To(Equal([]string{"x-w2", "y-w2", "z-w2"}))
+		})
+
+		It("should rewrite actions and expected traffic, without modifying the original", func() {
+			testCases, err := ParseTestCases([]byte(scenarioYaml))
+			Expect(err).ToNot(HaveOccurred())
+			original, err := MarshalTestCase(testCases[0])
+			Expect(err).ToNot(HaveOccurred())
+
+			rewritten, err := RewriteNamespaces(testCases[0], mapping)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rewritten.Steps[0].Actions[0].CreatePolicy.Policy.Namespace).To(Equal("x-w1"))
+			Expect(rewritten.Steps[0].Expected.Exceptions[0].From).To(Equal("y-w1/b"))
+			Expect(rewritten.Steps[0].Expected.Exceptions[0].To).To(Equal("x-w1/a"))
+			Expect(rewritten.Steps[1].Actions[0].SetPodLabels.Namespace).To(Equal("x-w1"))
+			Expect(rewritten.Steps[1].Actions[0].SetPodLabels.Labels).To(Equal(map[string]string{"pod": "a"}))
+			Expect(MarshalTestCase(testCases[0])).To(Equal(original))
+		})
+
+		It("should rewrite the namespace selectors of generated network policies", func() {
+			gen := NewTestCaseGenerator(true, "1.2.3.4", namespaces, []string{}, []string{})
+			for _, testCase := range gen.GenerateAllTestCases() {
+				if testCase.UsesAdminPolicies() {
+					continue
+				}
+				rewritten, err := RewriteNamespaces(testCase, mapping)
+				Expect(err).ToNot(HaveOccurred())
+				for _, step := range rewritten.Steps {
+					for _, action := range step.Actions {
+						var policy *networkingv1.NetworkPolicy
+						if action.CreatePolicy != nil {
+							policy = action.CreatePolicy.Policy
+						} else if action.UpdatePolicy != nil {
+							policy = action.UpdatePolicy.Policy
+						} else {
+							continue
+						}
+						Expect(policy.Namespace).To(BeElementOf("x-w1", "y-w1", "z-w1"), testCase.Description)
+						var peers []networkingv1.NetworkPolicyPeer
+						for _, rule := range policy.Spec.Ingress {
+							peers = append(peers, rule.From...)
+						}
+						for _, rule := range policy.Spec.Egress {
+							peers = append(peers, rule.To...)
+						}
+						for _, peer := range peers {
+							if peer.NamespaceSelector == nil {
+								continue
+							}
+							for key, value := range peer.NamespaceSelector.MatchLabels {
+								if namespaceLabelKeys[key] {
+									Expect(value).ToNot(BeElementOf(namespaces), testCase.Description)
+								}
+							}
+							for _, expression := range peer.NamespaceSelector.MatchExpressions {
+								if namespaceLabelKeys[expression.Key] {
+									for _, value := range expression.Values {
+										Expect(value).ToNot(BeElementOf(namespaces), testCase.Description)
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,252 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	. "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+// RandomPolicyGenerator builds random but valid policies for the default resources: namespaces labeled
+// ns=<namespace>, pods labeled pod=<pod>, and a container for each port and protocol, serving on
+// port serve-<port>-<protocol>.  The same seed always produces the same sequence of policies.
+type RandomPolicyGenerator struct {
+	Seed       int64
+	Namespaces []string
+	Pods       []string
+	Ports      []int
+	Protocols  []v1.Protocol
+	rand       *rand.Rand
+}
+
+func NewRandomPolicyGenerator(seed int64, namespaces []string, pods []string, ports []int, protocols []v1.Protocol) *RandomPolicyGenerator {
+	return &RandomPolicyGenerator{
+		Seed:       seed,
+		Namespaces: namespaces,
+		Pods:       pods,
+		Ports:      ports,
+		Protocols:  protocols,
+		rand:       rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (g *RandomPolicyGenerator) chance(probability float64) bool {
+	return g.rand.Float64() < probability
+}
+
+func (g *RandomPolicyGenerator) choose(values []string) string {
+	return values[g.rand.Intn(len(values))]
+}
+
+func (g *RandomPolicyGenerator) subset(values []string) []string {
+	var chosen []string
+	for _, v := range values {
+		if g.chance(0.5) {
+			chosen = append(chosen, v)
+		}
+	}
+	if len(chosen) == 0 {
+		chosen = []string{g.choose(values)}
+	}
+	return chosen
+}
+
+// selector selects by the key, which is either "ns" or "pod".
+func (g *RandomPolicyGenerator) selector(key string, values []string) *metav1.LabelSelector {
+	switch g.rand.Intn(4) {
+	case 0:
+		return &metav1.LabelSelector{}
+	case 1:
+		return &metav1.LabelSelector{MatchLabels: map[string]string{key: g.choose(values)}}
+	case 2:
+		return &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: key, Operator: metav1.LabelSelectorOpIn, Values: g.subset(values)},
+		}}
+	default:
+		return &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: key, Operator: metav1.LabelSelectorOpNotIn, Values: g.subset(values)},
+		}}
+	}
+}
+
+func (g *RandomPolicyGenerator) protocol() v1.Protocol {
+	return g.Protocols[g.rand.Intn(len(g.Protocols))]
+}
+
+func (g *RandomPolicyGenerator) port() int {
+	return g.Ports[g.rand.Intn(len(g.Ports))]
+}
+
+func (g *RandomPolicyGenerator) portName(port int, protocol v1.Protocol) string {
+	return fmt.Sprintf("serve-%d-%s", port, strings.ToLower(string(protocol)))
+}
+
+func (g *RandomPolicyGenerator) netpolPort() NetworkPolicyPort {
+	protocol := g.protocol()
+	switch g.rand.Intn(4) {
+	case 0:
+		return NetworkPolicyPort{Protocol: &protocol}
+	case 1:
+		port := intstr.FromInt(g.port())
+		return NetworkPolicyPort{Protocol: &protocol, Port: &port}
+	case 2:
+		port := intstr.FromString(g.portName(g.port(), protocol))
+		return NetworkPolicyPort{Protocol: &protocol, Port: &port}
+	default:
+		start := g.port()
+		port, endPort := intstr.FromInt(start), int32(start+g.rand.Intn(2))
+		return NetworkPolicyPort{Protocol: &protocol, Port: &port, EndPort: &endPort}
+	}
+}
+
+func (g *RandomPolicyGenerator) netpolPeer() NetworkPolicyPeer {
+	switch g.rand.Intn(3) {
+	case 0:
+		return NetworkPolicyPeer{PodSelector: g.selector("pod", g.Pods)}
+	case 1:
+		return NetworkPolicyPeer{NamespaceSelector: g.selector("ns", g.Namespaces)}
+	default:
+		return NetworkPolicyPeer{PodSelector: g.selector("pod", g.Pods), NamespaceSelector: g.selector("ns", g.Namespaces)}
+	}
+}
+
+func (g *RandomPolicyGenerator) netpolRules() *NetpolPeers {
+	peers := &NetpolPeers{}
+	for i := g.rand.Intn(3); i > 0; i-- {
+		rule := &Rule{}
+		for j := g.rand.Intn(3); j > 0; j-- {
+			rule.Ports = append(rule.Ports, g.netpolPort())
+		}
+		for j := g.rand.Intn(3); j > 0; j-- {
+			rule.Peers = append(rule.Peers, g.netpolPeer())
+		}
+		peers.Rules = append(peers.Rules, rule)
+	}
+	return peers
+}
+
+// Netpol builds a random NetworkPolicy in one of the namespaces, with ingress, egress, or both.
+func (g *RandomPolicyGenerator) Netpol(name string) *Netpol {
+	netpol := &Netpol{
+		Name:        name,
+		Description: fmt.Sprintf("random policy from seed %d", g.Seed),
+		Target: &NetpolTarget{
+			Namespace:   g.choose(g.Namespaces),
+			PodSelector: *g.selector("pod", g.Pods),
+		},
+	}
+	switch g.rand.Intn(3) {
+	case 0:
+		netpol.Ingress = g.netpolRules()
+	case 1:
+		netpol.Egress = g.netpolRules()
+	default:
+		netpol.Ingress = g.netpolRules()
+		netpol.Egress = g.netpolRules()
+	}
+	return netpol
+}
+
+func (g *RandomPolicyGenerator) adminPort() v1alpha1.AdminNetworkPolicyPort {
+	switch g.rand.Intn(3) {
+	case 0:
+		return AdminPortNumber(g.protocol(), int32(g.port()))
+	case 1:
+		return AdminNamedPort(g.portName(g.port(), g.protocol()))
+	default:
+		start := int32(g.port())
+		return AdminPortRange(g.protocol(), start, start+1)
+	}
+}
+
+func (g *RandomPolicyGenerator) adminPeer() v1alpha1.AdminNetworkPolicyPeer {
+	if g.chance(0.5) {
+		return AdminNamespacesPeer(g.selector("ns", g.Namespaces))
+	}
+	return AdminPodsPeer(g.selector("ns", g.Namespaces), g.selector("pod", g.Pods))
+}
+
+func (g *RandomPolicyGenerator) adminSubject() v1alpha1.AdminNetworkPolicySubject {
+	if g.chance(0.5) {
+		return v1alpha1.AdminNetworkPolicySubject{Namespaces: g.selector("ns", g.Namespaces)}
+	}
+	return v1alpha1.AdminNetworkPolicySubject{Pods: &v1alpha1.NamespacedPodSubject{
+		NamespaceSelector: *g.selector("ns", g.Namespaces),
+		PodSelector:       *g.selector("pod", g.Pods),
+	}}
+}
+
+// adminRules builds 1 to 3 rules, choosing actions from the given ones.
+func (g *RandomPolicyGenerator) adminRules(prefix string, actions []v1alpha1.AdminNetworkPolicyRuleAction) []*AdminRule {
+	var rules []*AdminRule
+	count := 1 + g.rand.Intn(3)
+	for i := 0; i < count; i++ {
+		var ports []v1alpha1.AdminNetworkPolicyPort
+		for j := g.rand.Intn(3); j > 0; j-- {
+			ports = append(ports, g.adminPort())
+		}
+		var peers []v1alpha1.AdminNetworkPolicyPeer
+		for j := 1 + g.rand.Intn(2); j > 0; j-- {
+			peers = append(peers, g.adminPeer())
+		}
+		action := actions[g.rand.Intn(len(actions))]
+		rules = append(rules, NewAdminRule(fmt.Sprintf("%s-%d", prefix, i+1), action, ports, peers...))
+	}
+	return rules
+}
+
+// AdminPolicy builds a random ANP with ingress, egress, or both.
+func (g *RandomPolicyGenerator) AdminPolicy(name string, priority int32) *v1alpha1.AdminNetworkPolicy {
+	actions := []v1alpha1.AdminNetworkPolicyRuleAction{anpAllow, anpDeny, anpPass}
+	direction := g.rand.Intn(3)
+	policy := BuildAdminPolicy(name, priority, true)
+	policy.Spec.Subject = g.adminSubject()
+	if direction != 1 {
+		policy.Spec.Ingress = BuildAdminPolicy(name, priority, true, g.adminRules("ingress", actions)...).Spec.Ingress
+	}
+	if direction != 0 {
+		policy.Spec.Egress = BuildAdminPolicy(name, priority, false, g.adminRules("egress", actions)...).Spec.Egress
+	}
+	return policy
+}
+
+// BaselineAdminPolicy builds a random BANP with ingress, egress, or both.
+func (g *RandomPolicyGenerator) BaselineAdminPolicy() *v1alpha1.BaselineAdminNetworkPolicy {
+	actions := []v1alpha1.AdminNetworkPolicyRuleAction{anpAllow, anpDeny}
+	direction := g.rand.Intn(3)
+	policy := BuildBaselineAdminPolicy(true)
+	policy.Spec.Subject = g.adminSubject()
+	if direction != 1 {
+		policy.Spec.Ingress = BuildBaselineAdminPolicy(true, g.adminRules("ingress", actions)...).Spec.Ingress
+	}
+	if direction != 0 {
+		policy.Spec.Egress = BuildBaselineAdminPolicy(false, g.adminRules("egress", actions)...).Spec.Egress
+	}
+	return policy
+}
+
+// TestCase builds a single step test case, creating up to maxNetpols NetworkPolicies, up to maxANPs ANPs
+// (with distinct priorities), and possibly a BANP.  Random egress policies would often block DNS, so
+// probes go to service IPs rather than service names.
+func (g *RandomPolicyGenerator) TestCase(description string, maxNetpols int, maxANPs int, includeBANP bool) *TestCase {
+	var actions []*Action
+	netpolCount, anpCount := g.rand.Intn(maxNetpols+1), g.rand.Intn(maxANPs+1)
+	for i := 0; i < netpolCount; i++ {
+		actions = append(actions, CreatePolicy(g.Netpol(fmt.Sprintf("random-%d", i+1)).NetworkPolicy()))
+	}
+	for i := 0; i < anpCount; i++ {
+		actions = append(actions, CreateAdminPolicy(g.AdminPolicy(fmt.Sprintf("random-%d", i+1), int32(i+1))))
+	}
+	if includeBANP && g.chance(0.5) {
+		actions = append(actions, CreateBaselineAdminPolicy(g.BaselineAdminPolicy()))
+	}
+	if len(actions) == 0 {
+		actions = append(actions, CreatePolicy(g.Netpol("random-1").NetworkPolicy()))
+	}
+	return NewSingleStepTestCase(description, NewStringSet(TagRandom), NewAllAvailable(ProbeModeServiceIP), actions...)
+}
@@ -0,0 +1,79 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/mattfenwick/collections/pkg/json"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+)
+
+func newTestRandomPolicyGenerator(seed int64) *RandomPolicyGenerator {
+	return NewRandomPolicyGenerator(seed, []string{"x", "y", "z"}, []string{"a", "b", "c"}, []int{80, 81}, []v1.Protocol{v1.ProtocolTCP, v1.ProtocolUDP, v1.ProtocolSCTP})
+}
+
+func simulateTestCase(testCase *TestCase) {
+	var netpols []*networkingv1.NetworkPolicy
+	var anps []*v1alpha1.AdminNetworkPolicy
+	var banp *v1alpha1.BaselineAdminNetworkPolicy
+	for _, step := range testCase.Steps {
+		for _, action := range step.Actions {
+			if action.CreatePolicy != nil {
+				netpols = append(netpols, action.CreatePolicy.Policy)
+			} else if action.CreateAdminPolicy != nil {
+				anps = append(anps, action.CreateAdminPolicy.Policy)
+			} else if action.CreateBaselineAdminPolicy != nil {
+				banp = action.CreateBaselineAdminPolicy.Policy
+			}
+		}
+	}
+	Expect(func() { matcher.BuildV1AndV2NetPols(true, netpols, anps, banp) }).ToNot(Panic(), testCase.Description)
+}
+
+func RunRandomPolicyTests() {
+	Describe("RandomPolicyGenerator", func() {
+		It("should generate the same test cases from the same seed", func() {
+			first, second, other := newTestRandomPolicyGenerator(17), newTestRandomPolicyGenerator(17), newTestRandomPolicyGenerator(18)
+			for i := 0; i < 10; i++ {
+				firstCase := json.MustMarshalToString(first.TestCase("", 3, 3, true))
+				Expect(json.MustMarshalToString(second.TestCase("", 3, 3, true))).To(Equal(firstCase))
+				Expect(json.MustMarshalToString(other.TestCase("", 3, 3, true))).ToNot(Equal(firstCase))
+			}
+		})
+
+		It("should round trip test cases through json", func() {
+			testCase := newTestRandomPolicyGenerator(3).TestCase("round trip", 3, 3, true)
+			parsed, err := json.ParseString[TestCase](json.MustMarshalToString(testCase))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(json.MustMarshalToString(parsed)).To(Equal(json.MustMarshalToString(testCase)))
+		})
+
+		It("should generate test cases which can be simulated", func() {
+			gen := newTestRandomPolicyGenerator(1)
+			for i := 0; i < 200; i++ {
+				testCase := gen.TestCase(fmt.Sprintf("random %d", i), 3, 3, true)
+				Expect(testCase.Steps[0].Actions).ToNot(BeEmpty())
+				simulateTestCase(testCase)
+			}
+		})
+
+		It("should shrink test cases to smaller ones which can be simulated", func() {
+			gen := newTestRandomPolicyGenerator(2)
+			for i := 0; i < 20; i++ {
+				testCase := gen.TestCase(fmt.Sprintf("random %d", i), 3, 3, true)
+				original := json.MustMarshalToString(testCase)
+				candidates := ShrinkCandidates(testCase)
+				for _, candidate := range candidates {
+					Expect(len(candidate.Steps[0].Actions)).To(BeNumerically("<=", len(testCase.Steps[0].Actions)))
+					Expect(json.MustMarshalToString(candidate)).ToNot(Equal(original))
+					simulateTestCase(candidate)
+				}
+				Expect(json.MustMarshalToString(testCase)).To(Equal(original))
+			}
+		})
+	})
+}
@@ -0,0 +1,221 @@
+package generator
+
+import (
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+// ShrinkCandidates returns test cases which are each one step smaller than the given one: a single action
+// is removed, or a single rule, peer or port is removed from a policy that an action creates.
+// Candidates are ordered from the most to the least aggressive reduction.
+func ShrinkCandidates(testCase *TestCase) []*TestCase {
+	actionCount := 0
+	for _, step := range testCase.Steps {
+		actionCount += len(step.Actions)
+	}
+
+	var candidates []*TestCase
+	if actionCount > 1 {
+		for i, step := range testCase.Steps {
+			for j := range step.Actions {
+				candidates = append(candidates, testCase.replaceAction(i, j, nil))
+			}
+		}
+	}
+	for i, step := range testCase.Steps {
+		for j, action := range step.Actions {
+			for _, smaller := range shrinkAction(action) {
+				candidates = append(candidates, testCase.replaceAction(i, j, smaller))
+			}
+		}
+	}
+	return candidates
+}
+
+// replaceAction copies the test case, replacing the action at the given step and index -- or removing it,
+// if the replacement is nil.
+func (t *TestCase) replaceAction(stepIndex int, actionIndex int, replacement *Action) *TestCase {
+	var steps []*TestStep
+	for i, step := range t.Steps {
+		if i != stepIndex {
+			steps = append(steps, step)
+			continue
+		}
+		var actions []*Action
+		for j, action := range step.Actions {
+			if j != actionIndex {
+				actions = append(actions, action)
+			} else if replacement != nil {
+				actions = append(actions, replacement)
+			}
+		}
+		steps = append(steps, NewTestStep(step.Probe, actions...))
+	}
+	return NewTestCase(t.Description, t.Tags, steps...)
+}
+
+func shrinkAction(action *Action) []*Action {
+	var actions []*Action
+	if action.CreatePolicy != nil {
+		for _, policy := range shrinkNetworkPolicy(action.CreatePolicy.Policy) {
+			actions = append(actions, CreatePolicy(policy))
+		}
+	} else if action.CreateAdminPolicy != nil {
+		for _, policy := range shrinkAdminPolicy(action.CreateAdminPolicy.Policy) {
+			actions = append(actions, CreateAdminPolicy(policy))
+		}
+	} else if action.CreateBaselineAdminPolicy != nil {
+		for _, policy := range shrinkBaselineAdminPolicy(action.CreateBaselineAdminPolicy.Policy) {
+			actions = append(actions, CreateBaselineAdminPolicy(policy))
+		}
+	}
+	return actions
+}
+
+func removeAt[T any](values []T, index int) []T {
+	var out []T
+	out = append(out, values[:index]...)
+	return append(out, values[index+1:]...)
+}
+
+func shrinkNetworkPolicy(policy *networkingv1.NetworkPolicy) []*networkingv1.NetworkPolicy {
+	var policies []*networkingv1.NetworkPolicy
+	for i, rule := range policy.Spec.Ingress {
+		smaller := policy.DeepCopy()
+		smaller.Spec.Ingress = removeAt(smaller.Spec.Ingress, i)
+		policies = append(policies, smaller)
+		for j := range rule.From {
+			smaller := policy.DeepCopy()
+			smaller.Spec.Ingress[i].From = removeAt(smaller.Spec.Ingress[i].From, j)
+			policies = append(policies, smaller)
+		}
+		for j := range rule.Ports {
+			smaller := policy.DeepCopy()
+			smaller.Spec.Ingress[i].Ports = removeAt(smaller.Spec.Ingress[i].Ports, j)
+			policies = append(policies, smaller)
+		}
+	}
+	for i, rule := range policy.Spec.Egress {
+		smaller := policy.DeepCopy()
+		smaller.Spec.Egress = removeAt(smaller.Spec.Egress, i)
+		policies = append(policies, smaller)
+		for j := range rule.To {
+			smaller := policy.DeepCopy()
+			smaller.Spec.Egress[i].To = removeAt(smaller.Spec.Egress[i].To, j)
+			policies = append(policies, smaller)
+		}
+		for j := range rule.Ports {
+			smaller := policy.DeepCopy()
+			smaller.Spec.Egress[i].Ports = removeAt(smaller.Spec.Egress[i].Ports, j)
+			policies = append(policies, smaller)
+		}
+	}
+	return policies
+}
+
+// shrinkAdminPorts removes a single port; since ANP rules must not have an empty port list, removing the
+// last port drops the list altogether.
+func shrinkAdminPorts(ports *[]v1alpha1.AdminNetworkPolicyPort, index int) *[]v1alpha1.AdminNetworkPolicyPort {
+	smaller := removeAt(*ports, index)
+	if len(smaller) == 0 {
+		return nil
+	}
+	return &smaller
+}
+
+// shrinkAdminPolicy only removes a rule if it isn't the last one, since admin policies need at least one rule.
+func shrinkAdminPolicy(policy *v1alpha1.AdminNetworkPolicy) []*v1alpha1.AdminNetworkPolicy {
+	var policies []*v1alpha1.AdminNetworkPolicy
+	ruleCount := len(policy.Spec.Ingress) + len(policy.Spec.Egress)
+	for i, rule := range policy.Spec.Ingress {
+		if ruleCount > 1 {
+			smaller := policy.DeepCopy()
+			smaller.Spec.Ingress = removeAt(smaller.Spec.Ingress, i)
+			policies = append(policies, smaller)
+		}
+		for j := range rule.From {
+			if len(rule.From) > 1 {
+				smaller := policy.DeepCopy()
+				smaller.Spec.Ingress[i].From = removeAt(smaller.Spec.Ingress[i].From, j)
+				policies = append(policies, smaller)
+			}
+		}
+		if rule.Ports != nil {
+			for j := range *rule.Ports {
+				smaller := policy.DeepCopy()
+				smaller.Spec.Ingress[i].Ports = shrinkAdminPorts(smaller.Spec.Ingress[i].Ports, j)
+				policies = append(policies, smaller)
+			}
+		}
+	}
+	for i, rule := range policy.Spec.Egress {
+		if ruleCount > 1 {
+			smaller := policy.DeepCopy()
+			smaller.Spec.Egress = removeAt(smaller.Spec.Egress, i)
+			policies = append(policies, smaller)
+		}
+		for j := range rule.To {
+			if len(rule.To) > 1 {
+				smaller := policy.DeepCopy()
+				smaller.Spec.Egress[i].To = removeAt(smaller.Spec.Egress[i].To, j)
+				policies = append(policies, smaller)
+			}
+		}
+		if rule.Ports != nil {
+			for j := range *rule.Ports {
+				smaller := policy.DeepCopy()
+				smaller.Spec.Egress[i].Ports = shrinkAdminPorts(smaller.Spec.Egress[i].Ports, j)
+				policies = append(policies, smaller)
+			}
+		}
+	}
+	return policies
+}
+
+func shrinkBaselineAdminPolicy(policy *v1alpha1.BaselineAdminNetworkPolicy) []*v1alpha1.BaselineAdminNetworkPolicy {
+	var policies []*v1alpha1.BaselineAdminNetworkPolicy
+	ruleCount := len(policy.Spec.Ingress) + len(policy.Spec.Egress)
+	for i, rule := range policy.Spec.Ingress {
+		if ruleCount > 1 {
+			smaller := policy.DeepCopy()
+			smaller.Spec.Ingress = removeAt(smaller.Spec.Ingress, i)
+			policies = append(policies, smaller)
+		}
+		for j := range rule.From {
+			if len(rule.From) > 1 {
+				smaller := policy.DeepCopy()
+				smaller.Spec.Ingress[i].From = removeAt(smaller.Spec.Ingress[i].From, j)
+				policies = append(policies, smaller)
+			}
+		}
+		if rule.Ports != nil {
+			for j := range *rule.Ports {
+				smaller := policy.DeepCopy()
+				smaller.Spec.Ingress[i].Ports = shrinkAdminPorts(smaller.Spec.Ingress[i].Ports, j)
+				policies = append(policies, smaller)
+			}
+		}
+	}
+	for i, rule := range policy.Spec.Egress {
+		if ruleCount > 1 {
+			smaller := policy.DeepCopy()
+			smaller.Spec.Egress = removeAt(smaller.Spec.Egress, i)
+			policies = append(policies, smaller)
+		}
+		for j := range rule.To {
+			if len(rule.To) > 1 {
+				smaller := policy.DeepCopy()
+				smaller.Spec.Egress[i].To = removeAt(smaller.Spec.Egress[i].To, j)
+				policies = append(policies, smaller)
+			}
+		}
+		if rule.Ports != nil {
+			for j := range *rule.Ports {
+				smaller := policy.DeepCopy()
+				smaller.Spec.Egress[i].Ports = shrinkAdminPorts(smaller.Spec.Egress[i].Ports, j)
+				policies = append(policies, smaller)
+			}
+		}
+	}
+	return policies
+}
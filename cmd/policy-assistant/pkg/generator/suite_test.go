@@ -10,5 +10,8 @@ import (
 func TestGenerator(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunTestCaseGeneratorTests()
+	RunRandomPolicyTests()
+	RunTestCaseFileTests()
+	RunNamespaceRewriteTests()
 	RunSpecs(t, "generator suite")
 }
@@ -13,6 +13,7 @@ const (
 	TagTarget        = "target"
 	TagDirection     = "direction"
 	TagPolicyStack   = "policy-stack"
+	TagAdminTier     = "admin-tier"
 	TagRule          = "rule"
 	TagProtocol      = "protocol"
 	TagPort          = "port"
@@ -31,6 +32,28 @@ const (
 	TagCreateNamespace    = "create-namespace"
 	TagDeleteNamespace    = "delete-namespace"
 	TagSetNamespaceLabels = "set-namespace-labels"
+
+	TagCreateAdminPolicy         = "create-admin-policy"
+	TagDeleteAdminPolicy         = "delete-admin-policy"
+	TagUpdateAdminPolicy         = "update-admin-policy"
+	TagCreateBaselineAdminPolicy = "create-baseline-admin-policy"
+	TagDeleteBaselineAdminPolicy = "delete-baseline-admin-policy"
+	TagUpdateBaselineAdminPolicy = "update-baseline-admin-policy"
+)
+
+const (
+	TagANP               = "anp"
+	TagBANP              = "banp"
+	TagTierOrdering      = "tier-ordering"
+	TagPriority          = "priority"
+	TagPass              = "pass"
+	TagRuleOrdering      = "rule-ordering"
+	TagSameLabels        = "same-labels"
+	TagNotSameLabels     = "not-same-labels"
+	TagAdminPodPeer      = "admin-pod-peer"
+	TagAdminNumberedPort = "admin-numbered-port"
+	TagAdminNamedPort    = "admin-named-port"
+	TagAdminPortRange    = "admin-port-range"
 )
 
 const (
@@ -84,6 +107,7 @@ const (
 	TagConflict     = "conflict"
 	TagExample      = "example"
 	TagUpstreamE2E  = "upstream-e2e"
+	TagRandom       = "random"
 )
 
 var AllTags = map[string][]string{
@@ -97,6 +121,12 @@ var AllTags = map[string][]string{
 		TagCreateNamespace,
 		TagDeleteNamespace,
 		TagSetNamespaceLabels,
+		TagCreateAdminPolicy,
+		TagDeleteAdminPolicy,
+		TagUpdateAdminPolicy,
+		TagCreateBaselineAdminPolicy,
+		TagDeleteBaselineAdminPolicy,
+		TagUpdateBaselineAdminPolicy,
 	},
 	TagTarget: {
 		TagTargetNamespace,
@@ -107,6 +137,20 @@ var AllTags = map[string][]string{
 		TagEgress,
 	},
 	TagPolicyStack: {},
+	TagAdminTier: {
+		TagANP,
+		TagBANP,
+		TagTierOrdering,
+		TagPriority,
+		TagPass,
+		TagRuleOrdering,
+		TagSameLabels,
+		TagNotSameLabels,
+		TagAdminPodPeer,
+		TagAdminNumberedPort,
+		TagAdminNamedPort,
+		TagAdminPortRange,
+	},
 	TagRule: {
 		TagDenyAll,
 		TagAllowAll,
@@ -143,6 +187,7 @@ var AllTags = map[string][]string{
 		TagConflict,
 		TagExample,
 		TagUpstreamE2E,
+		TagRandom,
 	},
 }
 
@@ -0,0 +1,376 @@
+package generator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/file"
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	TestCaseAPIVersion = "policy-assistant.networking.k8s.io/v1alpha1"
+	TestCaseKind       = "TestCase"
+)
+
+// TestCaseDocument is the versioned yaml/json representation of a test case.
+type TestCaseDocument struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	*TestCase
+}
+
+func NewTestCaseDocument(testCase *TestCase) *TestCaseDocument {
+	return &TestCaseDocument{APIVersion: TestCaseAPIVersion, Kind: TestCaseKind, TestCase: testCase}
+}
+
+type ExpectedResult string
+
+const (
+	ExpectedAllowed ExpectedResult = "allowed"
+	ExpectedBlocked ExpectedResult = "blocked"
+)
+
+// ExpectedConnectivity is a truth table: all traffic is expected to have the default result, except for
+// traffic matching an exception.  Loopback traffic is not checked.
+type ExpectedConnectivity struct {
+	Default    ExpectedResult     `json:"default"`
+	Exceptions []*ExpectedTraffic `json:"exceptions,omitempty"`
+}
+
+// ExpectedTraffic matches traffic from one pod to another, given as namespace/pod.  An empty protocol
+// or a 0 port matches all protocols or ports.
+type ExpectedTraffic struct {
+	From     string         `json:"from"`
+	To       string         `json:"to"`
+	Protocol v1.Protocol    `json:"protocol,omitempty"`
+	Port     int            `json:"port,omitempty"`
+	Result   ExpectedResult `json:"result"`
+}
+
+func (e *ExpectedTraffic) Matches(from string, to string, protocol v1.Protocol, port int) bool {
+	return e.From == from && e.To == to && (e.Protocol == "" || e.Protocol == protocol) && (e.Port == 0 || e.Port == port)
+}
+
+// Expect returns the expected result for traffic; if several exceptions match, the last one wins.
+func (e *ExpectedConnectivity) Expect(from string, to string, protocol v1.Protocol, port int) ExpectedResult {
+	result := e.Default
+	for _, exception := range e.Exceptions {
+		if exception.Matches(from, to, protocol, port) {
+			result = exception.Result
+		}
+	}
+	return result
+}
+
+func validateExpectedResult(result ExpectedResult) error {
+	if result != ExpectedAllowed && result != ExpectedBlocked {
+		return errors.Errorf("invalid expected result '%s', must be one of %s, %s", result, ExpectedAllowed, ExpectedBlocked)
+	}
+	return nil
+}
+
+func (e *ExpectedConnectivity) Validate() error {
+	if err := validateExpectedResult(e.Default); err != nil {
+		return err
+	}
+	for i, exception := range e.Exceptions {
+		if len(strings.Split(exception.From, "/")) != 2 || len(strings.Split(exception.To, "/")) != 2 {
+			return errors.Errorf("exception %d: from and to must be given as namespace/pod", i)
+		}
+		if err := validateExpectedResult(exception.Result); err != nil {
+			return errors.WithMessagef(err, "exception %d", i)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON writes a StringSet as a sorted list.
+func (s StringSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Keys())
+}
+
+func (s *StringSet) UnmarshalJSON(bs []byte) error {
+	var tags []string
+	if err := json.Unmarshal(bs, &tags); err != nil {
+		return errors.Wrapf(err, "unable to unmarshal tags")
+	}
+	if err := ValidateTags(tags); err != nil {
+		return err
+	}
+	*s = NewStringSet()
+	for _, tag := range tags {
+		if _, ok := AllTags[tag]; ok {
+			(*s)[tag] = true
+		} else {
+			s.Add(tag)
+		}
+	}
+	return nil
+}
+
+func (a *Action) Validate() error {
+	count := 0
+	for _, isSet := range []bool{
+		a.CreatePolicy != nil, a.UpdatePolicy != nil, a.DeletePolicy != nil,
+		a.CreateAdminPolicy != nil, a.UpdateAdminPolicy != nil, a.DeleteAdminPolicy != nil,
+		a.CreateBaselineAdminPolicy != nil, a.UpdateBaselineAdminPolicy != nil, a.DeleteBaselineAdminPolicy != nil,
+		a.CreateNamespace != nil, a.SetNamespaceLabels != nil, a.DeleteNamespace != nil,
+		a.ReadNetworkPolicies != nil,
+		a.CreatePod != nil, a.SetPodLabels != nil, a.DeletePod != nil,
+	} {
+		if isSet {
+			count++
+		}
+	}
+	if count != 1 {
+		return errors.Errorf("action must have exactly 1 field set, found %d", count)
+	}
+	for _, isMissing := range []bool{
+		a.CreatePolicy != nil && a.CreatePolicy.Policy == nil, a.UpdatePolicy != nil && a.UpdatePolicy.Policy == nil,
+		a.CreateAdminPolicy != nil && a.CreateAdminPolicy.Policy == nil, a.UpdateAdminPolicy != nil && a.UpdateAdminPolicy.Policy == nil,
+		a.CreateBaselineAdminPolicy != nil && a.CreateBaselineAdminPolicy.Policy == nil, a.UpdateBaselineAdminPolicy != nil && a.UpdateBaselineAdminPolicy.Policy == nil,
+	} {
+		if isMissing {
+			return errors.Errorf("action is missing its policy")
+		}
+	}
+	var netpol *networkingv1.NetworkPolicy
+	if a.CreatePolicy != nil {
+		netpol = a.CreatePolicy.Policy
+	} else if a.UpdatePolicy != nil {
+		netpol = a.UpdatePolicy.Policy
+	}
+	if netpol != nil && len(netpol.Spec.PolicyTypes) == 0 {
+		return errors.Errorf("policy %s/%s: need at least 1 policy type", netpol.Namespace, netpol.Name)
+	}
+	return nil
+}
+
+// adminPolicies tracks the admin policies that a test case's actions create, update and delete, so that
+// they can be validated together: the matcher can't build ANPs with the same priority.
+type adminPolicies struct {
+	anps map[string]*v1alpha1.AdminNetworkPolicy
+	banp *v1alpha1.BaselineAdminNetworkPolicy
+}
+
+func (p *adminPolicies) apply(a *Action) {
+	switch {
+	case a.CreateAdminPolicy != nil:
+		p.anps[a.CreateAdminPolicy.Policy.Name] = a.CreateAdminPolicy.Policy
+	case a.UpdateAdminPolicy != nil:
+		p.anps[a.UpdateAdminPolicy.Policy.Name] = a.UpdateAdminPolicy.Policy
+	case a.DeleteAdminPolicy != nil:
+		delete(p.anps, a.DeleteAdminPolicy.Name)
+	case a.CreateBaselineAdminPolicy != nil:
+		p.banp = a.CreateBaselineAdminPolicy.Policy
+	case a.UpdateBaselineAdminPolicy != nil:
+		p.banp = a.UpdateBaselineAdminPolicy.Policy
+	case a.DeleteBaselineAdminPolicy != nil:
+		p.banp = nil
+	}
+}
+
+func (p *adminPolicies) validate() error {
+	var anps []*v1alpha1.AdminNetworkPolicy
+	for _, name := range slice.Sort(maps.Keys(p.anps)) {
+		anps = append(anps, p.anps[name])
+	}
+	return matcher.ValidateAdminPolicies(anps, p.banp)
+}
+
+func (p *ProbeConfig) Validate() error {
+	if p.AllAvailable == (p.PortProtocol != nil) {
+		return errors.Errorf("probe must set exactly one of allAvailable and portProtocol")
+	}
+	if _, err := ParseProbeMode(string(p.Mode)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *TestCase) Validate() error {
+	if len(t.Steps) == 0 {
+		return errors.Errorf("test case '%s' has no steps", t.Description)
+	}
+	admin := &adminPolicies{anps: map[string]*v1alpha1.AdminNetworkPolicy{}}
+	for i, step := range t.Steps {
+		if step.Probe == nil {
+			return errors.Errorf("test case '%s', step %d: missing probe", t.Description, i+1)
+		}
+		if err := step.Probe.Validate(); err != nil {
+			return errors.WithMessagef(err, "test case '%s', step %d", t.Description, i+1)
+		}
+		for j, action := range step.Actions {
+			if err := action.Validate(); err != nil {
+				return errors.WithMessagef(err, "test case '%s', step %d, action %d", t.Description, i+1, j+1)
+			}
+			admin.apply(action)
+		}
+		if err := admin.validate(); err != nil {
+			return errors.WithMessagef(err, "test case '%s', step %d", t.Description, i+1)
+		}
+		if step.Expected != nil {
+			if err := step.Expected.Validate(); err != nil {
+				return errors.WithMessagef(err, "test case '%s', step %d, expected", t.Description, i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// MarshalTestCase renders a test case as a versioned yaml document.
+func MarshalTestCase(testCase *TestCase) (string, error) {
+	bs, err := yaml.Marshal(NewTestCaseDocument(testCase))
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to marshal test case '%s'", testCase.Description)
+	}
+	return string(bs), nil
+}
+
+// ParseTestCases parses one or more yaml documents -- or json objects -- each holding a test case.
+// Unknown fields are rejected, to catch typos in hand-written test cases, and probes without a mode
+// default to probing service names.
+func ParseTestCases(bs []byte) ([]*TestCase, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(bs), 4096)
+	var testCases []*TestCase
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			return testCases, nil
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "unable to decode test case document %d", len(testCases)+1)
+		}
+		if len(bytes.TrimSpace(raw)) == 0 || string(raw) == "null" {
+			continue
+		}
+
+		strict := json.NewDecoder(bytes.NewReader(raw))
+		strict.DisallowUnknownFields()
+		document := &TestCaseDocument{}
+		if err := strict.Decode(document); err != nil {
+			return nil, errors.Wrapf(err, "unable to parse test case document %d", len(testCases)+1)
+		}
+		if document.APIVersion != TestCaseAPIVersion || document.Kind != TestCaseKind {
+			return nil, errors.Errorf("test case document %d: unsupported apiVersion/kind %s/%s, expected %s/%s",
+				len(testCases)+1, document.APIVersion, document.Kind, TestCaseAPIVersion, TestCaseKind)
+		}
+		if document.TestCase == nil {
+			return nil, errors.Errorf("test case document %d is empty", len(testCases)+1)
+		}
+		if document.Tags == nil {
+			document.Tags = NewStringSet()
+		}
+		for _, step := range document.Steps {
+			if step.Probe != nil && step.Probe.Mode == "" {
+				step.Probe.Mode = ProbeModeServiceName
+			}
+		}
+		if err := document.Validate(); err != nil {
+			return nil, err
+		}
+		testCases = append(testCases, document.TestCase)
+	}
+}
+
+func ReadTestCasesFromFile(filePath string) ([]*TestCase, error) {
+	bs, err := file.Read(filePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read test cases from %s", filePath)
+	}
+	testCases, err := ParseTestCases(bs)
+	return testCases, errors.WithMessagef(err, "in %s", filePath)
+}
+
+// ReadTestCasesFromDirectory reads the test cases from every .yaml, .yml or .json file in the directory,
+// in order of file name.
+func ReadTestCasesFromDirectory(dir string) ([]*TestCase, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read directory %s", dir)
+	}
+	var names []string
+	for _, entry := range entries {
+		switch path.Ext(entry.Name()) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var testCases []*TestCase
+	for _, name := range names {
+		fileCases, err := ReadTestCasesFromFile(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		testCases = append(testCases, fileCases...)
+	}
+	return testCases, nil
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+func descriptionSlug(testCase *TestCase) string {
+	slug := strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(testCase.Description), "-"), "-")
+	if len(slug) > 80 {
+		slug = strings.TrimRight(slug[:80], "-")
+	}
+	return slug
+}
+
+// TestCaseFileName builds a file name from the index and description of a test case.
+func TestCaseFileName(index int, testCase *TestCase) string {
+	return fmt.Sprintf("%03d-%s.yaml", index, descriptionSlug(testCase))
+}
+
+var ipv4Address = regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}\b`)
+
+// TestCaseID identifies a test case by its description and a hash of its contents, so that the ID is
+// stable across runs, and changes if the test case changes.  IP addresses are left out of the hash,
+// since ipBlock test cases are built from the IP of a pod, which changes whenever the pod is recreated;
+// prefix lengths are kept, so ipBlocks which differ in size still give different IDs.
+func TestCaseID(testCase *TestCase) (string, error) {
+	contents, err := MarshalTestCase(testCase)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256([]byte(ipv4Address.ReplaceAllString(contents, "x.x.x.x")))
+	return fmt.Sprintf("%s-%s", descriptionSlug(testCase), hex.EncodeToString(hash[:])[:12]), nil
+}
+
+// WriteTestCasesToDirectory writes each test case to its own file, numbered in order.
+func WriteTestCasesToDirectory(dir string, testCases []*TestCase) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "unable to create directory %s", dir)
+	}
+	for i, testCase := range testCases {
+		contents, err := MarshalTestCase(testCase)
+		if err != nil {
+			return err
+		}
+		if err := file.WriteString(path.Join(dir, TestCaseFileName(i+1, testCase)), contents, 0644); err != nil {
+			return errors.Wrapf(err, "unable to write test case '%s'", testCase.Description)
+		}
+	}
+	return nil
+}
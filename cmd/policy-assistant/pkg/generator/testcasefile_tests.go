@@ -0,0 +1,149 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+)
+
+const scenarioYaml = `
+apiVersion: policy-assistant.networking.k8s.io/v1alpha1
+kind: TestCase
+description: deny ingress to x/a, then relabel x/b
+tags: [deny-all, set-pod-labels]
+steps:
+- probe:
+    portProtocol: {protocol: TCP, port: 80}
+  actions:
+  - createPolicy:
+      policy:
+        metadata: {name: deny-all, namespace: x}
+        spec:
+          podSelector: {matchLabels: {pod: a}}
+          policyTypes: [Ingress]
+  expected:
+    default: allowed
+    exceptions:
+    - {from: y/b, to: x/a, result: blocked}
+- probe:
+    allAvailable: true
+    mode: pod-ip
+  actions:
+  - setPodLabels: {namespace: x, pod: b, labels: {pod: a}}
+---
+apiVersion: policy-assistant.networking.k8s.io/v1alpha1
+kind: TestCase
+description: second
+steps:
+- probe: {allAvailable: true}
+`
+
+func RunTestCaseFileTests() {
+	Describe("TestCase files", func() {
+		It("should round trip all generated test cases through yaml", func() {
+			gen := NewTestCaseGenerator(true, "1.2.3.4", []string{"x", "y", "z"}, []string{}, []string{})
+			for _, testCase := range gen.GenerateAllTestCases() {
+				contents, err := MarshalTestCase(testCase)
+				Expect(err).ToNot(HaveOccurred())
+				parsed, err := ParseTestCases([]byte(contents))
+				Expect(err).ToNot(HaveOccurred(), testCase.Description)
+				Expect(parsed).To(HaveLen(1))
+				Expect(MarshalTestCase(parsed[0])).To(Equal(contents))
+			}
+		})
+
+		It("should parse hand-written multi-document scenarios", func() {
+			testCases, err := ParseTestCases([]byte(scenarioYaml))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(testCases).To(HaveLen(2))
+
+			scenario := testCases[0]
+			Expect(scenario.Tags.Keys()).To(Equal([]string{TagAction, TagDenyAll, TagRule, TagSetPodLabels}))
+			Expect(scenario.Steps).To(HaveLen(2))
+			Expect(scenario.Steps[0].Probe.Mode).To(BeEquivalentTo(ProbeModeServiceName))
+			Expect(scenario.Steps[0].Actions[0].CreatePolicy.Policy.Spec.PodSelector.MatchLabels).To(Equal(map[string]string{"pod": "a"}))
+			Expect(scenario.Steps[0].Expected.Expect("y/b", "x/a", v1.ProtocolTCP, 80)).To(Equal(ExpectedBlocked))
+			Expect(scenario.Steps[0].Expected.Expect("y/c", "x/a", v1.ProtocolTCP, 80)).To(Equal(ExpectedAllowed))
+			Expect(scenario.Steps[1].Probe.Mode).To(BeEquivalentTo(ProbeModePodIP))
+			Expect(scenario.Steps[1].Actions[0].SetPodLabels.Labels).To(Equal(map[string]string{"pod": "a"}))
+
+			Expect(testCases[1].Tags).To(BeEmpty())
+		})
+
+		It("should reject invalid documents", func() {
+			for _, contents := range []string{
+				"apiVersion: v1\nkind: TestCase\ndescription: wrong version\nsteps: [{probe: {allAvailable: true}}]",
+				"apiVersion: policy-assistant.networking.k8s.io/v1alpha1\nkind: TestCase\ndescription: typo\nstep: [{probe: {allAvailable: true}}]",
+				"apiVersion: policy-assistant.networking.k8s.io/v1alpha1\nkind: TestCase\ndescription: no steps",
+				"apiVersion: policy-assistant.networking.k8s.io/v1alpha1\nkind: TestCase\ndescription: bad tag\ntags: [nope]\nsteps: [{probe: {allAvailable: true}}]",
+				"apiVersion: policy-assistant.networking.k8s.io/v1alpha1\nkind: TestCase\ndescription: empty action\nsteps: [{probe: {allAvailable: true}, actions: [{}]}]",
+				"apiVersion: policy-assistant.networking.k8s.io/v1alpha1\nkind: TestCase\ndescription: bad expected\nsteps: [{probe: {allAvailable: true}, expected: {default: maybe}}]",
+			} {
+				_, err := ParseTestCases([]byte(contents))
+				Expect(err).To(HaveOccurred(), contents)
+			}
+		})
+
+		It("should reject policies the simulation can't build", func() {
+			header := "apiVersion: policy-assistant.networking.k8s.io/v1alpha1\nkind: TestCase\ndescription: invalid policies\n"
+			anp := func(name string, priority int, peer string) string {
+				return fmt.Sprintf(`{createAdminPolicy: {policy: {metadata: {name: %s}, spec: {priority: %d, subject: {namespaces: {}}, ingress: [{action: Deny, from: [%s]}]}}}}`, name, priority, peer)
+			}
+			for contents, message := range map[string]string{
+				header + "steps: [{probe: {allAvailable: true}, actions: [{createPolicy: {}}]}]": "action is missing its policy",
+				header + "steps: [{probe: {allAvailable: true}, actions: [" + anp("a", 10, "{namespaces: {namespaceSelector: {}}}") + "]}, {probe: {allAvailable: true}, actions: [" + anp("b", 10, "{namespaces: {namespaceSelector: {}}}") + "]}]": "AdminNetworkPolicies a and b have the same priority 10",
+				header + "steps: [{probe: {allAvailable: true}, actions: [" + anp("a", 10, "{}") + "]}]":                                        "invalid admin peer: must have exactly one of Namespaces or Pods",
+				header + "steps: [{probe: {allAvailable: true}, actions: [" + anp("a", 10, "{pods: {namespaces: {}, podSelector: {}}}") + "]}]": "invalid admin peer: must have exactly one of NamespaceSelector, SameLabels, or NotSameLabels",
+			} {
+				_, err := ParseTestCases([]byte(contents))
+				Expect(err).To(HaveOccurred(), contents)
+				Expect(err.Error()).To(ContainSubstring(message))
+			}
+
+			// deleting an ANP frees its priority
+			_, err := ParseTestCases([]byte(header + "steps: [{probe: {allAvailable: true}, actions: [" + anp("a", 10, "{namespaces: {namespaceSelector: {}}}") + ", {deleteAdminPolicy: {name: a}}, " + anp("b", 10, "{namespaces: {namespaceSelector: {}}}") + "]}]"))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should write and read directories of test cases", func() {
+			dir, err := os.MkdirTemp("", "testcases")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			testCases := NewTestCaseGenerator(true, "1.2.3.4", []string{"x", "y", "z"}, []string{}, []string{}).ActionTestCases()
+			Expect(WriteTestCasesToDirectory(dir, testCases)).To(Succeed())
+			Expect(os.WriteFile(path.Join(dir, "README.md"), []byte("not a test case"), 0644)).To(Succeed())
+
+			read, err := ReadTestCasesFromDirectory(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(read).To(HaveLen(len(testCases)))
+			for i := range testCases {
+				Expect(read[i].Description).To(Equal(testCases[i].Description))
+			}
+		})
+	})
+	Describe("TestCaseID", func() {
+		It("should be stable across pod IPs, and unique among generated test cases", func() {
+			ids := map[string]bool{}
+			first := NewTestCaseGenerator(true, "1.2.3.4", []string{"x", "y", "z"}, []string{}, []string{}).GenerateAllTestCases()
+			// the pod IP which ipBlocks are built from changes whenever the pods are recreated
+			second := NewTestCaseGenerator(true, "5.6.7.8", []string{"x", "y", "z"}, []string{}, []string{}).GenerateAllTestCases()
+			for i, testCase := range first {
+				id, err := TestCaseID(testCase)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(TestCaseID(second[i])).To(Equal(id), testCase.Description)
+				Expect(ids).ToNot(HaveKey(id))
+				ids[id] = true
+			}
+
+			changed := first[0]
+			id, _ := TestCaseID(changed)
+			changed.Steps[0].Probe = NewAllAvailable(ProbeModePodIP)
+			Expect(TestCaseID(changed)).ToNot(Equal(id))
+		})
+	})
+}
@@ -70,14 +70,21 @@ func (t *TestCaseGenerator) GenerateAllTestCases() []*TestCase {
 		t.PortProtocolTestCases(),
 		t.ExampleTestCases(),
 		t.ActionTestCases(),
+		t.AdminActionTestCases(),
+		t.AdminTestCases(),
 		t.ConflictTestCases(),
 		t.NamespaceTestCases(),
 		t.UpstreamE2ETestCases())
 }
 
 func (t *TestCaseGenerator) GenerateTestCases() []*TestCase {
+	return t.SelectTestCases(t.GenerateAllTestCases())
+}
+
+// SelectTestCases filters test cases by the generator's included and excluded tags.
+func (t *TestCaseGenerator) SelectTestCases(testCases []*TestCase) []*TestCase {
 	var cases []*TestCase
-	for _, testcase := range t.GenerateAllTestCases() {
+	for _, testcase := range testCases {
 		if (len(t.Tags) == 0 || testcase.Tags.ContainsAny(t.Tags)) && !testcase.Tags.ContainsAny(t.ExcludedTags) {
 			cases = append(cases, testcase)
 		}
@@ -3,6 +3,9 @@ package generator
 import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
 )
 
 func RunTestCaseGeneratorTests() {
@@ -12,6 +15,8 @@ func RunTestCaseGeneratorTests() {
 
 			Expect(len(gen.PeersTestCases())).To(Equal(112))
 			Expect(len(gen.ActionTestCases())).To(Equal(6))
+			Expect(len(gen.AdminActionTestCases())).To(Equal(5))
+			Expect(len(gen.AdminTestCases())).To(Equal(38))
 			Expect(len(gen.RulesTestCases())).To(Equal(4))
 			Expect(len(gen.UpstreamE2ETestCases())).To(Equal(13))
 			Expect(len(gen.TargetTestCases())).To(Equal(6))
@@ -20,7 +25,34 @@ func RunTestCaseGeneratorTests() {
 			Expect(len(gen.ConflictTestCases())).To(Equal(16))
 			Expect(len(gen.NamespaceTestCases())).To(Equal(2))
 
-			Expect(len(gen.GenerateTestCases())).To(Equal(230))
+			Expect(len(gen.GenerateTestCases())).To(Equal(273))
+		})
+
+		It("Admin test cases can be simulated", func() {
+			gen := NewTestCaseGenerator(true, "1.2.3.4", []string{"x", "y", "z"}, []string{}, []string{})
+			for _, testCase := range append(gen.AdminActionTestCases(), gen.AdminTestCases()...) {
+				var netpols []*networkingv1.NetworkPolicy
+				anps := map[string]*v1alpha1.AdminNetworkPolicy{}
+				var banp *v1alpha1.BaselineAdminNetworkPolicy
+				for _, step := range testCase.Steps {
+					for _, action := range step.Actions {
+						if action.CreatePolicy != nil {
+							netpols = append(netpols, action.CreatePolicy.Policy)
+						} else if action.CreateAdminPolicy != nil {
+							anps[action.CreateAdminPolicy.Policy.Name] = action.CreateAdminPolicy.Policy
+						} else if action.UpdateAdminPolicy != nil {
+							anps[action.UpdateAdminPolicy.Policy.Name] = action.UpdateAdminPolicy.Policy
+						} else if action.CreateBaselineAdminPolicy != nil {
+							banp = action.CreateBaselineAdminPolicy.Policy
+						}
+					}
+					var anpSlice []*v1alpha1.AdminNetworkPolicy
+					for _, anp := range anps {
+						anpSlice = append(anpSlice, anp)
+					}
+					Expect(func() { matcher.BuildV1AndV2NetPols(true, netpols, anpSlice, banp) }).ToNot(Panic(), testCase.Description)
+				}
+			}
 		})
 	})
 }
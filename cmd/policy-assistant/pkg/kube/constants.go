@@ -2,4 +2,12 @@ package kube
 
 const (
 	DefaultNamespaceLabel = "kubernetes.io/metadata.name"
+
+	// DNSNamespace and DNSPodLabels identify the cluster's DNS pods, as deployed by kubeadm; DNS lookups
+	// are sent to them on DNSPort
+	DNSNamespace = "kube-system"
+	DNSPort      = 53
+	DNSPortName  = "dns"
 )
+
+var DNSPodLabels = map[string]string{"k8s-app": "kube-dns"}
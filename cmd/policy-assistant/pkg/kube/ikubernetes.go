@@ -3,7 +3,9 @@ package kube
 import (
 	"context"
 	"fmt"
+	"io"
 	"math/rand"
+	"sync"
 
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
@@ -46,8 +48,10 @@ type IKubernetes interface {
 	DeletePod(namespace string, pod string) error
 	SetPodLabels(namespace string, pod string, labels map[string]string) (*v1.Pod, error)
 	GetPodsInNamespace(namespace string) ([]v1.Pod, error)
+	AddEphemeralContainer(namespace string, pod string, container *v1.EphemeralContainer) (*v1.Pod, error)
 
 	ExecuteRemoteCommand(namespace string, pod string, container string, command []string) (string, string, error, error)
+	StreamRemoteCommand(namespace string, pod string, container string, command []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error
 }
 
 func GetNetworkPoliciesInNamespaces(ctx context.Context, kubernetes IKubernetes, namespaces []string) ([]networkingv1.NetworkPolicy, error) {
@@ -111,6 +115,10 @@ type MockNamespace struct {
 	Services        map[string]*v1.Service
 }
 
+// mockNodeIP is the IP of the mock's only node, which host network pods share
+const mockNodeIP = "172.18.0.2"
+
+// MockKubernetes is safe for concurrent use, so that several interpreters can share it.
 type MockKubernetes struct {
 	AdminNetworkPolicies        []v1alpha1.AdminNetworkPolicy
 	AdminNetworkPolicyError     error
@@ -120,6 +128,7 @@ type MockKubernetes struct {
 	NetworkPolicyError          error
 	passRate                    float64
 	podID                       int
+	lock                        sync.Mutex
 }
 
 func NewMockKubernetes(passRate float64) *MockKubernetes {
@@ -138,6 +147,13 @@ func (m *MockKubernetes) getNamespaceObject(namespace string) (*MockNamespace, e
 }
 
 func (m *MockKubernetes) GetNamespace(namespace string) (*v1.Namespace, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.getNamespace(namespace)
+}
+
+func (m *MockKubernetes) getNamespace(namespace string) (*v1.Namespace, error) {
 	if ns, ok := m.Namespaces[namespace]; ok {
 		labels := map[string]string{}
 		for k, v := range ns.NamespaceObject.Labels {
@@ -155,15 +171,21 @@ func (m *MockKubernetes) GetNamespace(namespace string) (*v1.Namespace, error) {
 }
 
 func (m *MockKubernetes) SetNamespaceLabels(namespace string, labels map[string]string) (*v1.Namespace, error) {
-	ns, err := m.GetNamespace(namespace)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	nsObject, err := m.getNamespaceObject(namespace)
 	if err != nil {
 		return nil, err
 	}
-	ns.Labels = labels
-	return ns, nil
+	nsObject.NamespaceObject.Labels = labels
+	return m.getNamespace(namespace)
 }
 
 func (m *MockKubernetes) DeleteNamespace(ns string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	if _, ok := m.Namespaces[ns]; !ok {
 		return errors.Errorf("namespace %s not found", ns)
 	}
@@ -172,9 +194,12 @@ func (m *MockKubernetes) DeleteNamespace(ns string) error {
 }
 
 func (m *MockKubernetes) GetAllNamespaces() (*v1.NamespaceList, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	var namespaces []v1.Namespace
 	for name := range m.Namespaces {
-		ns, err := m.GetNamespace(name)
+		ns, err := m.getNamespace(name)
 		utils.DoOrDie(err)
 		namespaces = append(namespaces, *ns)
 	}
@@ -184,6 +209,9 @@ func (m *MockKubernetes) GetAllNamespaces() (*v1.NamespaceList, error) {
 }
 
 func (m *MockKubernetes) CreateNamespace(ns *v1.Namespace) (*v1.Namespace, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	if _, ok := m.Namespaces[ns.Name]; ok {
 		return nil, errors.Errorf("namespace %s already present", ns.Name)
 	}
@@ -197,6 +225,9 @@ func (m *MockKubernetes) CreateNamespace(ns *v1.Namespace) (*v1.Namespace, error
 }
 
 func (m *MockKubernetes) DeleteAllNetworkPoliciesInNamespace(ns string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	nsObject, err := m.getNamespaceObject(ns)
 	if err != nil {
 		return err
@@ -206,6 +237,9 @@ func (m *MockKubernetes) DeleteAllNetworkPoliciesInNamespace(ns string) error {
 }
 
 func (m *MockKubernetes) DeleteNetworkPolicy(ns string, name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	nsObject, err := m.getNamespaceObject(ns)
 	if err != nil {
 		return err
@@ -218,6 +252,9 @@ func (m *MockKubernetes) DeleteNetworkPolicy(ns string, name string) error {
 }
 
 func (m *MockKubernetes) GetNetworkPoliciesInNamespace(ctx context.Context, namespace string) ([]networkingv1.NetworkPolicy, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	if m.NetworkPolicyError != nil {
 		return nil, m.NetworkPolicyError
 	}
@@ -234,6 +271,9 @@ func (m *MockKubernetes) GetNetworkPoliciesInNamespace(ctx context.Context, name
 }
 
 func (m *MockKubernetes) UpdateNetworkPolicy(policy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	nsObject, err := m.getNamespaceObject(policy.Namespace)
 	if err != nil {
 		return nil, err
@@ -246,6 +286,9 @@ func (m *MockKubernetes) UpdateNetworkPolicy(policy *networkingv1.NetworkPolicy)
 }
 
 func (m *MockKubernetes) CreateNetworkPolicy(policy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	nsObject, err := m.getNamespaceObject(policy.Namespace)
 	if err != nil {
 		return nil, err
@@ -258,6 +301,9 @@ func (m *MockKubernetes) CreateNetworkPolicy(policy *networkingv1.NetworkPolicy)
 }
 
 func (m *MockKubernetes) GetService(namespace string, name string) (*v1.Service, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	nsObject, err := m.getNamespaceObject(namespace)
 	if err != nil {
 		return nil, err
@@ -269,6 +315,9 @@ func (m *MockKubernetes) GetService(namespace string, name string) (*v1.Service,
 }
 
 func (m *MockKubernetes) CreateService(svc *v1.Service) (*v1.Service, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	nsObject, err := m.getNamespaceObject(svc.Namespace)
 	if err != nil {
 		return nil, err
@@ -281,6 +330,9 @@ func (m *MockKubernetes) CreateService(svc *v1.Service) (*v1.Service, error) {
 }
 
 func (m *MockKubernetes) DeleteService(namespace string, name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	nsObject, err := m.getNamespaceObject(namespace)
 	if err != nil {
 		return err
@@ -293,6 +345,9 @@ func (m *MockKubernetes) DeleteService(namespace string, name string) error {
 }
 
 func (m *MockKubernetes) GetServicesInNamespace(namespace string) ([]v1.Service, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	nsObject, err := m.getNamespaceObject(namespace)
 	if err != nil {
 		return nil, err
@@ -305,6 +360,9 @@ func (m *MockKubernetes) GetServicesInNamespace(namespace string) ([]v1.Service,
 }
 
 func (m *MockKubernetes) GetPodsInNamespace(namespace string) ([]v1.Pod, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	var pods []v1.Pod
 	nsObject, err := m.getNamespaceObject(namespace)
 	if err != nil {
@@ -317,6 +375,13 @@ func (m *MockKubernetes) GetPodsInNamespace(namespace string) ([]v1.Pod, error)
 }
 
 func (m *MockKubernetes) GetPod(namespace string, podName string) (*v1.Pod, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.getPod(namespace, podName)
+}
+
+func (m *MockKubernetes) getPod(namespace string, podName string) (*v1.Pod, error) {
 	nsObject, err := m.getNamespaceObject(namespace)
 	if err != nil {
 		return nil, err
@@ -328,7 +393,10 @@ func (m *MockKubernetes) GetPod(namespace string, podName string) (*v1.Pod, erro
 }
 
 func (m *MockKubernetes) SetPodLabels(namespace string, podName string, labels map[string]string) (*v1.Pod, error) {
-	pod, err := m.GetPod(namespace, podName)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	pod, err := m.getPod(namespace, podName)
 	if err != nil {
 		return nil, err
 	}
@@ -337,6 +405,9 @@ func (m *MockKubernetes) SetPodLabels(namespace string, podName string, labels m
 }
 
 func (m *MockKubernetes) CreatePod(pod *v1.Pod) (*v1.Pod, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	nsObject, err := m.getNamespaceObject(pod.Namespace)
 	if err != nil {
 		return nil, err
@@ -348,13 +419,43 @@ func (m *MockKubernetes) CreatePod(pod *v1.Pod) (*v1.Pod, error) {
 		panic(errors.Errorf("unable to handle more than 254 pods in mock"))
 	}
 	pod.Status.Phase = v1.PodRunning
-	pod.Status.PodIP = fmt.Sprintf("192.168.1.%d", m.podID)
-	m.podID++
+	pod.Status.HostIP = mockNodeIP
+	if pod.Spec.HostNetwork {
+		pod.Status.PodIP = mockNodeIP
+	} else {
+		pod.Status.PodIP = fmt.Sprintf("192.168.1.%d", m.podID)
+		m.podID++
+	}
 	nsObject.Pods[pod.Name] = pod
 	return pod, nil
 }
 
+// AddEphemeralContainer reports the new container as running immediately.
+func (m *MockKubernetes) AddEphemeralContainer(namespace string, podName string, container *v1.EphemeralContainer) (*v1.Pod, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	pod, err := m.getPod(namespace, podName)
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range pod.Spec.EphemeralContainers {
+		if existing.Name == container.Name {
+			return nil, errors.Errorf("ephemeral container %s already exists in pod %s/%s", container.Name, namespace, podName)
+		}
+	}
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, *container)
+	pod.Status.EphemeralContainerStatuses = append(pod.Status.EphemeralContainerStatuses, v1.ContainerStatus{
+		Name:  container.Name,
+		State: v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+	})
+	return pod, nil
+}
+
 func (m *MockKubernetes) DeletePod(namespace string, podName string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	nsObject, err := m.getNamespaceObject(namespace)
 	if err != nil {
 		return err
@@ -367,6 +468,9 @@ func (m *MockKubernetes) DeletePod(namespace string, podName string) error {
 }
 
 func (m *MockKubernetes) ExecuteRemoteCommand(namespace string, pod string, container string, command []string) (string, string, error, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	nsObject, err := m.getNamespaceObject(namespace)
 	if err != nil {
 		return "", "", nil, err
@@ -394,36 +498,98 @@ func (m *MockKubernetes) ExecuteRemoteCommand(namespace string, pod string, cont
 	return "", "", nil, nil
 }
 
+// StreamRemoteCommand isn't supported by the mock, since it has no way of running the command.
+func (m *MockKubernetes) StreamRemoteCommand(namespace string, pod string, container string, command []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	return ErrNotImplemented
+}
+
 func (m *MockKubernetes) GetAdminNetworkPolicies(ctx context.Context) ([]v1alpha1.AdminNetworkPolicy, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	return m.AdminNetworkPolicies, m.AdminNetworkPolicyError
 }
 
-func (k *MockKubernetes) CreateAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.AdminNetworkPolicy) (*v1alpha1.AdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+func (m *MockKubernetes) findAdminNetworkPolicy(name string) int {
+	for i, anp := range m.AdminNetworkPolicies {
+		if anp.Name == name {
+			return i
+		}
+	}
+	return -1
 }
 
-func (k *MockKubernetes) UpdateAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.AdminNetworkPolicy) (*v1alpha1.AdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+func (m *MockKubernetes) CreateAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.AdminNetworkPolicy) (*v1alpha1.AdminNetworkPolicy, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.findAdminNetworkPolicy(policy.Name) >= 0 {
+		return nil, errors.Errorf("admin network policy %s already present", policy.Name)
+	}
+	m.AdminNetworkPolicies = append(m.AdminNetworkPolicies, *policy)
+	return policy, nil
 }
 
-func (k *MockKubernetes) DeleteAdminNetworkPolicy(ctx context.Context, name string) error {
-	//TODO: implement
-	return ErrNotImplemented
+func (m *MockKubernetes) UpdateAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.AdminNetworkPolicy) (*v1alpha1.AdminNetworkPolicy, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	index := m.findAdminNetworkPolicy(policy.Name)
+	if index < 0 {
+		return nil, errors.Errorf("admin network policy %s not found", policy.Name)
+	}
+	m.AdminNetworkPolicies[index] = *policy
+	return policy, nil
+}
+
+func (m *MockKubernetes) DeleteAdminNetworkPolicy(ctx context.Context, name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	index := m.findAdminNetworkPolicy(name)
+	if index < 0 {
+		return errors.Errorf("admin network policy %s not found", name)
+	}
+	m.AdminNetworkPolicies = append(m.AdminNetworkPolicies[:index], m.AdminNetworkPolicies[index+1:]...)
+	return nil
 }
 
 func (m *MockKubernetes) GetBaselineAdminNetworkPolicy(ctx context.Context) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
 	return m.BaselineNetworkPolicy, m.BaseAdminNetworkPolicyError
 }
 
-func (k *MockKubernetes) CreateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.BaselineAdminNetworkPolicy) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+func (m *MockKubernetes) CreateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.BaselineAdminNetworkPolicy) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.BaselineNetworkPolicy != nil {
+		return nil, errors.Errorf("baseline admin network policy %s already present", m.BaselineNetworkPolicy.Name)
+	}
+	m.BaselineNetworkPolicy = policy
+	return policy, nil
 }
 
-func (k *MockKubernetes) UpdateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.BaselineAdminNetworkPolicy) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+func (m *MockKubernetes) UpdateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.BaselineAdminNetworkPolicy) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.BaselineNetworkPolicy == nil || m.BaselineNetworkPolicy.Name != policy.Name {
+		return nil, errors.Errorf("baseline admin network policy %s not found", policy.Name)
+	}
+	m.BaselineNetworkPolicy = policy
+	return policy, nil
 }
 
-func (k *MockKubernetes) DeleteBaselineAdminNetworkPolicy(ctx context.Context, name string) error {
-	//TODO: implement
-	return ErrNotImplemented
+func (m *MockKubernetes) DeleteBaselineAdminNetworkPolicy(ctx context.Context, name string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.BaselineNetworkPolicy == nil || m.BaselineNetworkPolicy.Name != name {
+		return errors.Errorf("baseline admin network policy %s not found", name)
+	}
+	m.BaselineNetworkPolicy = nil
+	return nil
 }
@@ -0,0 +1,816 @@
+package inmemory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/worker"
+)
+
+const clusterDomainSuffix = ".svc.cluster.local"
+
+// Policies are all the policies in the cluster, of every kind.
+type Policies struct {
+	NetworkPolicies            []*networkingv1.NetworkPolicy
+	AdminNetworkPolicies       []*v1alpha1.AdminNetworkPolicy
+	BaselineAdminNetworkPolicy *v1alpha1.BaselineAdminNetworkPolicy
+}
+
+// Engine decides whether the cluster's policies allow traffic.
+type Engine interface {
+	IsTrafficAllowed(policies *Policies, traffic *matcher.Traffic) bool
+}
+
+// MatcherEngine is the reference engine: the same one that the simulation uses.
+type MatcherEngine struct {
+	Simplify bool
+}
+
+func (e *MatcherEngine) IsTrafficAllowed(policies *Policies, traffic *matcher.Traffic) bool {
+	policy := matcher.BuildV1AndV2NetPols(e.Simplify, policies.NetworkPolicies, policies.AdminNetworkPolicies, policies.BaselineAdminNetworkPolicy)
+	return policy.IsTrafficAllowed(traffic).IsAllowed()
+}
+
+// Faults are injected into probes, to check that a misbehaving network is noticed.
+type Faults struct {
+	// Delay is added to every probe; probes whose timeout is shorter than Delay time out
+	Delay time.Duration
+	// DropRate is the probability that a probe of allowed traffic times out anyway
+	DropRate float64
+	// MisenforceRate is the probability that a probe gets the opposite of the engine's decision
+	MisenforceRate float64
+}
+
+// Cluster stores namespaces, pods, services and policies in memory, like kube.MockKubernetes, but decides
+// the outcome of each probe -- an agnhost, curl or dig command, or a batch of worker requests -- by
+// evaluating the stored policies with its Engine, instead of at random.  Servers are assumed to listen
+// on every port declared by their containers, and to answer http requests with a 200.
+//
+// DNS lookups are sent to DNS pods which aren't stored, labeled like kubeadm's, so policies can block them
+// like any other traffic; lookups which get through resolve any service or IP.
+//
+// Services forward each connection to one of their endpoints, taking turns for each client, and headless
+// services resolve to them likewise.  NodePort services are reachable on any pod's host IP.
+//
+// Traffic to an IP which isn't a pod's or a service's, or to a host network pod, is treated as leaving
+// the cluster: policies see only the destination's IP, and anything outside the cluster is assumed to
+// be listening.  Other hostnames are resolved locally.
+type Cluster struct {
+	*kube.MockKubernetes
+	Engine Engine
+	Faults *Faults
+
+	lock      sync.Mutex
+	random    *rand.Rand
+	serviceID int
+	nodePort  int
+	// turns counts the connections from each pod to each service, to rotate through its endpoints
+	turns map[string]int
+}
+
+func NewCluster(engine Engine, faults *Faults) *Cluster {
+	if faults == nil {
+		faults = &Faults{}
+	}
+	return &Cluster{
+		MockKubernetes: kube.NewMockKubernetes(1.0),
+		Engine:         engine,
+		Faults:         faults,
+		random:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		serviceID:      1,
+		nodePort:       30000,
+		turns:          map[string]int{},
+	}
+}
+
+func NewDefaultCluster() *Cluster {
+	return NewCluster(&MatcherEngine{Simplify: true}, nil)
+}
+
+// CreateService allocates a cluster IP, unless the service is headless, and node ports for a NodePort
+// service.
+func (c *Cluster) CreateService(svc *v1.Service) (*v1.Service, error) {
+	svc = svc.DeepCopy()
+	c.lock.Lock()
+	if svc.Spec.ClusterIP == "" {
+		if c.serviceID >= 255 {
+			c.lock.Unlock()
+			return nil, errors.Errorf("unable to handle more than 254 services in in-memory cluster")
+		}
+		svc.Spec.ClusterIP = fmt.Sprintf("10.96.0.%d", c.serviceID)
+		c.serviceID++
+	}
+	if svc.Spec.Type == v1.ServiceTypeNodePort {
+		for i := range svc.Spec.Ports {
+			if svc.Spec.Ports[i].NodePort == 0 {
+				svc.Spec.Ports[i].NodePort = int32(c.nodePort)
+				c.nodePort++
+			}
+		}
+	}
+	c.lock.Unlock()
+	return c.MockKubernetes.CreateService(svc)
+}
+
+// CreateAdminNetworkPolicy rejects policies which the Engine can't evaluate alongside the others -- e.g.
+// an ANP at the priority of an existing one, since their order is undefined -- instead of failing every
+// probe afterwards.
+func (c *Cluster) CreateAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.AdminNetworkPolicy) (*v1alpha1.AdminNetworkPolicy, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := c.validateAdminPolicies(policy, nil); err != nil {
+		return nil, err
+	}
+	return c.MockKubernetes.CreateAdminNetworkPolicy(ctx, policy)
+}
+
+func (c *Cluster) UpdateAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.AdminNetworkPolicy) (*v1alpha1.AdminNetworkPolicy, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := c.validateAdminPolicies(policy, nil); err != nil {
+		return nil, err
+	}
+	return c.MockKubernetes.UpdateAdminNetworkPolicy(ctx, policy)
+}
+
+func (c *Cluster) CreateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.BaselineAdminNetworkPolicy) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := c.validateAdminPolicies(nil, policy); err != nil {
+		return nil, err
+	}
+	return c.MockKubernetes.CreateBaselineAdminNetworkPolicy(ctx, policy)
+}
+
+func (c *Cluster) UpdateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha1.BaselineAdminNetworkPolicy) (*v1alpha1.BaselineAdminNetworkPolicy, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := c.validateAdminPolicies(nil, policy); err != nil {
+		return nil, err
+	}
+	return c.MockKubernetes.UpdateBaselineAdminNetworkPolicy(ctx, policy)
+}
+
+// validateAdminPolicies checks the admin policies the cluster would have with anp created or updated, or
+// with banp replacing the current BANP, returning an Invalid API error for the changed policy if they
+// can't be built.
+func (c *Cluster) validateAdminPolicies(anp *v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy) error {
+	current, err := c.MockKubernetes.GetAdminNetworkPolicies(context.TODO())
+	if err != nil {
+		return err
+	}
+	var anps []*v1alpha1.AdminNetworkPolicy
+	for i := range current {
+		if anp == nil || current[i].Name != anp.Name {
+			anps = append(anps, &current[i])
+		}
+	}
+	var kind, name string
+	if anp != nil {
+		anps = append(anps, anp)
+		kind, name = "AdminNetworkPolicy", anp.Name
+	}
+	if banp != nil {
+		kind, name = "BaselineAdminNetworkPolicy", banp.Name
+	} else {
+		banp, err = c.MockKubernetes.GetBaselineAdminNetworkPolicy(context.TODO())
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := matcher.ValidateAdminPolicies(anps, banp); err != nil {
+		return kerrors.NewInvalid(
+			schema.GroupKind{Group: v1alpha1.GroupName, Kind: kind},
+			name,
+			field.ErrorList{field.Invalid(field.NewPath("spec"), field.OmitValueType{}, err.Error())})
+	}
+	return nil
+}
+
+func (c *Cluster) ExecuteRemoteCommand(namespace string, pod string, container string, command []string) (string, string, error, error) {
+	from, err := c.getContainerPod(namespace, pod, container)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	if len(command) > 0 && command[0] == "/worker" {
+		batch, err := parseWorkerCommand(command)
+		if err != nil {
+			return "", "", nil, err
+		}
+		state, err := c.readState()
+		if err != nil {
+			return "", "", nil, err
+		}
+		var results []*worker.Result
+		for _, request := range batch.Requests {
+			results = append(results, c.issue(state, from, request))
+		}
+		bytes, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return "", "", nil, errors.Wrapf(err, "unable to marshal json")
+		}
+		return string(bytes), "", nil, nil
+	}
+
+	request, err := parseRequestCommand(command)
+	if err != nil {
+		return "", "", nil, err
+	}
+	state, err := c.readState()
+	if err != nil {
+		return "", "", nil, err
+	}
+	output, commandErr := c.respond(state, from, request)
+	return output, "", commandErr, nil
+}
+
+// StreamRemoteCommand runs a worker server whose requests are decided like those of ExecuteRemoteCommand.
+func (c *Cluster) StreamRemoteCommand(namespace string, pod string, container string, command []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	from, err := c.getContainerPod(namespace, pod, container)
+	if err != nil {
+		return err
+	}
+	if len(command) < 2 || command[0] != "/worker" || command[1] != "--serve" {
+		return errors.Errorf("unsupported command %+v", command)
+	}
+
+	server := worker.NewServer(10)
+	server.Issue = func(r *worker.Request) *worker.Result {
+		state, err := c.readState()
+		if err != nil {
+			return &worker.Result{Request: r, Error: err.Error(), Outcome: worker.OutcomeFailed}
+		}
+		return c.issue(state, from, r)
+	}
+	return server.Serve(stdin, stdout)
+}
+
+func (c *Cluster) getContainerPod(namespace string, podName string, container string) (*v1.Pod, error) {
+	pod, err := c.GetPod(namespace, podName)
+	if err != nil {
+		return nil, err
+	}
+	for _, cont := range pod.Spec.Containers {
+		if cont.Name == container {
+			return pod, nil
+		}
+	}
+	for _, cont := range pod.Spec.EphemeralContainers {
+		if cont.Name == container {
+			return pod, nil
+		}
+	}
+	return nil, errors.Errorf("container %s/%s/%s not found", namespace, podName, container)
+}
+
+// issue builds a worker result the way the worker does, from the output of the command.
+func (c *Cluster) issue(state *clusterState, from *v1.Pod, r *worker.Request) *worker.Result {
+	output, err := c.respond(state, from, r)
+	outcome := r.Classify(output, err)
+	var errString string
+	if err != nil {
+		errString = err.Error()
+	} else if outcome != worker.OutcomeSuccess {
+		errString = fmt.Sprintf("%s from command '%s'", outcome, strings.Join(r.Command(), " "))
+	}
+	return &worker.Result{Request: r, Output: output, Error: errString, Outcome: outcome}
+}
+
+type response string
+
+const (
+	responseSuccess    response = "success"
+	responseRefused    response = "refused"
+	responseTimeout    response = "timeout"
+	responseUnresolved response = "unresolved"
+)
+
+// respond returns what the request's command would print, and the error from running it.
+func (c *Cluster) respond(state *clusterState, from *v1.Pod, r *worker.Request) (string, error) {
+	if r.Kind == worker.RequestKindDNS {
+		if c.decideDNS(state, from) == responseTimeout {
+			return ";; connection timed out; no servers could be reached", errors.Errorf("command terminated with exit code 9")
+		}
+		if _, _, err := state.resolve(r.Host, r.Port, r.Protocol); err != nil && net.ParseIP(r.Host) == nil {
+			return ";; ->>HEADER<<- opcode: QUERY, status: NXDOMAIN", nil
+		}
+		return ";; ->>HEADER<<- opcode: QUERY, status: NOERROR", nil
+	}
+
+	resp := c.decide(state, from, r)
+	if c.Faults.Delay > 0 {
+		timeout := time.Duration(r.TimeoutSeconds) * time.Second
+		if r.TimeoutSeconds <= 0 {
+			timeout = time.Second
+		}
+		if c.Faults.Delay >= timeout {
+			time.Sleep(timeout)
+			resp = responseTimeout
+		} else {
+			time.Sleep(c.Faults.Delay)
+		}
+	}
+	logrus.Tracef("in-memory cluster: %s/%s -> %s: %s", from.Namespace, from.Name, r.Address(), resp)
+
+	exitErr := errors.Errorf("command terminated with exit code 1")
+	switch r.Kind {
+	case worker.RequestKindHTTP, worker.RequestKindTLS:
+		switch resp {
+		case responseSuccess:
+			if r.Kind == worker.RequestKindHTTP {
+				return "200", nil
+			}
+			return "", nil
+		case responseRefused:
+			return fmt.Sprintf("curl: (7) Failed to connect to %s port %d: Connection refused", r.Host, r.Port), exitErr
+		case responseTimeout:
+			return fmt.Sprintf("curl: (28) Connection timed out after %d milliseconds", r.TimeoutSeconds*1000), exitErr
+		default:
+			return fmt.Sprintf("curl: (6) Could not resolve host: %s", r.Host), exitErr
+		}
+	default:
+		switch resp {
+		case responseSuccess:
+			return "", nil
+		case responseRefused:
+			return "REFUSED", exitErr
+		case responseTimeout:
+			return "TIMEOUT", exitErr
+		default:
+			return fmt.Sprintf("DNS: unable to resolve %s", r.Host), exitErr
+		}
+	}
+}
+
+// decide applies the engine's decision and the faults to a request: traffic blocked by a policy is
+// dropped, so it times out, while allowed traffic to a port without a server is refused.
+func (c *Cluster) decide(state *clusterState, from *v1.Pod, r *worker.Request) response {
+	if ip, ok := state.externalIP(r.Host, r.Port, r.Protocol); ok {
+		return c.decideExternal(state, from, ip, r)
+	}
+	service, endpoints, err := state.resolve(r.Host, r.Port, r.Protocol)
+	if err != nil {
+		logrus.Debugf("in-memory cluster: %+v", err)
+		return responseUnresolved
+	}
+	if len(endpoints) == 0 {
+		// a service without endpoints rejects connections
+		return responseRefused
+	}
+	to, port := endpoints[0].pod, endpoints[0].port
+	if service != "" {
+		endpoint := endpoints[c.turn(from.Namespace+"/"+from.Name+" "+service)%len(endpoints)]
+		to, port = endpoint.pod, endpoint.port
+	}
+
+	allowed := true
+	// traffic from a pod to itself isn't subject to policies
+	if from.Namespace != to.Namespace || from.Name != to.Name {
+		allowed = c.Engine.IsTrafficAllowed(state.policies, state.traffic(from, to, port, r.Protocol))
+	}
+	if c.chance(c.Faults.MisenforceRate) {
+		allowed = !allowed
+	}
+	if !allowed || c.chance(c.Faults.DropRate) {
+		return responseTimeout
+	}
+	if _, ok := servedPortName(to, port, r.Protocol); !ok {
+		return responseRefused
+	}
+	return responseSuccess
+}
+
+// decideDNS decides whether a lookup reaches the DNS pods.
+func (c *Cluster) decideDNS(state *clusterState, from *v1.Pod) response {
+	allowed := c.Engine.IsTrafficAllowed(state.policies, &matcher.Traffic{
+		Source: state.source(from),
+		Destination: &matcher.TrafficPeer{
+			Internal: &matcher.InternalPeer{
+				PodLabels:       kube.DNSPodLabels,
+				NamespaceLabels: map[string]string{kube.DefaultNamespaceLabel: kube.DNSNamespace},
+				Namespace:       kube.DNSNamespace,
+			},
+		},
+		ResolvedPort:     kube.DNSPort,
+		ResolvedPortName: kube.DNSPortName,
+		Protocol:         v1.ProtocolUDP,
+	})
+	if c.chance(c.Faults.MisenforceRate) {
+		allowed = !allowed
+	}
+	if !allowed || c.chance(c.Faults.DropRate) {
+		return responseTimeout
+	}
+	return responseSuccess
+}
+
+func (c *Cluster) decideExternal(state *clusterState, from *v1.Pod, ip string, r *worker.Request) response {
+	allowed := c.Engine.IsTrafficAllowed(state.policies, &matcher.Traffic{
+		Source:       state.source(from),
+		Destination:  &matcher.TrafficPeer{IP: ip},
+		ResolvedPort: r.Port,
+		Protocol:     r.Protocol,
+	})
+	if c.chance(c.Faults.MisenforceRate) {
+		allowed = !allowed
+	}
+	if !allowed || c.chance(c.Faults.DropRate) {
+		return responseTimeout
+	}
+	for _, pod := range state.pods {
+		if pod.Spec.HostNetwork && pod.Status.PodIP == ip {
+			if _, ok := servedPortName(pod, r.Port, r.Protocol); !ok {
+				return responseRefused
+			}
+		}
+	}
+	return responseSuccess
+}
+
+func (c *Cluster) turn(key string) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	turn := c.turns[key]
+	c.turns[key]++
+	return turn
+}
+
+func (c *Cluster) chance(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.random.Float64() < rate
+}
+
+// clusterState is a snapshot of everything needed to decide requests.
+type clusterState struct {
+	namespaceLabels map[string]map[string]string
+	pods            []*v1.Pod
+	services        []*v1.Service
+	policies        *Policies
+}
+
+func (c *Cluster) readState() (*clusterState, error) {
+	namespaces, err := c.GetAllNamespaces()
+	if err != nil {
+		return nil, err
+	}
+	state := &clusterState{namespaceLabels: map[string]map[string]string{}, policies: &Policies{}}
+	for _, ns := range namespaces.Items {
+		state.namespaceLabels[ns.Name] = ns.Labels
+
+		pods, err := c.GetPodsInNamespace(ns.Name)
+		if err != nil {
+			return nil, err
+		}
+		for i := range pods {
+			state.pods = append(state.pods, &pods[i])
+		}
+		services, err := c.GetServicesInNamespace(ns.Name)
+		if err != nil {
+			return nil, err
+		}
+		for i := range services {
+			state.services = append(state.services, &services[i])
+		}
+		netpols, err := c.GetNetworkPoliciesInNamespace(context.TODO(), ns.Name)
+		if err != nil {
+			return nil, err
+		}
+		for i := range netpols {
+			state.policies.NetworkPolicies = append(state.policies.NetworkPolicies, &netpols[i])
+		}
+	}
+	anps, err := c.GetAdminNetworkPolicies(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	for i := range anps {
+		state.policies.AdminNetworkPolicies = append(state.policies.AdminNetworkPolicies, anps[i].DeepCopy())
+	}
+	banp, err := c.GetBaselineAdminNetworkPolicy(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	if banp != nil {
+		state.policies.BaselineAdminNetworkPolicy = banp.DeepCopy()
+	}
+
+	sort.Slice(state.pods, func(i, j int) bool {
+		return state.pods[i].Namespace+"/"+state.pods[i].Name < state.pods[j].Namespace+"/"+state.pods[j].Name
+	})
+	return state, nil
+}
+
+type endpoint struct {
+	pod  *v1.Pod
+	port int
+}
+
+// resolve finds the pods and ports that traffic to host:port may be delivered to: host may be a pod IP,
+// a service IP, a node IP -- for a NodePort service -- or a service name.  For a service, its key is
+// returned too.  No endpoints, without an error, means a service without endpoints.
+func (s *clusterState) resolve(host string, port int, protocol v1.Protocol) (string, []*endpoint, error) {
+	if net.ParseIP(host) != nil {
+		for _, pod := range s.pods {
+			if pod.Status.PodIP == host && !pod.Spec.HostNetwork {
+				return "", []*endpoint{{pod: pod, port: port}}, nil
+			}
+		}
+		for _, svc := range s.services {
+			if svc.Spec.ClusterIP == host {
+				return svc.Namespace + "/" + svc.Name, s.serviceEndpoints(svc, servicePortFor(svc, port, protocol, false), protocol), nil
+			}
+		}
+		if svc, servicePort := s.nodePortService(host, port, protocol); svc != nil {
+			return svc.Namespace + "/" + svc.Name, s.serviceEndpoints(svc, servicePort, protocol), nil
+		}
+		return "", nil, errors.Errorf("no pod or service found with IP %s", host)
+	}
+
+	parts := strings.Split(strings.TrimSuffix(host, clusterDomainSuffix), ".")
+	if !strings.HasSuffix(host, clusterDomainSuffix) || len(parts) != 2 {
+		return "", nil, errors.Errorf("unable to resolve host %s", host)
+	}
+	for _, svc := range s.services {
+		if svc.Name == parts[0] && svc.Namespace == parts[1] {
+			if svc.Spec.ClusterIP == v1.ClusterIPNone {
+				// the name of a headless service resolves to its endpoints' IPs, so there's no port mapping
+				return svc.Namespace + "/" + svc.Name, s.serviceEndpoints(svc, &v1.ServicePort{Port: int32(port), Protocol: protocol}, protocol), nil
+			}
+			return svc.Namespace + "/" + svc.Name, s.serviceEndpoints(svc, servicePortFor(svc, port, protocol, false), protocol), nil
+		}
+	}
+	return "", nil, errors.Errorf("service %s/%s not found", parts[1], parts[0])
+}
+
+// nodePortService finds the NodePort service reached by traffic to a node's port.
+func (s *clusterState) nodePortService(host string, port int, protocol v1.Protocol) (*v1.Service, *v1.ServicePort) {
+	isNode := false
+	for _, pod := range s.pods {
+		if pod.Status.HostIP == host {
+			isNode = true
+			break
+		}
+	}
+	if !isNode {
+		return nil, nil
+	}
+	for _, svc := range s.services {
+		if svc.Spec.Type != v1.ServiceTypeNodePort {
+			continue
+		}
+		if servicePort := servicePortFor(svc, port, protocol, true); servicePort != nil {
+			return svc, servicePort
+		}
+	}
+	return nil, nil
+}
+
+// externalIP returns the IP of host if traffic to it leaves the cluster.
+func (s *clusterState) externalIP(host string, port int, protocol v1.Protocol) (string, bool) {
+	if net.ParseIP(host) == nil {
+		if strings.HasSuffix(host, clusterDomainSuffix) {
+			return "", false
+		}
+		ips, err := net.LookupIP(host)
+		if err != nil || len(ips) == 0 {
+			return "", false
+		}
+		host = ips[0].String()
+	}
+	for _, pod := range s.pods {
+		if pod.Status.PodIP == host && !pod.Spec.HostNetwork {
+			return "", false
+		}
+	}
+	for _, svc := range s.services {
+		if svc.Spec.ClusterIP == host {
+			return "", false
+		}
+	}
+	if svc, _ := s.nodePortService(host, port, protocol); svc != nil {
+		return "", false
+	}
+	return host, true
+}
+
+// servicePortFor finds the service's port -- or node port -- for traffic to port.
+func servicePortFor(svc *v1.Service, port int, protocol v1.Protocol, nodePort bool) *v1.ServicePort {
+	for i, sp := range svc.Spec.Ports {
+		spProtocol := sp.Protocol
+		if spProtocol == "" {
+			spProtocol = v1.ProtocolTCP
+		}
+		spPort := sp.Port
+		if nodePort {
+			spPort = sp.NodePort
+		}
+		if int(spPort) == port && spProtocol == protocol {
+			return &svc.Spec.Ports[i]
+		}
+	}
+	return nil
+}
+
+// serviceEndpoints finds the ready pods selected by the service, and resolves their target ports.
+func (s *clusterState) serviceEndpoints(svc *v1.Service, servicePort *v1.ServicePort, protocol v1.Protocol) []*endpoint {
+	if servicePort == nil || len(svc.Spec.Selector) == 0 {
+		return nil
+	}
+
+	var endpoints []*endpoint
+	selector := labels.SelectorFromSet(svc.Spec.Selector)
+	for _, pod := range s.pods {
+		if pod.Namespace != svc.Namespace || pod.Status.PodIP == "" || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		switch {
+		case servicePort.TargetPort.Type == intstr.String:
+			if targetPort, ok := namedPort(pod, servicePort.TargetPort.StrVal, protocol); ok {
+				endpoints = append(endpoints, &endpoint{pod: pod, port: targetPort})
+			}
+		case servicePort.TargetPort.IntVal != 0:
+			endpoints = append(endpoints, &endpoint{pod: pod, port: int(servicePort.TargetPort.IntVal)})
+		default:
+			endpoints = append(endpoints, &endpoint{pod: pod, port: int(servicePort.Port)})
+		}
+	}
+	return endpoints
+}
+
+func (s *clusterState) traffic(from *v1.Pod, to *v1.Pod, port int, protocol v1.Protocol) *matcher.Traffic {
+	portName, _ := servedPortName(to, port, protocol)
+	return &matcher.Traffic{
+		Source: s.source(from),
+		Destination: &matcher.TrafficPeer{
+			Internal: &matcher.InternalPeer{
+				PodLabels:       to.Labels,
+				NamespaceLabels: s.namespaceLabels[to.Namespace],
+				Namespace:       to.Namespace,
+			},
+			IP: to.Status.PodIP,
+		},
+		ResolvedPort:     port,
+		ResolvedPortName: portName,
+		Protocol:         protocol,
+	}
+}
+
+func (s *clusterState) source(from *v1.Pod) *matcher.TrafficPeer {
+	return &matcher.TrafficPeer{
+		Internal: &matcher.InternalPeer{
+			PodLabels:       from.Labels,
+			NamespaceLabels: s.namespaceLabels[from.Namespace],
+			Namespace:       from.Namespace,
+		},
+		IP: from.Status.PodIP,
+	}
+}
+
+// servedPortName looks up a port declared by one of the pod's containers, returning its name.
+func servedPortName(pod *v1.Pod, port int, protocol v1.Protocol) (string, bool) {
+	for _, cont := range pod.Spec.Containers {
+		for _, cp := range cont.Ports {
+			cpProtocol := cp.Protocol
+			if cpProtocol == "" {
+				cpProtocol = v1.ProtocolTCP
+			}
+			if int(cp.ContainerPort) == port && cpProtocol == protocol {
+				return cp.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+func namedPort(pod *v1.Pod, name string, protocol v1.Protocol) (int, bool) {
+	for _, cont := range pod.Spec.Containers {
+		for _, cp := range cont.Ports {
+			if cp.Name == name && (cp.Protocol == protocol || (cp.Protocol == "" && protocol == v1.ProtocolTCP)) {
+				return int(cp.ContainerPort), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// parseWorkerCommand parses the batch from a one-shot worker command, as built by worker.Client.
+func parseWorkerCommand(command []string) (*worker.Batch, error) {
+	var jobs string
+	for i, arg := range command {
+		if arg == "--jobs" && i+1 < len(command) {
+			jobs = command[i+1]
+		} else if strings.HasPrefix(arg, "--jobs=") {
+			jobs = strings.TrimPrefix(arg, "--jobs=")
+		}
+	}
+	var batch worker.Batch
+	if err := json.Unmarshal([]byte(jobs), &batch); err != nil {
+		return nil, errors.Wrapf(err, "unable to unmarshal json from '%s'", jobs)
+	}
+	return &batch, batch.IsValid()
+}
+
+// parseRequestCommand is the inverse of worker.Request.Command.
+func parseRequestCommand(command []string) (*worker.Request, error) {
+	if len(command) == 0 {
+		return nil, errors.Errorf("empty command")
+	}
+	request := &worker.Request{}
+	switch command[0] {
+	case "/agnhost":
+		if len(command) < 3 || command[1] != "connect" {
+			return nil, errors.Errorf("unsupported agnhost command %+v", command)
+		}
+		request.Kind = worker.RequestKindConnect
+		if err := parseAddress(request, command[2]); err != nil {
+			return nil, err
+		}
+		request.Protocol = v1.ProtocolTCP
+		for _, arg := range command[3:] {
+			if strings.HasPrefix(arg, "--timeout=") {
+				seconds, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(arg, "--timeout="), "s"))
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid timeout %s", arg)
+				}
+				request.TimeoutSeconds = seconds
+			} else if strings.HasPrefix(arg, "--protocol=") {
+				request.Protocol = v1.Protocol(strings.ToUpper(strings.TrimPrefix(arg, "--protocol=")))
+			}
+		}
+	case "curl":
+		request.Protocol = v1.ProtocolTCP
+		url := command[len(command)-1]
+		for i, arg := range command {
+			if arg == "--max-time" && i+1 < len(command) {
+				seconds, err := strconv.Atoi(command[i+1])
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid max time %s", command[i+1])
+				}
+				request.TimeoutSeconds = seconds
+			}
+		}
+		if strings.HasPrefix(url, "https://") {
+			request.Kind = worker.RequestKindTLS
+			url = strings.TrimPrefix(url, "https://")
+		} else {
+			request.Kind = worker.RequestKindHTTP
+			url = strings.TrimPrefix(url, "http://")
+		}
+		address, path := url, "/"
+		if index := strings.Index(url, "/"); index >= 0 {
+			address, path = url[:index], url[index:]
+		}
+		request.Path = path
+		if err := parseAddress(request, address); err != nil {
+			return nil, err
+		}
+	case "dig":
+		request.Kind = worker.RequestKindDNS
+		request.Protocol = v1.ProtocolUDP
+		request.Host = command[len(command)-1]
+	default:
+		return nil, errors.Errorf("unsupported command %+v", command)
+	}
+	return request, nil
+}
+
+func parseAddress(request *worker.Request, address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return errors.Wrapf(err, "invalid address %s", address)
+	}
+	portInt, err := strconv.Atoi(port)
+	if err != nil {
+		return errors.Wrapf(err, "invalid port in address %s", address)
+	}
+	request.Host = host
+	request.Port = portInt
+	return nil
+}
@@ -0,0 +1,241 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/worker"
+)
+
+func RunClusterTests() {
+	Describe("parseRequestCommand", func() {
+		It("should parse the commands built by worker.Request", func() {
+			for _, request := range []*worker.Request{
+				{Kind: worker.RequestKindConnect, Protocol: v1.ProtocolUDP, Host: "192.168.1.2", Port: 81, TimeoutSeconds: 3},
+				{Kind: worker.RequestKindHTTP, Protocol: v1.ProtocolTCP, Host: "s-x-a.x.svc.cluster.local", Port: 80, Path: "/healthz", TimeoutSeconds: 1},
+				{Kind: worker.RequestKindTLS, Protocol: v1.ProtocolTCP, Host: "10.96.0.1", Port: 443, Path: "/", TimeoutSeconds: 2},
+			} {
+				parsed, err := parseRequestCommand(request.Command())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(parsed).To(Equal(request))
+			}
+		})
+	})
+
+	Describe("Cluster", func() {
+		namespaces := []string{"x", "y", "z"}
+
+		setup := func(cluster *Cluster, config *connectivity.InterpreterConfig) (*connectivity.Interpreter, []*generator.TestCase) {
+			resources, err := probe.NewDefaultResources(cluster, namespaces, []string{"a", "b", "c"}, []int{80, 81}, []v1.Protocol{v1.ProtocolTCP, v1.ProtocolUDP}, []string{}, []string{}, 10, config.BatchJobs, "registry.k8s.io")
+			Expect(err).ToNot(HaveOccurred())
+			zc, err := resources.GetPod("z", "c")
+			Expect(err).ToNot(HaveOccurred())
+
+			config.ResetClusterBeforeTestCase = true
+			config.VerifyClusterStateBeforeTestCase = true
+			config.IgnoreLoopback = true
+			testCases := generator.NewTestCaseGenerator(true, zc.IP, namespaces, []string{}, []string{}).GenerateAllTestCases()
+			config.ResetAdminPolicies = generator.AnyUsesAdminPolicies(testCases)
+			return connectivity.NewInterpreter(cluster, resources, config), testCases
+		}
+
+		expectAllPassed := func(interpreter *connectivity.Interpreter, testCases []*generator.TestCase) {
+			for _, testCase := range testCases {
+				result := interpreter.ExecuteTestCase(testCase)
+				Expect(result.Err).ToNot(HaveOccurred(), testCase.Description)
+				Expect(result.Passed(true)).To(BeTrue(), testCase.Description)
+			}
+		}
+
+		It("should enforce policies as simulated", func() {
+			interpreter, testCases := setup(NewDefaultCluster(), &connectivity.InterpreterConfig{})
+			expectAllPassed(interpreter, testCases)
+		})
+
+		It("should enforce policies as simulated for batch jobs and worker servers", func() {
+			interpreter, testCases := setup(NewDefaultCluster(), &connectivity.InterpreterConfig{BatchJobs: true})
+			expectAllPassed(interpreter, testCases[:10])
+			interpreter, testCases = setup(NewDefaultCluster(), &connectivity.InterpreterConfig{BatchJobs: true, WorkerServer: true})
+			expectAllPassed(interpreter, testCases[:10])
+		})
+
+		It("should enforce policies through services", func() {
+			interpreter, testCases := setup(NewDefaultCluster(), &connectivity.InterpreterConfig{})
+			for _, mode := range []generator.ProbeMode{generator.ProbeModeServiceName, generator.ProbeModeServiceIP} {
+				for _, testCase := range testCases[:10] {
+					for _, step := range testCase.Steps {
+						step.Probe.Mode = mode
+					}
+				}
+				expectAllPassed(interpreter, testCases[:10])
+			}
+		})
+
+		It("should forward traffic to services across their endpoints", func() {
+			cluster := NewDefaultCluster()
+			services := []string{"x/ab=a,b", "nodeport:y/all=a,b,c", "headless:z/bc=b,c"}
+			resources, err := probe.NewDefaultResources(cluster, namespaces, []string{"a", "b", "c"}, []int{80}, []v1.Protocol{v1.ProtocolTCP, v1.ProtocolUDP}, []string{}, services, 10, false, "registry.k8s.io")
+			Expect(err).ToNot(HaveOccurred())
+			zc, err := resources.GetPod("z", "c")
+			Expect(err).ToNot(HaveOccurred())
+			interpreter := connectivity.NewInterpreter(cluster, resources, &connectivity.InterpreterConfig{
+				ResetClusterBeforeTestCase:       true,
+				VerifyClusterStateBeforeTestCase: true,
+				ResetAdminPolicies:               true,
+				IgnoreLoopback:                   true,
+			})
+			testCases := generator.NewTestCaseGenerator(true, zc.IP, namespaces, []string{}, []string{}).GenerateAllTestCases()
+			for _, mode := range []generator.ProbeMode{generator.ProbeModeServiceName, generator.ProbeModeServiceIP} {
+				for _, testCase := range testCases[:20] {
+					for _, step := range testCase.Steps {
+						step.Probe.Mode = mode
+					}
+				}
+				expectAllPassed(interpreter, testCases[:20])
+			}
+
+			denyToXA := generator.NewSingleStepTestCase("deny ingress to x/a", generator.NewStringSet(generator.TagIngress), generator.NewAllAvailable(generator.ProbeModeServiceIP),
+				generator.CreatePolicy(&networkingv1.NetworkPolicy{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "deny-to-a"},
+					Spec: networkingv1.NetworkPolicySpec{
+						PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"pod": "a"}},
+						PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+					},
+				}))
+			result := interpreter.ExecuteTestCase(denyToXA)
+			Expect(result.Err).ToNot(HaveOccurred())
+			Expect(result.Passed(true)).To(BeTrue())
+			Expect(result.Steps[0].LastKubeProbe().Get("y/a", "svc/x/ab").JobResults["TCP/80"].Combined).To(Equal(probe.ConnectivityMixed))
+			Expect(result.Steps[0].LastKubeProbe().Get("y/a", "svc/y/all").JobResults["TCP/80"].Combined).To(Equal(probe.ConnectivityAllowed))
+		})
+
+		It("should enforce egress policies on external targets", func() {
+			cluster := NewDefaultCluster()
+			standIn, err := probe.NewStandInTarget(cluster, 8080, "registry.k8s.io", 10)
+			Expect(err).ToNot(HaveOccurred())
+			resources, err := probe.NewDefaultResources(cluster, namespaces, []string{"a", "b", "c"}, []int{80}, []v1.Protocol{v1.ProtocolTCP}, []string{"8.8.8.8:80", standIn.Key()}, []string{}, 10, false, "registry.k8s.io")
+			Expect(err).ToNot(HaveOccurred())
+			zc, err := resources.GetPod("z", "c")
+			Expect(err).ToNot(HaveOccurred())
+			interpreter := connectivity.NewInterpreter(cluster, resources, &connectivity.InterpreterConfig{
+				ResetClusterBeforeTestCase:       true,
+				VerifyClusterStateBeforeTestCase: true,
+				ResetAdminPolicies:               true,
+				IgnoreLoopback:                   true,
+			})
+			testCases := generator.NewTestCaseGenerator(true, zc.IP, namespaces, []string{generator.TagEgress}, []string{}).GenerateTestCases()
+			expectAllPassed(interpreter, testCases)
+
+			allowGoogleDNS := generator.NewSingleStepTestCase("allow egress to 8.8.8.0/24", generator.NewStringSet(generator.TagEgress), generator.NewAllAvailable(generator.ProbeModePodIP),
+				generator.CreatePolicy(&networkingv1.NetworkPolicy{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "x", Name: "allow-google-dns"},
+					Spec: networkingv1.NetworkPolicySpec{
+						PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"pod": "a"}},
+						Egress: []networkingv1.NetworkPolicyEgressRule{
+							{To: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "8.8.8.0/24"}}}},
+						},
+						PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+					},
+				}))
+			result := interpreter.ExecuteTestCase(allowGoogleDNS)
+			Expect(result.Err).ToNot(HaveOccurred())
+			Expect(result.Passed(true)).To(BeTrue())
+			kubeProbe := result.Steps[0].LastKubeProbe()
+			Expect(kubeProbe.Get("x/a", "tcp://8.8.8.8:80").JobResults["TCP/80"].Combined).To(Equal(probe.ConnectivityAllowed))
+			Expect(kubeProbe.Get("x/a", standIn.Key()).JobResults["TCP/8080"].Combined).To(Equal(probe.ConnectivityTimedOut))
+			Expect(kubeProbe.Get("x/b", standIn.Key()).JobResults["TCP/8080"].Combined).To(Equal(probe.ConnectivityAllowed))
+		})
+
+		It("should block lookups as simulated", func() {
+			cluster := NewDefaultCluster()
+			resources, err := probe.NewDefaultResources(cluster, namespaces, []string{"a", "b", "c"}, []int{80}, []v1.Protocol{v1.ProtocolTCP}, []string{}, []string{}, 10, false, "registry.k8s.io")
+			Expect(err).ToNot(HaveOccurred())
+			zc, err := resources.GetPod("z", "c")
+			Expect(err).ToNot(HaveOccurred())
+			interpreter := connectivity.NewInterpreter(cluster, resources, &connectivity.InterpreterConfig{
+				ResetClusterBeforeTestCase:       true,
+				VerifyClusterStateBeforeTestCase: true,
+				IgnoreLoopback:                   true,
+				ProbeKind:                        worker.RequestKindDNS,
+			})
+			// without allowing DNS, egress policies block lookups
+			testCases := generator.NewTestCaseGenerator(false, zc.IP, namespaces, []string{generator.TagEgress}, []string{generator.TagAdminTier}).GenerateTestCases()
+			for _, testCase := range testCases {
+				for _, step := range testCase.Steps {
+					step.Probe.Mode = generator.ProbeModeServiceName
+				}
+			}
+			expectAllPassed(interpreter, testCases)
+		})
+
+		It("should reject admin policies it can't evaluate, and keep probing", func() {
+			cluster := NewDefaultCluster()
+			_, err := probe.NewDefaultResources(cluster, namespaces, []string{"a", "b"}, []int{80}, []v1.Protocol{v1.ProtocolTCP}, []string{}, []string{}, 10, false, "registry.k8s.io")
+			Expect(err).ToNot(HaveOccurred())
+			anp := func(name string, priority int32) *v1alpha1.AdminNetworkPolicy {
+				return &v1alpha1.AdminNetworkPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: name},
+					Spec: v1alpha1.AdminNetworkPolicySpec{
+						Priority: priority,
+						Subject:  v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+						Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{{
+							Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+							From:   []v1alpha1.AdminNetworkPolicyPeer{{Namespaces: &v1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{}}}},
+						}},
+					},
+				}
+			}
+
+			_, err = cluster.CreateAdminNetworkPolicy(context.TODO(), anp("first", 10))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = cluster.CreateAdminNetworkPolicy(context.TODO(), anp("second", 10))
+			Expect(kerrors.IsInvalid(err)).To(BeTrue(), fmt.Sprintf("%+v", err))
+			_, err = cluster.CreateAdminNetworkPolicy(context.TODO(), anp("second", 20))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = cluster.UpdateAdminNetworkPolicy(context.TODO(), anp("second", 10))
+			Expect(kerrors.IsInvalid(err)).To(BeTrue(), fmt.Sprintf("%+v", err))
+			_, err = cluster.UpdateAdminNetworkPolicy(context.TODO(), anp("first", 10))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = cluster.CreateBaselineAdminNetworkPolicy(context.TODO(), &v1alpha1.BaselineAdminNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+			Expect(kerrors.IsInvalid(err)).To(BeTrue(), fmt.Sprintf("%+v", err))
+
+			anps, err := cluster.GetAdminNetworkPolicies(context.TODO())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(anps).To(HaveLen(2))
+			stdout, _, commandErr, err := cluster.ExecuteRemoteCommand("x", "a", "cont-80-tcp", (&worker.Request{Protocol: v1.ProtocolTCP, Host: "192.168.1.2", Port: 80}).Command())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(commandErr).To(HaveOccurred())
+			Expect(stdout).To(Equal("TIMEOUT"))
+		})
+
+		It("should fail test cases when misenforcing policies", func() {
+			interpreter, testCases := setup(NewCluster(&MatcherEngine{}, &Faults{MisenforceRate: 1}), &connectivity.InterpreterConfig{})
+			result := interpreter.ExecuteTestCase(testCases[0])
+			Expect(result.Err).ToNot(HaveOccurred())
+			Expect(result.Passed(true)).To(BeFalse())
+		})
+
+		It("should time out probes when dropping traffic", func() {
+			cluster := NewCluster(&MatcherEngine{}, &Faults{DropRate: 1})
+			interpreter, testCases := setup(cluster, &connectivity.InterpreterConfig{})
+			result := interpreter.ExecuteTestCase(testCases[0])
+			Expect(result.Err).ToNot(HaveOccurred())
+			Expect(result.Passed(true)).To(BeFalse())
+
+			stdout, _, commandErr, err := cluster.ExecuteRemoteCommand("x", "a", "cont-80-tcp", (&worker.Request{Protocol: v1.ProtocolTCP, Host: "192.168.1.2", Port: 80}).Command())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(commandErr).To(HaveOccurred())
+			Expect(stdout).To(Equal("TIMEOUT"))
+		})
+	})
+}
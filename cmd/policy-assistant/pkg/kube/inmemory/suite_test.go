@@ -0,0 +1,14 @@
+package inmemory
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestInMemory(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunClusterTests()
+	RunSpecs(t, "inmemory suite")
+}
@@ -3,6 +3,7 @@ package kube
 import (
 	"bytes"
 	"context"
+	"io"
 
 	v1alpha12 "sigs.k8s.io/network-policy-api/apis/v1alpha1"
 	"sigs.k8s.io/network-policy-api/pkg/client/clientset/versioned/typed/apis/v1alpha1"
@@ -121,16 +122,20 @@ func (k *Kubernetes) GetAdminNetworkPolicies(ctx context.Context) ([]v1alpha12.A
 }
 
 func (k *Kubernetes) CreateAdminNetworkPolicy(ctx context.Context, policy *v1alpha12.AdminNetworkPolicy) (*v1alpha12.AdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+	logrus.Debugf("creating admin network policy %s", policy.Name)
+	createdPolicy, err := k.alphaClientSet.AdminNetworkPolicies().Create(ctx, policy, metav1.CreateOptions{})
+	return createdPolicy, errors.Wrapf(err, "unable to create admin network policy %s", policy.Name)
 }
 
 func (k *Kubernetes) UpdateAdminNetworkPolicy(ctx context.Context, policy *v1alpha12.AdminNetworkPolicy) (*v1alpha12.AdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+	logrus.Debugf("updating admin network policy %s", policy.Name)
+	updatedPolicy, err := k.alphaClientSet.AdminNetworkPolicies().Update(ctx, policy, metav1.UpdateOptions{})
+	return updatedPolicy, errors.Wrapf(err, "unable to update admin network policy %s", policy.Name)
 }
 
 func (k *Kubernetes) DeleteAdminNetworkPolicy(ctx context.Context, name string) error {
-	//TODO: implement
-	return ErrNotImplemented
+	logrus.Debugf("deleting admin network policy %s", name)
+	return errors.Wrapf(k.alphaClientSet.AdminNetworkPolicies().Delete(ctx, name, metav1.DeleteOptions{}), "unable to delete admin network policy %s", name)
 }
 
 func (k *Kubernetes) GetBaselineAdminNetworkPolicy(ctx context.Context) (*v1alpha12.BaselineAdminNetworkPolicy, error) {
@@ -146,16 +151,20 @@ func (k *Kubernetes) GetBaselineAdminNetworkPolicy(ctx context.Context) (*v1alph
 }
 
 func (k *Kubernetes) CreateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha12.BaselineAdminNetworkPolicy) (*v1alpha12.BaselineAdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+	logrus.Debugf("creating baseline admin network policy %s", policy.Name)
+	createdPolicy, err := k.alphaClientSet.BaselineAdminNetworkPolicies().Create(ctx, policy, metav1.CreateOptions{})
+	return createdPolicy, errors.Wrapf(err, "unable to create baseline admin network policy %s", policy.Name)
 }
 
 func (k *Kubernetes) UpdateBaselineAdminNetworkPolicy(ctx context.Context, policy *v1alpha12.BaselineAdminNetworkPolicy) (*v1alpha12.BaselineAdminNetworkPolicy, error) {
-	return nil, ErrNotImplemented
+	logrus.Debugf("updating baseline admin network policy %s", policy.Name)
+	updatedPolicy, err := k.alphaClientSet.BaselineAdminNetworkPolicies().Update(ctx, policy, metav1.UpdateOptions{})
+	return updatedPolicy, errors.Wrapf(err, "unable to update baseline admin network policy %s", policy.Name)
 }
 
 func (k *Kubernetes) DeleteBaselineAdminNetworkPolicy(ctx context.Context, name string) error {
-	//TODO: implement
-	return ErrNotImplemented
+	logrus.Debugf("deleting baseline admin network policy %s", name)
+	return errors.Wrapf(k.alphaClientSet.BaselineAdminNetworkPolicies().Delete(ctx, name, metav1.DeleteOptions{}), "unable to delete baseline admin network policy %s", name)
 }
 
 func (k *Kubernetes) UpdateNetworkPolicy(policy *networkingv1.NetworkPolicy) (*networkingv1.NetworkPolicy, error) {
@@ -268,6 +277,19 @@ func (k *Kubernetes) CreatePod(pod *v1.Pod) (*v1.Pod, error) {
 	return createdPod, errors.Wrapf(err, "unable to create pod %s/%s", ns, pod.Name)
 }
 
+// AddEphemeralContainer adds a container to a running pod, without restarting it.  Ephemeral containers
+// can't be removed: they remain until the pod is deleted.
+func (k *Kubernetes) AddEphemeralContainer(namespace string, podName string, container *v1.EphemeralContainer) (*v1.Pod, error) {
+	logrus.Debugf("adding ephemeral container %s to pod %s/%s", container.Name, namespace, podName)
+	pod, err := k.GetPod(namespace, podName)
+	if err != nil {
+		return nil, err
+	}
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, *container)
+	updatedPod, err := k.ClientSet.CoreV1().Pods(namespace).UpdateEphemeralContainers(context.TODO(), podName, pod, metav1.UpdateOptions{})
+	return updatedPod, errors.Wrapf(err, "unable to add ephemeral container %s to pod %s/%s", container.Name, namespace, podName)
+}
+
 func (k *Kubernetes) DeletePod(namespace string, podName string) error {
 	logrus.Debugf("deleting pod %s/%s", namespace, podName)
 	err := k.ClientSet.CoreV1().Pods(namespace).Delete(context.TODO(), podName, metav1.DeleteOptions{})
@@ -313,3 +335,38 @@ func (k *Kubernetes) ExecuteRemoteCommand(namespace string, pod string, containe
 	out, errOut := buf.String(), errBuf.String()
 	return out, errOut, errors.Wrapf(err, "unable to stream command"), nil
 }
+
+// StreamRemoteCommand runs a command which reads from stdin and writes to stdout and stderr while it runs,
+// returning once the command exits.  Unlike ExecuteRemoteCommand, no TTY is allocated, so that the
+// streams aren't altered.
+func (k *Kubernetes) StreamRemoteCommand(namespace string, pod string, container string, command []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	request := k.ClientSet.
+		CoreV1().
+		RESTClient().
+		Post().
+		Namespace(namespace).
+		Resource("pods").
+		Name(pod).
+		SubResource("exec").
+		Param("container", container).
+		VersionedParams(
+			&v1.PodExecOptions{
+				Container: container,
+				Command:   command,
+				Stdin:     true,
+				Stdout:    true,
+				Stderr:    true,
+				TTY:       false,
+			},
+			scheme.ParameterCodec)
+
+	exec, err := remotecommand.NewSPDYExecutor(k.RestConfig, "POST", request.URL())
+	if err != nil {
+		return errors.Wrapf(err, "unable to instantiate SPDYExecutor")
+	}
+	return errors.Wrapf(exec.Stream(remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	}), "unable to stream command")
+}
@@ -216,6 +216,97 @@ func BuildSinglePortMatcher(npPort networkingv1.NetworkPolicyPort) (*PortProtoco
 	}
 }
 
+// ValidateAdminPolicies returns an error for anything BuildV1AndV2NetPols can't build: ANPs sharing a
+// priority, whose order is undefined, and policies without rules, or with a malformed peer or port.
+func ValidateAdminPolicies(anps []*v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy) error {
+	priorities := map[int32]string{}
+	for _, anp := range anps {
+		if other, ok := priorities[anp.Spec.Priority]; ok {
+			return errors.Errorf("AdminNetworkPolicies %s and %s have the same priority %d: their order is undefined", other, anp.Name, anp.Spec.Priority)
+		}
+		priorities[anp.Spec.Priority] = anp.Name
+
+		if len(anp.Spec.Ingress) == 0 && len(anp.Spec.Egress) == 0 {
+			return errors.Errorf("invalid AdminNetworkPolicy %s: need at least one egress or ingress rule", anp.Name)
+		}
+		for i, r := range anp.Spec.Ingress {
+			if err := validateAdminPeers(r.From, r.Ports); err != nil {
+				return errors.WithMessagef(err, "AdminNetworkPolicy %s, ingress rule %d", anp.Name, i+1)
+			}
+		}
+		for i, r := range anp.Spec.Egress {
+			if err := validateAdminPeers(r.To, r.Ports); err != nil {
+				return errors.WithMessagef(err, "AdminNetworkPolicy %s, egress rule %d", anp.Name, i+1)
+			}
+		}
+	}
+
+	if banp != nil {
+		if len(banp.Spec.Ingress) == 0 && len(banp.Spec.Egress) == 0 {
+			return errors.Errorf("invalid BaselineAdminNetworkPolicy: need at least one egress or ingress rule")
+		}
+		for i, r := range banp.Spec.Ingress {
+			if err := validateAdminPeers(r.From, r.Ports); err != nil {
+				return errors.WithMessagef(err, "BaselineAdminNetworkPolicy, ingress rule %d", i+1)
+			}
+		}
+		for i, r := range banp.Spec.Egress {
+			if err := validateAdminPeers(r.To, r.Ports); err != nil {
+				return errors.WithMessagef(err, "BaselineAdminNetworkPolicy, egress rule %d", i+1)
+			}
+		}
+	}
+	return nil
+}
+
+func validateAdminPeers(peers []v1alpha1.AdminNetworkPolicyPeer, ports *[]v1alpha1.AdminNetworkPolicyPort) error {
+	if len(peers) == 0 {
+		return errors.Errorf("invalid admin to/from field: must have at least one peer")
+	}
+	for _, peer := range peers {
+		if (peer.Namespaces == nil && peer.Pods == nil) || (peer.Namespaces != nil && peer.Pods != nil) {
+			return errors.Errorf("invalid admin peer: must have exactly one of Namespaces or Pods")
+		}
+		ns := peer.Namespaces
+		if peer.Pods != nil {
+			ns = &peer.Pods.Namespaces
+		}
+		nonNilCount := 0
+		for _, isSet := range []bool{ns.NamespaceSelector != nil, ns.SameLabels != nil, ns.NotSameLabels != nil} {
+			if isSet {
+				nonNilCount++
+			}
+		}
+		if nonNilCount != 1 {
+			return errors.Errorf("invalid admin peer: must have exactly one of NamespaceSelector, SameLabels, or NotSameLabels")
+		}
+	}
+	if ports != nil {
+		for _, port := range *ports {
+			if err := validateAdminPort(port); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateAdminPort(port v1alpha1.AdminNetworkPolicyPort) error {
+	nonNilCount := 0
+	for _, isSet := range []bool{port.PortNumber != nil, port.NamedPort != nil, port.PortRange != nil} {
+		if isSet {
+			nonNilCount++
+		}
+	}
+	if nonNilCount != 1 {
+		return errors.Errorf("invalid port: must have exactly one of PortNumber, NamedPort, or PortRange")
+	}
+	if port.PortRange != nil && port.PortRange.Start >= port.PortRange.End {
+		return errors.Errorf("invalid port range: start >= end")
+	}
+	return nil
+}
+
 func BuildTargetANP(anp *v1alpha1.AdminNetworkPolicy) (*Target, *Target) {
 	if len(anp.Spec.Ingress) == 0 && len(anp.Spec.Egress) == 0 {
 		panic(errors.Errorf("invalid AdminNetworkPolicy: need at least one egress or ingress rule"))
@@ -303,8 +394,8 @@ func BuildTargetBANP(banp *v1alpha1.BaselineAdminNetworkPolicy) (*Target, *Targe
 }
 
 func BuildPeerMatcherAdmin(peers []v1alpha1.AdminNetworkPolicyPeer, ports *[]v1alpha1.AdminNetworkPolicyPort) []*PodPeerMatcher {
-	if len(peers) == 0 {
-		panic(errors.Errorf("invalid admin to/from field: must have at least one peer"))
+	if err := validateAdminPeers(peers, ports); err != nil {
+		panic(err)
 	}
 
 	// 1. build port matcher
@@ -318,10 +409,6 @@ func BuildPeerMatcherAdmin(peers []v1alpha1.AdminNetworkPolicyPeer, ports *[]v1a
 	// 2. build Peers
 	var peerMatchers []*PodPeerMatcher
 	for _, peer := range peers {
-		if (peer.Namespaces == nil && peer.Pods == nil) || (peer.Namespaces != nil && peer.Pods != nil) {
-			panic(errors.Errorf("invalid admin peer: must have exactly one of Namespaces or Pods"))
-		}
-
 		var ns v1alpha1.NamespacedPeer
 		var podMatcher PodMatcher
 		if peer.Pods != nil {
@@ -340,20 +427,11 @@ func BuildPeerMatcherAdmin(peers []v1alpha1.AdminNetworkPolicyPeer, ports *[]v1a
 			podMatcher = &AllPodMatcher{}
 		}
 
-		nonNilCount := 0
-		if ns.NamespaceSelector != nil {
-			nonNilCount++
-		}
 		if ns.SameLabels != nil {
 			fmt.Println("WARN: SameLabels is deprecated and will be removed after v0.1.1 (alpha) of sigs.k8s.io/network-policy-api. Tenancy will replace this concept.")
-			nonNilCount++
 		}
 		if ns.NotSameLabels != nil {
 			fmt.Println("WARN: NotSameLabels is deprecated and will be removed after v0.1.1 (alpha) of sigs.k8s.io/network-policy-api. Tenancy will replace this concept.")
-			nonNilCount++
-		}
-		if nonNilCount != 1 {
-			panic(errors.Errorf("invalid admin peer: must have exactly one of NamespaceSelector, SameLabels, or NotSameLabels"))
 		}
 
 		var nsMatcher NamespaceMatcher
@@ -405,18 +483,8 @@ func BuildPortMatcherAdmin(ports []v1alpha1.AdminNetworkPolicyPort) PortMatcher
 }
 
 func BuildSinglePortMatcherAdmin(port v1alpha1.AdminNetworkPolicyPort) (*PortProtocolMatcher, *PortRangeMatcher) {
-	nonNilCount := 0
-	if port.PortNumber != nil {
-		nonNilCount++
-	}
-	if port.NamedPort != nil {
-		nonNilCount++
-	}
-	if port.PortRange != nil {
-		nonNilCount++
-	}
-	if nonNilCount != 1 {
-		panic(errors.Errorf("invalid port: must have exactly one of PortNumber, NamedPort, or PortRange"))
+	if err := validateAdminPort(port); err != nil {
+		panic(err)
 	}
 
 	if port.PortNumber != nil {
@@ -461,10 +529,6 @@ func BuildSinglePortMatcherAdmin(port v1alpha1.AdminNetworkPolicyPort) (*PortPro
 		proto = port.PortRange.Protocol
 	}
 
-	if port.PortRange.Start >= port.PortRange.End {
-		panic(errors.Errorf("invalid port range: start >= end"))
-	}
-
 	return nil, &PortRangeMatcher{
 		From:     int(port.PortRange.Start),
 		To:       int(port.PortRange.End),
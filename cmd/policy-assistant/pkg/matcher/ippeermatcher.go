@@ -36,6 +36,10 @@ func (i *IPPeerMatcher) MarshalJSON() (b []byte, e error) {
 }
 
 func (i *IPPeerMatcher) Matches(_, peer *TrafficPeer, portInt int, portName string, protocol v1.Protocol) bool {
+	// a peer whose IP isn't known, such as the cluster's DNS pods, can't be matched by IP
+	if peer.IP == "" {
+		return false
+	}
 	isIpMatch, err := kube.IsIPAddressMatchForIPBlock(peer.IP, i.IPBlock)
 	// TODO propagate this error instead of panic
 	if err != nil {
@@ -25,6 +25,7 @@ func NewPeerMatcherANP(peer *PodPeerMatcher, v Verdict, priority int, policyName
 		PolicyName:     policyName,
 		RuleName:       ruleName,
 		effectFromMatch: Effect{
+			PolicyName: policyName,
 			RuleName:   ruleName,
 			PolicyKind: AdminNetworkPolicy,
 			Priority:   priority,
@@ -40,6 +41,7 @@ func NewPeerMatcherBANP(peer *PodPeerMatcher, v Verdict, policyName, ruleName st
 		PolicyName:     policyName,
 		RuleName:       ruleName,
 		effectFromMatch: Effect{
+			PolicyName: policyName,
 			RuleName:   ruleName,
 			PolicyKind: BaselineAdminNetworkPolicy,
 			Verdict:    v,
@@ -49,7 +51,9 @@ func NewPeerMatcherBANP(peer *PodPeerMatcher, v Verdict, policyName, ruleName st
 
 // Effect models the effect of one or more v1/v2 NetPol rules on a peer
 type Effect struct {
-	RuleName string
+	// PolicyName is only set for ANP and BANP, to attribute the Effect to a single rule
+	PolicyName string
+	RuleName   string
 	PolicyKind
 	// Priority is only used for ANP (there can only be one BANP)
 	Priority int
@@ -73,9 +77,9 @@ func NewV1Effect(allow bool, policyNames []string) Effect {
 	joinedNames := strings.Join(cleanNames, ", ")
 
 	if allow {
-		return Effect{joinedNames, NetworkPolicyV1, 0, Allow}
+		return Effect{RuleName: joinedNames, PolicyKind: NetworkPolicyV1, Verdict: Allow}
 	}
-	return Effect{joinedNames, NetworkPolicyV1, 0, None}
+	return Effect{RuleName: joinedNames, PolicyKind: NetworkPolicyV1, Verdict: None}
 }
 
 type Verdict string
@@ -0,0 +1,461 @@
+package synthesis
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/slice"
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/generator"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+)
+
+const (
+	maxAdminRules    = 100
+	maxAdminPriority = 1000
+)
+
+// peer is one end of an observed flow: either a workload, or an IP outside the cluster.
+type peer struct {
+	Workload *Workload
+	IP       string
+}
+
+func (p *peer) key() string {
+	if p.Workload != nil {
+		return p.Workload.Key()
+	}
+	return p.IP
+}
+
+type portProtocol struct {
+	Port     int
+	Protocol v1.Protocol
+}
+
+func (pp portProtocol) String() string {
+	return fmt.Sprintf("%s/%05d", pp.Protocol, pp.Port)
+}
+
+// Synthesis holds the flows allowed for each workload, aggregated from a corpus of traffic.
+// Pods are generalized to workloads by their labels, so that traffic observed between two pods is
+// allowed between all pods of the same two workloads.
+type Synthesis struct {
+	// Resources is the snapshot, with every namespace labeled by its name
+	Resources *probe.Resources
+	// Traffic is the corpus, with namespace labels taken from the snapshot
+	Traffic []*matcher.Traffic
+	// Ignored contains traffic between two IPs outside the cluster, which policies can't apply to
+	Ignored []*matcher.Traffic
+
+	workloads map[string]*Workload
+	peers     map[string]*peer
+	// ingress maps workload -> source peer -> ports; egress maps workload -> destination peer -> ports
+	ingress map[string]map[string]map[portProtocol]bool
+	egress  map[string]map[string]map[portProtocol]bool
+}
+
+func Synthesize(resources *probe.Resources, traffic []*matcher.Traffic) *Synthesis {
+	s := &Synthesis{
+		Resources: withNamespaceNameLabels(resources),
+		workloads: map[string]*Workload{},
+		peers:     map[string]*peer{},
+		ingress:   map[string]map[string]map[portProtocol]bool{},
+		egress:    map[string]map[string]map[portProtocol]bool{},
+	}
+
+	for _, pod := range s.Resources.Pods {
+		s.addWorkload(NewWorkload(pod.Namespace, pod.Labels))
+	}
+
+	for _, t := range traffic {
+		if t.Source.Internal == nil && t.Destination.Internal == nil {
+			s.Ignored = append(s.Ignored, t)
+			continue
+		}
+		t = s.normalize(t)
+		s.Traffic = append(s.Traffic, t)
+
+		source, destination := s.addPeer(t.Source), s.addPeer(t.Destination)
+		pp := portProtocol{Port: t.ResolvedPort, Protocol: t.Protocol}
+		if destination.Workload != nil {
+			addPort(s.ingress, destination.key(), source.key(), pp)
+		}
+		if source.Workload != nil {
+			addPort(s.egress, source.key(), destination.key(), pp)
+		}
+	}
+
+	return s
+}
+
+func withNamespaceNameLabels(resources *probe.Resources) *probe.Resources {
+	namespaces := map[string]map[string]string{}
+	for ns, labels := range resources.Namespaces {
+		namespaces[ns] = namespaceLabels(ns, labels)
+	}
+	return &probe.Resources{Namespaces: namespaces, Pods: resources.Pods}
+}
+
+func namespaceLabels(ns string, labels map[string]string) map[string]string {
+	withName := map[string]string{NamespaceNameLabel: ns}
+	for key, value := range labels {
+		withName[key] = value
+	}
+	return withName
+}
+
+// normalize copies traffic, using namespace labels from the snapshot where possible.
+func (s *Synthesis) normalize(t *matcher.Traffic) *matcher.Traffic {
+	normalizePeer := func(p *matcher.TrafficPeer) *matcher.TrafficPeer {
+		if p.Internal == nil {
+			return p
+		}
+		labels, ok := s.Resources.Namespaces[p.Internal.Namespace]
+		if !ok {
+			labels = namespaceLabels(p.Internal.Namespace, p.Internal.NamespaceLabels)
+		}
+		return &matcher.TrafficPeer{
+			Internal: &matcher.InternalPeer{
+				Workload:        p.Internal.Workload,
+				PodLabels:       p.Internal.PodLabels,
+				NamespaceLabels: labels,
+				Namespace:       p.Internal.Namespace,
+			},
+			IP: p.IP,
+		}
+	}
+	return &matcher.Traffic{
+		Source:           normalizePeer(t.Source),
+		Destination:      normalizePeer(t.Destination),
+		ResolvedPort:     t.ResolvedPort,
+		ResolvedPortName: t.ResolvedPortName,
+		Protocol:         t.Protocol,
+	}
+}
+
+func (s *Synthesis) addWorkload(w *Workload) *Workload {
+	if existing, ok := s.workloads[w.Key()]; ok {
+		return existing
+	}
+	s.workloads[w.Key()] = w
+	return w
+}
+
+func (s *Synthesis) addPeer(p *matcher.TrafficPeer) *peer {
+	var result *peer
+	if p.Internal == nil {
+		result = &peer{IP: p.IP}
+	} else {
+		result = &peer{Workload: s.addWorkload(NewWorkload(p.Internal.Namespace, p.Internal.PodLabels))}
+	}
+	if existing, ok := s.peers[result.key()]; ok {
+		return existing
+	}
+	s.peers[result.key()] = result
+	return result
+}
+
+func addPort(dict map[string]map[string]map[portProtocol]bool, subject string, peer string, pp portProtocol) {
+	if _, ok := dict[subject]; !ok {
+		dict[subject] = map[string]map[portProtocol]bool{}
+	}
+	if _, ok := dict[subject][peer]; !ok {
+		dict[subject][peer] = map[portProtocol]bool{}
+	}
+	dict[subject][peer][pp] = true
+}
+
+func (s *Synthesis) isObserved(from *Workload, to *Workload, pp portProtocol) bool {
+	return s.ingress[to.Key()][from.Key()][pp]
+}
+
+// Workloads returns all workloads from the snapshot and the traffic, sorted by key.
+func (s *Synthesis) Workloads() []*Workload {
+	return slice.SortOn(func(w *Workload) string { return w.Key() }, maps.Values(s.workloads))
+}
+
+// names assigns a unique name to each workload, within its namespace.
+func (s *Synthesis) names() map[string]string {
+	names := map[string]string{}
+	taken := map[string]bool{}
+	for _, w := range s.Workloads() {
+		name := w.name()
+		for i := 2; taken[w.Namespace+"/"+name]; i++ {
+			name = fmt.Sprintf("%s-%d", w.name(), i)
+		}
+		taken[w.Namespace+"/"+name] = true
+		names[w.Key()] = name
+	}
+	return names
+}
+
+func sortedPeers(peers map[string]map[portProtocol]bool) []string {
+	return slice.Sort(maps.Keys(peers))
+}
+
+func sortedPorts(ports map[portProtocol]bool) []portProtocol {
+	return slice.SortOn(func(pp portProtocol) string { return pp.String() }, maps.Keys(ports))
+}
+
+func matchLabels(labels map[string]string) metav1.LabelSelector {
+	if len(labels) == 0 {
+		return metav1.LabelSelector{}
+	}
+	return metav1.LabelSelector{MatchLabels: labels}
+}
+
+func namespaceSelector(ns string) *metav1.LabelSelector {
+	return &metav1.LabelSelector{MatchLabels: map[string]string{NamespaceNameLabel: ns}}
+}
+
+func ipBlock(ip string) *networkingv1.IPBlock {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return &networkingv1.IPBlock{CIDR: ip + "/128"}
+	}
+	return &networkingv1.IPBlock{CIDR: ip + "/32"}
+}
+
+func (s *Synthesis) netpolPeer(policyNamespace string, key string) networkingv1.NetworkPolicyPeer {
+	p := s.peers[key]
+	if p.Workload == nil {
+		return networkingv1.NetworkPolicyPeer{IPBlock: ipBlock(p.IP)}
+	}
+	podSelector := matchLabels(p.Workload.Labels)
+	netpolPeer := networkingv1.NetworkPolicyPeer{PodSelector: &podSelector}
+	if p.Workload.Namespace != policyNamespace {
+		netpolPeer.NamespaceSelector = namespaceSelector(p.Workload.Namespace)
+	}
+	return netpolPeer
+}
+
+func netpolPorts(ports map[portProtocol]bool) []networkingv1.NetworkPolicyPort {
+	var netpolPorts []networkingv1.NetworkPolicyPort
+	for _, pp := range sortedPorts(ports) {
+		protocol, port := pp.Protocol, intstr.FromInt(pp.Port)
+		netpolPorts = append(netpolPorts, networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port})
+	}
+	return netpolPorts
+}
+
+// NetworkPolicies returns one policy per workload, isolating it for ingress and egress and allowing only
+// the observed traffic.  Workloads without any observed traffic are completely isolated.
+func (s *Synthesis) NetworkPolicies() []*networkingv1.NetworkPolicy {
+	names := s.names()
+	var netpols []*networkingv1.NetworkPolicy
+	for _, w := range s.Workloads() {
+		netpol := &networkingv1.NetworkPolicy{
+			TypeMeta: metav1.TypeMeta{Kind: "NetworkPolicy", APIVersion: "networking.k8s.io/v1"},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: w.Namespace,
+				Name:      "allow-" + names[w.Key()],
+			},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: matchLabels(w.Labels),
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			},
+		}
+		ingress := s.ingress[w.Key()]
+		for _, source := range sortedPeers(ingress) {
+			netpol.Spec.Ingress = append(netpol.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+				Ports: netpolPorts(ingress[source]),
+				From:  []networkingv1.NetworkPolicyPeer{s.netpolPeer(w.Namespace, source)},
+			})
+		}
+		egress := s.egress[w.Key()]
+		for _, destination := range sortedPeers(egress) {
+			netpol.Spec.Egress = append(netpol.Spec.Egress, networkingv1.NetworkPolicyEgressRule{
+				Ports: netpolPorts(egress[destination]),
+				To:    []networkingv1.NetworkPolicyPeer{s.netpolPeer(w.Namespace, destination)},
+			})
+		}
+		netpols = append(netpols, netpol)
+	}
+	return netpols
+}
+
+func adminPorts(ports map[portProtocol]bool) *[]v1alpha1.AdminNetworkPolicyPort {
+	var adminPorts []v1alpha1.AdminNetworkPolicyPort
+	for _, pp := range sortedPorts(ports) {
+		adminPorts = append(adminPorts, v1alpha1.AdminNetworkPolicyPort{
+			PortNumber: &v1alpha1.Port{Protocol: pp.Protocol, Port: int32(pp.Port)},
+		})
+	}
+	return &adminPorts
+}
+
+func adminPeer(w *Workload) v1alpha1.AdminNetworkPolicyPeer {
+	return v1alpha1.AdminNetworkPolicyPeer{
+		Pods: &v1alpha1.NamespacedPodPeer{
+			Namespaces:  v1alpha1.NamespacedPeer{NamespaceSelector: namespaceSelector(w.Namespace)},
+			PodSelector: matchLabels(w.Labels),
+		},
+	}
+}
+
+// AdminNetworkPolicies returns one ANP per workload with observed traffic, allowing only that traffic,
+// starting at the given priority, and a BANP denying all other traffic in the namespaces of all workloads.
+// Admin policies can only select pods, so traffic to or from IPs outside the cluster is returned as a warning,
+// and is neither allowed nor denied by the policies.
+func (s *Synthesis) AdminNetworkPolicies(priority int) ([]*v1alpha1.AdminNetworkPolicy, *v1alpha1.BaselineAdminNetworkPolicy, []string, error) {
+	names := s.names()
+	var anps []*v1alpha1.AdminNetworkPolicy
+	var warnings []string
+	namespaces := map[string]bool{}
+	for _, w := range s.Workloads() {
+		namespaces[w.Namespace] = true
+		ingress, egress := s.ingress[w.Key()], s.egress[w.Key()]
+		if len(ingress) == 0 && len(egress) == 0 {
+			continue
+		}
+		if priority > maxAdminPriority {
+			return nil, nil, nil, errors.Errorf("unable to synthesize ANP for workload %s: priority %d exceeds %d", w.Key(), priority, maxAdminPriority)
+		}
+
+		anp := &v1alpha1.AdminNetworkPolicy{
+			TypeMeta:   metav1.TypeMeta{Kind: "AdminNetworkPolicy", APIVersion: v1alpha1.GroupVersion.String()},
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("allow-%s-%s", w.Namespace, names[w.Key()])},
+			Spec: v1alpha1.AdminNetworkPolicySpec{
+				Priority: int32(priority),
+				Subject: v1alpha1.AdminNetworkPolicySubject{
+					Pods: &v1alpha1.NamespacedPodSubject{
+						NamespaceSelector: *namespaceSelector(w.Namespace),
+						PodSelector:       matchLabels(w.Labels),
+					},
+				},
+			},
+		}
+		priority++
+
+		for i, source := range sortedPeers(ingress) {
+			p := s.peers[source]
+			if p.Workload == nil {
+				warnings = append(warnings, fmt.Sprintf("unable to allow ingress from %s to %s: admin policies can only select pods", p.IP, w.Key()))
+				continue
+			}
+			anp.Spec.Ingress = append(anp.Spec.Ingress, v1alpha1.AdminNetworkPolicyIngressRule{
+				Name:   fmt.Sprintf("allow-from-%d", i+1),
+				Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+				From:   []v1alpha1.AdminNetworkPolicyPeer{adminPeer(p.Workload)},
+				Ports:  adminPorts(ingress[source]),
+			})
+		}
+		for i, destination := range sortedPeers(egress) {
+			p := s.peers[destination]
+			if p.Workload == nil {
+				warnings = append(warnings, fmt.Sprintf("unable to allow egress from %s to %s: admin policies can only select pods", w.Key(), p.IP))
+				continue
+			}
+			anp.Spec.Egress = append(anp.Spec.Egress, v1alpha1.AdminNetworkPolicyEgressRule{
+				Name:   fmt.Sprintf("allow-to-%d", i+1),
+				Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+				To:     []v1alpha1.AdminNetworkPolicyPeer{adminPeer(p.Workload)},
+				Ports:  adminPorts(egress[destination]),
+			})
+		}
+		if len(anp.Spec.Ingress) > maxAdminRules || len(anp.Spec.Egress) > maxAdminRules {
+			return nil, nil, nil, errors.Errorf("unable to synthesize ANP for workload %s: more than %d rules", w.Key(), maxAdminRules)
+		}
+		anps = append(anps, anp)
+	}
+
+	allNamespaces := v1alpha1.AdminNetworkPolicyPeer{Namespaces: &v1alpha1.NamespacedPeer{NamespaceSelector: &metav1.LabelSelector{}}}
+	banp := &v1alpha1.BaselineAdminNetworkPolicy{
+		TypeMeta:   metav1.TypeMeta{Kind: "BaselineAdminNetworkPolicy", APIVersion: v1alpha1.GroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{
+				Namespaces: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{
+						Key:      NamespaceNameLabel,
+						Operator: metav1.LabelSelectorOpIn,
+						Values:   slice.Sort(maps.Keys(namespaces)),
+					}},
+				},
+			},
+			Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{{
+				Name:   "deny-all",
+				Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+				From:   []v1alpha1.AdminNetworkPolicyPeer{allNamespaces},
+			}},
+			Egress: []v1alpha1.BaselineAdminNetworkPolicyEgressRule{{
+				Name:   "deny-all",
+				Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+				To:     []v1alpha1.AdminNetworkPolicyPeer{allNamespaces},
+			}},
+		},
+	}
+
+	return anps, banp, warnings, nil
+}
+
+// Verification checks synthesized policies against the corpus and the snapshot.
+type Verification struct {
+	// Denied contains traffic from the corpus which the policies don't allow
+	Denied []*matcher.Traffic
+	// Excess contains simulated results between pods of the snapshot which the policies allow, but which weren't observed
+	Excess []*probe.JobResult
+}
+
+// Verify evaluates the corpus against the policies, and runs a simulated probe on all available ports
+// between the pods of the snapshot.
+func (s *Synthesis) Verify(policies *matcher.Policy) *Verification {
+	v := &Verification{}
+	for _, t := range s.Traffic {
+		if !policies.IsTrafficAllowed(t).IsAllowed() {
+			v.Denied = append(v.Denied, t)
+		}
+	}
+
+	runner := probe.NewSimulatedRunner(policies, &probe.JobBuilder{TimeoutSeconds: 10})
+	table := runner.RunProbeForConfig(generator.ProbeAllAvailable, s.Resources)
+	for _, from := range s.Resources.SortedPodNames() {
+		for _, to := range s.Resources.SortedPodNames() {
+			item := table.Get(from, to)
+			for _, key := range slice.Sort(maps.Keys(item.JobResults)) {
+				result := item.JobResults[key]
+				if result.Combined != probe.ConnectivityAllowed {
+					continue
+				}
+				job := result.Job
+				fromWorkload, toWorkload := NewWorkload(job.FromNamespace, job.FromPodLabels), NewWorkload(job.ToNamespace, job.ToPodLabels)
+				if !s.isObserved(fromWorkload, toWorkload, portProtocol{Port: job.ResolvedPort, Protocol: job.Protocol}) {
+					v.Excess = append(v.Excess, result)
+				}
+			}
+		}
+	}
+	return v
+}
+
+func (v *Verification) Passed() bool {
+	return len(v.Denied) == 0 && len(v.Excess) == 0
+}
+
+func (v *Verification) RenderTable() string {
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"Problem", "Traffic"})
+	for _, t := range v.Denied {
+		table.Append([]string{"observed, but denied", t.PrettyString()})
+	}
+	for _, result := range v.Excess {
+		job := result.Job
+		table.Append([]string{"allowed, but not observed", fmt.Sprintf("%s -> %s:%d (%s)", job.FromKey, job.ToKey, job.ResolvedPort, job.Protocol)})
+	}
+
+	table.Render()
+	return tableString.String()
+}
@@ -0,0 +1,111 @@
+package synthesis
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/connectivity/probe"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/matcher"
+)
+
+func synthesisTestResources() *probe.Resources {
+	pod := func(ns string, name string, app string, ip string) *probe.Pod {
+		return &probe.Pod{
+			Namespace: ns,
+			Name:      name,
+			Labels:    map[string]string{"app": app, "pod-template-hash": name},
+			IP:        ip,
+			Containers: []*probe.Container{
+				{Name: "cont-80-tcp", Port: 80, Protocol: v1.ProtocolTCP, PortName: "serve-80-tcp"},
+				{Name: "cont-81-tcp", Port: 81, Protocol: v1.ProtocolTCP, PortName: "serve-81-tcp"},
+			},
+		}
+	}
+	return &probe.Resources{
+		Namespaces: map[string]map[string]string{"x": {}, "y": {}},
+		Pods: []*probe.Pod{
+			pod("x", "web-1", "web", "10.0.0.1"),
+			pod("x", "web-2", "web", "10.0.0.2"),
+			pod("y", "db-1", "db", "10.0.0.3"),
+		},
+	}
+}
+
+func RunSynthesisTests() {
+	Describe("Workload", func() {
+		It("should drop pod-specific labels", func() {
+			w := NewWorkload("x", map[string]string{"app": "web", "pod-template-hash": "abc"})
+			Expect(w.Labels).To(Equal(map[string]string{"app": "web"}))
+			Expect(w.Key()).To(Equal("x/[app=web]"))
+			Expect(w.name()).To(Equal("web"))
+			Expect(NewWorkload("x", nil).name()).To(Equal("unlabeled"))
+		})
+	})
+
+	Describe("Synthesis", func() {
+		resources := synthesisTestResources()
+		peer := func(ns string, app string) *matcher.TrafficPeer {
+			return &matcher.TrafficPeer{Internal: &matcher.InternalPeer{
+				PodLabels:       map[string]string{"app": app, "pod-template-hash": "xyz"},
+				NamespaceLabels: map[string]string{},
+				Namespace:       ns,
+			}}
+		}
+		traffic := []*matcher.Traffic{
+			{Source: peer("x", "web"), Destination: peer("y", "db"), ResolvedPort: 80, Protocol: v1.ProtocolTCP},
+			{Source: peer("x", "web"), Destination: peer("x", "web"), ResolvedPort: 81, Protocol: v1.ProtocolTCP},
+			{Source: peer("y", "db"), Destination: &matcher.TrafficPeer{IP: "192.168.0.1"}, ResolvedPort: 443, Protocol: v1.ProtocolTCP},
+			{Source: &matcher.TrafficPeer{IP: "192.168.0.1"}, Destination: &matcher.TrafficPeer{IP: "192.168.0.2"}, ResolvedPort: 443, Protocol: v1.ProtocolTCP},
+		}
+		s := Synthesize(resources, traffic)
+
+		It("should generalize pods to workloads", func() {
+			Expect(s.Workloads()).To(HaveLen(2))
+			Expect(s.Traffic).To(HaveLen(3))
+			Expect(s.Ignored).To(HaveLen(1))
+			Expect(s.Resources.Namespaces["x"]).To(Equal(map[string]string{NamespaceNameLabel: "x"}))
+		})
+
+		It("should synthesize network policies allowing exactly the observed traffic", func() {
+			netpols := s.NetworkPolicies()
+			Expect(netpols).To(HaveLen(2))
+			Expect(netpols[0].Namespace).To(Equal("x"))
+			Expect(netpols[0].Name).To(Equal("allow-web"))
+			Expect(netpols[0].Spec.Ingress).To(HaveLen(1))
+			Expect(netpols[0].Spec.Egress).To(HaveLen(2))
+			Expect(netpols[1].Spec.Egress[0].To[0].IPBlock.CIDR).To(Equal("192.168.0.1/32"))
+
+			v := s.Verify(matcher.BuildNetworkPolicies(true, netpols))
+			Expect(v.Denied).To(BeEmpty())
+			Expect(v.Excess).To(BeEmpty())
+			Expect(v.Passed()).To(BeTrue())
+		})
+
+		It("should synthesize admin network policies allowing exactly the observed traffic between pods", func() {
+			anps, banp, warnings, err := s.AdminNetworkPolicies(10)
+			Expect(err).To(Succeed())
+			Expect(anps).To(HaveLen(2))
+			Expect(anps[0].Spec.Priority).To(BeEquivalentTo(10))
+			Expect(anps[1].Spec.Priority).To(BeEquivalentTo(11))
+			Expect(warnings).To(HaveLen(1))
+			Expect(banp.Spec.Subject.Namespaces.MatchExpressions[0].Values).To(Equal([]string{"x", "y"}))
+
+			v := s.Verify(matcher.BuildV1AndV2NetPols(true, nil, anps, banp))
+			// egress to 192.168.0.1 isn't affected by the admin policies
+			Expect(v.Denied).To(BeEmpty())
+			Expect(v.Excess).To(BeEmpty())
+		})
+
+		It("should fail if priorities run out", func() {
+			_, _, _, err := s.AdminNetworkPolicies(1000)
+			Expect(err).ToNot(Succeed())
+		})
+
+		It("should detect policies allowing too much", func() {
+			v := s.Verify(matcher.BuildNetworkPolicies(true, nil))
+			Expect(v.Denied).To(BeEmpty())
+			Expect(v.Excess).ToNot(BeEmpty())
+			Expect(v.Passed()).To(BeFalse())
+		})
+	})
+}
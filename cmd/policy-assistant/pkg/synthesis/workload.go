@@ -0,0 +1,73 @@
+package synthesis
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mattfenwick/collections/pkg/slice"
+	"golang.org/x/exp/maps"
+)
+
+// NamespaceNameLabel is set on every namespace by kube, so that namespaces can be selected by name.
+const NamespaceNameLabel = "kubernetes.io/metadata.name"
+
+// podSpecificLabels are added by controllers to tell apart pods (or revisions) of a single workload,
+// so they are left out of selectors.
+var podSpecificLabels = map[string]bool{
+	"pod-template-hash":                  true,
+	"controller-revision-hash":           true,
+	"pod-template-generation":            true,
+	"statefulset.kubernetes.io/pod-name": true,
+	"apps.kubernetes.io/pod-index":       true,
+	"controller-uid":                     true,
+	"batch.kubernetes.io/controller-uid": true,
+}
+
+// WorkloadLabels drops pod-specific labels, so that all pods of a workload have the same labels.
+func WorkloadLabels(podLabels map[string]string) map[string]string {
+	labels := map[string]string{}
+	for key, value := range podLabels {
+		if !podSpecificLabels[key] {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// Workload is a group of pods in a namespace, which is selected by labels.
+type Workload struct {
+	Namespace string
+	Labels    map[string]string
+}
+
+func NewWorkload(namespace string, podLabels map[string]string) *Workload {
+	return &Workload{Namespace: namespace, Labels: WorkloadLabels(podLabels)}
+}
+
+func (w *Workload) Key() string {
+	format := func(k string) string { return fmt.Sprintf("%s=%s", k, w.Labels[k]) }
+	return fmt.Sprintf("%s/[%s]", w.Namespace, strings.Join(slice.Map(format, slice.Sort(maps.Keys(w.Labels))), ","))
+}
+
+var invalidNameCharacters = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// name derives a readable DNS-1123 name from the label values, preferring the conventional app name labels.
+func (w *Workload) name() string {
+	var parts []string
+	if name, ok := w.Labels["app.kubernetes.io/name"]; ok {
+		parts = []string{name}
+	} else if name, ok := w.Labels["app"]; ok {
+		parts = []string{name}
+	} else {
+		parts = slice.Map(func(k string) string { return w.Labels[k] }, slice.Sort(maps.Keys(w.Labels)))
+	}
+	name := strings.Trim(invalidNameCharacters.ReplaceAllString(strings.ToLower(strings.Join(parts, "-")), "-"), "-")
+	if name == "" {
+		return "unlabeled"
+	}
+	if len(name) > 200 {
+		return name[:200]
+	}
+	return name
+}
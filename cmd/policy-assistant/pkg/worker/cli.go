@@ -22,6 +22,7 @@ type Args struct {
 	//Verbosity string
 	Jobs        string
 	Concurrency int
+	Serve       bool
 }
 
 func SetupRootCommand() *cobra.Command {
@@ -38,8 +39,8 @@ func SetupRootCommand() *cobra.Command {
 
 	command.Flags().IntVar(&args.Concurrency, "concurrency", 10, "number of jobs to simultaneously run")
 
-	command.Flags().StringVar(&args.Jobs, "jobs", "", "JSON-formatted string of jobs")
-	utils.DoOrDie(command.MarkFlagRequired("jobs"))
+	command.Flags().StringVar(&args.Jobs, "jobs", "", "JSON-formatted string of jobs; required unless serving")
+	command.Flags().BoolVar(&args.Serve, "serve", false, "if true, keep running: read batches of jobs as JSON from stdin, and write each result as a line of JSON to stdout as soon as it completes")
 
 	return command
 }
@@ -47,6 +48,14 @@ func SetupRootCommand() *cobra.Command {
 func RunWorkerCommand(args *Args) {
 	//utils.DoOrDie(utils.SetUpLogger(args.Verbosity))
 
+	if args.Serve {
+		utils.DoOrDie(NewServer(args.Concurrency).Serve(os.Stdin, os.Stdout))
+		return
+	}
+	if args.Jobs == "" {
+		logrus.Fatalf("--jobs is required unless serving")
+	}
+
 	out, err := RunWorker(args.Jobs, args.Concurrency)
 	utils.DoOrDie(err)
 	fmt.Printf("%s\n", out)
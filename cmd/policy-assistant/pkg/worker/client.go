@@ -8,6 +8,12 @@ import (
 	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
 )
 
+// BatchClient issues a batch of requests from a pod, returning a result for each request.
+type BatchClient interface {
+	Batch(b *Batch) ([]*Result, error)
+}
+
+// Client runs the worker once for each batch, passing the batch as a command line argument.
 type Client struct {
 	Kubernetes kube.IKubernetes
 }
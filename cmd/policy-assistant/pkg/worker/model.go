@@ -2,10 +2,12 @@ package worker
 
 import (
 	"fmt"
-	"github.com/pkg/errors"
-	v1 "k8s.io/api/core/v1"
 	"net"
 	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
 )
 
 type Batch struct {
@@ -24,40 +26,212 @@ func (b *Batch) IsValid() error {
 		if !protocols[r.Protocol] {
 			return errors.Errorf("invalid protocol %+v", r)
 		}
+		if _, err := ParseRequestKind(string(r.kind())); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// Outcome distinguishes the ways a request can fail, so that traffic blocked by a policy can be told apart
+// from, for example, a server which isn't listening or which answers with an error.
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeRefused Outcome = "refused"
+	OutcomeTimeout Outcome = "timeout"
+	OutcomeReset   Outcome = "reset"
+	// OutcomeAppError means a connection was made, but the application-layer check failed: an unexpected
+	// HTTP status, a failed TLS handshake or a failed DNS lookup
+	OutcomeAppError Outcome = "app-error"
+	// OutcomeFailed is any other failure
+	OutcomeFailed Outcome = "failed"
+)
+
 type Result struct {
 	Request *Request
 	Output  string
 	Error   string
+	Outcome Outcome
 }
 
 func (r *Result) IsSuccess() bool {
 	return r.Error == ""
 }
 
+type RequestKind string
+
+const (
+	// RequestKindConnect only checks that a connection can be made
+	RequestKindConnect RequestKind = "connect"
+	// RequestKindHTTP issues an HTTP GET, and checks the status
+	RequestKindHTTP RequestKind = "http"
+	// RequestKindTLS issues an HTTPS GET, which succeeds if the TLS handshake does, whatever the status
+	RequestKindTLS RequestKind = "tls"
+	// RequestKindDNS looks up the host through the pod's resolver; the port is ignored
+	RequestKindDNS RequestKind = "dns"
+)
+
+var AllRequestKinds = []string{
+	string(RequestKindConnect),
+	string(RequestKindHTTP),
+	string(RequestKindTLS),
+	string(RequestKindDNS),
+}
+
+func ParseRequestKind(kind string) (RequestKind, error) {
+	for _, k := range AllRequestKinds {
+		if kind == k {
+			return RequestKind(k), nil
+		}
+	}
+	return "", errors.Errorf("invalid request kind '%s', must be one of %s", kind, strings.Join(AllRequestKinds, ", "))
+}
+
 type Request struct {
 	Key      string
 	Protocol v1.Protocol
 	Host     string
 	Port     int
+	// Kind defaults to connect
+	Kind RequestKind `json:",omitempty"`
+	// Path and ExpectedStatus apply to http requests; they default to / and 200
+	Path           string `json:",omitempty"`
+	ExpectedStatus int    `json:",omitempty"`
+	// TimeoutSeconds defaults to 1
+	TimeoutSeconds int `json:",omitempty"`
 }
 
 func (r *Request) Address() string {
 	return net.JoinHostPort(r.Host, strconv.Itoa(r.Port))
 }
 
+func (r *Request) kind() RequestKind {
+	if r.Kind == "" {
+		return RequestKindConnect
+	}
+	return r.Kind
+}
+
+func (r *Request) timeoutSeconds() int {
+	if r.TimeoutSeconds <= 0 {
+		return 1
+	}
+	return r.TimeoutSeconds
+}
+
+func (r *Request) path() string {
+	if r.Path == "" {
+		return "/"
+	}
+	return r.Path
+}
+
+func (r *Request) expectedStatus() int {
+	if r.ExpectedStatus == 0 {
+		return 200
+	}
+	return r.ExpectedStatus
+}
+
+// Command builds the command which issues the request from an agnhost container: 'agnhost connect'
+// for connect requests, curl for http and tls requests, and dig for dns requests.
 func (r *Request) Command() []string {
-	switch r.Protocol {
-	case v1.ProtocolSCTP:
-		return []string{"/agnhost", "connect", r.Address(), "--timeout=1s", "--protocol=sctp"}
-	case v1.ProtocolTCP:
-		return []string{"/agnhost", "connect", r.Address(), "--timeout=1s", "--protocol=tcp"}
-	case v1.ProtocolUDP:
-		return []string{"/agnhost", "connect", r.Address(), "--timeout=1s", "--protocol=udp"}
+	timeout := strconv.Itoa(r.timeoutSeconds())
+	switch r.kind() {
+	case RequestKindConnect:
+		switch r.Protocol {
+		case v1.ProtocolSCTP, v1.ProtocolTCP, v1.ProtocolUDP:
+			return []string{"/agnhost", "connect", r.Address(), fmt.Sprintf("--timeout=%ss", timeout), fmt.Sprintf("--protocol=%s", strings.ToLower(string(r.Protocol)))}
+		default:
+			panic(errors.Errorf("protocol %s not supported", r.Protocol))
+		}
+	case RequestKindHTTP:
+		return []string{"curl", "--silent", "--show-error", "--output", "/dev/null", "--write-out", "%{http_code}", "--max-time", timeout, fmt.Sprintf("http://%s%s", r.Address(), r.path())}
+	case RequestKindTLS:
+		return []string{"curl", "--silent", "--show-error", "--insecure", "--output", "/dev/null", "--max-time", timeout, fmt.Sprintf("https://%s%s", r.Address(), r.path())}
+	case RequestKindDNS:
+		return []string{"dig", fmt.Sprintf("+time=%s", timeout), "+tries=1", r.Host}
 	default:
-		panic(errors.Errorf("protocol %s not supported", r.Protocol))
+		panic(errors.Errorf("request kind %s not supported", r.Kind))
+	}
+}
+
+// tlsCurlExitCodes are the curl exit codes of a failed TLS handshake or certificate check, which can only
+// happen once the connection is made.
+var tlsCurlExitCodes = map[int]bool{
+	35: true, // SSL connect error
+	51: true, // peer certificate or fingerprint wasn't OK
+	53: true, // crypto engine not found
+	54: true, // cannot set crypto engine as default
+	58: true, // problem with the local certificate
+	59: true, // couldn't use the specified cipher
+	60: true, // peer certificate cannot be authenticated
+	66: true, // failed to initialize the TLS engine
+	77: true, // problem reading the CA certificates
+	80: true, // failed to shut down the TLS connection
+	82: true, // could not load the CRL file
+	83: true, // TLS issuer check failed
+	90: true, // SSL public key doesn't match the pinned key
+	91: true, // invalid SSL certificate status
+}
+
+// curlExitCode parses the exit code from curl's error message, such as "curl: (35) ...".
+func curlExitCode(output string) (int, bool) {
+	_, rest, ok := strings.Cut(output, "curl: (")
+	if !ok {
+		return 0, false
+	}
+	code, _, ok := strings.Cut(rest, ")")
+	if !ok {
+		return 0, false
+	}
+	exitCode, err := strconv.Atoi(code)
+	return exitCode, err == nil
+}
+
+// Classify determines the outcome of the request from the output of its command, and the error from
+// running the command, if any.  Failures are only application errors if the response shows that a
+// connection was made: an unexpected HTTP status, a failed TLS handshake, or a DNS response code other
+// than NOERROR.  Any other failure -- for example, curl failing to connect, or "no route to host" from
+// traffic rejected with ICMP -- is counted as blocked.
+func (r *Request) Classify(output string, err error) Outcome {
+	lowerOutput := strings.ToLower(output)
+	if err != nil {
+		switch {
+		case strings.Contains(lowerOutput, "refused"):
+			return OutcomeRefused
+		case strings.Contains(lowerOutput, "timeout") || strings.Contains(lowerOutput, "timed out"):
+			return OutcomeTimeout
+		case strings.Contains(lowerOutput, "reset"):
+			return OutcomeReset
+		}
+		switch r.kind() {
+		case RequestKindHTTP, RequestKindTLS:
+			if code, ok := curlExitCode(output); ok && tlsCurlExitCodes[code] {
+				return OutcomeAppError
+			}
+		case RequestKindDNS:
+			if strings.Contains(lowerOutput, "status: ") {
+				return OutcomeAppError
+			}
+		}
+		return OutcomeFailed
+	}
+	switch r.kind() {
+	case RequestKindHTTP:
+		if strings.TrimSpace(output) != strconv.Itoa(r.expectedStatus()) {
+			return OutcomeAppError
+		}
+	case RequestKindDNS:
+		if !strings.Contains(lowerOutput, "status: ") {
+			// no response from the server
+			return OutcomeFailed
+		}
+		if !strings.Contains(lowerOutput, "status: noerror") {
+			return OutcomeAppError
+		}
 	}
+	return OutcomeSuccess
 }
@@ -0,0 +1,56 @@
+package worker
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+func RunModelTests() {
+	Describe("Request", func() {
+		request := func(kind RequestKind) *Request {
+			return &Request{Key: "x/a", Protocol: v1.ProtocolTCP, Host: "s-x-a.x.svc.cluster.local", Port: 80, Kind: kind, TimeoutSeconds: 3}
+		}
+		failed := errors.Errorf("command terminated with exit code 1")
+
+		It("should build a command for each kind", func() {
+			Expect(request("").Command()).To(Equal([]string{"/agnhost", "connect", "s-x-a.x.svc.cluster.local:80", "--timeout=3s", "--protocol=tcp"}))
+			Expect(request(RequestKindHTTP).Command()).To(ContainElement("http://s-x-a.x.svc.cluster.local:80/"))
+			Expect(request(RequestKindTLS).Command()).To(ContainElement("https://s-x-a.x.svc.cluster.local:80/"))
+			Expect(request(RequestKindDNS).Command()).To(Equal([]string{"dig", "+time=3", "+tries=1", "s-x-a.x.svc.cluster.local"}))
+		})
+
+		It("should classify connection failures", func() {
+			Expect(request("").Classify("", nil)).To(Equal(OutcomeSuccess))
+			Expect(request("").Classify("TIMEOUT\n", failed)).To(Equal(OutcomeTimeout))
+			Expect(request("").Classify("REFUSED\n", failed)).To(Equal(OutcomeRefused))
+			Expect(request("").Classify("OTHER: something\n", failed)).To(Equal(OutcomeFailed))
+			Expect(request(RequestKindHTTP).Classify("curl: (7) Failed to connect to s-x-a port 80: Connection refused", failed)).To(Equal(OutcomeRefused))
+			Expect(request(RequestKindHTTP).Classify("curl: (28) Connection timed out after 3001 milliseconds", failed)).To(Equal(OutcomeTimeout))
+			Expect(request(RequestKindHTTP).Classify("curl: (56) Recv failure: Connection reset by peer", failed)).To(Equal(OutcomeReset))
+			Expect(request(RequestKindDNS).Classify(";; connection timed out; no servers could be reached", failed)).To(Equal(OutcomeTimeout))
+			Expect(request(RequestKindHTTP).Classify("curl: (7) Failed to connect to s-x-a port 80 after 3 ms: Couldn't connect to server", failed)).To(Equal(OutcomeFailed))
+			Expect(request(RequestKindHTTP).Classify("curl: (7) Failed to connect to s-x-a port 80: No route to host", failed)).To(Equal(OutcomeFailed))
+			Expect(request(RequestKindTLS).Classify("curl: (6) Could not resolve host: s-x-a.x.svc.cluster.local", failed)).To(Equal(OutcomeFailed))
+			Expect(request(RequestKindDNS).Classify(";; communications error to 10.96.0.10#53: host unreachable", failed)).To(Equal(OutcomeFailed))
+			Expect(request(RequestKindDNS).Classify("", nil)).To(Equal(OutcomeFailed))
+		})
+
+		It("should classify application errors", func() {
+			Expect(request(RequestKindHTTP).Classify("200", nil)).To(Equal(OutcomeSuccess))
+			Expect(request(RequestKindHTTP).Classify("503", nil)).To(Equal(OutcomeAppError))
+			Expect((&Request{Kind: RequestKindHTTP, ExpectedStatus: 503}).Classify("503", nil)).To(Equal(OutcomeSuccess))
+			Expect(request(RequestKindTLS).Classify("curl: (35) error:0A00010B:SSL routines::wrong version number", failed)).To(Equal(OutcomeAppError))
+			Expect(request(RequestKindTLS).Classify("curl: (60) SSL certificate problem: self-signed certificate", failed)).To(Equal(OutcomeAppError))
+			Expect(request(RequestKindDNS).Classify(";; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 1", nil)).To(Equal(OutcomeSuccess))
+			Expect(request(RequestKindDNS).Classify(";; ->>HEADER<<- opcode: QUERY, status: NXDOMAIN, id: 1", nil)).To(Equal(OutcomeAppError))
+		})
+
+		It("should reject unknown kinds", func() {
+			_, err := ParseRequestKind("icmp")
+			Expect(err).To(HaveOccurred())
+			Expect((&Batch{Requests: []*Request{request("icmp")}}).IsValid()).ToNot(Succeed())
+		})
+	})
+}
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Server is the long-lived mode of the worker: it reads batches, as a stream of json objects, from its
+// input, and writes each result as a line of json as soon as its request completes.  Like the one-shot
+// mode, it's meant to be run through an exec stream, so that its traffic isn't subject to network policies.
+type Server struct {
+	Concurrency int
+	Issue       func(r *Request) *Result
+}
+
+func NewServer(concurrency int) *Server {
+	return &Server{
+		Concurrency: concurrency,
+		Issue: func(r *Request) *Result {
+			return IssueRequestWithRetries(r, 1)
+		},
+	}
+}
+
+// Serve runs until its input is closed and all requests have completed.  Requests from different batches
+// may run at the same time, so results are only ordered by completion.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	requests := make(chan *Request)
+	encoder := json.NewEncoder(out)
+	var lock sync.Mutex
+	var writeErr error
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for request := range requests {
+				result := s.Issue(request)
+				lock.Lock()
+				if err := encoder.Encode(result); err != nil && writeErr == nil {
+					writeErr = errors.Wrapf(err, "unable to write result")
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	decoder := json.NewDecoder(in)
+	var readErr error
+	for {
+		var batch Batch
+		if err := decoder.Decode(&batch); err == io.EOF {
+			break
+		} else if err != nil {
+			readErr = errors.Wrapf(err, "unable to read batch")
+			break
+		}
+		if err := batch.IsValid(); err != nil {
+			readErr = err
+			break
+		}
+		for _, request := range batch.Requests {
+			requests <- request
+		}
+	}
+	close(requests)
+	wg.Wait()
+
+	if readErr != nil {
+		return readErr
+	}
+	return writeErr
+}
@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+)
+
+// fakeIssue succeeds for requests to port 80, and fails for all others.
+func fakeIssue(r *Request) *Result {
+	if r.Port == 80 {
+		return &Result{Request: r}
+	}
+	return &Result{Request: r, Error: "connection refused"}
+}
+
+// streamingMock runs a worker server in-process for each streamed command.
+type streamingMock struct {
+	*kube.MockKubernetes
+	lock   sync.Mutex
+	starts int
+	err    error
+}
+
+func (s *streamingMock) StreamRemoteCommand(namespace string, pod string, container string, command []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	s.lock.Lock()
+	s.starts++
+	err := s.err
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	return (&Server{Concurrency: 3, Issue: fakeIssue}).Serve(stdin, stdout)
+}
+
+func newBatch(ports ...int) *Batch {
+	batch := &Batch{Namespace: "x", Pod: "a", Container: "cont-80-tcp"}
+	for _, port := range ports {
+		batch.Requests = append(batch.Requests, &Request{Key: fmt.Sprintf("y/b:%d", port), Protocol: v1.ProtocolTCP, Host: "1.2.3.4", Port: port})
+	}
+	return batch
+}
+
+func RunServerTests() {
+	Describe("Server", func() {
+		It("should write a result for every request of every batch", func() {
+			var in bytes.Buffer
+			for _, batch := range []*Batch{newBatch(80, 81), newBatch(82, 80, 83)} {
+				Expect(json.NewEncoder(&in).Encode(batch)).To(Succeed())
+			}
+			var out bytes.Buffer
+			Expect((&Server{Concurrency: 2, Issue: fakeIssue}).Serve(&in, &out)).To(Succeed())
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			Expect(lines).To(HaveLen(5))
+			successes := 0
+			for _, line := range lines {
+				var result Result
+				Expect(json.Unmarshal([]byte(line), &result)).To(Succeed())
+				if result.IsSuccess() {
+					successes++
+				}
+			}
+			Expect(successes).To(Equal(2))
+		})
+
+		It("should stop on an invalid batch", func() {
+			batch := newBatch(80)
+			batch.Requests[0].Protocol = "ICMP"
+			bs, err := json.Marshal(batch)
+			Expect(err).ToNot(HaveOccurred())
+			in := bytes.NewBuffer(bs)
+			Expect((&Server{Concurrency: 1, Issue: fakeIssue}).Serve(in, io.Discard)).ToNot(Succeed())
+		})
+	})
+
+	Describe("StreamClient", func() {
+		It("should reuse one server for several batches", func() {
+			mock := &streamingMock{MockKubernetes: kube.NewMockKubernetes(1.0)}
+			client := NewStreamClient(mock, 3, time.Minute)
+
+			for _, batch := range []*Batch{newBatch(80, 81, 82), newBatch(80, 83)} {
+				results, err := client.Batch(batch)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(results).To(HaveLen(len(batch.Requests)))
+			}
+			Expect(mock.starts).To(Equal(1))
+		})
+
+		It("should stop its servers when closed", func() {
+			mock := &streamingMock{MockKubernetes: kube.NewMockKubernetes(1.0)}
+			client := NewStreamClient(mock, 3, time.Minute)
+
+			_, err := client.Batch(newBatch(80))
+			Expect(err).ToNot(HaveOccurred())
+			session := client.sessions[newBatch().Key()]
+			Expect(client.Close()).To(Succeed())
+			Expect(session.stopped()).To(BeTrue())
+			Expect(client.sessions).To(BeEmpty())
+
+			results, err := client.Batch(newBatch(80))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(mock.starts).To(Equal(2))
+		})
+
+		It("should restart a server which stopped", func() {
+			mock := &streamingMock{MockKubernetes: kube.NewMockKubernetes(1.0), err: errors.Errorf("pod not found")}
+			client := NewStreamClient(mock, 3, time.Minute)
+
+			_, err := client.Batch(newBatch(80))
+			Expect(err).To(HaveOccurred())
+
+			mock.lock.Lock()
+			mock.err = nil
+			mock.lock.Unlock()
+			results, err := client.Batch(newBatch(80))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(mock.starts).To(Equal(2))
+		})
+	})
+}
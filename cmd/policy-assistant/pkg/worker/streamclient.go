@@ -0,0 +1,202 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"sigs.k8s.io/network-policy-api/policy-assistant/pkg/kube"
+)
+
+// StreamClient keeps a worker server running in each container it sends batches to, connected through an
+// exec stream, which avoids the setup cost of an exec per batch and the size limit of passing the batch
+// as an argument.  If a server stops -- for example, because its pod was deleted -- it's restarted on the
+// next batch.
+type StreamClient struct {
+	Kubernetes  kube.IKubernetes
+	Concurrency int
+	Timeout     time.Duration
+
+	lock     sync.Mutex
+	sessions map[string]*streamSession
+}
+
+func NewStreamClient(kubernetes kube.IKubernetes, concurrency int, timeout time.Duration) *StreamClient {
+	return &StreamClient{
+		Kubernetes:  kubernetes,
+		Concurrency: concurrency,
+		Timeout:     timeout,
+		sessions:    map[string]*streamSession{},
+	}
+}
+
+type streamSession struct {
+	stdin *io.PipeWriter
+
+	lock    sync.Mutex
+	pending map[string]chan<- *Result
+	done    chan struct{}
+	err     error
+}
+
+func (c *StreamClient) session(b *Batch) *streamSession {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if session, ok := c.sessions[b.Key()]; ok && !session.stopped() {
+		return session
+	}
+	logrus.Infof("starting worker server in %s", b.Key())
+	session := c.startSession(b)
+	c.sessions[b.Key()] = session
+	return session
+}
+
+func (c *StreamClient) startSession(b *Batch) *streamSession {
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+	session := &streamSession{stdin: stdinWriter, pending: map[string]chan<- *Result{}, done: make(chan struct{})}
+
+	command := []string{"/worker", "--serve", fmt.Sprintf("--concurrency=%d", c.Concurrency)}
+	go func() {
+		stderr := logrus.StandardLogger().WriterLevel(logrus.DebugLevel)
+		defer stderr.Close()
+		err := c.Kubernetes.StreamRemoteCommand(b.Namespace, b.Pod, b.Container, command, stdinReader, stdoutWriter, stderr)
+		if err == nil {
+			err = errors.Errorf("worker server exited")
+		}
+		stdoutWriter.CloseWithError(err)
+	}()
+	go func() {
+		err := session.receive(stdoutReader)
+		session.stop(err)
+		stdinReader.CloseWithError(err)
+	}()
+	return session
+}
+
+// receive dispatches results to the batches waiting for them, until the output of the server ends.
+func (s *streamSession) receive(stdout io.Reader) error {
+	decoder := json.NewDecoder(stdout)
+	for {
+		var result Result
+		if err := decoder.Decode(&result); err != nil {
+			return errors.Wrapf(err, "unable to read result")
+		}
+		if result.Request == nil {
+			return errors.Errorf("invalid result: missing request")
+		}
+		s.lock.Lock()
+		results, ok := s.pending[result.Request.Key]
+		delete(s.pending, result.Request.Key)
+		s.lock.Unlock()
+		if ok {
+			results <- &result
+		} else {
+			logrus.Debugf("dropping result for %s: no batch is waiting for it", result.Request.Key)
+		}
+	}
+}
+
+func (s *streamSession) stop(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.err = err
+	close(s.done)
+}
+
+func (s *streamSession) stopped() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *streamSession) register(requests []*Request, results chan<- *Result) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, r := range requests {
+		if _, ok := s.pending[r.Key]; ok {
+			return errors.Errorf("request %s is already in progress", r.Key)
+		}
+	}
+	for _, r := range requests {
+		s.pending[r.Key] = results
+	}
+	return nil
+}
+
+func (s *streamSession) unregister(requests []*Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, r := range requests {
+		delete(s.pending, r.Key)
+	}
+}
+
+func (c *StreamClient) Batch(b *Batch) ([]*Result, error) {
+	bytes, err := json.Marshal(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to marshal json")
+	}
+
+	session := c.session(b)
+	resultsChan := make(chan *Result, len(b.Requests))
+	if err := session.register(b.Requests, resultsChan); err != nil {
+		return nil, err
+	}
+	defer session.unregister(b.Requests)
+
+	logrus.Infof("sending %s worker server %d requests", b.Key(), len(b.Requests))
+	if _, err := session.stdin.Write(append(bytes, '\n')); err != nil {
+		return nil, errors.Wrapf(err, "unable to send batch to %s worker server", b.Key())
+	}
+
+	var results []*Result
+	timeout := time.After(c.Timeout)
+	for len(results) < len(b.Requests) {
+		select {
+		case result := <-resultsChan:
+			results = append(results, result)
+		case <-session.done:
+			// results may have arrived just before the server stopped
+			for len(results) < len(b.Requests) && len(resultsChan) > 0 {
+				results = append(results, <-resultsChan)
+			}
+			if len(results) < len(b.Requests) {
+				return results, errors.WithMessagef(session.err, "%s worker server stopped after %d of %d results", b.Key(), len(results), len(b.Requests))
+			}
+		case <-timeout:
+			return results, errors.Errorf("timed out waiting for %s worker server: got %d of %d results", b.Key(), len(results), len(b.Requests))
+		}
+	}
+	return results, nil
+}
+
+// Close stops the worker servers by closing their input, and waits for them to finish the requests they
+// have in progress, or for the timeout.  Batches sent afterwards start new servers.
+func (c *StreamClient) Close() error {
+	c.lock.Lock()
+	sessions := c.sessions
+	c.sessions = map[string]*streamSession{}
+	c.lock.Unlock()
+
+	timeout := time.After(c.Timeout)
+	for key, session := range sessions {
+		if err := session.stdin.Close(); err != nil {
+			return errors.Wrapf(err, "unable to stop %s worker server", key)
+		}
+		select {
+		case <-session.done:
+		case <-timeout:
+			return errors.Errorf("timed out waiting for %s worker server to stop", key)
+		}
+	}
+	return nil
+}
@@ -2,9 +2,11 @@ package worker
 
 import (
 	"encoding/json"
+	"fmt"
+	"os/exec"
+
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
-	"os/exec"
 )
 
 var (
@@ -71,15 +73,19 @@ func IssueRequest(r *Request) *Result {
 	command := r.Command()
 	name, args := command[0], command[1:]
 	cmd := exec.Command(name, args...)
-	out, err := cmd.Output()
+	out, err := cmd.CombinedOutput()
+	outcome := r.Classify(string(out), err)
 	var errString string
 	if err != nil {
 		errString = errors.Wrapf(err, "unable to run command '%s'", cmd.String()).Error()
+	} else if outcome != OutcomeSuccess {
+		errString = fmt.Sprintf("%s from command '%s'", outcome, cmd.String())
 	}
 	return &Result{
 		Request: r,
 		Output:  string(out),
 		Error:   errString,
+		Outcome: outcome,
 	}
 }
 
@@ -0,0 +1,110 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command validating-webhook serves a validating admission webhook which runs
+// semantic checks on ClusterNetworkPolicies, warning about or denying
+// policies which the CRD's schema accepts but which likely don't do what
+// their author intended. See config/validating-webhook for its deployment.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/network-policy-api/pkg/client/clientset/versioned"
+	"sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions"
+	"sigs.k8s.io/network-policy-api/pkg/webhook"
+)
+
+var (
+	kubeConfig = flag.String("kubeConfig", "", "KUBE_CONFIG for the cluster to use. If unset, the in-cluster configuration is used")
+	checks     = flag.String("checks", "", fmt.Sprintf("comma-separated <check>=<action> pairs, e.g. %s=%s; checks are %v, actions are %s, %s and %s; checks which aren't listed warn",
+		webhook.OverlappingSubjectsCheck, webhook.ActionDeny, webhook.AllChecks, webhook.ActionIgnore, webhook.ActionWarn, webhook.ActionDeny))
+	port     = flag.Int("port", 8443, "port to serve HTTPS on")
+	certFile = flag.String("tls-cert-file", "/etc/webhook/tls/tls.crt", "file containing the serving certificate")
+	keyFile  = flag.String("tls-private-key-file", "/etc/webhook/tls/tls.key", "file containing the serving certificate's private key")
+)
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	checkConfig, err := webhook.ParseConfig(*checks)
+	if err != nil {
+		klog.Fatalf("Invalid -checks: %v", err)
+	}
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeConfig)
+	if err != nil {
+		klog.Fatalf("Failed to load the client configuration: %v", err)
+	}
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		klog.Fatalf("Failed to create the client: %v", err)
+	}
+	policyClient, err := versioned.NewForConfig(config)
+	if err != nil {
+		klog.Fatalf("Failed to create the client: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	kubeInformers := informers.NewSharedInformerFactory(kubeClient, 0)
+	policyInformers := externalversions.NewSharedInformerFactory(policyClient, 0)
+	validator := webhook.NewValidator(checkConfig, kubeInformers, policyInformers)
+	kubeInformers.Start(ctx.Done())
+	policyInformers.Start(ctx.Done())
+	defer kubeInformers.Shutdown()
+	defer policyInformers.Shutdown()
+	if !cache.WaitForCacheSync(ctx.Done(), validator.Synced...) {
+		klog.Fatalf("Failed to sync the informers")
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/validate", validator)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", *port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("Failed to shut down the server: %v", err)
+		}
+	}()
+
+	klog.Infof("Serving on port %d with checks %q", *port, checkConfig)
+	if err := server.ListenAndServeTLS(*certFile, *keyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		klog.Fatalf("Server failed: %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/network-policy-api/apis/v1alpha2"
+	"sigs.k8s.io/network-policy-api/pkg/client/clientset/versioned"
+	"sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions"
+	listersv1alpha1 "sigs.k8s.io/network-policy-api/pkg/client/listers/apis/v1alpha1"
+	listersv1alpha2 "sigs.k8s.io/network-policy-api/pkg/client/listers/apis/v1alpha2"
+)
+
+const (
+	// MigratedFromAnnotation is set on the ClusterNetworkPolicies created by
+	// the Controller to the kind and name of their v1alpha1 policy, e.g.
+	// "AdminNetworkPolicy/deny-all". The Controller only updates
+	// ClusterNetworkPolicies which have it.
+	MigratedFromAnnotation = "policy.networking.k8s.io/migrated-from"
+
+	// MigratedCondition is the type of the condition the Controller sets on
+	// v1alpha1 policies.
+	MigratedCondition = "Migrated"
+
+	// ReasonConverted means that the ClusterNetworkPolicy is up to date.
+	ReasonConverted = "Converted"
+	// ReasonConversionIssues means that the policy has no faithful
+	// ClusterNetworkPolicy equivalent; the condition's message lists the
+	// issues. The ClusterNetworkPolicy is left as it is.
+	ReasonConversionIssues = "ConversionIssues"
+	// ReasonConflict means that a ClusterNetworkPolicy with the policy's name
+	// exists, but wasn't created from it.
+	ReasonConflict = "Conflict"
+	// ReasonFailed means that the ClusterNetworkPolicy couldn't be written.
+	ReasonFailed = "Failed"
+
+	adminNetworkPolicyKind         = "AdminNetworkPolicy"
+	baselineAdminNetworkPolicyKind = "BaselineAdminNetworkPolicy"
+)
+
+// Controller keeps a ClusterNetworkPolicy equivalent to each v1alpha1
+// AdminNetworkPolicy and BaselineAdminNetworkPolicy, and reports the outcome
+// in a Migrated condition of the v1alpha1 policy. Policies with conversion
+// issues aren't converted.
+//
+// ClusterNetworkPolicies aren't deleted along with their v1alpha1 policy, so
+// that the v1alpha1 policies can be removed once the migration is done.
+type Controller struct {
+	client     versioned.Interface
+	anpLister  listersv1alpha1.AdminNetworkPolicyLister
+	banpLister listersv1alpha1.BaselineAdminNetworkPolicyLister
+	cnpLister  listersv1alpha2.ClusterNetworkPolicyLister
+	synced     []cache.InformerSynced
+	// queue holds keys of the form "<kind>/<name>" of v1alpha1 policies.
+	queue workqueue.TypedRateLimitingInterface[string]
+}
+
+// NewController creates a Controller which watches policies through the
+// informers. The informers must be started after the Controller is created.
+func NewController(client versioned.Interface, informers externalversions.SharedInformerFactory) (*Controller, error) {
+	anpInformer := informers.Policy().V1alpha1().AdminNetworkPolicies()
+	banpInformer := informers.Policy().V1alpha1().BaselineAdminNetworkPolicies()
+	cnpInformer := informers.Policy().V1alpha2().ClusterNetworkPolicies()
+	c := &Controller{
+		client:     client,
+		anpLister:  anpInformer.Lister(),
+		banpLister: banpInformer.Lister(),
+		cnpLister:  cnpInformer.Lister(),
+		synced: []cache.InformerSynced{
+			anpInformer.Informer().HasSynced,
+			banpInformer.Informer().HasSynced,
+			cnpInformer.Informer().HasSynced,
+		},
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "migrate"},
+		),
+	}
+
+	if _, err := anpInformer.Informer().AddEventHandler(c.policyHandler(adminNetworkPolicyKind)); err != nil {
+		return nil, err
+	}
+	if _, err := banpInformer.Informer().AddEventHandler(c.policyHandler(baselineAdminNetworkPolicyKind)); err != nil {
+		return nil, err
+	}
+	// Changes to a converted ClusterNetworkPolicy, including its deletion,
+	// resync its v1alpha1 policy.
+	enqueueSource := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		if cnp, ok := obj.(*v1alpha2.ClusterNetworkPolicy); ok && cnp.Annotations[MigratedFromAnnotation] != "" {
+			c.queue.Add(cnp.Annotations[MigratedFromAnnotation])
+		}
+	}
+	if _, err := cnpInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, obj interface{}) { enqueueSource(obj) },
+		DeleteFunc: enqueueSource,
+	}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Controller) policyHandler(kind string) cache.ResourceEventHandler {
+	enqueue := func(obj interface{}) {
+		name, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err != nil {
+			utilruntime.HandleError(err)
+			return
+		}
+		c.queue.Add(kind + "/" + name)
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(_, obj interface{}) { enqueue(obj) },
+		DeleteFunc: enqueue,
+	}
+}
+
+// Run processes policies with the given number of workers until the context
+// is cancelled.
+func (c *Controller) Run(ctx context.Context, workers int) error {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Waiting for informer caches to sync")
+	if !cache.WaitForCacheSync(ctx.Done(), c.synced...) {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+	klog.Infof("Starting %d workers", workers)
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (c *Controller) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextItem(ctx context.Context) bool {
+	key, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.sync(ctx, key); err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to sync %s: %w", key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+// sync converts the v1alpha1 policy with the given key, and writes the
+// ClusterNetworkPolicy and the policy's condition.
+func (c *Controller) sync(ctx context.Context, key string) error {
+	kind, name, _ := strings.Cut(key, "/")
+	switch kind {
+	case adminNetworkPolicyKind:
+		anp, err := c.anpLister.Get(name)
+		if apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		cnp, issues := ConvertAdminNetworkPolicy(anp)
+		condition, applyErr := c.apply(ctx, key, anp.Generation, cnp, issues)
+		updated := anp.DeepCopy()
+		if meta.SetStatusCondition(&updated.Status.Conditions, condition) {
+			if _, err := c.client.PolicyV1alpha1().AdminNetworkPolicies().UpdateStatus(ctx, updated, metav1.UpdateOptions{}); err != nil {
+				return err
+			}
+		}
+		return applyErr
+	case baselineAdminNetworkPolicyKind:
+		banp, err := c.banpLister.Get(name)
+		if apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		cnp, issues := ConvertBaselineAdminNetworkPolicy(banp)
+		condition, applyErr := c.apply(ctx, key, banp.Generation, cnp, issues)
+		updated := banp.DeepCopy()
+		if meta.SetStatusCondition(&updated.Status.Conditions, condition) {
+			if _, err := c.client.PolicyV1alpha1().BaselineAdminNetworkPolicies().UpdateStatus(ctx, updated, metav1.UpdateOptions{}); err != nil {
+				return err
+			}
+		}
+		return applyErr
+	default:
+		return fmt.Errorf("unknown kind %q", kind)
+	}
+}
+
+// apply creates or updates the converted ClusterNetworkPolicy, unless there
+// are conversion issues or the existing ClusterNetworkPolicy wasn't created
+// from this policy, and returns the resulting condition. The error is only
+// set if writing the ClusterNetworkPolicy failed, so that it's retried.
+func (c *Controller) apply(ctx context.Context, source string, generation int64, cnp *v1alpha2.ClusterNetworkPolicy, issues []*Issue) (metav1.Condition, error) {
+	condition := metav1.Condition{
+		Type:               MigratedCondition,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+	}
+	if len(issues) > 0 {
+		var messages []string
+		for _, issue := range issues {
+			messages = append(messages, issue.Field+": "+issue.Message)
+		}
+		condition.Reason = ReasonConversionIssues
+		condition.Message = strings.Join(messages, "; ")
+		return condition, nil
+	}
+
+	if cnp.Annotations == nil {
+		cnp.Annotations = map[string]string{}
+	}
+	cnp.Annotations[MigratedFromAnnotation] = source
+
+	existing, err := c.cnpLister.Get(cnp.Name)
+	switch {
+	case apierrors.IsNotFound(err):
+		_, err = c.client.PolicyV1alpha2().ClusterNetworkPolicies().Create(ctx, cnp, metav1.CreateOptions{})
+	case err != nil:
+		// Reported below.
+	case existing.Annotations[MigratedFromAnnotation] != source:
+		condition.Reason = ReasonConflict
+		condition.Message = fmt.Sprintf("ClusterNetworkPolicy %s already exists and wasn't created from this policy", cnp.Name)
+		return condition, nil
+	case !apiequality.Semantic.DeepEqual(existing.Spec, cnp.Spec) ||
+		!apiequality.Semantic.DeepEqual(existing.Labels, cnp.Labels) ||
+		!apiequality.Semantic.DeepEqual(existing.Annotations, cnp.Annotations):
+		updated := existing.DeepCopy()
+		updated.Labels = cnp.Labels
+		updated.Annotations = cnp.Annotations
+		updated.Spec = cnp.Spec
+		_, err = c.client.PolicyV1alpha2().ClusterNetworkPolicies().Update(ctx, updated, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		condition.Reason = ReasonFailed
+		condition.Message = fmt.Sprintf("unable to write ClusterNetworkPolicy %s: %s", cnp.Name, err)
+		return condition, err
+	}
+
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = ReasonConverted
+	condition.Message = fmt.Sprintf("converted to ClusterNetworkPolicy %s", cnp.Name)
+	return condition, nil
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha2"
+	"sigs.k8s.io/network-policy-api/pkg/client/clientset/versioned/fake"
+	"sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions"
+)
+
+func newTestController(t *testing.T, objects ...runtime.Object) (*Controller, *fake.Clientset) {
+	client := fake.NewSimpleClientset(objects...)
+	informers := externalversions.NewSharedInformerFactory(client, 0)
+	c, err := NewController(client, informers)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	informers.Start(ctx.Done())
+	informers.WaitForCacheSync(ctx.Done())
+	return c, client
+}
+
+func testANP(name string, rules int) *v1alpha1.AdminNetworkPolicy {
+	anp := &v1alpha1.AdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 3},
+		Spec: v1alpha1.AdminNetworkPolicySpec{
+			Priority: 5,
+			Subject:  v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+		},
+	}
+	for i := 0; i < rules; i++ {
+		anp.Spec.Ingress = append(anp.Spec.Ingress, v1alpha1.AdminNetworkPolicyIngressRule{
+			Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+			From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: namespaces("tier", "app")}},
+		})
+	}
+	return anp
+}
+
+func TestControllerSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		anp      *v1alpha1.AdminNetworkPolicy
+		existing *v1alpha2.ClusterNetworkPolicy
+		reason   string
+		// rules is the number of ingress rules of the resulting
+		// ClusterNetworkPolicy, or -1 if there's none.
+		rules int
+	}{{
+		name:   "create",
+		anp:    testANP("deny-app", 1),
+		reason: ReasonConverted,
+		rules:  1,
+	}, {
+		name: "update",
+		anp:  testANP("deny-app", 2),
+		existing: &v1alpha2.ClusterNetworkPolicy{ObjectMeta: metav1.ObjectMeta{
+			Name:        "deny-app",
+			Annotations: map[string]string{MigratedFromAnnotation: "AdminNetworkPolicy/deny-app"},
+		}},
+		reason: ReasonConverted,
+		rules:  2,
+	}, {
+		name:     "conflict",
+		anp:      testANP("deny-app", 1),
+		existing: &v1alpha2.ClusterNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "deny-app"}},
+		reason:   ReasonConflict,
+		rules:    0,
+	}, {
+		name:   "conversion issues",
+		anp:    testANP("deny-app", 26),
+		reason: ReasonConversionIssues,
+		rules:  -1,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := []runtime.Object{tc.anp}
+			if tc.existing != nil {
+				objects = append(objects, tc.existing)
+			}
+			c, client := newTestController(t, objects...)
+			ctx := context.Background()
+			require.NoError(t, c.sync(ctx, "AdminNetworkPolicy/"+tc.anp.Name))
+
+			anp, err := client.PolicyV1alpha1().AdminNetworkPolicies().Get(ctx, tc.anp.Name, metav1.GetOptions{})
+			require.NoError(t, err)
+			condition := meta.FindStatusCondition(anp.Status.Conditions, MigratedCondition)
+			require.NotNil(t, condition)
+			require.Equal(t, tc.reason, condition.Reason)
+			require.Equal(t, int64(3), condition.ObservedGeneration)
+			require.Equal(t, tc.reason == ReasonConverted, condition.Status == metav1.ConditionTrue)
+
+			cnp, err := client.PolicyV1alpha2().ClusterNetworkPolicies().Get(ctx, tc.anp.Name, metav1.GetOptions{})
+			if tc.rules < 0 {
+				require.True(t, apierrors.IsNotFound(err))
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, cnp.Spec.Ingress, tc.rules)
+		})
+	}
+}
+
+func TestControllerSyncBaselineAdminNetworkPolicy(t *testing.T) {
+	banp := &v1alpha1.BaselineAdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+		},
+	}
+	c, client := newTestController(t, banp)
+	ctx := context.Background()
+	require.NoError(t, c.sync(ctx, "BaselineAdminNetworkPolicy/default"))
+
+	cnp, err := client.PolicyV1alpha2().ClusterNetworkPolicies().Get(ctx, "default", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, v1alpha2.BaselineTier, cnp.Spec.Tier)
+	require.Equal(t, "BaselineAdminNetworkPolicy/default", cnp.Annotations[MigratedFromAnnotation])
+
+	updated, err := client.PolicyV1alpha1().BaselineAdminNetworkPolicies().Get(ctx, "default", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.True(t, meta.IsStatusConditionTrue(updated.Status.Conditions, MigratedCondition))
+}
+
+func TestControllerSyncDeleted(t *testing.T) {
+	c, client := newTestController(t)
+	ctx := context.Background()
+	require.NoError(t, c.sync(ctx, "AdminNetworkPolicy/gone"))
+
+	cnps, err := client.PolicyV1alpha2().ClusterNetworkPolicies().List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, cnps.Items)
+}
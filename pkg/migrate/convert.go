@@ -0,0 +1,366 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha2"
+)
+
+const (
+	// maxRules is the maximum number of ingress or egress rules of a
+	// ClusterNetworkPolicy; v1alpha1 allowed 100.
+	maxRules = 25
+	// maxPeers is the maximum number of peers, and of protocols, of a
+	// ClusterNetworkPolicy rule; v1alpha1 allowed 100 of each.
+	maxPeers = 25
+
+	// lastAppliedAnnotation is dropped from converted policies, since it
+	// describes the v1alpha1 object.
+	lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// Issue describes a part of a v1alpha1 policy which has no faithful
+// v1alpha2 equivalent. The converted policy is still returned, but it either
+// behaves differently or will be rejected by the API server.
+type Issue struct {
+	// Policy is the kind and name of the v1alpha1 policy, e.g.
+	// "AdminNetworkPolicy/deny-all".
+	Policy string
+	// Field is the path to the offending field, e.g. "spec.ingress[3]".
+	Field   string
+	Message string
+}
+
+func (i *Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Policy, i.Field, i.Message)
+}
+
+// ConvertAdminNetworkPolicy converts an AdminNetworkPolicy to an Admin tier
+// ClusterNetworkPolicy with the same name and priority.
+func ConvertAdminNetworkPolicy(anp *v1alpha1.AdminNetworkPolicy) (*v1alpha2.ClusterNetworkPolicy, []*Issue) {
+	c := &converter{policy: "AdminNetworkPolicy/" + anp.Name}
+	cnp := newClusterNetworkPolicy(&anp.ObjectMeta, v1alpha2.AdminTier, anp.Spec.Priority, &anp.Spec.Subject)
+
+	for i, rule := range anp.Spec.Ingress {
+		field := fmt.Sprintf("spec.ingress[%d]", i)
+		cnp.Spec.Ingress = append(cnp.Spec.Ingress, c.convertIngressRule(field, rule.Name, string(rule.Action), convertIngressPeers(rule.From), rule.Ports)...)
+	}
+	for i, rule := range anp.Spec.Egress {
+		field := fmt.Sprintf("spec.egress[%d]", i)
+		cnp.Spec.Egress = append(cnp.Spec.Egress, c.convertEgressRule(field, rule.Name, string(rule.Action), convertAdminEgressPeers(rule.To), rule.Ports)...)
+	}
+	c.checkRuleCounts(cnp)
+	return cnp, c.issues
+}
+
+// ConvertBaselineAdminNetworkPolicy converts a BaselineAdminNetworkPolicy to a
+// Baseline tier ClusterNetworkPolicy with the same name. Since there's at most
+// one BaselineAdminNetworkPolicy, its priority is 0.
+func ConvertBaselineAdminNetworkPolicy(banp *v1alpha1.BaselineAdminNetworkPolicy) (*v1alpha2.ClusterNetworkPolicy, []*Issue) {
+	c := &converter{policy: "BaselineAdminNetworkPolicy/" + banp.Name}
+	cnp := newClusterNetworkPolicy(&banp.ObjectMeta, v1alpha2.BaselineTier, 0, &banp.Spec.Subject)
+
+	for i, rule := range banp.Spec.Ingress {
+		field := fmt.Sprintf("spec.ingress[%d]", i)
+		cnp.Spec.Ingress = append(cnp.Spec.Ingress, c.convertIngressRule(field, rule.Name, string(rule.Action), convertIngressPeers(rule.From), rule.Ports)...)
+	}
+	for i, rule := range banp.Spec.Egress {
+		field := fmt.Sprintf("spec.egress[%d]", i)
+		cnp.Spec.Egress = append(cnp.Spec.Egress, c.convertEgressRule(field, rule.Name, string(rule.Action), convertBaselineEgressPeers(rule.To), rule.Ports)...)
+	}
+	c.checkRuleCounts(cnp)
+	return cnp, c.issues
+}
+
+// Convert converts a set of v1alpha1 policies; banp may be nil. Besides the
+// issues of each policy, it reports converted policies whose names collide,
+// which happens when an AdminNetworkPolicy is named like the
+// BaselineAdminNetworkPolicy.
+func Convert(anps []*v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy) ([]*v1alpha2.ClusterNetworkPolicy, []*Issue) {
+	var cnps []*v1alpha2.ClusterNetworkPolicy
+	var issues []*Issue
+	sources := map[string]string{}
+	add := func(source string, cnp *v1alpha2.ClusterNetworkPolicy, cnpIssues []*Issue) {
+		if previous, ok := sources[cnp.Name]; ok {
+			issues = append(issues, &Issue{
+				Policy:  source,
+				Field:   "metadata.name",
+				Message: fmt.Sprintf("converts to the same ClusterNetworkPolicy name as %s", previous),
+			})
+		}
+		sources[cnp.Name] = source
+		cnps = append(cnps, cnp)
+		issues = append(issues, cnpIssues...)
+	}
+
+	for _, anp := range anps {
+		cnp, cnpIssues := ConvertAdminNetworkPolicy(anp)
+		add("AdminNetworkPolicy/"+anp.Name, cnp, cnpIssues)
+	}
+	if banp != nil {
+		cnp, cnpIssues := ConvertBaselineAdminNetworkPolicy(banp)
+		add("BaselineAdminNetworkPolicy/"+banp.Name, cnp, cnpIssues)
+	}
+	return cnps, issues
+}
+
+type converter struct {
+	policy string
+	issues []*Issue
+}
+
+func (c *converter) addIssue(field string, format string, args ...interface{}) {
+	c.issues = append(c.issues, &Issue{Policy: c.policy, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// convertIngressRule converts a rule, splitting it if it has more peers or
+// ports than v1alpha2 allows. The parts keep the rule's action and are
+// consecutive, so they match the same traffic with the same precedence.
+func (c *converter) convertIngressRule(field, name, action string, from []v1alpha2.ClusterNetworkPolicyIngressPeer, ports *[]v1alpha1.AdminNetworkPolicyPort) []v1alpha2.ClusterNetworkPolicyIngressRule {
+	convertedAction := c.convertAction(field, action)
+	protocols := c.convertPorts(field, ports)
+	var rules []v1alpha2.ClusterNetworkPolicyIngressRule
+	for n, peers := range chunk(from, maxPeers) {
+		for m, chunkProtocols := range chunk(protocols, maxPeers) {
+			rules = append(rules, v1alpha2.ClusterNetworkPolicyIngressRule{
+				Name:      splitRuleName(name, n, m, len(from), len(protocols)),
+				Action:    convertedAction,
+				From:      peers,
+				Protocols: chunkProtocols,
+			})
+		}
+	}
+	return rules
+}
+
+func (c *converter) convertEgressRule(field, name, action string, to []v1alpha2.ClusterNetworkPolicyEgressPeer, ports *[]v1alpha1.AdminNetworkPolicyPort) []v1alpha2.ClusterNetworkPolicyEgressRule {
+	convertedAction := c.convertAction(field, action)
+	protocols := c.convertPorts(field, ports)
+	var rules []v1alpha2.ClusterNetworkPolicyEgressRule
+	for n, peers := range chunk(to, maxPeers) {
+		for m, chunkProtocols := range chunk(protocols, maxPeers) {
+			rules = append(rules, v1alpha2.ClusterNetworkPolicyEgressRule{
+				Name:      splitRuleName(name, n, m, len(to), len(protocols)),
+				Action:    convertedAction,
+				To:        peers,
+				Protocols: chunkProtocols,
+			})
+		}
+	}
+	return rules
+}
+
+func (c *converter) convertAction(field string, action string) v1alpha2.ClusterNetworkPolicyRuleAction {
+	switch action {
+	case string(v1alpha1.AdminNetworkPolicyRuleActionAllow):
+		return v1alpha2.ClusterNetworkPolicyRuleActionAccept
+	case string(v1alpha1.AdminNetworkPolicyRuleActionDeny):
+		return v1alpha2.ClusterNetworkPolicyRuleActionDeny
+	case string(v1alpha1.AdminNetworkPolicyRuleActionPass):
+		return v1alpha2.ClusterNetworkPolicyRuleActionPass
+	default:
+		c.addIssue(field+".action", "unknown action %q", action)
+		return v1alpha2.ClusterNetworkPolicyRuleAction(action)
+	}
+}
+
+func (c *converter) convertPorts(field string, ports *[]v1alpha1.AdminNetworkPolicyPort) []v1alpha2.ClusterNetworkPolicyProtocol {
+	if ports == nil {
+		return nil
+	}
+	var protocols []v1alpha2.ClusterNetworkPolicyProtocol
+	for i, port := range *ports {
+		portField := fmt.Sprintf("%s.ports[%d]", field, i)
+		switch {
+		case port.PortNumber != nil:
+			protocol, ok := c.convertProtocol(portField+".portNumber.protocol", port.PortNumber.Protocol, &v1alpha2.Port{Number: port.PortNumber.Port})
+			if ok {
+				protocols = append(protocols, protocol)
+			}
+		case port.PortRange != nil:
+			destination := &v1alpha2.Port{Range: &v1alpha2.PortRange{Start: port.PortRange.Start, End: port.PortRange.End}}
+			if port.PortRange.Start == port.PortRange.End {
+				// v1alpha2 requires Start < End.
+				destination = &v1alpha2.Port{Number: port.PortRange.Start}
+			}
+			protocol, ok := c.convertProtocol(portField+".portRange.protocol", port.PortRange.Protocol, destination)
+			if ok {
+				protocols = append(protocols, protocol)
+			}
+		case port.NamedPort != nil:
+			protocols = append(protocols, v1alpha2.ClusterNetworkPolicyProtocol{DestinationNamedPort: *port.NamedPort})
+		default:
+			c.addIssue(portField, "no port is set")
+		}
+	}
+	return protocols
+}
+
+func (c *converter) convertProtocol(field string, protocol v1.Protocol, destination *v1alpha2.Port) (v1alpha2.ClusterNetworkPolicyProtocol, bool) {
+	switch protocol {
+	case v1.ProtocolTCP, "":
+		return v1alpha2.ClusterNetworkPolicyProtocol{TCP: &v1alpha2.ClusterNetworkPolicyProtocolTCP{DestinationPort: destination}}, true
+	case v1.ProtocolUDP:
+		return v1alpha2.ClusterNetworkPolicyProtocol{UDP: &v1alpha2.ClusterNetworkPolicyProtocolUDP{DestinationPort: destination}}, true
+	case v1.ProtocolSCTP:
+		return v1alpha2.ClusterNetworkPolicyProtocol{SCTP: &v1alpha2.ClusterNetworkPolicyProtocolSCTP{DestinationPort: destination}}, true
+	default:
+		c.addIssue(field, "unknown protocol %q is dropped", protocol)
+		return v1alpha2.ClusterNetworkPolicyProtocol{}, false
+	}
+}
+
+// checkRuleCounts flags policies with more rules than v1alpha2 allows, either
+// because the v1alpha1 policy had more than 25, or because rules with more
+// than 25 peers or ports were split.
+func (c *converter) checkRuleCounts(cnp *v1alpha2.ClusterNetworkPolicy) {
+	if len(cnp.Spec.Ingress) > maxRules {
+		c.addIssue("spec.ingress", "converts to %d rules but at most %d are allowed; split the policy", len(cnp.Spec.Ingress), maxRules)
+	}
+	if len(cnp.Spec.Egress) > maxRules {
+		c.addIssue("spec.egress", "converts to %d rules but at most %d are allowed; split the policy", len(cnp.Spec.Egress), maxRules)
+	}
+}
+
+func newClusterNetworkPolicy(meta *metav1.ObjectMeta, tier v1alpha2.Tier, priority int32, subject *v1alpha1.AdminNetworkPolicySubject) *v1alpha2.ClusterNetworkPolicy {
+	cnp := &v1alpha2.ClusterNetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha2.GroupVersion.String(),
+			Kind:       "ClusterNetworkPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   meta.Name,
+			Labels: meta.Labels,
+		},
+		Spec: v1alpha2.ClusterNetworkPolicySpec{
+			Tier:     tier,
+			Priority: priority,
+			Subject: v1alpha2.ClusterNetworkPolicySubject{
+				Namespaces: subject.Namespaces.DeepCopy(),
+				Pods:       convertNamespacedPod(subject.Pods),
+			},
+		},
+	}
+	for key, value := range meta.Annotations {
+		if key == lastAppliedAnnotation {
+			continue
+		}
+		if cnp.Annotations == nil {
+			cnp.Annotations = map[string]string{}
+		}
+		cnp.Annotations[key] = value
+	}
+	return cnp
+}
+
+func convertNamespacedPod(pods *v1alpha1.NamespacedPod) *v1alpha2.NamespacedPod {
+	if pods == nil {
+		return nil
+	}
+	return &v1alpha2.NamespacedPod{
+		NamespaceSelector: *pods.NamespaceSelector.DeepCopy(),
+		PodSelector:       *pods.PodSelector.DeepCopy(),
+	}
+}
+
+func convertIngressPeers(peers []v1alpha1.AdminNetworkPolicyIngressPeer) []v1alpha2.ClusterNetworkPolicyIngressPeer {
+	var converted []v1alpha2.ClusterNetworkPolicyIngressPeer
+	for _, peer := range peers {
+		converted = append(converted, v1alpha2.ClusterNetworkPolicyIngressPeer{
+			Namespaces: peer.Namespaces.DeepCopy(),
+			Pods:       convertNamespacedPod(peer.Pods),
+		})
+	}
+	return converted
+}
+
+func convertAdminEgressPeers(peers []v1alpha1.AdminNetworkPolicyEgressPeer) []v1alpha2.ClusterNetworkPolicyEgressPeer {
+	var converted []v1alpha2.ClusterNetworkPolicyEgressPeer
+	for _, peer := range peers {
+		converted = append(converted, v1alpha2.ClusterNetworkPolicyEgressPeer{
+			Namespaces:  peer.Namespaces.DeepCopy(),
+			Pods:        convertNamespacedPod(peer.Pods),
+			Nodes:       peer.Nodes.DeepCopy(),
+			Networks:    convertNetworks(peer.Networks),
+			DomainNames: convertDomainNames(peer.DomainNames),
+		})
+	}
+	return converted
+}
+
+func convertBaselineEgressPeers(peers []v1alpha1.BaselineAdminNetworkPolicyEgressPeer) []v1alpha2.ClusterNetworkPolicyEgressPeer {
+	var converted []v1alpha2.ClusterNetworkPolicyEgressPeer
+	for _, peer := range peers {
+		converted = append(converted, v1alpha2.ClusterNetworkPolicyEgressPeer{
+			Namespaces: peer.Namespaces.DeepCopy(),
+			Pods:       convertNamespacedPod(peer.Pods),
+			Nodes:      peer.Nodes.DeepCopy(),
+			Networks:   convertNetworks(peer.Networks),
+		})
+	}
+	return converted
+}
+
+func convertNetworks(networks []v1alpha1.CIDR) []v1alpha2.CIDR {
+	var converted []v1alpha2.CIDR
+	for _, network := range networks {
+		converted = append(converted, v1alpha2.CIDR(network))
+	}
+	return converted
+}
+
+func convertDomainNames(domainNames []v1alpha1.DomainName) []v1alpha2.DomainName {
+	var converted []v1alpha2.DomainName
+	for _, domainName := range domainNames {
+		converted = append(converted, v1alpha2.DomainName(domainName))
+	}
+	return converted
+}
+
+// chunk splits items into slices of at most size elements. An empty input
+// results in a single nil chunk, so that a rule without ports is still
+// converted once.
+func chunk[T any](items []T, size int) [][]T {
+	if len(items) == 0 {
+		return [][]T{nil}
+	}
+	var chunks [][]T
+	for len(items) > size {
+		chunks = append(chunks, items[:size])
+		items = items[size:]
+	}
+	return append(chunks, items)
+}
+
+// splitRuleName names the parts of a rule which was split because it had more
+// peers or ports than v1alpha2 allows. Unsplit rules keep their name.
+func splitRuleName(name string, peerChunk, protocolChunk, peers, protocols int) string {
+	if name == "" || (peers <= maxPeers && protocols <= maxPeers) {
+		return name
+	}
+	protocolChunks := (protocols + maxPeers - 1) / maxPeers
+	if protocolChunks == 0 {
+		protocolChunks = 1
+	}
+	return fmt.Sprintf("%s-%d", name, peerChunk*protocolChunks+protocolChunk+1)
+}
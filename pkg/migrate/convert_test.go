@@ -0,0 +1,219 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha2"
+)
+
+func namespaces(key, value string) *metav1.LabelSelector {
+	return &metav1.LabelSelector{MatchLabels: map[string]string{key: value}}
+}
+
+func ports(ports ...v1alpha1.AdminNetworkPolicyPort) *[]v1alpha1.AdminNetworkPolicyPort {
+	return &ports
+}
+
+func TestConvertAdminNetworkPolicy(t *testing.T) {
+	namedPort := "http"
+	anp := &v1alpha1.AdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "sensitive",
+			Labels: map[string]string{"team": "security"},
+			Annotations: map[string]string{
+				"owner":               "security",
+				lastAppliedAnnotation: "{}",
+			},
+		},
+		Spec: v1alpha1.AdminNetworkPolicySpec{
+			Priority: 10,
+			Subject: v1alpha1.AdminNetworkPolicySubject{
+				Pods: &v1alpha1.NamespacedPod{
+					NamespaceSelector: *namespaces("tier", "db"),
+					PodSelector:       *namespaces("app", "postgres"),
+				},
+			},
+			Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{{
+				Name:   "allow-app",
+				Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+				From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: namespaces("tier", "app")}},
+				Ports: ports(
+					v1alpha1.AdminNetworkPolicyPort{PortNumber: &v1alpha1.Port{Protocol: v1.ProtocolTCP, Port: 5432}},
+					v1alpha1.AdminNetworkPolicyPort{NamedPort: &namedPort},
+				),
+			}, {
+				Name:   "pass-monitoring",
+				Action: v1alpha1.AdminNetworkPolicyRuleActionPass,
+				From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: namespaces("tier", "monitoring")}},
+			}},
+			Egress: []v1alpha1.AdminNetworkPolicyEgressRule{{
+				Name:   "deny-internet",
+				Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+				To: []v1alpha1.AdminNetworkPolicyEgressPeer{
+					{Networks: []v1alpha1.CIDR{"0.0.0.0/0"}},
+					{Nodes: namespaces("role", "worker")},
+				},
+				Ports: ports(v1alpha1.AdminNetworkPolicyPort{PortRange: &v1alpha1.PortRange{Protocol: v1.ProtocolUDP, Start: 1000, End: 2000}}),
+			}},
+		},
+	}
+
+	cnp, issues := ConvertAdminNetworkPolicy(anp)
+	require.Empty(t, issues)
+	require.Equal(t, &v1alpha2.ClusterNetworkPolicy{
+		TypeMeta: metav1.TypeMeta{APIVersion: "policy.networking.k8s.io/v1alpha2", Kind: "ClusterNetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "sensitive",
+			Labels:      map[string]string{"team": "security"},
+			Annotations: map[string]string{"owner": "security"},
+		},
+		Spec: v1alpha2.ClusterNetworkPolicySpec{
+			Tier:     v1alpha2.AdminTier,
+			Priority: 10,
+			Subject: v1alpha2.ClusterNetworkPolicySubject{
+				Pods: &v1alpha2.NamespacedPod{
+					NamespaceSelector: *namespaces("tier", "db"),
+					PodSelector:       *namespaces("app", "postgres"),
+				},
+			},
+			Ingress: []v1alpha2.ClusterNetworkPolicyIngressRule{{
+				Name:   "allow-app",
+				Action: v1alpha2.ClusterNetworkPolicyRuleActionAccept,
+				From:   []v1alpha2.ClusterNetworkPolicyIngressPeer{{Namespaces: namespaces("tier", "app")}},
+				Protocols: []v1alpha2.ClusterNetworkPolicyProtocol{
+					{TCP: &v1alpha2.ClusterNetworkPolicyProtocolTCP{DestinationPort: &v1alpha2.Port{Number: 5432}}},
+					{DestinationNamedPort: "http"},
+				},
+			}, {
+				Name:   "pass-monitoring",
+				Action: v1alpha2.ClusterNetworkPolicyRuleActionPass,
+				From:   []v1alpha2.ClusterNetworkPolicyIngressPeer{{Namespaces: namespaces("tier", "monitoring")}},
+			}},
+			Egress: []v1alpha2.ClusterNetworkPolicyEgressRule{{
+				Name:   "deny-internet",
+				Action: v1alpha2.ClusterNetworkPolicyRuleActionDeny,
+				To: []v1alpha2.ClusterNetworkPolicyEgressPeer{
+					{Networks: []v1alpha2.CIDR{"0.0.0.0/0"}},
+					{Nodes: namespaces("role", "worker")},
+				},
+				Protocols: []v1alpha2.ClusterNetworkPolicyProtocol{
+					{UDP: &v1alpha2.ClusterNetworkPolicyProtocolUDP{DestinationPort: &v1alpha2.Port{Range: &v1alpha2.PortRange{Start: 1000, End: 2000}}}},
+				},
+			}},
+		},
+	}, cnp)
+}
+
+func TestConvertBaselineAdminNetworkPolicy(t *testing.T) {
+	banp := &v1alpha1.BaselineAdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+			Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{{
+				Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+				From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}},
+				Ports:  ports(v1alpha1.AdminNetworkPolicyPort{PortNumber: &v1alpha1.Port{Port: 22}}),
+			}},
+		},
+	}
+
+	cnp, issues := ConvertBaselineAdminNetworkPolicy(banp)
+	require.Empty(t, issues)
+	require.Equal(t, v1alpha2.BaselineTier, cnp.Spec.Tier)
+	require.Equal(t, int32(0), cnp.Spec.Priority)
+	require.Equal(t, []v1alpha2.ClusterNetworkPolicyIngressRule{{
+		Action: v1alpha2.ClusterNetworkPolicyRuleActionDeny,
+		From:   []v1alpha2.ClusterNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}},
+		// The protocol defaults to TCP.
+		Protocols: []v1alpha2.ClusterNetworkPolicyProtocol{
+			{TCP: &v1alpha2.ClusterNetworkPolicyProtocolTCP{DestinationPort: &v1alpha2.Port{Number: 22}}},
+		},
+	}}, cnp.Spec.Ingress)
+}
+
+func TestConvertIssues(t *testing.T) {
+	manyPeers := make([]v1alpha1.AdminNetworkPolicyIngressPeer, 30)
+	for i := range manyPeers {
+		manyPeers[i] = v1alpha1.AdminNetworkPolicyIngressPeer{Namespaces: namespaces("peer", fmt.Sprint(i))}
+	}
+	manyRules := make([]v1alpha1.AdminNetworkPolicyIngressRule, 26)
+	for i := range manyRules {
+		manyRules[i] = v1alpha1.AdminNetworkPolicyIngressRule{Action: v1alpha1.AdminNetworkPolicyRuleActionDeny, From: manyPeers[:1]}
+	}
+
+	tests := []struct {
+		name     string
+		anp      v1alpha1.AdminNetworkPolicySpec
+		rules    int
+		expected []string
+	}{{
+		name: "rule with too many peers is split",
+		anp: v1alpha1.AdminNetworkPolicySpec{Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{{
+			Name:   "many",
+			Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+			From:   manyPeers,
+		}}},
+		rules: 2,
+	}, {
+		name:     "too many rules",
+		anp:      v1alpha1.AdminNetworkPolicySpec{Ingress: manyRules},
+		rules:    26,
+		expected: []string{"AdminNetworkPolicy/test: spec.ingress: converts to 26 rules but at most 25 are allowed; split the policy"},
+	}, {
+		name: "unknown protocol",
+		anp: v1alpha1.AdminNetworkPolicySpec{Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{{
+			Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+			From:   manyPeers[:1],
+			Ports: ports(
+				v1alpha1.AdminNetworkPolicyPort{PortNumber: &v1alpha1.Port{Protocol: "ICMP", Port: 1}},
+				v1alpha1.AdminNetworkPolicyPort{PortNumber: &v1alpha1.Port{Protocol: v1.ProtocolTCP, Port: 1}},
+			),
+		}}},
+		rules:    1,
+		expected: []string{`AdminNetworkPolicy/test: spec.ingress[0].ports[0].portNumber.protocol: unknown protocol "ICMP" is dropped`},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cnp, issues := ConvertAdminNetworkPolicy(&v1alpha1.AdminNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: tc.anp})
+			var messages []string
+			for _, issue := range issues {
+				messages = append(messages, issue.String())
+			}
+			require.Equal(t, tc.expected, messages)
+			require.Len(t, cnp.Spec.Ingress, tc.rules)
+		})
+	}
+}
+
+func TestConvertNameCollision(t *testing.T) {
+	anps := []*v1alpha1.AdminNetworkPolicy{{ObjectMeta: metav1.ObjectMeta{Name: "default"}}}
+	banp := &v1alpha1.BaselineAdminNetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+
+	cnps, issues := Convert(anps, banp)
+	require.Len(t, cnps, 2)
+	require.Len(t, issues, 1)
+	require.Equal(t, "BaselineAdminNetworkPolicy/default: metadata.name: converts to the same ClusterNetworkPolicy name as AdminNetworkPolicy/default", issues[0].String())
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+)
+
+const sampleLabelValue = "sample"
+
+// SampleCluster builds a cluster to simulate the policies against when no
+// real cluster is available. For each selector of the policies it creates a
+// namespace, pod or node with labels that match the selector, plus one
+// without labels. Every namespace holds one pod of each set of pod labels, and
+// every pod has a TCP container port for each named port of the policies.
+func SampleCluster(anps []*v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy) *Cluster {
+	s := &sampler{namespaces: newLabelSets(), pods: newLabelSets(), nodes: newLabelSets(), namedPorts: map[string]bool{}}
+	for _, anp := range anps {
+		s.addSubject(&anp.Spec.Subject)
+		for _, rule := range anp.Spec.Ingress {
+			for _, peer := range rule.From {
+				s.addPeer(peer.Namespaces, peer.Pods, nil)
+			}
+			s.addPorts(rule.Ports)
+		}
+		for _, rule := range anp.Spec.Egress {
+			for _, peer := range rule.To {
+				s.addPeer(peer.Namespaces, peer.Pods, peer.Nodes)
+			}
+			s.addPorts(rule.Ports)
+		}
+	}
+	if banp != nil {
+		s.addSubject(&banp.Spec.Subject)
+		for _, rule := range banp.Spec.Ingress {
+			for _, peer := range rule.From {
+				s.addPeer(peer.Namespaces, peer.Pods, nil)
+			}
+			s.addPorts(rule.Ports)
+		}
+		for _, rule := range banp.Spec.Egress {
+			for _, peer := range rule.To {
+				s.addPeer(peer.Namespaces, peer.Pods, peer.Nodes)
+			}
+			s.addPorts(rule.Ports)
+		}
+	}
+
+	var ports []v1.ContainerPort
+	for i, name := range sortedKeys(s.namedPorts) {
+		ports = append(ports, v1.ContainerPort{Name: name, ContainerPort: int32(8080 + i), Protocol: v1.ProtocolTCP})
+	}
+
+	cluster := &Cluster{}
+	for i, namespaceLabels := range s.namespaces.sets {
+		name := fmt.Sprintf("ns-%d", i)
+		namespace := &Namespace{Name: name, Labels: labels.Merge(namespaceLabels, map[string]string{v1.LabelMetadataName: name})}
+		cluster.Namespaces = append(cluster.Namespaces, namespace)
+		for j, podLabels := range s.pods.sets {
+			cluster.Pods = append(cluster.Pods, &Pod{
+				Namespace: name,
+				Name:      fmt.Sprintf("pod-%d", j),
+				Labels:    podLabels,
+				IP:        fmt.Sprintf("10.%d.%d.%d", i/256, i%256, j+1),
+				Ports:     ports,
+			})
+		}
+	}
+	for i, nodeLabels := range s.nodes.sets {
+		cluster.Nodes = append(cluster.Nodes, &Node{Name: fmt.Sprintf("node-%d", i), Labels: nodeLabels, IP: fmt.Sprintf("172.18.%d.%d", i/256, i%256+1)})
+	}
+	return cluster
+}
+
+type sampler struct {
+	namespaces *labelSets
+	pods       *labelSets
+	nodes      *labelSets
+	namedPorts map[string]bool
+}
+
+func (s *sampler) addSubject(subject *v1alpha1.AdminNetworkPolicySubject) {
+	s.addPeer(subject.Namespaces, subject.Pods, nil)
+}
+
+func (s *sampler) addPeer(namespaces *metav1.LabelSelector, pods *v1alpha1.NamespacedPod, nodes *metav1.LabelSelector) {
+	s.namespaces.add(namespaces)
+	if pods != nil {
+		s.namespaces.add(&pods.NamespaceSelector)
+		s.pods.add(&pods.PodSelector)
+	}
+	s.nodes.add(nodes)
+}
+
+func (s *sampler) addPorts(ports *[]v1alpha1.AdminNetworkPolicyPort) {
+	if ports == nil {
+		return
+	}
+	for _, port := range *ports {
+		if port.NamedPort != nil {
+			s.namedPorts[*port.NamedPort] = true
+		}
+	}
+}
+
+// labelSets collects distinct sets of labels, starting with the empty set.
+type labelSets struct {
+	sets []map[string]string
+	seen map[string]bool
+}
+
+func newLabelSets() *labelSets {
+	return &labelSets{sets: []map[string]string{{}}, seen: map[string]bool{"": true}}
+}
+
+// add adds labels matching the selector. Keys which must exist take their
+// first allowed value, or a sample value; keys which must not have some values
+// are left out.
+func (l *labelSets) add(selector *metav1.LabelSelector) {
+	if selector == nil {
+		return
+	}
+	set := map[string]string{}
+	for key, value := range selector.MatchLabels {
+		set[key] = value
+	}
+	for _, requirement := range selector.MatchExpressions {
+		switch requirement.Operator {
+		case metav1.LabelSelectorOpIn:
+			if len(requirement.Values) > 0 {
+				set[requirement.Key] = requirement.Values[0]
+			}
+		case metav1.LabelSelectorOpExists:
+			set[requirement.Key] = sampleLabelValue
+		}
+	}
+	key := labels.Set(set).String()
+	if !l.seen[key] {
+		l.seen[key] = true
+		l.sets = append(l.sets, set)
+	}
+}
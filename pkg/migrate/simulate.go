@@ -0,0 +1,635 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha2"
+)
+
+// Cluster is the set of objects against which policies are simulated.
+type Cluster struct {
+	Namespaces []*Namespace
+	Pods       []*Pod
+	Nodes      []*Node
+}
+
+type Namespace struct {
+	Name   string
+	Labels map[string]string
+}
+
+type Pod struct {
+	Namespace string
+	Name      string
+	Labels    map[string]string
+	IP        string
+	// Ports are the pod's container ports, which named ports resolve to.
+	Ports []v1.ContainerPort
+}
+
+type Node struct {
+	Name   string
+	Labels map[string]string
+	IP     string
+}
+
+// Connection is a simulated connection from a pod. Exactly one of
+// DestinationPod, DestinationNode and DestinationIP is set.
+type Connection struct {
+	Source          *Pod
+	DestinationPod  *Pod
+	DestinationNode *Node
+	DestinationIP   string
+	Protocol        v1.Protocol
+	Port            int32
+}
+
+func (c *Connection) String() string {
+	destination := c.DestinationIP
+	if c.DestinationPod != nil {
+		destination = c.DestinationPod.Namespace + "/" + c.DestinationPod.Name
+	} else if c.DestinationNode != nil {
+		destination = "node/" + c.DestinationNode.Name
+	}
+	return fmt.Sprintf("%s/%s -> %s %s/%d", c.Source.Namespace, c.Source.Name, destination, c.Protocol, c.Port)
+}
+
+func (c *Connection) destinationIP() string {
+	if c.DestinationPod != nil {
+		return c.DestinationPod.IP
+	}
+	if c.DestinationNode != nil {
+		return c.DestinationNode.IP
+	}
+	return c.DestinationIP
+}
+
+// Verdict is the outcome of a tier for one direction of a connection. v1alpha2
+// Accept is reported as Allow, so that verdicts of both versions compare equal.
+type Verdict string
+
+const (
+	VerdictAllow Verdict = "Allow"
+	VerdictDeny  Verdict = "Deny"
+	VerdictPass  Verdict = "Pass"
+	// VerdictNone means that no rule of the tier matched.
+	VerdictNone Verdict = "None"
+)
+
+// Decision holds the verdicts of the Admin and Baseline tiers. Both are kept,
+// rather than the resulting connectivity, since a NetworkPolicy may decide
+// traffic which the Admin tier doesn't.
+type Decision struct {
+	Admin    Verdict
+	Baseline Verdict
+}
+
+func (d Decision) String() string {
+	return fmt.Sprintf("admin: %s, baseline: %s", d.Admin, d.Baseline)
+}
+
+// Difference is a direction of a connection which v1alpha1 and v1alpha2
+// policies decide differently.
+type Difference struct {
+	Connection *Connection
+	// Direction is "ingress" or "egress".
+	Direction string
+	Before    Decision
+	After     Decision
+}
+
+func (d *Difference) String() string {
+	return fmt.Sprintf("%s (%s): before: %s; after: %s", d.Connection, d.Direction, d.Before, d.After)
+}
+
+// CompareConnectivity simulates every connection between the cluster's pods,
+// and from its pods to its nodes and to the networks the policies refer to,
+// on every port the policies refer to. It returns the connections which the
+// v1alpha1 policies decide differently than the ClusterNetworkPolicies.
+// DomainNames peers aren't simulated.
+func CompareConnectivity(cluster *Cluster, anps []*v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy, cnps []*v1alpha2.ClusterNetworkPolicy) ([]*Difference, error) {
+	before, err := newV1alpha1Simulator(cluster, anps, banp)
+	if err != nil {
+		return nil, err
+	}
+	after, err := newV1alpha2Simulator(cluster, cnps)
+	if err != nil {
+		return nil, err
+	}
+
+	var differences []*Difference
+	for _, connection := range connections(cluster, before, after) {
+		if connection.DestinationPod != nil {
+			beforeIngress, afterIngress := before.ingress(connection), after.ingress(connection)
+			if beforeIngress != afterIngress {
+				differences = append(differences, &Difference{Connection: connection, Direction: "ingress", Before: beforeIngress, After: afterIngress})
+			}
+		}
+		beforeEgress, afterEgress := before.egress(connection), after.egress(connection)
+		if beforeEgress != afterEgress {
+			differences = append(differences, &Difference{Connection: connection, Direction: "egress", Before: beforeEgress, After: afterEgress})
+		}
+	}
+	return differences, nil
+}
+
+// connections lists the simulated connections. Every pod is probed on the
+// port numbers, range bounds and container ports the policies refer to, for
+// every protocol, so that protocol mismatches are simulated as well.
+func connections(cluster *Cluster, simulators ...*simulator) []*Connection {
+	ports := map[int32]bool{80: true}
+	networks := map[string]bool{}
+	for _, s := range simulators {
+		for _, policy := range append(append([]*simulatedPolicy{}, s.admin...), s.baseline...) {
+			for _, rule := range append(append([]*simulatedRule{}, policy.ingress...), policy.egress...) {
+				for _, port := range rule.ports {
+					if port.name != "" {
+						continue
+					}
+					ports[port.start] = true
+					ports[port.end] = true
+					if port.end < 65535 {
+						ports[port.end+1] = true
+					}
+				}
+				for _, network := range rule.networks {
+					networks[network.IP.String()] = true
+				}
+			}
+		}
+	}
+	for _, pod := range cluster.Pods {
+		for _, port := range pod.Ports {
+			ports[port.ContainerPort] = true
+		}
+	}
+
+	var destinations []*Connection
+	for _, pod := range cluster.Pods {
+		destinations = append(destinations, &Connection{DestinationPod: pod})
+	}
+	for _, node := range cluster.Nodes {
+		destinations = append(destinations, &Connection{DestinationNode: node})
+	}
+	for _, ip := range sortedKeys(networks) {
+		destinations = append(destinations, &Connection{DestinationIP: ip})
+	}
+
+	var connections []*Connection
+	for _, source := range cluster.Pods {
+		for _, destination := range destinations {
+			for _, protocol := range []v1.Protocol{v1.ProtocolTCP, v1.ProtocolUDP, v1.ProtocolSCTP} {
+				for _, port := range sortedKeys(ports) {
+					connection := *destination
+					connection.Source = source
+					connection.Protocol = protocol
+					connection.Port = port
+					connections = append(connections, &connection)
+				}
+			}
+		}
+	}
+	return connections
+}
+
+type simulator struct {
+	namespaces map[string]*Namespace
+	// admin and baseline are ordered by precedence.
+	admin    []*simulatedPolicy
+	baseline []*simulatedPolicy
+}
+
+// simulatedPolicy is the common form of v1alpha1 and v1alpha2 policies. Each
+// version is compiled to it separately from the conversion code, so that a
+// mistake in the conversion shows up as a difference.
+type simulatedPolicy struct {
+	name     string
+	priority int32
+	subject  *podSelector
+	ingress  []*simulatedRule
+	egress   []*simulatedRule
+}
+
+type simulatedRule struct {
+	action   Verdict
+	pods     []*podSelector
+	nodes    []labels.Selector
+	networks []*net.IPNet
+	// ports is nil if the rule matches all ports.
+	ports []*simulatedPort
+}
+
+type simulatedPort struct {
+	protocol   v1.Protocol
+	start, end int32
+	name       string
+}
+
+// podSelector selects pods by namespace labels and, if pods is set, by pod
+// labels.
+type podSelector struct {
+	namespaces labels.Selector
+	pods       labels.Selector
+}
+
+func (s *simulator) matchesPod(selector *podSelector, pod *Pod) bool {
+	namespace, ok := s.namespaces[pod.Namespace]
+	if !ok {
+		return false
+	}
+	return selector.namespaces.Matches(labels.Set(namespace.Labels)) &&
+		(selector.pods == nil || selector.pods.Matches(labels.Set(pod.Labels)))
+}
+
+func (s *simulator) ingress(connection *Connection) Decision {
+	matches := func(rule *simulatedRule) bool {
+		for _, peer := range rule.pods {
+			if s.matchesPod(peer, connection.Source) {
+				return rule.matchesPort(connection)
+			}
+		}
+		return false
+	}
+	ingress := func(policy *simulatedPolicy) []*simulatedRule { return policy.ingress }
+	return Decision{
+		Admin:    s.decide(s.admin, connection.DestinationPod, ingress, matches),
+		Baseline: s.decide(s.baseline, connection.DestinationPod, ingress, matches),
+	}
+}
+
+func (s *simulator) egress(connection *Connection) Decision {
+	matches := func(rule *simulatedRule) bool {
+		return rule.matchesDestination(s, connection) && rule.matchesPort(connection)
+	}
+	egress := func(policy *simulatedPolicy) []*simulatedRule { return policy.egress }
+	return Decision{
+		Admin:    s.decide(s.admin, connection.Source, egress, matches),
+		Baseline: s.decide(s.baseline, connection.Source, egress, matches),
+	}
+}
+
+// decide returns the action of the first matching rule of the first policy
+// which selects the subject pod and has a matching rule.
+func (s *simulator) decide(policies []*simulatedPolicy, subject *Pod, rules func(*simulatedPolicy) []*simulatedRule, matches func(*simulatedRule) bool) Verdict {
+	for _, policy := range policies {
+		if !s.matchesPod(policy.subject, subject) {
+			continue
+		}
+		for _, rule := range rules(policy) {
+			if matches(rule) {
+				return rule.action
+			}
+		}
+	}
+	return VerdictNone
+}
+
+func (r *simulatedRule) matchesDestination(s *simulator, connection *Connection) bool {
+	if connection.DestinationPod != nil {
+		for _, peer := range r.pods {
+			if s.matchesPod(peer, connection.DestinationPod) {
+				return true
+			}
+		}
+	}
+	if connection.DestinationNode != nil {
+		for _, nodes := range r.nodes {
+			if nodes.Matches(labels.Set(connection.DestinationNode.Labels)) {
+				return true
+			}
+		}
+	}
+	ip := net.ParseIP(connection.destinationIP())
+	for _, network := range r.networks {
+		if ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *simulatedRule) matchesPort(connection *Connection) bool {
+	if r.ports == nil {
+		return true
+	}
+	for _, port := range r.ports {
+		if port.name == "" {
+			if port.protocol == connection.Protocol && port.start <= connection.Port && connection.Port <= port.end {
+				return true
+			}
+			continue
+		}
+		// Named ports only exist on pods.
+		if connection.DestinationPod == nil {
+			continue
+		}
+		for _, containerPort := range connection.DestinationPod.Ports {
+			protocol := containerPort.Protocol
+			if protocol == "" {
+				protocol = v1.ProtocolTCP
+			}
+			if containerPort.Name == port.name && protocol == connection.Protocol && containerPort.ContainerPort == connection.Port {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newSimulator(cluster *Cluster, admin, baseline []*simulatedPolicy) *simulator {
+	s := &simulator{namespaces: map[string]*Namespace{}, admin: admin, baseline: baseline}
+	for _, namespace := range cluster.Namespaces {
+		s.namespaces[namespace.Name] = namespace
+	}
+	for _, policies := range [][]*simulatedPolicy{s.admin, s.baseline} {
+		// Policies with the same priority may be applied in any order; order
+		// them by name, so that both versions are simulated alike.
+		sort.SliceStable(policies, func(i, j int) bool {
+			if policies[i].priority != policies[j].priority {
+				return policies[i].priority < policies[j].priority
+			}
+			return policies[i].name < policies[j].name
+		})
+	}
+	return s
+}
+
+func newV1alpha1Simulator(cluster *Cluster, anps []*v1alpha1.AdminNetworkPolicy, banp *v1alpha1.BaselineAdminNetworkPolicy) (*simulator, error) {
+	var admin, baseline []*simulatedPolicy
+	for _, anp := range anps {
+		policy, err := compileV1alpha1(anp.Name, anp.Spec.Priority, &anp.Spec.Subject)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range anp.Spec.Ingress {
+			compiled, err := compileV1alpha1Rule(Verdict(rule.Action), ingressPeersAsEgress(rule.From), rule.Ports)
+			if err != nil {
+				return nil, err
+			}
+			policy.ingress = append(policy.ingress, compiled)
+		}
+		for _, rule := range anp.Spec.Egress {
+			compiled, err := compileV1alpha1Rule(Verdict(rule.Action), rule.To, rule.Ports)
+			if err != nil {
+				return nil, err
+			}
+			policy.egress = append(policy.egress, compiled)
+		}
+		admin = append(admin, policy)
+	}
+	if banp != nil {
+		policy, err := compileV1alpha1(banp.Name, 0, &banp.Spec.Subject)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range banp.Spec.Ingress {
+			compiled, err := compileV1alpha1Rule(Verdict(rule.Action), ingressPeersAsEgress(rule.From), rule.Ports)
+			if err != nil {
+				return nil, err
+			}
+			policy.ingress = append(policy.ingress, compiled)
+		}
+		for _, rule := range banp.Spec.Egress {
+			var to []v1alpha1.AdminNetworkPolicyEgressPeer
+			for _, peer := range rule.To {
+				to = append(to, v1alpha1.AdminNetworkPolicyEgressPeer{Namespaces: peer.Namespaces, Pods: peer.Pods, Nodes: peer.Nodes, Networks: peer.Networks})
+			}
+			compiled, err := compileV1alpha1Rule(Verdict(rule.Action), to, rule.Ports)
+			if err != nil {
+				return nil, err
+			}
+			policy.egress = append(policy.egress, compiled)
+		}
+		baseline = append(baseline, policy)
+	}
+	return newSimulator(cluster, admin, baseline), nil
+}
+
+func ingressPeersAsEgress(peers []v1alpha1.AdminNetworkPolicyIngressPeer) []v1alpha1.AdminNetworkPolicyEgressPeer {
+	var egress []v1alpha1.AdminNetworkPolicyEgressPeer
+	for _, peer := range peers {
+		egress = append(egress, v1alpha1.AdminNetworkPolicyEgressPeer{Namespaces: peer.Namespaces, Pods: peer.Pods})
+	}
+	return egress
+}
+
+func compileV1alpha1(name string, priority int32, subject *v1alpha1.AdminNetworkPolicySubject) (*simulatedPolicy, error) {
+	var podSubject *podSelector
+	var err error
+	if subject.Pods != nil {
+		podSubject, err = compilePodSelector(nil, &subject.Pods.NamespaceSelector, &subject.Pods.PodSelector)
+	} else {
+		podSubject, err = compilePodSelector(subject.Namespaces, nil, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %w", name, err)
+	}
+	return &simulatedPolicy{name: name, priority: priority, subject: podSubject}, nil
+}
+
+func compileV1alpha1Rule(action Verdict, peers []v1alpha1.AdminNetworkPolicyEgressPeer, ports *[]v1alpha1.AdminNetworkPolicyPort) (*simulatedRule, error) {
+	rule := &simulatedRule{action: action}
+	for _, peer := range peers {
+		var podNamespaces, pods *metav1.LabelSelector
+		if peer.Pods != nil {
+			podNamespaces, pods = &peer.Pods.NamespaceSelector, &peer.Pods.PodSelector
+		}
+		var networks []string
+		for _, network := range peer.Networks {
+			networks = append(networks, string(network))
+		}
+		if err := rule.addPeer(peer.Namespaces, podNamespaces, pods, peer.Nodes, networks); err != nil {
+			return nil, err
+		}
+	}
+	if ports != nil {
+		rule.ports = []*simulatedPort{}
+		for _, port := range *ports {
+			switch {
+			case port.PortNumber != nil:
+				rule.ports = append(rule.ports, &simulatedPort{protocol: defaultProtocol(port.PortNumber.Protocol), start: port.PortNumber.Port, end: port.PortNumber.Port})
+			case port.PortRange != nil:
+				rule.ports = append(rule.ports, &simulatedPort{protocol: defaultProtocol(port.PortRange.Protocol), start: port.PortRange.Start, end: port.PortRange.End})
+			case port.NamedPort != nil:
+				rule.ports = append(rule.ports, &simulatedPort{name: *port.NamedPort})
+			}
+		}
+	}
+	return rule, nil
+}
+
+func newV1alpha2Simulator(cluster *Cluster, cnps []*v1alpha2.ClusterNetworkPolicy) (*simulator, error) {
+	var admin, baseline []*simulatedPolicy
+	for _, cnp := range cnps {
+		var subject *podSelector
+		var err error
+		if cnp.Spec.Subject.Pods != nil {
+			subject, err = compilePodSelector(nil, &cnp.Spec.Subject.Pods.NamespaceSelector, &cnp.Spec.Subject.Pods.PodSelector)
+		} else {
+			subject, err = compilePodSelector(cnp.Spec.Subject.Namespaces, nil, nil)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", cnp.Name, err)
+		}
+		policy := &simulatedPolicy{name: cnp.Name, priority: cnp.Spec.Priority, subject: subject}
+		for _, rule := range cnp.Spec.Ingress {
+			var to []v1alpha2.ClusterNetworkPolicyEgressPeer
+			for _, peer := range rule.From {
+				to = append(to, v1alpha2.ClusterNetworkPolicyEgressPeer{Namespaces: peer.Namespaces, Pods: peer.Pods})
+			}
+			compiled, err := compileV1alpha2Rule(rule.Action, to, rule.Protocols)
+			if err != nil {
+				return nil, err
+			}
+			policy.ingress = append(policy.ingress, compiled)
+		}
+		for _, rule := range cnp.Spec.Egress {
+			compiled, err := compileV1alpha2Rule(rule.Action, rule.To, rule.Protocols)
+			if err != nil {
+				return nil, err
+			}
+			policy.egress = append(policy.egress, compiled)
+		}
+		if cnp.Spec.Tier == v1alpha2.BaselineTier {
+			baseline = append(baseline, policy)
+		} else {
+			admin = append(admin, policy)
+		}
+	}
+	return newSimulator(cluster, admin, baseline), nil
+}
+
+func compileV1alpha2Rule(action v1alpha2.ClusterNetworkPolicyRuleAction, peers []v1alpha2.ClusterNetworkPolicyEgressPeer, protocols []v1alpha2.ClusterNetworkPolicyProtocol) (*simulatedRule, error) {
+	verdict := Verdict(action)
+	if action == v1alpha2.ClusterNetworkPolicyRuleActionAccept {
+		verdict = VerdictAllow
+	}
+	rule := &simulatedRule{action: verdict}
+	for _, peer := range peers {
+		var podNamespaces, pods *metav1.LabelSelector
+		if peer.Pods != nil {
+			podNamespaces, pods = &peer.Pods.NamespaceSelector, &peer.Pods.PodSelector
+		}
+		var networks []string
+		for _, network := range peer.Networks {
+			networks = append(networks, string(network))
+		}
+		if err := rule.addPeer(peer.Namespaces, podNamespaces, pods, peer.Nodes, networks); err != nil {
+			return nil, err
+		}
+	}
+	if protocols != nil {
+		rule.ports = []*simulatedPort{}
+		for _, protocol := range protocols {
+			switch {
+			case protocol.TCP != nil:
+				rule.ports = append(rule.ports, v1alpha2Port(v1.ProtocolTCP, protocol.TCP.DestinationPort))
+			case protocol.UDP != nil:
+				rule.ports = append(rule.ports, v1alpha2Port(v1.ProtocolUDP, protocol.UDP.DestinationPort))
+			case protocol.SCTP != nil:
+				rule.ports = append(rule.ports, v1alpha2Port(v1.ProtocolSCTP, protocol.SCTP.DestinationPort))
+			case protocol.DestinationNamedPort != "":
+				rule.ports = append(rule.ports, &simulatedPort{name: protocol.DestinationNamedPort})
+			}
+		}
+	}
+	return rule, nil
+}
+
+func v1alpha2Port(protocol v1.Protocol, port *v1alpha2.Port) *simulatedPort {
+	switch {
+	case port == nil:
+		return &simulatedPort{protocol: protocol, start: 1, end: 65535}
+	case port.Range != nil:
+		return &simulatedPort{protocol: protocol, start: port.Range.Start, end: port.Range.End}
+	default:
+		return &simulatedPort{protocol: protocol, start: port.Number, end: port.Number}
+	}
+}
+
+// addPeer adds a peer, given as its namespaces selector, the namespace and pod
+// selectors of its pods, its nodes selector or its networks; the shapes of
+// peers are the same in both versions.
+func (r *simulatedRule) addPeer(namespaces, podNamespaces, pods, nodes *metav1.LabelSelector, networks []string) error {
+	if namespaces != nil || podNamespaces != nil {
+		selector, err := compilePodSelector(namespaces, podNamespaces, pods)
+		if err != nil {
+			return err
+		}
+		r.pods = append(r.pods, selector)
+	}
+	if nodes != nil {
+		selector, err := metav1.LabelSelectorAsSelector(nodes)
+		if err != nil {
+			return err
+		}
+		r.nodes = append(r.nodes, selector)
+	}
+	for _, network := range networks {
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			return err
+		}
+		r.networks = append(r.networks, ipNet)
+	}
+	return nil
+}
+
+// compilePodSelector compiles either a namespaces selector, or the namespace
+// and pod selectors of a NamespacedPod.
+func compilePodSelector(namespaces, podNamespaces, pods *metav1.LabelSelector) (*podSelector, error) {
+	if namespaces == nil {
+		namespaces = podNamespaces
+	}
+	selector := &podSelector{}
+	var err error
+	// A nil selector matches nothing, as an unset subject does.
+	if selector.namespaces, err = metav1.LabelSelectorAsSelector(namespaces); err != nil {
+		return nil, err
+	}
+	if pods != nil {
+		if selector.pods, err = metav1.LabelSelectorAsSelector(pods); err != nil {
+			return nil, err
+		}
+	}
+	return selector, nil
+}
+
+func defaultProtocol(protocol v1.Protocol) v1.Protocol {
+	if protocol == "" {
+		return v1.ProtocolTCP
+	}
+	return protocol
+}
+
+func sortedKeys[K int32 | string](m map[K]bool) []K {
+	var keys []K
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
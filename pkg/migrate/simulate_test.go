@@ -0,0 +1,274 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/network-policy-api/apis/v1alpha1"
+	"sigs.k8s.io/network-policy-api/apis/v1alpha2"
+)
+
+func examplePolicies() ([]*v1alpha1.AdminNetworkPolicy, *v1alpha1.BaselineAdminNetworkPolicy) {
+	namedPort := "metrics"
+	anps := []*v1alpha1.AdminNetworkPolicy{{
+		ObjectMeta: metav1.ObjectMeta{Name: "isolate-db"},
+		Spec: v1alpha1.AdminNetworkPolicySpec{
+			Priority: 10,
+			Subject:  v1alpha1.AdminNetworkPolicySubject{Namespaces: namespaces("tier", "db")},
+			Ingress: []v1alpha1.AdminNetworkPolicyIngressRule{{
+				Action: v1alpha1.AdminNetworkPolicyRuleActionPass,
+				From: []v1alpha1.AdminNetworkPolicyIngressPeer{{Pods: &v1alpha1.NamespacedPod{
+					NamespaceSelector: *namespaces("tier", "app"),
+					PodSelector:       metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: metav1.LabelSelectorOpExists}}},
+				}}},
+				Ports: ports(v1alpha1.AdminNetworkPolicyPort{PortRange: &v1alpha1.PortRange{Start: 5432, End: 5433}}),
+			}, {
+				Action: v1alpha1.AdminNetworkPolicyRuleActionAllow,
+				From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: namespaces("tier", "monitoring")}},
+				Ports:  ports(v1alpha1.AdminNetworkPolicyPort{NamedPort: &namedPort}),
+			}, {
+				Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+				From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}},
+			}},
+			Egress: []v1alpha1.AdminNetworkPolicyEgressRule{{
+				Action: v1alpha1.AdminNetworkPolicyRuleActionDeny,
+				To: []v1alpha1.AdminNetworkPolicyEgressPeer{
+					{Networks: []v1alpha1.CIDR{"192.168.0.0/16"}},
+					{Nodes: namespaces("role", "control-plane")},
+				},
+				Ports: ports(v1alpha1.AdminNetworkPolicyPort{PortNumber: &v1alpha1.Port{Protocol: v1.ProtocolUDP, Port: 53}}),
+			}},
+		},
+	}}
+	banp := &v1alpha1.BaselineAdminNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.BaselineAdminNetworkPolicySpec{
+			Subject: v1alpha1.AdminNetworkPolicySubject{Namespaces: &metav1.LabelSelector{}},
+			Ingress: []v1alpha1.BaselineAdminNetworkPolicyIngressRule{{
+				Action: v1alpha1.BaselineAdminNetworkPolicyRuleActionDeny,
+				From:   []v1alpha1.AdminNetworkPolicyIngressPeer{{Namespaces: namespaces("tier", "app")}},
+			}},
+		},
+	}
+	return anps, banp
+}
+
+func TestCompareConnectivity(t *testing.T) {
+	anps, banp := examplePolicies()
+	cluster := SampleCluster(anps, banp)
+	// Namespaces: unlabelled, db, app, monitoring. Pods: unlabelled, app.
+	require.Len(t, cluster.Namespaces, 4)
+	require.Len(t, cluster.Pods, 8)
+	require.Len(t, cluster.Nodes, 2)
+
+	cnps, issues := Convert(anps, banp)
+	require.Empty(t, issues)
+	differences, err := CompareConnectivity(cluster, anps, banp, cnps)
+	require.NoError(t, err)
+	require.Empty(t, differences)
+}
+
+func TestCompareConnectivityDifferences(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cnps []*v1alpha2.ClusterNetworkPolicy)
+	}{{
+		name: "action",
+		modify: func(cnps []*v1alpha2.ClusterNetworkPolicy) {
+			cnps[0].Spec.Ingress[0].Action = v1alpha2.ClusterNetworkPolicyRuleActionAccept
+		},
+	}, {
+		name: "port range",
+		modify: func(cnps []*v1alpha2.ClusterNetworkPolicy) {
+			cnps[0].Spec.Ingress[0].Protocols[0].TCP.DestinationPort = &v1alpha2.Port{Number: 5432}
+		},
+	}, {
+		name: "protocol",
+		modify: func(cnps []*v1alpha2.ClusterNetworkPolicy) {
+			cnps[0].Spec.Egress[0].Protocols[0] = v1alpha2.ClusterNetworkPolicyProtocol{TCP: &v1alpha2.ClusterNetworkPolicyProtocolTCP{DestinationPort: &v1alpha2.Port{Number: 53}}}
+		},
+	}, {
+		name: "named port",
+		modify: func(cnps []*v1alpha2.ClusterNetworkPolicy) {
+			cnps[0].Spec.Ingress[1].Protocols[0].DestinationNamedPort = "http"
+		},
+	}, {
+		name: "nodes",
+		modify: func(cnps []*v1alpha2.ClusterNetworkPolicy) {
+			cnps[0].Spec.Egress[0].To = cnps[0].Spec.Egress[0].To[:1]
+		},
+	}, {
+		name: "tier",
+		modify: func(cnps []*v1alpha2.ClusterNetworkPolicy) {
+			cnps[1].Spec.Tier = v1alpha2.AdminTier
+		},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			anps, banp := examplePolicies()
+			cnps, _ := Convert(anps, banp)
+			tc.modify(cnps)
+			differences, err := CompareConnectivity(SampleCluster(anps, banp), anps, banp, cnps)
+			require.NoError(t, err)
+			require.NotEmpty(t, differences)
+		})
+	}
+}
+
+// TestSimulateGolden pins the verdicts of both simulators on a hand-written
+// cluster, so that a mistake shared by the v1alpha1 simulator and the
+// conversion can't hide behind their agreement.
+func TestSimulateGolden(t *testing.T) {
+	cluster := &Cluster{
+		Namespaces: []*Namespace{
+			{Name: "db", Labels: map[string]string{"tier": "db"}},
+			{Name: "app", Labels: map[string]string{"tier": "app"}},
+			{Name: "monitoring", Labels: map[string]string{"tier": "monitoring"}},
+			{Name: "other"},
+		},
+		Pods: []*Pod{
+			{Namespace: "db", Name: "postgres", IP: "10.0.0.1", Ports: []v1.ContainerPort{
+				{Name: "postgres", ContainerPort: 5432},
+				{Name: "metrics", ContainerPort: 9187},
+			}},
+			{Namespace: "app", Name: "web", Labels: map[string]string{"app": "web"}, IP: "10.0.1.1"},
+			{Namespace: "app", Name: "batch", IP: "10.0.1.2"},
+			{Namespace: "monitoring", Name: "prometheus", IP: "10.0.2.1"},
+			{Namespace: "other", Name: "client", IP: "10.0.3.1"},
+		},
+		Nodes: []*Node{
+			{Name: "control-plane", Labels: map[string]string{"role": "control-plane"}, IP: "172.18.0.2"},
+			{Name: "worker", Labels: map[string]string{"role": "worker"}, IP: "172.18.0.3"},
+		},
+	}
+	pod := func(key string) *Pod {
+		for _, p := range cluster.Pods {
+			if p.Namespace+"/"+p.Name == key {
+				return p
+			}
+		}
+		t.Fatalf("pod %s not found", key)
+		return nil
+	}
+	node := func(name string) *Node {
+		for _, n := range cluster.Nodes {
+			if n.Name == name {
+				return n
+			}
+		}
+		t.Fatalf("node %s not found", name)
+		return nil
+	}
+
+	tests := []struct {
+		name       string
+		connection *Connection
+		// ingress is only checked for connections to pods.
+		ingress Decision
+		egress  Decision
+	}{{
+		name:       "app pods with an app label pass to the baseline, which denies the app tier",
+		connection: &Connection{Source: pod("app/web"), DestinationPod: pod("db/postgres"), Protocol: v1.ProtocolTCP, Port: 5432},
+		ingress:    Decision{Admin: VerdictPass, Baseline: VerdictDeny},
+		egress:     Decision{Admin: VerdictNone, Baseline: VerdictNone},
+	}, {
+		name:       "the end of the port range is included",
+		connection: &Connection{Source: pod("app/web"), DestinationPod: pod("db/postgres"), Protocol: v1.ProtocolTCP, Port: 5433},
+		ingress:    Decision{Admin: VerdictPass, Baseline: VerdictDeny},
+		egress:     Decision{Admin: VerdictNone, Baseline: VerdictNone},
+	}, {
+		name:       "past the port range",
+		connection: &Connection{Source: pod("app/web"), DestinationPod: pod("db/postgres"), Protocol: v1.ProtocolTCP, Port: 5434},
+		ingress:    Decision{Admin: VerdictDeny, Baseline: VerdictDeny},
+		egress:     Decision{Admin: VerdictNone, Baseline: VerdictNone},
+	}, {
+		name:       "port ranges default to TCP",
+		connection: &Connection{Source: pod("app/web"), DestinationPod: pod("db/postgres"), Protocol: v1.ProtocolUDP, Port: 5432},
+		ingress:    Decision{Admin: VerdictDeny, Baseline: VerdictDeny},
+		egress:     Decision{Admin: VerdictNone, Baseline: VerdictNone},
+	}, {
+		name:       "app pods without an app label",
+		connection: &Connection{Source: pod("app/batch"), DestinationPod: pod("db/postgres"), Protocol: v1.ProtocolTCP, Port: 5432},
+		ingress:    Decision{Admin: VerdictDeny, Baseline: VerdictDeny},
+		egress:     Decision{Admin: VerdictNone, Baseline: VerdictNone},
+	}, {
+		name:       "monitoring reaches the named port",
+		connection: &Connection{Source: pod("monitoring/prometheus"), DestinationPod: pod("db/postgres"), Protocol: v1.ProtocolTCP, Port: 9187},
+		ingress:    Decision{Admin: VerdictAllow, Baseline: VerdictNone},
+		egress:     Decision{Admin: VerdictNone, Baseline: VerdictNone},
+	}, {
+		name:       "monitoring is denied other ports",
+		connection: &Connection{Source: pod("monitoring/prometheus"), DestinationPod: pod("db/postgres"), Protocol: v1.ProtocolTCP, Port: 5432},
+		ingress:    Decision{Admin: VerdictDeny, Baseline: VerdictNone},
+		egress:     Decision{Admin: VerdictNone, Baseline: VerdictNone},
+	}, {
+		name:       "the baseline denies the app tier to any namespace",
+		connection: &Connection{Source: pod("app/web"), DestinationPod: pod("app/batch"), Protocol: v1.ProtocolTCP, Port: 80},
+		ingress:    Decision{Admin: VerdictNone, Baseline: VerdictDeny},
+		egress:     Decision{Admin: VerdictNone, Baseline: VerdictNone},
+	}, {
+		name:       "no rule matches",
+		connection: &Connection{Source: pod("other/client"), DestinationPod: pod("app/web"), Protocol: v1.ProtocolTCP, Port: 80},
+		ingress:    Decision{Admin: VerdictNone, Baseline: VerdictNone},
+		egress:     Decision{Admin: VerdictNone, Baseline: VerdictNone},
+	}, {
+		name:       "db is denied DNS to control plane nodes",
+		connection: &Connection{Source: pod("db/postgres"), DestinationNode: node("control-plane"), Protocol: v1.ProtocolUDP, Port: 53},
+		egress:     Decision{Admin: VerdictDeny, Baseline: VerdictNone},
+	}, {
+		name:       "db may reach control plane nodes over TCP",
+		connection: &Connection{Source: pod("db/postgres"), DestinationNode: node("control-plane"), Protocol: v1.ProtocolTCP, Port: 53},
+		egress:     Decision{Admin: VerdictNone, Baseline: VerdictNone},
+	}, {
+		name:       "db may reach other nodes",
+		connection: &Connection{Source: pod("db/postgres"), DestinationNode: node("worker"), Protocol: v1.ProtocolUDP, Port: 53},
+		egress:     Decision{Admin: VerdictNone, Baseline: VerdictNone},
+	}, {
+		name:       "db is denied DNS to the network",
+		connection: &Connection{Source: pod("db/postgres"), DestinationIP: "192.168.1.1", Protocol: v1.ProtocolUDP, Port: 53},
+		egress:     Decision{Admin: VerdictDeny, Baseline: VerdictNone},
+	}, {
+		name:       "other pods may reach the network",
+		connection: &Connection{Source: pod("app/web"), DestinationIP: "192.168.1.1", Protocol: v1.ProtocolUDP, Port: 53},
+		egress:     Decision{Admin: VerdictNone, Baseline: VerdictNone},
+	}}
+
+	anps, banp := examplePolicies()
+	cnps, issues := Convert(anps, banp)
+	require.Empty(t, issues)
+	before, err := newV1alpha1Simulator(cluster, anps, banp)
+	require.NoError(t, err)
+	after, err := newV1alpha2Simulator(cluster, cnps)
+	require.NoError(t, err)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for version, s := range map[string]*simulator{"v1alpha1": before, "v1alpha2": after} {
+				if tc.connection.DestinationPod != nil {
+					require.Equal(t, tc.ingress, s.ingress(tc.connection), "%s ingress of %s", version, tc.connection)
+				}
+				require.Equal(t, tc.egress, s.egress(tc.connection), "%s egress of %s", version, tc.connection)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Check identifies a semantic check of a ClusterNetworkPolicy.
+type Check string
+
+const (
+	// OverlappingSubjectsCheck finds Admin tier policies with the same
+	// priority whose subjects select a common pod. Which of them applies to
+	// the pod's traffic is undefined. Only the pods which currently exist are
+	// considered.
+	OverlappingSubjectsCheck Check = "OverlappingSubjects"
+	// ShadowedRulesCheck finds rules which never match, because an earlier
+	// rule of the same policy matches all of their traffic.
+	ShadowedRulesCheck Check = "ShadowedRules"
+	// UnknownLabelKeysCheck finds selectors which require a label key that
+	// no namespace, pod or node, respectively, has. These are often typos.
+	UnknownLabelKeysCheck Check = "UnknownLabelKeys"
+)
+
+// AllChecks lists the checks in the order they're run.
+var AllChecks = []Check{OverlappingSubjectsCheck, ShadowedRulesCheck, UnknownLabelKeysCheck}
+
+// Action is what the webhook does when a check finds a problem.
+type Action string
+
+const (
+	ActionIgnore Action = "Ignore"
+	// ActionWarn admits the policy and returns the problem as an admission
+	// warning, which kubectl prints.
+	ActionWarn Action = "Warn"
+	ActionDeny Action = "Deny"
+)
+
+// Config holds the action of each check. Checks which aren't set warn.
+type Config map[Check]Action
+
+func (c Config) Action(check Check) Action {
+	if action, ok := c[check]; ok {
+		return action
+	}
+	return ActionWarn
+}
+
+// ParseConfig parses a comma-separated list of check=action pairs, e.g.
+// "OverlappingSubjects=Deny,UnknownLabelKeys=Ignore".
+func ParseConfig(s string) (Config, error) {
+	config := Config{}
+	if s == "" {
+		return config, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid check %q: expected <check>=<action>", pair)
+		}
+		check, action := Check(name), Action(value)
+		if !isCheck(check) {
+			return nil, fmt.Errorf("unknown check %q", name)
+		}
+		switch action {
+		case ActionIgnore, ActionWarn, ActionDeny:
+		default:
+			return nil, fmt.Errorf("unknown action %q for check %s: expected %s, %s or %s", value, name, ActionIgnore, ActionWarn, ActionDeny)
+		}
+		config[check] = action
+	}
+	return config, nil
+}
+
+func (c Config) String() string {
+	var pairs []string
+	for check, action := range c {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", check, action))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func isCheck(check Check) bool {
+	for _, known := range AllChecks {
+		if check == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Config
+		err      string
+	}{{
+		name:     "empty",
+		input:    "",
+		expected: Config{},
+	}, {
+		name:     "several checks",
+		input:    "OverlappingSubjects=Deny, UnknownLabelKeys=Ignore",
+		expected: Config{OverlappingSubjectsCheck: ActionDeny, UnknownLabelKeysCheck: ActionIgnore},
+	}, {
+		name:  "missing action",
+		input: "ShadowedRules",
+		err:   `invalid check "ShadowedRules": expected <check>=<action>`,
+	}, {
+		name:  "unknown check",
+		input: "Typos=Deny",
+		err:   `unknown check "Typos"`,
+	}, {
+		name:  "unknown action",
+		input: "ShadowedRules=Block",
+		err:   `unknown action "Block" for check ShadowedRules: expected Ignore, Warn or Deny`,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := ParseConfig(tc.input)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, config)
+		})
+	}
+
+	config, err := ParseConfig("UnknownLabelKeys=Ignore,OverlappingSubjects=Deny")
+	require.NoError(t, err)
+	require.Equal(t, "OverlappingSubjects=Deny,UnknownLabelKeys=Ignore", config.String())
+	require.Equal(t, ActionWarn, config.Action(ShadowedRulesCheck))
+}
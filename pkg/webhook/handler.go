@@ -0,0 +1,96 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/network-policy-api/apis/v1alpha2"
+)
+
+// ServeHTTP handles AdmissionReviews of ClusterNetworkPolicies. Findings of
+// checks which warn are returned as warnings; findings of checks which deny
+// reject the policy. If a check fails, the policy is admitted with a warning,
+// so that the webhook never blocks policies because of its own problems.
+func (v *Validator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review := &admissionv1.AdmissionReview{}
+	if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+		http.Error(w, fmt.Sprintf("unable to decode AdmissionReview: %s", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "AdmissionReview has no request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = v.review(review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		klog.Errorf("Failed to write AdmissionReview response: %v", err)
+	}
+}
+
+func (v *Validator) review(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{Allowed: true}
+	if request.Operation != admissionv1.Create && request.Operation != admissionv1.Update {
+		return response
+	}
+	cnp := &v1alpha2.ClusterNetworkPolicy{}
+	if err := json.Unmarshal(request.Object.Raw, cnp); err != nil {
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusBadRequest,
+			Reason:  metav1.StatusReasonBadRequest,
+			Message: fmt.Sprintf("unable to decode ClusterNetworkPolicy: %s", err),
+		}
+		return response
+	}
+
+	findings, err := v.Validate(cnp)
+	if err != nil {
+		klog.Errorf("Failed to validate ClusterNetworkPolicy %s: %v", cnp.Name, err)
+		response.Warnings = []string{fmt.Sprintf("semantic validation failed: %s", err)}
+		return response
+	}
+	for _, finding := range findings[ActionWarn] {
+		response.Warnings = append(response.Warnings, finding.String())
+	}
+	if denials := findings[ActionDeny]; len(denials) > 0 {
+		var messages []string
+		for _, finding := range denials {
+			messages = append(messages, finding.String())
+		}
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusForbidden,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: strings.Join(messages, "; "),
+		}
+	}
+	return response
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestServeHTTP(t *testing.T) {
+	cnp := adminPolicy("db", 10, selector("teir", "db"))
+	raw, err := json.Marshal(cnp)
+	require.NoError(t, err)
+	warning := `UnknownLabelKeys: spec.subject.namespaces: label key "teir" isn't set on any Namespace`
+
+	tests := []struct {
+		name      string
+		config    Config
+		operation admissionv1.Operation
+		allowed   bool
+		warnings  []string
+		message   string
+	}{{
+		name:      "warn",
+		config:    Config{},
+		operation: admissionv1.Create,
+		allowed:   true,
+		warnings:  []string{warning},
+	}, {
+		name:      "deny",
+		config:    Config{UnknownLabelKeysCheck: ActionDeny},
+		operation: admissionv1.Update,
+		allowed:   false,
+		message:   warning,
+	}, {
+		name:      "ignore",
+		config:    Config{UnknownLabelKeysCheck: ActionIgnore},
+		operation: admissionv1.Create,
+		allowed:   true,
+	}, {
+		name:      "delete isn't checked",
+		config:    Config{UnknownLabelKeysCheck: ActionDeny},
+		operation: admissionv1.Delete,
+		allowed:   true,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newTestValidator(t, tc.config, testCluster())
+			body, err := json.Marshal(&admissionv1.AdmissionReview{
+				TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+				Request: &admissionv1.AdmissionRequest{
+					UID:       types.UID("uid"),
+					Operation: tc.operation,
+					Object:    runtime.RawExtension{Raw: raw},
+				},
+			})
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			v.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)))
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			review := &admissionv1.AdmissionReview{}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), review))
+			require.Nil(t, review.Request)
+			require.Equal(t, types.UID("uid"), review.Response.UID)
+			require.Equal(t, tc.allowed, review.Response.Allowed)
+			require.Equal(t, tc.warnings, review.Response.Warnings)
+			if tc.message != "" {
+				require.Equal(t, tc.message, review.Response.Result.Message)
+				require.Equal(t, int32(http.StatusForbidden), review.Response.Result.Code)
+			}
+		})
+	}
+}
+
+func TestServeHTTPInvalidRequest(t *testing.T) {
+	v := newTestValidator(t, Config{}, nil)
+	recorder := httptest.NewRecorder()
+	v.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader([]byte("{"))))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
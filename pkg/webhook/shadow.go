@@ -0,0 +1,268 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"net"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"sigs.k8s.io/network-policy-api/apis/v1alpha2"
+)
+
+// shadowedRules reports the rules whose traffic is all matched by an earlier
+// rule of the policy. Coverage is decided from the rules alone, so that it
+// doesn't depend on the cluster's current pods: a selector covers another if
+// each of its requirements is also required by the other one. Shadowing which
+// only follows from the meaning of labels isn't found.
+func shadowedRules(cnp *v1alpha2.ClusterNetworkPolicy) []string {
+	var messages []string
+	for j, rule := range cnp.Spec.Ingress {
+		for i := 0; i < j; i++ {
+			earlier := cnp.Spec.Ingress[i]
+			if protocolsCover(earlier.Protocols, rule.Protocols) && ingressPeersCover(earlier.From, rule.From) {
+				messages = append(messages, shadowedMessage("ingress", j, rule.Name, i, earlier.Name))
+				break
+			}
+		}
+	}
+	for j, rule := range cnp.Spec.Egress {
+		for i := 0; i < j; i++ {
+			earlier := cnp.Spec.Egress[i]
+			if protocolsCover(earlier.Protocols, rule.Protocols) && egressPeersCover(earlier.To, rule.To) {
+				messages = append(messages, shadowedMessage("egress", j, rule.Name, i, earlier.Name))
+				break
+			}
+		}
+	}
+	return messages
+}
+
+func shadowedMessage(direction string, rule int, name string, earlier int, earlierName string) string {
+	describe := func(index int, name string) string {
+		if name == "" {
+			return fmt.Sprintf("spec.%s[%d]", direction, index)
+		}
+		return fmt.Sprintf("spec.%s[%d] (%s)", direction, index, name)
+	}
+	return fmt.Sprintf("%s: never matches, since %s matches all of its traffic", describe(rule, name), describe(earlier, earlierName))
+}
+
+func ingressPeersCover(earlier, peers []v1alpha2.ClusterNetworkPolicyIngressPeer) bool {
+	var egress, egressPeers []v1alpha2.ClusterNetworkPolicyEgressPeer
+	for _, peer := range earlier {
+		egress = append(egress, v1alpha2.ClusterNetworkPolicyEgressPeer{Namespaces: peer.Namespaces, Pods: peer.Pods})
+	}
+	for _, peer := range peers {
+		egressPeers = append(egressPeers, v1alpha2.ClusterNetworkPolicyEgressPeer{Namespaces: peer.Namespaces, Pods: peer.Pods})
+	}
+	return egressPeersCover(egress, egressPeers)
+}
+
+// egressPeersCover returns whether each peer is covered by one of the earlier
+// peers.
+func egressPeersCover(earlier, peers []v1alpha2.ClusterNetworkPolicyEgressPeer) bool {
+	for _, peer := range peers {
+		covered := false
+		for _, earlierPeer := range earlier {
+			if peerCovers(&earlierPeer, &peer) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+func peerCovers(earlier, peer *v1alpha2.ClusterNetworkPolicyEgressPeer) bool {
+	switch {
+	case peer.Namespaces != nil:
+		if earlier.Namespaces != nil {
+			return selectorCovers(earlier.Namespaces, peer.Namespaces)
+		}
+		// All pods of the namespaces.
+		return earlier.Pods != nil && selectorCovers(&earlier.Pods.NamespaceSelector, peer.Namespaces) &&
+			selectorCovers(&earlier.Pods.PodSelector, &metav1.LabelSelector{})
+	case peer.Pods != nil:
+		if earlier.Namespaces != nil {
+			return selectorCovers(earlier.Namespaces, &peer.Pods.NamespaceSelector)
+		}
+		return earlier.Pods != nil && selectorCovers(&earlier.Pods.NamespaceSelector, &peer.Pods.NamespaceSelector) &&
+			selectorCovers(&earlier.Pods.PodSelector, &peer.Pods.PodSelector)
+	case peer.Nodes != nil:
+		return earlier.Nodes != nil && selectorCovers(earlier.Nodes, peer.Nodes)
+	case len(peer.Networks) > 0:
+		for _, network := range peer.Networks {
+			if !networksCover(earlier.Networks, network) {
+				return false
+			}
+		}
+		return len(earlier.Networks) > 0
+	case len(peer.DomainNames) > 0:
+		for _, domainName := range peer.DomainNames {
+			found := false
+			for _, earlierName := range earlier.DomainNames {
+				found = found || earlierName == domainName
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		// A peer of an unknown shape.
+		return false
+	}
+}
+
+func networksCover(earlier []v1alpha2.CIDR, network v1alpha2.CIDR) bool {
+	_, ipNet, err := net.ParseCIDR(string(network))
+	if err != nil {
+		return false
+	}
+	ones, bits := ipNet.Mask.Size()
+	for _, earlierNetwork := range earlier {
+		_, earlierNet, err := net.ParseCIDR(string(earlierNetwork))
+		if err != nil {
+			continue
+		}
+		earlierOnes, earlierBits := earlierNet.Mask.Size()
+		if earlierBits == bits && earlierOnes <= ones && earlierNet.Contains(ipNet.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectorCovers returns whether every object the selector selects is also
+// selected by the earlier selector: each requirement of the earlier selector
+// must be required by the selector too, or implied by one of its
+// requirements.
+func selectorCovers(earlier, selector *metav1.LabelSelector) bool {
+	earlierSelector, err := metav1.LabelSelectorAsSelector(earlier)
+	if err != nil {
+		return false
+	}
+	compiled, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	earlierRequirements, _ := earlierSelector.Requirements()
+	requirements, _ := compiled.Requirements()
+	for _, earlierRequirement := range earlierRequirements {
+		implied := false
+		for _, requirement := range requirements {
+			if requirementImplies(requirement, earlierRequirement) {
+				implied = true
+				break
+			}
+		}
+		if !implied {
+			return false
+		}
+	}
+	return true
+}
+
+// requirementImplies returns whether labels which meet the requirement also
+// meet the implied one.
+func requirementImplies(requirement, implied labels.Requirement) bool {
+	if requirement.Key() != implied.Key() {
+		return false
+	}
+	if requirement.String() == implied.String() {
+		return true
+	}
+	values := requirement.Values()
+	switch requirement.Operator() {
+	case selection.Equals, selection.DoubleEquals, selection.In:
+		switch implied.Operator() {
+		case selection.Exists:
+			return true
+		case selection.Equals, selection.DoubleEquals, selection.In:
+			return implied.Values().IsSuperset(values)
+		case selection.NotEquals, selection.NotIn:
+			return !implied.Values().HasAny(values.UnsortedList()...)
+		}
+	}
+	return false
+}
+
+// protocolsCover returns whether each protocol is matched by one of the
+// earlier protocols. Rules without protocols match all traffic.
+func protocolsCover(earlier, protocols []v1alpha2.ClusterNetworkPolicyProtocol) bool {
+	if len(earlier) == 0 {
+		return true
+	}
+	if len(protocols) == 0 {
+		return false
+	}
+	for _, protocol := range protocols {
+		covered := false
+		for _, earlierProtocol := range earlier {
+			if protocolCovers(&earlierProtocol, &protocol) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+func protocolCovers(earlier, protocol *v1alpha2.ClusterNetworkPolicyProtocol) bool {
+	switch {
+	case protocol.TCP != nil:
+		return earlier.TCP != nil && portCovers(earlier.TCP.DestinationPort, protocol.TCP.DestinationPort)
+	case protocol.UDP != nil:
+		return earlier.UDP != nil && portCovers(earlier.UDP.DestinationPort, protocol.UDP.DestinationPort)
+	case protocol.SCTP != nil:
+		return earlier.SCTP != nil && portCovers(earlier.SCTP.DestinationPort, protocol.SCTP.DestinationPort)
+	case protocol.DestinationNamedPort != "":
+		return earlier.DestinationNamedPort == protocol.DestinationNamedPort
+	default:
+		return false
+	}
+}
+
+// portCovers compares destination ports; a nil port matches all ports.
+func portCovers(earlier, port *v1alpha2.Port) bool {
+	if earlier == nil {
+		return true
+	}
+	if port == nil {
+		return false
+	}
+	start, end := portBounds(port)
+	earlierStart, earlierEnd := portBounds(earlier)
+	return earlierStart <= start && end <= earlierEnd
+}
+
+func portBounds(port *v1alpha2.Port) (int32, int32) {
+	if port.Range != nil {
+		return port.Range.Start, port.Range.End
+	}
+	return port.Number, port.Number
+}
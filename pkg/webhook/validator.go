@@ -0,0 +1,282 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/network-policy-api/apis/v1alpha2"
+	"sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions"
+	listersv1alpha2 "sigs.k8s.io/network-policy-api/pkg/client/listers/apis/v1alpha2"
+)
+
+// Finding is a problem found by a check.
+type Finding struct {
+	Check   Check
+	Message string
+}
+
+func (f *Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Check, f.Message)
+}
+
+// Validator runs the checks of a Config against a ClusterNetworkPolicy and
+// the cluster's namespaces, pods, nodes and other ClusterNetworkPolicies.
+type Validator struct {
+	config     Config
+	namespaces corelisters.NamespaceLister
+	pods       corelisters.PodLister
+	nodes      corelisters.NodeLister
+	cnps       listersv1alpha2.ClusterNetworkPolicyLister
+	// Synced lists the informers which must be synced before validating.
+	Synced []cache.InformerSynced
+}
+
+// NewValidator creates a Validator which reads the cluster through the
+// informers. The informers must be started after the Validator is created.
+func NewValidator(config Config, kubeInformers informers.SharedInformerFactory, policyInformers externalversions.SharedInformerFactory) *Validator {
+	v := &Validator{config: config}
+	cnpInformer := policyInformers.Policy().V1alpha2().ClusterNetworkPolicies()
+	v.cnps = cnpInformer.Lister()
+	v.Synced = append(v.Synced, cnpInformer.Informer().HasSynced)
+	// Only the informers the enabled checks need are created, so that the
+	// webhook doesn't watch pods if it doesn't have to.
+	if config.Action(OverlappingSubjectsCheck) != ActionIgnore || config.Action(UnknownLabelKeysCheck) != ActionIgnore {
+		namespaceInformer := kubeInformers.Core().V1().Namespaces()
+		podInformer := kubeInformers.Core().V1().Pods()
+		v.namespaces, v.pods = namespaceInformer.Lister(), podInformer.Lister()
+		v.Synced = append(v.Synced, namespaceInformer.Informer().HasSynced, podInformer.Informer().HasSynced)
+	}
+	if config.Action(UnknownLabelKeysCheck) != ActionIgnore {
+		nodeInformer := kubeInformers.Core().V1().Nodes()
+		v.nodes = nodeInformer.Lister()
+		v.Synced = append(v.Synced, nodeInformer.Informer().HasSynced)
+	}
+	return v
+}
+
+// Validate runs the enabled checks, and returns the findings by the action
+// configured for their check.
+func (v *Validator) Validate(cnp *v1alpha2.ClusterNetworkPolicy) (map[Action][]*Finding, error) {
+	findings := map[Action][]*Finding{}
+	for _, check := range AllChecks {
+		action := v.config.Action(check)
+		if action == ActionIgnore {
+			continue
+		}
+		var messages []string
+		var err error
+		switch check {
+		case OverlappingSubjectsCheck:
+			messages, err = v.overlappingSubjects(cnp)
+		case ShadowedRulesCheck:
+			messages = shadowedRules(cnp)
+		case UnknownLabelKeysCheck:
+			messages, err = v.unknownLabelKeys(cnp)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("check %s failed: %w", check, err)
+		}
+		for _, message := range messages {
+			findings[action] = append(findings[action], &Finding{Check: check, Message: message})
+		}
+	}
+	return findings, nil
+}
+
+// overlappingSubjects reports the other Admin tier policies with the same
+// priority, whose subjects select a pod which this policy's subject selects.
+// Unlike shadowedRules, it depends on the cluster's current pods and
+// namespaces: subjects which would only overlap on pods that don't exist yet
+// aren't reported, and a policy which passes may overlap once such pods are
+// created or relabeled.
+func (v *Validator) overlappingSubjects(cnp *v1alpha2.ClusterNetworkPolicy) ([]string, error) {
+	if cnp.Spec.Tier != v1alpha2.AdminTier {
+		return nil, nil
+	}
+	others, err := v.cnps.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].Name < others[j].Name })
+
+	subject, err := compileSubject(&cnp.Spec.Subject)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := v.pods.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var messages []string
+	for _, other := range others {
+		if other.Name == cnp.Name || other.Spec.Tier != v1alpha2.AdminTier || other.Spec.Priority != cnp.Spec.Priority {
+			continue
+		}
+		otherSubject, err := compileSubject(&other.Spec.Subject)
+		if err != nil {
+			// The other policy was admitted, so its selectors are valid.
+			return nil, err
+		}
+		for _, pod := range pods {
+			namespace, err := v.namespaces.Get(pod.Namespace)
+			if err != nil {
+				continue
+			}
+			if subject.matches(namespace.Labels, pod.Labels) && otherSubject.matches(namespace.Labels, pod.Labels) {
+				messages = append(messages, fmt.Sprintf(
+					"spec.subject: selects pod %s/%s, as does ClusterNetworkPolicy %s with the same priority %d; which of them applies to its traffic is undefined",
+					pod.Namespace, pod.Name, other.Name, cnp.Spec.Priority))
+				break
+			}
+		}
+	}
+	return messages, nil
+}
+
+// podSelector selects pods by namespace labels and, if pods is set, by pod
+// labels.
+type podSelector struct {
+	namespaces labels.Selector
+	pods       labels.Selector
+}
+
+func compileSubject(subject *v1alpha2.ClusterNetworkPolicySubject) (*podSelector, error) {
+	if subject.Pods != nil {
+		return compilePodSelector(&subject.Pods.NamespaceSelector, &subject.Pods.PodSelector)
+	}
+	return compilePodSelector(subject.Namespaces, nil)
+}
+
+func compilePodSelector(namespaces, pods *metav1.LabelSelector) (*podSelector, error) {
+	selector := &podSelector{}
+	var err error
+	if selector.namespaces, err = metav1.LabelSelectorAsSelector(namespaces); err != nil {
+		return nil, err
+	}
+	if pods != nil {
+		if selector.pods, err = metav1.LabelSelectorAsSelector(pods); err != nil {
+			return nil, err
+		}
+	}
+	return selector, nil
+}
+
+func (s *podSelector) matches(namespaceLabels, podLabels map[string]string) bool {
+	return s.namespaces.Matches(labels.Set(namespaceLabels)) &&
+		(s.pods == nil || s.pods.Matches(labels.Set(podLabels)))
+}
+
+// unknownLabelKeys reports selectors which require a label key that no
+// object of the selected kind has. Keys which must not exist, or must not
+// have some values, are satisfied by objects without them, and aren't
+// reported.
+func (v *Validator) unknownLabelKeys(cnp *v1alpha2.ClusterNetworkPolicy) ([]string, error) {
+	namespaces, err := v.namespaces.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	pods, err := v.pods.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := v.nodes.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	keys := map[string]map[string]bool{"Namespace": {}, "Pod": {}, "Node": {}}
+	for _, namespace := range namespaces {
+		for key := range namespace.Labels {
+			keys["Namespace"][key] = true
+		}
+	}
+	for _, pod := range pods {
+		for key := range pod.Labels {
+			keys["Pod"][key] = true
+		}
+	}
+	for _, node := range nodes {
+		for key := range node.Labels {
+			keys["Node"][key] = true
+		}
+	}
+
+	var messages []string
+	check := func(field, kind string, selector *metav1.LabelSelector) {
+		if selector == nil {
+			return
+		}
+		for _, key := range requiredKeys(selector) {
+			if !keys[kind][key] {
+				messages = append(messages, fmt.Sprintf("%s: label key %q isn't set on any %s", field, key, kind))
+			}
+		}
+	}
+	checkPods := func(field string, pods *v1alpha2.NamespacedPod) {
+		if pods != nil {
+			check(field+".namespaceSelector", "Namespace", &pods.NamespaceSelector)
+			check(field+".podSelector", "Pod", &pods.PodSelector)
+		}
+	}
+
+	check("spec.subject.namespaces", "Namespace", cnp.Spec.Subject.Namespaces)
+	checkPods("spec.subject.pods", cnp.Spec.Subject.Pods)
+	for i, rule := range cnp.Spec.Ingress {
+		for j, peer := range rule.From {
+			field := fmt.Sprintf("spec.ingress[%d].from[%d]", i, j)
+			check(field+".namespaces", "Namespace", peer.Namespaces)
+			checkPods(field+".pods", peer.Pods)
+		}
+	}
+	for i, rule := range cnp.Spec.Egress {
+		for j, peer := range rule.To {
+			field := fmt.Sprintf("spec.egress[%d].to[%d]", i, j)
+			check(field+".namespaces", "Namespace", peer.Namespaces)
+			checkPods(field+".pods", peer.Pods)
+			check(field+".nodes", "Node", peer.Nodes)
+		}
+	}
+	return messages, nil
+}
+
+// requiredKeys lists each label key which the selector requires once, even if
+// both matchLabels and an expression require it.
+func requiredKeys(selector *metav1.LabelSelector) []string {
+	var keys []string
+	for key := range selector.MatchLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, requirement := range selector.MatchExpressions {
+		if requirement.Operator != metav1.LabelSelectorOpIn && requirement.Operator != metav1.LabelSelectorOpExists {
+			continue
+		}
+		if slices.Contains(keys, requirement.Key) {
+			continue
+		}
+		keys = append(keys, requirement.Key)
+	}
+	return keys
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/network-policy-api/apis/v1alpha2"
+	policyfake "sigs.k8s.io/network-policy-api/pkg/client/clientset/versioned/fake"
+	"sigs.k8s.io/network-policy-api/pkg/client/informers/externalversions"
+)
+
+// newTestValidator creates a Validator backed by fake clientsets holding the
+// cluster's objects and existing ClusterNetworkPolicies.
+func newTestValidator(t *testing.T, config Config, cluster []runtime.Object, cnps ...runtime.Object) *Validator {
+	kubeInformers := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(cluster...), 0)
+	policyInformers := externalversions.NewSharedInformerFactory(policyfake.NewSimpleClientset(cnps...), 0)
+	v := NewValidator(config, kubeInformers, policyInformers)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	kubeInformers.Start(ctx.Done())
+	policyInformers.Start(ctx.Done())
+	require.True(t, cache.WaitForCacheSync(ctx.Done(), v.Synced...))
+	return v
+}
+
+func testCluster() []runtime.Object {
+	return []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "db", Labels: map[string]string{"tier": "db", "env": "prod"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app", Labels: map[string]string{"tier": "app"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "db", Name: "postgres", Labels: map[string]string{"app": "postgres"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "web", Labels: map[string]string{"app": "web"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: map[string]string{"role": "worker"}}},
+	}
+}
+
+func selector(key, value string) *metav1.LabelSelector {
+	return &metav1.LabelSelector{MatchLabels: map[string]string{key: value}}
+}
+
+func adminPolicy(name string, priority int32, subject *metav1.LabelSelector) *v1alpha2.ClusterNetworkPolicy {
+	return &v1alpha2.ClusterNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1alpha2.ClusterNetworkPolicySpec{
+			Tier:     v1alpha2.AdminTier,
+			Priority: priority,
+			Subject:  v1alpha2.ClusterNetworkPolicySubject{Namespaces: subject},
+		},
+	}
+}
+
+func findingMessages(findings map[Action][]*Finding) []string {
+	var messages []string
+	for _, finding := range findings[ActionWarn] {
+		messages = append(messages, finding.String())
+	}
+	return messages
+}
+
+func TestOverlappingSubjects(t *testing.T) {
+	existing := []runtime.Object{
+		adminPolicy("prod", 10, selector("env", "prod")),
+		adminPolicy("app", 10, selector("tier", "app")),
+		adminPolicy("db-other-priority", 20, selector("tier", "db")),
+	}
+	baseline := adminPolicy("db-baseline", 10, selector("tier", "db"))
+	baseline.Spec.Tier = v1alpha2.BaselineTier
+	existing = append(existing, baseline)
+	v := newTestValidator(t, Config{ShadowedRulesCheck: ActionIgnore, UnknownLabelKeysCheck: ActionIgnore}, testCluster(), existing...)
+
+	tests := []struct {
+		name     string
+		cnp      *v1alpha2.ClusterNetworkPolicy
+		expected []string
+	}{{
+		name: "overlap",
+		cnp:  adminPolicy("db", 10, selector("tier", "db")),
+		expected: []string{
+			"OverlappingSubjects: spec.subject: selects pod db/postgres, as does ClusterNetworkPolicy prod with the same priority 10; which of them applies to its traffic is undefined",
+		},
+	}, {
+		name: "update of the same policy",
+		cnp:  adminPolicy("prod", 10, selector("tier", "db")),
+	}, {
+		name: "different priority",
+		cnp:  adminPolicy("db", 11, selector("tier", "db")),
+	}, {
+		name: "baseline tier",
+		cnp: func() *v1alpha2.ClusterNetworkPolicy {
+			cnp := adminPolicy("db", 10, selector("tier", "db"))
+			cnp.Spec.Tier = v1alpha2.BaselineTier
+			return cnp
+		}(),
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			findings, err := v.Validate(tc.cnp)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, findingMessages(findings))
+		})
+	}
+}
+
+func TestUnknownLabelKeys(t *testing.T) {
+	v := newTestValidator(t, Config{OverlappingSubjectsCheck: ActionIgnore, ShadowedRulesCheck: ActionIgnore}, testCluster())
+
+	cnp := adminPolicy("typos", 10, selector("teir", "db"))
+	cnp.Spec.Ingress = []v1alpha2.ClusterNetworkPolicyIngressRule{{
+		Action: v1alpha2.ClusterNetworkPolicyRuleActionDeny,
+		From: []v1alpha2.ClusterNetworkPolicyIngressPeer{{Pods: &v1alpha2.NamespacedPod{
+			NamespaceSelector: *selector("tier", "app"),
+			PodSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "application", Operator: metav1.LabelSelectorOpExists},
+				// Absent keys satisfy DoesNotExist.
+				{Key: "canary", Operator: metav1.LabelSelectorOpDoesNotExist},
+			}},
+		}}},
+	}}
+	cnp.Spec.Egress = []v1alpha2.ClusterNetworkPolicyEgressRule{{
+		Action: v1alpha2.ClusterNetworkPolicyRuleActionDeny,
+		To: []v1alpha2.ClusterNetworkPolicyEgressPeer{
+			{Nodes: selector("role", "worker")},
+			// Keys required twice are reported once.
+			{Nodes: &metav1.LabelSelector{
+				MatchLabels:      map[string]string{"app": "web"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: metav1.LabelSelectorOpExists}},
+			}},
+		},
+	}}
+
+	findings, err := v.Validate(cnp)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`UnknownLabelKeys: spec.subject.namespaces: label key "teir" isn't set on any Namespace`,
+		`UnknownLabelKeys: spec.ingress[0].from[0].pods.podSelector: label key "application" isn't set on any Pod`,
+		`UnknownLabelKeys: spec.egress[0].to[1].nodes: label key "app" isn't set on any Node`,
+	}, findingMessages(findings))
+}
+
+func TestShadowedRules(t *testing.T) {
+	tcp := func(port *v1alpha2.Port) v1alpha2.ClusterNetworkPolicyProtocol {
+		return v1alpha2.ClusterNetworkPolicyProtocol{TCP: &v1alpha2.ClusterNetworkPolicyProtocolTCP{DestinationPort: port}}
+	}
+	tests := []struct {
+		name     string
+		ingress  []v1alpha2.ClusterNetworkPolicyIngressRule
+		egress   []v1alpha2.ClusterNetworkPolicyEgressRule
+		expected []string
+	}{{
+		name: "all namespaces before one namespace",
+		ingress: []v1alpha2.ClusterNetworkPolicyIngressRule{{
+			Name:   "deny-all",
+			Action: v1alpha2.ClusterNetworkPolicyRuleActionDeny,
+			From:   []v1alpha2.ClusterNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}},
+		}, {
+			Name:   "allow-app",
+			Action: v1alpha2.ClusterNetworkPolicyRuleActionAccept,
+			From:   []v1alpha2.ClusterNetworkPolicyIngressPeer{{Namespaces: selector("tier", "app")}},
+		}},
+		expected: []string{"ShadowedRules: spec.ingress[1] (allow-app): never matches, since spec.ingress[0] (deny-all) matches all of its traffic"},
+	}, {
+		name: "namespace before its pods on a port range",
+		ingress: []v1alpha2.ClusterNetworkPolicyIngressRule{{
+			Action:    v1alpha2.ClusterNetworkPolicyRuleActionDeny,
+			From:      []v1alpha2.ClusterNetworkPolicyIngressPeer{{Namespaces: selector("tier", "app")}},
+			Protocols: []v1alpha2.ClusterNetworkPolicyProtocol{tcp(&v1alpha2.Port{Range: &v1alpha2.PortRange{Start: 8000, End: 9000}})},
+		}, {
+			Action: v1alpha2.ClusterNetworkPolicyRuleActionAccept,
+			From: []v1alpha2.ClusterNetworkPolicyIngressPeer{{Pods: &v1alpha2.NamespacedPod{
+				NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"tier": "app", "env": "prod"}},
+				PodSelector:       *selector("app", "web"),
+			}}},
+			Protocols: []v1alpha2.ClusterNetworkPolicyProtocol{tcp(&v1alpha2.Port{Number: 8080})},
+		}},
+		expected: []string{"ShadowedRules: spec.ingress[1]: never matches, since spec.ingress[0] matches all of its traffic"},
+	}, {
+		name: "narrower earlier rule",
+		ingress: []v1alpha2.ClusterNetworkPolicyIngressRule{{
+			Action:    v1alpha2.ClusterNetworkPolicyRuleActionDeny,
+			From:      []v1alpha2.ClusterNetworkPolicyIngressPeer{{Namespaces: &metav1.LabelSelector{}}},
+			Protocols: []v1alpha2.ClusterNetworkPolicyProtocol{tcp(&v1alpha2.Port{Number: 22})},
+		}, {
+			Action: v1alpha2.ClusterNetworkPolicyRuleActionAccept,
+			From:   []v1alpha2.ClusterNetworkPolicyIngressPeer{{Namespaces: selector("tier", "app")}},
+		}},
+	}, {
+		name: "contained network",
+		egress: []v1alpha2.ClusterNetworkPolicyEgressRule{{
+			Action: v1alpha2.ClusterNetworkPolicyRuleActionDeny,
+			To:     []v1alpha2.ClusterNetworkPolicyEgressPeer{{Networks: []v1alpha2.CIDR{"10.0.0.0/8"}}},
+		}, {
+			Action: v1alpha2.ClusterNetworkPolicyRuleActionAccept,
+			To:     []v1alpha2.ClusterNetworkPolicyEgressPeer{{Networks: []v1alpha2.CIDR{"10.1.0.0/16"}}},
+		}, {
+			Action: v1alpha2.ClusterNetworkPolicyRuleActionAccept,
+			To:     []v1alpha2.ClusterNetworkPolicyEgressPeer{{Networks: []v1alpha2.CIDR{"192.168.0.0/16"}}},
+		}},
+		expected: []string{"ShadowedRules: spec.egress[1]: never matches, since spec.egress[0] matches all of its traffic"},
+	}}
+
+	v := newTestValidator(t, Config{OverlappingSubjectsCheck: ActionIgnore, UnknownLabelKeysCheck: ActionIgnore}, nil)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cnp := adminPolicy("test", 10, &metav1.LabelSelector{})
+			cnp.Spec.Ingress = tc.ingress
+			cnp.Spec.Egress = tc.egress
+			findings, err := v.Validate(cnp)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, findingMessages(findings))
+		})
+	}
+}